@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/znas-io/asyncapi-codegen/pkg/codegen"
+	"github.com/znas-io/asyncapi-codegen/pkg/codegen/generators"
 )
 
 var (
@@ -35,6 +36,27 @@ type Flags struct {
 	// DisableFormatting states if the formatting should be disabled when
 	// writing the generated code
 	DisableFormatting bool
+
+	// Check states if the generated code should only be compared to the
+	// existing files instead of being written
+	Check bool
+
+	// Naming is the strategy used to generate golang names
+	Naming string
+
+	// DisambiguateNames states if colliding golang names should be suffixed
+	// instead of failing
+	DisambiguateNames bool
+
+	// TagCasing is the casing of the JSON struct tags
+	TagCasing string
+
+	// OmitEmpty states if optional fields should have the 'omitempty' option
+	OmitEmpty bool
+
+	// ExtraTags contains additional struct tags to generate, separated by
+	// commas, with an optional casing (i.e. "yaml,db:snake")
+	ExtraTags string
 }
 
 // ProcessFlags processes command line flags and fill the Flags structure with them.
@@ -46,6 +68,12 @@ func ProcessFlags() Flags {
 	flag.StringVar(&f.PackageName, "p", "asyncapi", "Golang package name")
 	flag.StringVar(&f.Generate, "g", "user,application,types", "Generation options")
 	flag.BoolVar(&f.DisableFormatting, "disable-formatting", false, "Disables the code generation formatting")
+	flag.BoolVar(&f.Check, "check", false, "Checks that existing files are up to date, without writing them")
+	flag.StringVar(&f.Naming, "naming", "legacy", "Naming strategy ('legacy' or 'idiomatic')")
+	flag.BoolVar(&f.DisambiguateNames, "disambiguate-names", false, "Suffixes colliding names with a number instead of failing")
+	flag.StringVar(&f.TagCasing, "tag-casing", "property", "JSON tags casing ('property', 'snake' or 'camel')")
+	flag.BoolVar(&f.OmitEmpty, "omitempty", false, "Adds 'omitempty' to the tags of optional fields")
+	flag.StringVar(&f.ExtraTags, "extra-tags", "", "Additional struct tags with optional casing (i.e. 'yaml,db:snake')")
 
 	flag.Parse()
 
@@ -58,6 +86,22 @@ func (f Flags) ToCodegenOptions() (codegen.Options, error) {
 		OutputPath:        f.OutputPath,
 		PackageName:       f.PackageName,
 		DisableFormatting: f.DisableFormatting,
+		Naming: generators.NamingOptions{
+			Strategy:     generators.NamingStrategy(f.Naming),
+			Disambiguate: f.DisambiguateNames,
+		},
+		Tags: generators.TagsOptions{
+			Casing:    generators.TagCasing(f.TagCasing),
+			OmitEmpty: f.OmitEmpty,
+		},
+	}
+
+	if f.ExtraTags != "" {
+		opt.Tags.Extra = make(map[string]generators.TagCasing)
+		for _, v := range strings.Split(f.ExtraTags, ",") {
+			key, casing, _ := strings.Cut(v, ":")
+			opt.Tags.Extra[key] = generators.TagCasing(casing)
+		}
 	}
 
 	if f.Generate != "" {
@@ -70,6 +114,8 @@ func (f Flags) ToCodegenOptions() (codegen.Options, error) {
 				opt.Generate.User = true
 			case "types":
 				opt.Generate.Types = true
+			case "mocks":
+				opt.Generate.Mocks = true
 			default:
 				return opt, fmt.Errorf("%w: %q", ErrInvalidGenerate, v)
 			}
@@ -16,12 +16,20 @@ func run() int {
 		return 255
 	}
 
+	for _, w := range cg.Warnings {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", flags.InputPath, w.Error())
+	}
+
 	opt, err := flags.ToCodegenOptions()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
 		return 255
 	}
 
+	if flags.Check {
+		return check(cg, opt)
+	}
+
 	if err := cg.Generate(opt); err != nil {
 		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
 		return 255
@@ -30,6 +38,22 @@ func run() int {
 	return 0
 }
 
+func check(cg codegen.CodeGen, opt codegen.Options) int {
+	diff, err := cg.Diff(opt)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		return 255
+	}
+
+	if diff != "" {
+		fmt.Print(diff)
+		fmt.Fprintf(os.Stderr, "generated code is not up to date with %q\n", opt.OutputPath)
+		return 1
+	}
+
+	return 0
+}
+
 func main() {
 	os.Exit(run())
 }
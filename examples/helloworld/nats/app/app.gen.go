@@ -5,17 +5,34 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
 )
 
 // AppSubscriber represents all handlers that are expecting messages for App
 type AppSubscriber interface {
-	// Hello subscribes to messages placed on the 'hello' channel
-	Hello(ctx context.Context, msg HelloMessage)
+	// Hello subscribes to messages placed on the 'hello' channel.
+	// If an error is returned, the message will be negatively acknowledged.
+	Hello(ctx context.Context, msg HelloMessage) error
 }
 
+// AppControllerInterface is the interface of AppController, that
+// can be used to replace it in tests (i.e. with AppControllerMock).
+type AppControllerInterface interface {
+	Close(ctx context.Context)
+	Shutdown(ctx context.Context) error
+	SubscribeAll(ctx context.Context, as AppSubscriber) error
+	UnsubscribeAll(ctx context.Context)
+	SubscribeHello(ctx context.Context, fn func(ctx context.Context, msg HelloMessage) error) error
+	UnsubscribeHello(ctx context.Context)
+}
+
+var _ AppControllerInterface = (*AppController)(nil)
+
 // AppController is the structure that provides publishing capabilities to the
 // developer and and connect the broker with the App
 type AppController struct {
@@ -31,10 +48,14 @@ func NewAppController(bc extensions.BrokerController, options ...ControllerOptio
 
 	// Create default controller
 	controller := controller{
-		broker:        bc,
-		subscriptions: make(map[string]extensions.BrokerChannelSubscription),
-		logger:        extensions.DummyLogger{},
-		middlewares:   make([]extensions.Middleware, 0),
+		broker:             bc,
+		subscriptions:      make(map[string]extensions.BrokerChannelSubscription),
+		subscriptionsMutex: &sync.Mutex{},
+		replies:            make(map[string]*extensions.ReplyDispatcher),
+		logger:             extensions.DummyLogger{},
+		middlewares:        make([]extensions.Middleware, 0),
+		concurrency:        1,
+		tracker:            extensions.NewHandlersTracker(),
 	}
 
 	// Apply options
@@ -49,18 +70,14 @@ func (c AppController) wrapMiddlewares(
 	middlewares []extensions.Middleware,
 	callback extensions.NextMiddleware,
 ) func(ctx context.Context, msg *extensions.BrokerMessage) error {
-	var called bool
-
 	// If there is no more middleware
 	if len(middlewares) == 0 {
 		return func(ctx context.Context, msg *extensions.BrokerMessage) error {
-			// Call the callback if it exists and it has not been called already
-			if callback != nil && !called {
-				called = true
+			// Call the callback if it exists
+			if callback != nil {
 				return callback(ctx)
 			}
 
-			// Nil can be returned, as the callback has already been called
 			return nil
 		}
 	}
@@ -69,28 +86,27 @@ func (c AppController) wrapMiddlewares(
 	next := c.wrapMiddlewares(middlewares[1:], callback)
 
 	// Wrap middleware into a check function that will call execute the middleware
-	// and call the next wrapped middleware if the returned function has not been
-	// called already
+	// and call the next wrapped middleware if it has not been called by the
+	// middleware. Note: the middleware can call it several times (i.e. to retry).
 	return func(ctx context.Context, msg *extensions.BrokerMessage) error {
-		// Call the middleware and the following if it has not been done already
-		if !called {
-			// Create the next call with the context and the message
-			nextWithArgs := func(ctx context.Context) error {
-				return next(ctx, msg)
-			}
-
-			// Call the middleware and register it as already called
+		// Create the next call with the context and the message
+		var called bool
+		nextWithArgs := func(ctx context.Context) error {
 			called = true
-			if err := middlewares[0](ctx, msg, nextWithArgs); err != nil {
-				return err
-			}
+			return next(ctx, msg)
+		}
 
-			// If next has already been called in middleware, it should not be executed again
-			return nextWithArgs(ctx)
+		// Call the middleware
+		if err := middlewares[0](ctx, msg, nextWithArgs); err != nil {
+			return err
 		}
 
-		// Nil can be returned, as the next middleware has already been called
-		return nil
+		// If next has already been called in middleware, it should not be executed again
+		if called {
+			return nil
+		}
+
+		return nextWithArgs(ctx)
 	}
 }
 
@@ -102,6 +118,16 @@ func (c AppController) executeMiddlewares(ctx context.Context, msg *extensions.B
 	return wrapped(ctx, msg)
 }
 
+// dropMessage negatively acknowledges a received message that will not be
+// handled because of the controller shutdown.
+func (c AppController) dropMessage(ctx context.Context, msg extensions.BrokerMessage) {
+	c.logger.Warning(ctx, "Message dropped on shutdown")
+	if err := msg.Nack(); err != nil {
+		c.logger.Error(ctx, err.Error())
+	}
+	c.tracker.AddDropped()
+}
+
 func addAppContextValues(ctx context.Context, path string) context.Context {
 	ctx = context.WithValue(ctx, extensions.ContextKeyIsVersion, "0.1.0")
 	ctx = context.WithValue(ctx, extensions.ContextKeyIsProvider, "app")
@@ -116,6 +142,50 @@ func (c *AppController) Close(ctx context.Context) {
 	c.logger.Info(ctx, "Closed app controller")
 }
 
+// Shutdown will gracefully stop the controller: subscriptions stop receiving new
+// messages, then it waits for the already received messages to be handled and
+// flushes the messages published on the broker (if supported).
+//
+// If the context is done before every received message has been handled, the
+// remaining messages are dropped and an error wrapping extensions.ErrMessagesDropped
+// is returned with the number of messages that were not handled.
+func (c *AppController) Shutdown(ctx context.Context) error {
+	// Remove every subscription
+	c.subscriptionsMutex.Lock()
+	subscriptions := make([]extensions.BrokerChannelSubscription, 0, len(c.subscriptions))
+	for path, sub := range c.subscriptions {
+		subscriptions = append(subscriptions, sub)
+		delete(c.subscriptions, path)
+	}
+	c.subscriptionsMutex.Unlock()
+
+	// Stop receiving new messages
+	for _, sub := range subscriptions {
+		sub.Cancel(ctx)
+	}
+
+	// Wait for received messages to be handled
+	dropped := c.tracker.Wait(ctx)
+
+	// Flush published messages
+	if flusher, ok := c.broker.(extensions.BrokerFlusher); ok {
+		if err := flusher.Flush(ctx); err != nil {
+			c.logger.Error(ctx, err.Error())
+			return err
+		}
+	}
+
+	// Report dropped messages
+	if dropped > 0 {
+		err := fmt.Errorf("%w: %d message(s) not handled before shutdown", extensions.ErrMessagesDropped, dropped)
+		c.logger.Error(ctx, err.Error())
+		return err
+	}
+
+	c.logger.Info(ctx, "Shut down app controller")
+	return nil
+}
+
 // SubscribeAll will subscribe to channels without parameters on which the app is expecting messages.
 // For channels with parameters, they should be subscribed independently.
 func (c *AppController) SubscribeAll(ctx context.Context, as AppSubscriber) error {
@@ -138,13 +208,23 @@ func (c *AppController) UnsubscribeAll(ctx context.Context) {
 // SubscribeHello will subscribe to new messages from 'hello' channel.
 //
 // Callback function 'fn' will be called each time a new message is received.
-func (c *AppController) SubscribeHello(ctx context.Context, fn func(ctx context.Context, msg HelloMessage)) error {
+// The message will be acknowledged if it returns no error, or negatively
+// acknowledged to be redelivered otherwise (if supported by the broker).
+func (c *AppController) SubscribeHello(ctx context.Context, fn func(ctx context.Context, msg HelloMessage) error) error {
 	// Get channel path
 	path := "hello"
 
 	// Set context
 	ctx = addAppContextValues(ctx, path)
 	ctx = context.WithValue(ctx, extensions.ContextKeyIsDirection, "reception")
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsAMQPChannelBinding, extensions.AMQPChannelBinding{})
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTOperationBinding, nil)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTMessageBinding, nil)
+
+	// Lock the subscriptions until this one is added, to avoid a concurrent
+	// subscription on the same channel
+	c.subscriptionsMutex.Lock()
+	defer c.subscriptionsMutex.Unlock()
 
 	// Check if there is already a subscription
 	_, exists := c.subscriptions[path]
@@ -162,11 +242,66 @@ func (c *AppController) SubscribeHello(ctx context.Context, fn func(ctx context.
 	}
 	c.logger.Info(ctx, "Subscribed to channel")
 
+	// Handle a received message through middlewares and subscription function
+	handle := func(ctx context.Context, brokerMsg extensions.BrokerMessage) {
+		// Execute middlewares before handling the message
+		if err := c.executeMiddlewares(ctx, &brokerMsg, func(ctx context.Context) error {
+			// Process message
+			msg, err := newHelloMessageFromBrokerMessage(brokerMsg)
+			if err != nil {
+				return err
+			}
+
+			// Execute the subscription function
+			return fn(ctx, msg)
+		}); err != nil {
+			c.logger.Error(ctx, err.Error())
+
+			// Terminate the message if it can't be handled, or negatively
+			// acknowledge it to get it redelivered
+			nack := brokerMsg.Nack
+			if errors.Is(err, extensions.ErrUnrecoverable) {
+				nack = brokerMsg.Term
+			}
+			if err := nack(); err != nil {
+				c.logger.Error(ctx, err.Error())
+			}
+			return
+		}
+
+		// Acknowledge the message as it has been successfully handled
+		if err := brokerMsg.Ack(); err != nil {
+			c.logger.Error(ctx, err.Error())
+		}
+	}
+
 	// Asynchronously listen to new messages and pass them to app subscriber
+	c.tracker.StartSubscription()
 	go func() {
+		// Handle messages with workers, and wait for them before leaving
+		workers := extensions.NewWorkerPool(c.concurrency)
+		defer workers.Close()
+		defer c.tracker.EndSubscription()
+
 		for {
-			// Wait for next message
-			brokerMsg, open := <-sub.MessagesChannel()
+			// Wait for next message, or drop the remaining ones on shutdown
+			var brokerMsg extensions.BrokerMessage
+			var open bool
+			select {
+			case brokerMsg, open = <-sub.MessagesChannel():
+			case <-c.tracker.Dropping():
+				for {
+					select {
+					case brokerMsg, open := <-sub.MessagesChannel():
+						if open {
+							c.dropMessage(ctx, brokerMsg)
+							continue
+						}
+					default:
+					}
+					return
+				}
+			}
 
 			// If subscription is closed and there is no more message
 			// (i.e. uninitialized message), then exit the function
@@ -175,22 +310,22 @@ func (c *AppController) SubscribeHello(ctx context.Context, fn func(ctx context.
 			}
 
 			// Set broker message to context
-			ctx = context.WithValue(ctx, extensions.ContextKeyIsBrokerMessage, brokerMsg.String())
-
-			// Execute middlewares before handling the message
-			if err := c.executeMiddlewares(ctx, &brokerMsg, func(ctx context.Context) error {
-				// Process message
-				msg, err := newHelloMessageFromBrokerMessage(brokerMsg)
-				if err != nil {
-					return err
-				}
+			msgCtx := context.WithValue(ctx, extensions.ContextKeyIsBrokerMessage, brokerMsg.String())
 
-				// Execute the subscription function
-				fn(ctx, msg)
+			// Get the ordering key of the message
+			var key string
+			if c.orderingKey != nil {
+				key = c.orderingKey(msgCtx, brokerMsg)
+			}
 
-				return nil
-			}); err != nil {
-				c.logger.Error(ctx, err.Error())
+			// Handle the message on a worker, in order with messages with the same key
+			c.tracker.Accept()
+			if !workers.SubmitUntil(c.tracker.Dropping(), key, func() {
+				defer c.tracker.Done()
+				handle(msgCtx, brokerMsg)
+			}) {
+				c.tracker.Done()
+				c.dropMessage(msgCtx, brokerMsg)
 			}
 		}
 	}()
@@ -207,8 +342,12 @@ func (c *AppController) UnsubscribeHello(ctx context.Context) {
 	// Get channel path
 	path := "hello"
 
-	// Check if there subscribers for this channel
+	// Check if there subscribers for this channel and remove it from the
+	// subscribers, so it is cancelled only once
+	c.subscriptionsMutex.Lock()
 	sub, exists := c.subscriptions[path]
+	delete(c.subscriptions, path)
+	c.subscriptionsMutex.Unlock()
 	if !exists {
 		return
 	}
@@ -219,9 +358,6 @@ func (c *AppController) UnsubscribeHello(ctx context.Context) {
 	// Stop the subscription
 	sub.Cancel(ctx)
 
-	// Remove if from the subscribers
-	delete(c.subscriptions, path)
-
 	c.logger.Info(ctx, "Unsubscribed from channel")
 }
 
@@ -235,11 +371,27 @@ type controller struct {
 	broker extensions.BrokerController
 	// subscriptions is a map of all subscriptions
 	subscriptions map[string]extensions.BrokerChannelSubscription
+	// subscriptionsMutex protects the subscriptions and replies maps, as
+	// subscriptions can be made and cancelled from different goroutines
+	subscriptionsMutex *sync.Mutex
+	// replies is a map of the reply dispatchers, by reply channel
+	replies map[string]*extensions.ReplyDispatcher
+	// requestTimeout is the maximum duration to wait for a reply to a request
+	requestTimeout time.Duration
 	// logger is the logger that will be used² to log operations on controller
 	logger extensions.Logger
 	// middlewares are the middlewares that will be executed when sending or
 	// receiving messages
 	middlewares []extensions.Middleware
+	// concurrency is the maximum number of messages handled simultaneously by
+	// each subscription
+	concurrency int
+	// orderingKey is the function returning the key of received messages, in
+	// order to handle messages with the same key in order
+	orderingKey extensions.OrderingKey
+	// tracker tracks the received messages being handled, in order to wait for
+	// them on shutdown
+	tracker *extensions.HandlersTracker
 }
 
 // ControllerOption is the type of the options that can be passed
@@ -260,6 +412,31 @@ func WithMiddlewares(middlewares ...extensions.Middleware) ControllerOption {
 	}
 }
 
+// WithConcurrency sets the maximum number of messages handled simultaneously by
+// each subscription. Default is 1, meaning that messages are handled one by one.
+func WithConcurrency(workers int) ControllerOption {
+	return func(controller *controller) {
+		controller.concurrency = workers
+	}
+}
+
+// WithOrderingKey sets the function returning the key of received messages:
+// messages with the same key are handled in order, while messages with
+// different keys can be handled concurrently (see WithConcurrency).
+func WithOrderingKey(key extensions.OrderingKey) ControllerOption {
+	return func(controller *controller) {
+		controller.orderingKey = key
+	}
+}
+
+// WithRequestTimeout sets the maximum duration to wait for the reply of a
+// request. Default is 0, meaning that it waits until the context is done.
+func WithRequestTimeout(timeout time.Duration) ControllerOption {
+	return func(controller *controller) {
+		controller.requestTimeout = timeout
+	}
+}
+
 type MessageWithCorrelationID interface {
 	CorrelationID() string
 	SetCorrelationID(id string)
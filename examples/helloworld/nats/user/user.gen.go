@@ -6,10 +6,22 @@ package main
 import (
 	"context"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
 )
 
+// UserControllerInterface is the interface of UserController, that
+// can be used to replace it in tests (i.e. with UserControllerMock).
+type UserControllerInterface interface {
+	Close(ctx context.Context)
+	Shutdown(ctx context.Context) error
+	PublishHello(ctx context.Context, msg HelloMessage) error
+}
+
+var _ UserControllerInterface = (*UserController)(nil)
+
 // UserController is the structure that provides publishing capabilities to the
 // developer and and connect the broker with the User
 type UserController struct {
@@ -25,10 +37,14 @@ func NewUserController(bc extensions.BrokerController, options ...ControllerOpti
 
 	// Create default controller
 	controller := controller{
-		broker:        bc,
-		subscriptions: make(map[string]extensions.BrokerChannelSubscription),
-		logger:        extensions.DummyLogger{},
-		middlewares:   make([]extensions.Middleware, 0),
+		broker:             bc,
+		subscriptions:      make(map[string]extensions.BrokerChannelSubscription),
+		subscriptionsMutex: &sync.Mutex{},
+		replies:            make(map[string]*extensions.ReplyDispatcher),
+		logger:             extensions.DummyLogger{},
+		middlewares:        make([]extensions.Middleware, 0),
+		concurrency:        1,
+		tracker:            extensions.NewHandlersTracker(),
 	}
 
 	// Apply options
@@ -43,18 +59,14 @@ func (c UserController) wrapMiddlewares(
 	middlewares []extensions.Middleware,
 	callback extensions.NextMiddleware,
 ) func(ctx context.Context, msg *extensions.BrokerMessage) error {
-	var called bool
-
 	// If there is no more middleware
 	if len(middlewares) == 0 {
 		return func(ctx context.Context, msg *extensions.BrokerMessage) error {
-			// Call the callback if it exists and it has not been called already
-			if callback != nil && !called {
-				called = true
+			// Call the callback if it exists
+			if callback != nil {
 				return callback(ctx)
 			}
 
-			// Nil can be returned, as the callback has already been called
 			return nil
 		}
 	}
@@ -63,28 +75,27 @@ func (c UserController) wrapMiddlewares(
 	next := c.wrapMiddlewares(middlewares[1:], callback)
 
 	// Wrap middleware into a check function that will call execute the middleware
-	// and call the next wrapped middleware if the returned function has not been
-	// called already
+	// and call the next wrapped middleware if it has not been called by the
+	// middleware. Note: the middleware can call it several times (i.e. to retry).
 	return func(ctx context.Context, msg *extensions.BrokerMessage) error {
-		// Call the middleware and the following if it has not been done already
-		if !called {
-			// Create the next call with the context and the message
-			nextWithArgs := func(ctx context.Context) error {
-				return next(ctx, msg)
-			}
-
-			// Call the middleware and register it as already called
+		// Create the next call with the context and the message
+		var called bool
+		nextWithArgs := func(ctx context.Context) error {
 			called = true
-			if err := middlewares[0](ctx, msg, nextWithArgs); err != nil {
-				return err
-			}
+			return next(ctx, msg)
+		}
+
+		// Call the middleware
+		if err := middlewares[0](ctx, msg, nextWithArgs); err != nil {
+			return err
+		}
 
-			// If next has already been called in middleware, it should not be executed again
-			return nextWithArgs(ctx)
+		// If next has already been called in middleware, it should not be executed again
+		if called {
+			return nil
 		}
 
-		// Nil can be returned, as the next middleware has already been called
-		return nil
+		return nextWithArgs(ctx)
 	}
 }
 
@@ -96,6 +107,16 @@ func (c UserController) executeMiddlewares(ctx context.Context, msg *extensions.
 	return wrapped(ctx, msg)
 }
 
+// dropMessage negatively acknowledges a received message that will not be
+// handled because of the controller shutdown.
+func (c UserController) dropMessage(ctx context.Context, msg extensions.BrokerMessage) {
+	c.logger.Warning(ctx, "Message dropped on shutdown")
+	if err := msg.Nack(); err != nil {
+		c.logger.Error(ctx, err.Error())
+	}
+	c.tracker.AddDropped()
+}
+
 func addUserContextValues(ctx context.Context, path string) context.Context {
 	ctx = context.WithValue(ctx, extensions.ContextKeyIsVersion, "0.1.0")
 	ctx = context.WithValue(ctx, extensions.ContextKeyIsProvider, "user")
@@ -107,6 +128,50 @@ func (c *UserController) Close(ctx context.Context) {
 	// Unsubscribing remaining channels
 }
 
+// Shutdown will gracefully stop the controller: subscriptions stop receiving new
+// messages, then it waits for the already received messages to be handled and
+// flushes the messages published on the broker (if supported).
+//
+// If the context is done before every received message has been handled, the
+// remaining messages are dropped and an error wrapping extensions.ErrMessagesDropped
+// is returned with the number of messages that were not handled.
+func (c *UserController) Shutdown(ctx context.Context) error {
+	// Remove every subscription
+	c.subscriptionsMutex.Lock()
+	subscriptions := make([]extensions.BrokerChannelSubscription, 0, len(c.subscriptions))
+	for path, sub := range c.subscriptions {
+		subscriptions = append(subscriptions, sub)
+		delete(c.subscriptions, path)
+	}
+	c.subscriptionsMutex.Unlock()
+
+	// Stop receiving new messages
+	for _, sub := range subscriptions {
+		sub.Cancel(ctx)
+	}
+
+	// Wait for received messages to be handled
+	dropped := c.tracker.Wait(ctx)
+
+	// Flush published messages
+	if flusher, ok := c.broker.(extensions.BrokerFlusher); ok {
+		if err := flusher.Flush(ctx); err != nil {
+			c.logger.Error(ctx, err.Error())
+			return err
+		}
+	}
+
+	// Report dropped messages
+	if dropped > 0 {
+		err := fmt.Errorf("%w: %d message(s) not handled before shutdown", extensions.ErrMessagesDropped, dropped)
+		c.logger.Error(ctx, err.Error())
+		return err
+	}
+
+	c.logger.Info(ctx, "Shut down user controller")
+	return nil
+}
+
 // PublishHello will publish messages to 'hello' channel
 func (c *UserController) PublishHello(ctx context.Context, msg HelloMessage) error {
 	// Get channel path
@@ -115,6 +180,10 @@ func (c *UserController) PublishHello(ctx context.Context, msg HelloMessage) err
 	// Set context
 	ctx = addUserContextValues(ctx, path)
 	ctx = context.WithValue(ctx, extensions.ContextKeyIsDirection, "publication")
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsAMQPChannelBinding, extensions.AMQPChannelBinding{})
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTOperationBinding, nil)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTMessageBinding, nil)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsCorrelationID, nil)
 
 	// Convert to BrokerMessage
 	brokerMsg, err := msg.toBrokerMessage()
@@ -141,11 +210,27 @@ type controller struct {
 	broker extensions.BrokerController
 	// subscriptions is a map of all subscriptions
 	subscriptions map[string]extensions.BrokerChannelSubscription
+	// subscriptionsMutex protects the subscriptions and replies maps, as
+	// subscriptions can be made and cancelled from different goroutines
+	subscriptionsMutex *sync.Mutex
+	// replies is a map of the reply dispatchers, by reply channel
+	replies map[string]*extensions.ReplyDispatcher
+	// requestTimeout is the maximum duration to wait for a reply to a request
+	requestTimeout time.Duration
 	// logger is the logger that will be used² to log operations on controller
 	logger extensions.Logger
 	// middlewares are the middlewares that will be executed when sending or
 	// receiving messages
 	middlewares []extensions.Middleware
+	// concurrency is the maximum number of messages handled simultaneously by
+	// each subscription
+	concurrency int
+	// orderingKey is the function returning the key of received messages, in
+	// order to handle messages with the same key in order
+	orderingKey extensions.OrderingKey
+	// tracker tracks the received messages being handled, in order to wait for
+	// them on shutdown
+	tracker *extensions.HandlersTracker
 }
 
 // ControllerOption is the type of the options that can be passed
@@ -166,6 +251,31 @@ func WithMiddlewares(middlewares ...extensions.Middleware) ControllerOption {
 	}
 }
 
+// WithConcurrency sets the maximum number of messages handled simultaneously by
+// each subscription. Default is 1, meaning that messages are handled one by one.
+func WithConcurrency(workers int) ControllerOption {
+	return func(controller *controller) {
+		controller.concurrency = workers
+	}
+}
+
+// WithOrderingKey sets the function returning the key of received messages:
+// messages with the same key are handled in order, while messages with
+// different keys can be handled concurrently (see WithConcurrency).
+func WithOrderingKey(key extensions.OrderingKey) ControllerOption {
+	return func(controller *controller) {
+		controller.orderingKey = key
+	}
+}
+
+// WithRequestTimeout sets the maximum duration to wait for the reply of a
+// request. Default is 0, meaning that it waits until the context is done.
+func WithRequestTimeout(timeout time.Duration) ControllerOption {
+	return func(controller *controller) {
+		controller.requestTimeout = timeout
+	}
+}
+
 type MessageWithCorrelationID interface {
 	CorrelationID() string
 	SetCorrelationID(id string)
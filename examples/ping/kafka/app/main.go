@@ -16,7 +16,7 @@ type Subscriber struct {
 	Controller *AppController
 }
 
-func (s Subscriber) Ping(ctx context.Context, req PingMessage) {
+func (s Subscriber) Ping(ctx context.Context, req PingMessage) error {
 	// Generate a pong message, set as a response of the request
 	resp := NewPongMessage()
 	resp.SetAsResponseFrom(&req)
@@ -25,10 +25,7 @@ func (s Subscriber) Ping(ctx context.Context, req PingMessage) {
 
 	// Publish the pong message
 	// Note: it will indefinitely wait to publish as context has no timeout
-	err := s.Controller.PublishPong(ctx, resp)
-	if err != nil {
-		panic(err)
-	}
+	return s.Controller.PublishPong(ctx, resp)
 }
 
 func main() {
@@ -39,6 +36,7 @@ func main() {
 		kafka.WithLogger(logger),       // Attach an internal logger
 		kafka.WithGroupID("ping-apps"), // Change group id
 	)
+	defer broker.Close()
 
 	// Create a new app controller
 	ctrl, err := NewAppController(
@@ -18,6 +18,7 @@ func main() {
 		kafka.WithLogger(logger),        // Attach an internal logger
 		kafka.WithGroupID("ping-users"), // Change group id
 	)
+	defer broker.Close()
 
 	// Create a new user controller
 	ctrl, err := NewUserController(
@@ -6,7 +6,9 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
@@ -16,10 +18,27 @@ import (
 
 // UserSubscriber represents all handlers that are expecting messages for User
 type UserSubscriber interface {
-	// Pong subscribes to messages placed on the 'pong' channel
-	Pong(ctx context.Context, msg PongMessage)
+	// Pong subscribes to messages placed on the 'pong' channel.
+	// If an error is returned, the message will be negatively acknowledged.
+	Pong(ctx context.Context, msg PongMessage) error
 }
 
+// UserControllerInterface is the interface of UserController, that
+// can be used to replace it in tests (i.e. with UserControllerMock).
+type UserControllerInterface interface {
+	Close(ctx context.Context)
+	Shutdown(ctx context.Context) error
+	SubscribeAll(ctx context.Context, as UserSubscriber) error
+	UnsubscribeAll(ctx context.Context)
+	SubscribePong(ctx context.Context, fn func(ctx context.Context, msg PongMessage) error) error
+	UnsubscribePong(ctx context.Context)
+	PublishPing(ctx context.Context, msg PingMessage) error
+	RequestPing(ctx context.Context, msg PingMessage) (PongMessage, error)
+	WaitForPong(ctx context.Context, publishMsg MessageWithCorrelationID, pub func(ctx context.Context) error) (PongMessage, error)
+}
+
+var _ UserControllerInterface = (*UserController)(nil)
+
 // UserController is the structure that provides publishing capabilities to the
 // developer and and connect the broker with the User
 type UserController struct {
@@ -35,10 +54,14 @@ func NewUserController(bc extensions.BrokerController, options ...ControllerOpti
 
 	// Create default controller
 	controller := controller{
-		broker:        bc,
-		subscriptions: make(map[string]extensions.BrokerChannelSubscription),
-		logger:        extensions.DummyLogger{},
-		middlewares:   make([]extensions.Middleware, 0),
+		broker:             bc,
+		subscriptions:      make(map[string]extensions.BrokerChannelSubscription),
+		subscriptionsMutex: &sync.Mutex{},
+		replies:            make(map[string]*extensions.ReplyDispatcher),
+		logger:             extensions.DummyLogger{},
+		middlewares:        make([]extensions.Middleware, 0),
+		concurrency:        1,
+		tracker:            extensions.NewHandlersTracker(),
 	}
 
 	// Apply options
@@ -53,18 +76,14 @@ func (c UserController) wrapMiddlewares(
 	middlewares []extensions.Middleware,
 	callback extensions.NextMiddleware,
 ) func(ctx context.Context, msg *extensions.BrokerMessage) error {
-	var called bool
-
 	// If there is no more middleware
 	if len(middlewares) == 0 {
 		return func(ctx context.Context, msg *extensions.BrokerMessage) error {
-			// Call the callback if it exists and it has not been called already
-			if callback != nil && !called {
-				called = true
+			// Call the callback if it exists
+			if callback != nil {
 				return callback(ctx)
 			}
 
-			// Nil can be returned, as the callback has already been called
 			return nil
 		}
 	}
@@ -73,28 +92,27 @@ func (c UserController) wrapMiddlewares(
 	next := c.wrapMiddlewares(middlewares[1:], callback)
 
 	// Wrap middleware into a check function that will call execute the middleware
-	// and call the next wrapped middleware if the returned function has not been
-	// called already
+	// and call the next wrapped middleware if it has not been called by the
+	// middleware. Note: the middleware can call it several times (i.e. to retry).
 	return func(ctx context.Context, msg *extensions.BrokerMessage) error {
-		// Call the middleware and the following if it has not been done already
-		if !called {
-			// Create the next call with the context and the message
-			nextWithArgs := func(ctx context.Context) error {
-				return next(ctx, msg)
-			}
-
-			// Call the middleware and register it as already called
+		// Create the next call with the context and the message
+		var called bool
+		nextWithArgs := func(ctx context.Context) error {
 			called = true
-			if err := middlewares[0](ctx, msg, nextWithArgs); err != nil {
-				return err
-			}
+			return next(ctx, msg)
+		}
+
+		// Call the middleware
+		if err := middlewares[0](ctx, msg, nextWithArgs); err != nil {
+			return err
+		}
 
-			// If next has already been called in middleware, it should not be executed again
-			return nextWithArgs(ctx)
+		// If next has already been called in middleware, it should not be executed again
+		if called {
+			return nil
 		}
 
-		// Nil can be returned, as the next middleware has already been called
-		return nil
+		return nextWithArgs(ctx)
 	}
 }
 
@@ -106,6 +124,141 @@ func (c UserController) executeMiddlewares(ctx context.Context, msg *extensions.
 	return wrapped(ctx, msg)
 }
 
+// dropMessage negatively acknowledges a received message that will not be
+// handled because of the controller shutdown.
+func (c UserController) dropMessage(ctx context.Context, msg extensions.BrokerMessage) {
+	c.logger.Warning(ctx, "Message dropped on shutdown")
+	if err := msg.Nack(); err != nil {
+		c.logger.Error(ctx, err.Error())
+	}
+	c.tracker.AddDropped()
+}
+
+// replyDispatcher returns the dispatcher of the replies received on the channel,
+// subscribing to it on first use. The subscription is then kept for next requests.
+//
+// The bindings function sets the bindings of the reply channel in the context
+// of the subscription.
+func (c *UserController) replyDispatcher(
+	path string,
+	correlationID extensions.CorrelationIDGetter,
+	bindings func(ctx context.Context) context.Context,
+) (*extensions.ReplyDispatcher, error) {
+	c.subscriptionsMutex.Lock()
+	defer c.subscriptionsMutex.Unlock()
+
+	// Use the existing dispatcher if it still receives replies
+	if d, exists := c.replies[path]; exists && !d.Closed() {
+		return d, nil
+	}
+
+	// Set context, independently from the request context as the subscription
+	// is kept for next requests
+	ctx := addUserContextValues(context.Background(), path)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsDirection, "reception")
+	ctx = bindings(ctx)
+
+	// Receive the replies on every instance, as only the one that published
+	// the request expects its reply
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsDeliveryMode, extensions.DeliveryModeBroadcast)
+
+	// Subscribe to broker channel
+	sub, err := c.broker.Subscribe(ctx, path)
+	if err != nil {
+		c.logger.Error(ctx, err.Error())
+		return nil, err
+	}
+	c.logger.Info(ctx, "Subscribed to reply channel")
+
+	// Dispatch the replies to the requests
+	d := extensions.NewReplyDispatcher(ctx, sub, correlationID, c.logger)
+	c.replies[path] = d
+
+	return d, nil
+}
+
+// requestWithRequester publishes the request with the broker native request/reply,
+// through middlewares, and returns the reply.
+func (c *UserController) requestWithRequester(
+	ctx context.Context,
+	requester extensions.Requester,
+	path, replyPath, correlationID string,
+	brokerMsg extensions.BrokerMessage,
+) (extensions.BrokerMessage, error) {
+	// Set context
+	ctx = addUserContextValues(ctx, path)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsDirection, "publication")
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsCorrelationID, correlationID)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsBrokerMessage, brokerMsg.String())
+
+	// Send the request on event-broker through middlewares and get the reply
+	var reply extensions.BrokerMessage
+	err := c.executeMiddlewares(ctx, &brokerMsg, func(ctx context.Context) error {
+		var err error
+		reply, err = requester.Request(ctx, path, brokerMsg, replyPath)
+		return err
+	})
+	if err != nil {
+		c.logger.Error(ctx, "Reply not received: "+err.Error())
+		return extensions.BrokerMessage{}, err
+	}
+
+	return reply, nil
+}
+
+// requestWithReplyChannel publishes the request with the publication function
+// and waits for the reply with the same correlation ID on the reply channel.
+func (c *UserController) requestWithReplyChannel(
+	ctx context.Context,
+	replyPath, correlationID string,
+	replyCorrelationID extensions.CorrelationIDGetter,
+	replyBindings func(ctx context.Context) context.Context,
+	pub func(ctx context.Context) error,
+) (extensions.BrokerMessage, error) {
+	// Get the dispatcher of the replies
+	replies, err := c.replyDispatcher(replyPath, replyCorrelationID, replyBindings)
+	if err != nil {
+		return extensions.BrokerMessage{}, err
+	}
+
+	// Expect the reply before publishing the request, to not miss it
+	pending, err := replies.Expect(correlationID)
+	if err != nil {
+		c.logger.Error(ctx, err.Error())
+		return extensions.BrokerMessage{}, err
+	}
+	defer pending.Cancel()
+
+	// Publish the request
+	if err := pub(ctx); err != nil {
+		return extensions.BrokerMessage{}, err
+	}
+
+	// Wait for the reply
+	brokerMsg, err := pending.Wait(ctx)
+	if err != nil {
+		c.logger.Error(addUserContextValues(ctx, replyPath), "Reply not received: "+err.Error())
+		return extensions.BrokerMessage{}, err
+	}
+
+	return brokerMsg, nil
+}
+
+// closeReplyDispatchers cancels the reply subscriptions.
+func (c *UserController) closeReplyDispatchers(ctx context.Context) {
+	c.subscriptionsMutex.Lock()
+	dispatchers := make([]*extensions.ReplyDispatcher, 0, len(c.replies))
+	for path, d := range c.replies {
+		dispatchers = append(dispatchers, d)
+		delete(c.replies, path)
+	}
+	c.subscriptionsMutex.Unlock()
+
+	for _, d := range dispatchers {
+		d.Close(ctx)
+	}
+}
+
 func addUserContextValues(ctx context.Context, path string) context.Context {
 	ctx = context.WithValue(ctx, extensions.ContextKeyIsVersion, "1.0.0")
 	ctx = context.WithValue(ctx, extensions.ContextKeyIsProvider, "user")
@@ -116,10 +269,56 @@ func addUserContextValues(ctx context.Context, path string) context.Context {
 func (c *UserController) Close(ctx context.Context) {
 	// Unsubscribing remaining channels
 	c.UnsubscribeAll(ctx)
+	c.closeReplyDispatchers(ctx)
 
 	c.logger.Info(ctx, "Closed user controller")
 }
 
+// Shutdown will gracefully stop the controller: subscriptions stop receiving new
+// messages, then it waits for the already received messages to be handled and
+// flushes the messages published on the broker (if supported).
+//
+// If the context is done before every received message has been handled, the
+// remaining messages are dropped and an error wrapping extensions.ErrMessagesDropped
+// is returned with the number of messages that were not handled.
+func (c *UserController) Shutdown(ctx context.Context) error {
+	// Remove every subscription
+	c.subscriptionsMutex.Lock()
+	subscriptions := make([]extensions.BrokerChannelSubscription, 0, len(c.subscriptions))
+	for path, sub := range c.subscriptions {
+		subscriptions = append(subscriptions, sub)
+		delete(c.subscriptions, path)
+	}
+	c.subscriptionsMutex.Unlock()
+
+	// Stop receiving new messages
+	for _, sub := range subscriptions {
+		sub.Cancel(ctx)
+	}
+	c.closeReplyDispatchers(ctx)
+
+	// Wait for received messages to be handled
+	dropped := c.tracker.Wait(ctx)
+
+	// Flush published messages
+	if flusher, ok := c.broker.(extensions.BrokerFlusher); ok {
+		if err := flusher.Flush(ctx); err != nil {
+			c.logger.Error(ctx, err.Error())
+			return err
+		}
+	}
+
+	// Report dropped messages
+	if dropped > 0 {
+		err := fmt.Errorf("%w: %d message(s) not handled before shutdown", extensions.ErrMessagesDropped, dropped)
+		c.logger.Error(ctx, err.Error())
+		return err
+	}
+
+	c.logger.Info(ctx, "Shut down user controller")
+	return nil
+}
+
 // SubscribeAll will subscribe to channels without parameters on which the app is expecting messages.
 // For channels with parameters, they should be subscribed independently.
 func (c *UserController) SubscribeAll(ctx context.Context, as UserSubscriber) error {
@@ -142,13 +341,23 @@ func (c *UserController) UnsubscribeAll(ctx context.Context) {
 // SubscribePong will subscribe to new messages from 'pong' channel.
 //
 // Callback function 'fn' will be called each time a new message is received.
-func (c *UserController) SubscribePong(ctx context.Context, fn func(ctx context.Context, msg PongMessage)) error {
+// The message will be acknowledged if it returns no error, or negatively
+// acknowledged to be redelivered otherwise (if supported by the broker).
+func (c *UserController) SubscribePong(ctx context.Context, fn func(ctx context.Context, msg PongMessage) error) error {
 	// Get channel path
 	path := "pong"
 
 	// Set context
 	ctx = addUserContextValues(ctx, path)
 	ctx = context.WithValue(ctx, extensions.ContextKeyIsDirection, "reception")
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsAMQPChannelBinding, extensions.AMQPChannelBinding{})
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTOperationBinding, nil)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTMessageBinding, nil)
+
+	// Lock the subscriptions until this one is added, to avoid a concurrent
+	// subscription on the same channel
+	c.subscriptionsMutex.Lock()
+	defer c.subscriptionsMutex.Unlock()
 
 	// Check if there is already a subscription
 	_, exists := c.subscriptions[path]
@@ -166,11 +375,71 @@ func (c *UserController) SubscribePong(ctx context.Context, fn func(ctx context.
 	}
 	c.logger.Info(ctx, "Subscribed to channel")
 
+	// Handle a received message through middlewares and subscription function
+	handle := func(ctx context.Context, brokerMsg extensions.BrokerMessage) {
+		// Execute middlewares before handling the message
+		if err := c.executeMiddlewares(ctx, &brokerMsg, func(ctx context.Context) error {
+			// Process message
+			msg, err := newPongMessageFromBrokerMessage(brokerMsg)
+			if err != nil {
+				return err
+			}
+
+			// Add correlation ID to context if it exists
+			if id := msg.CorrelationID(); id != "" {
+				ctx = context.WithValue(ctx, extensions.ContextKeyIsCorrelationID, id)
+			}
+
+			// Execute the subscription function
+			return fn(ctx, msg)
+		}); err != nil {
+			c.logger.Error(ctx, err.Error())
+
+			// Terminate the message if it can't be handled, or negatively
+			// acknowledge it to get it redelivered
+			nack := brokerMsg.Nack
+			if errors.Is(err, extensions.ErrUnrecoverable) {
+				nack = brokerMsg.Term
+			}
+			if err := nack(); err != nil {
+				c.logger.Error(ctx, err.Error())
+			}
+			return
+		}
+
+		// Acknowledge the message as it has been successfully handled
+		if err := brokerMsg.Ack(); err != nil {
+			c.logger.Error(ctx, err.Error())
+		}
+	}
+
 	// Asynchronously listen to new messages and pass them to app subscriber
+	c.tracker.StartSubscription()
 	go func() {
+		// Handle messages with workers, and wait for them before leaving
+		workers := extensions.NewWorkerPool(c.concurrency)
+		defer workers.Close()
+		defer c.tracker.EndSubscription()
+
 		for {
-			// Wait for next message
-			brokerMsg, open := <-sub.MessagesChannel()
+			// Wait for next message, or drop the remaining ones on shutdown
+			var brokerMsg extensions.BrokerMessage
+			var open bool
+			select {
+			case brokerMsg, open = <-sub.MessagesChannel():
+			case <-c.tracker.Dropping():
+				for {
+					select {
+					case brokerMsg, open := <-sub.MessagesChannel():
+						if open {
+							c.dropMessage(ctx, brokerMsg)
+							continue
+						}
+					default:
+					}
+					return
+				}
+			}
 
 			// If subscription is closed and there is no more message
 			// (i.e. uninitialized message), then exit the function
@@ -179,27 +448,26 @@ func (c *UserController) SubscribePong(ctx context.Context, fn func(ctx context.
 			}
 
 			// Set broker message to context
-			ctx = context.WithValue(ctx, extensions.ContextKeyIsBrokerMessage, brokerMsg.String())
-
-			// Execute middlewares before handling the message
-			if err := c.executeMiddlewares(ctx, &brokerMsg, func(ctx context.Context) error {
-				// Process message
-				msg, err := newPongMessageFromBrokerMessage(brokerMsg)
-				if err != nil {
-					return err
-				}
+			msgCtx := context.WithValue(ctx, extensions.ContextKeyIsBrokerMessage, brokerMsg.String())
 
-				// Add correlation ID to context if it exists
-				if id := msg.CorrelationID(); id != "" {
-					ctx = context.WithValue(ctx, extensions.ContextKeyIsCorrelationID, id)
+			// Get the ordering key of the message
+			var key string
+			if c.orderingKey != nil {
+				// Add correlation ID to context if it exists, as it can be used as key
+				if msg, err := newPongMessageFromBrokerMessage(brokerMsg); err == nil && msg.CorrelationID() != "" {
+					msgCtx = context.WithValue(msgCtx, extensions.ContextKeyIsCorrelationID, msg.CorrelationID())
 				}
+				key = c.orderingKey(msgCtx, brokerMsg)
+			}
 
-				// Execute the subscription function
-				fn(ctx, msg)
-
-				return nil
-			}); err != nil {
-				c.logger.Error(ctx, err.Error())
+			// Handle the message on a worker, in order with messages with the same key
+			c.tracker.Accept()
+			if !workers.SubmitUntil(c.tracker.Dropping(), key, func() {
+				defer c.tracker.Done()
+				handle(msgCtx, brokerMsg)
+			}) {
+				c.tracker.Done()
+				c.dropMessage(msgCtx, brokerMsg)
 			}
 		}
 	}()
@@ -216,8 +484,12 @@ func (c *UserController) UnsubscribePong(ctx context.Context) {
 	// Get channel path
 	path := "pong"
 
-	// Check if there subscribers for this channel
+	// Check if there subscribers for this channel and remove it from the
+	// subscribers, so it is cancelled only once
+	c.subscriptionsMutex.Lock()
 	sub, exists := c.subscriptions[path]
+	delete(c.subscriptions, path)
+	c.subscriptionsMutex.Unlock()
 	if !exists {
 		return
 	}
@@ -228,9 +500,6 @@ func (c *UserController) UnsubscribePong(ctx context.Context) {
 	// Stop the subscription
 	sub.Cancel(ctx)
 
-	// Remove if from the subscribers
-	delete(c.subscriptions, path)
-
 	c.logger.Info(ctx, "Unsubscribed from channel")
 }
 
@@ -247,6 +516,9 @@ func (c *UserController) PublishPing(ctx context.Context, msg PingMessage) error
 	// Set context
 	ctx = addUserContextValues(ctx, path)
 	ctx = context.WithValue(ctx, extensions.ContextKeyIsDirection, "publication")
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsAMQPChannelBinding, extensions.AMQPChannelBinding{})
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTOperationBinding, nil)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTMessageBinding, nil)
 	ctx = context.WithValue(ctx, extensions.ContextKeyIsCorrelationID, msg.CorrelationID())
 
 	// Convert to BrokerMessage
@@ -264,6 +536,81 @@ func (c *UserController) PublishPing(ctx context.Context, msg PingMessage) error
 	})
 }
 
+// RequestPing will publish a request on 'ping' channel and wait for
+// the reply on 'pong' channel, with the same correlation ID.
+//
+// The reply channel is subscribed on the first request and the subscription is
+// kept for next requests, until the controller is closed.
+//
+// A timeout can be set in context or with WithRequestTimeout option to avoid
+// blocking operation, if needed.
+func (c *UserController) RequestPing(ctx context.Context, msg PingMessage) (PongMessage, error) {
+	// Set correlation ID if it does not exist
+	if id := msg.CorrelationID(); id == "" {
+		msg.SetCorrelationID(uuid.New().String())
+	}
+
+	// Set the request timeout if there is one
+	if c.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.requestTimeout)
+		defer cancel()
+	}
+
+	// Get channels paths
+	reqPath := "ping"
+	path := "pong"
+
+	// Send the request and wait for the reply, with the broker native
+	// request/reply if it is supported, or through the reply channel otherwise
+	var brokerMsg extensions.BrokerMessage
+	if requester, ok := c.broker.(extensions.Requester); ok {
+		reqMsg, err := msg.toBrokerMessage()
+		if err != nil {
+			return PongMessage{}, err
+		}
+
+		brokerMsg, err = c.requestWithRequester(ctx, requester, reqPath, path, msg.CorrelationID(), reqMsg)
+		if err != nil {
+			return PongMessage{}, err
+		}
+	} else {
+		var err error
+		brokerMsg, err = c.requestWithReplyChannel(ctx, path, msg.CorrelationID(),
+			func(brokerMsg extensions.BrokerMessage) (string, error) {
+				msg, err := newPongMessageFromBrokerMessage(brokerMsg)
+				return msg.CorrelationID(), err
+			},
+			func(ctx context.Context) context.Context {
+				ctx = context.WithValue(ctx, extensions.ContextKeyIsAMQPChannelBinding, extensions.AMQPChannelBinding{})
+				ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTOperationBinding, nil)
+				ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTMessageBinding, nil)
+				return ctx
+			},
+			func(ctx context.Context) error {
+				return c.PublishPing(ctx, msg)
+			})
+		if err != nil {
+			return PongMessage{}, err
+		}
+	}
+
+	// Set context with received values
+	msgCtx := addUserContextValues(ctx, path)
+	msgCtx = context.WithValue(msgCtx, extensions.ContextKeyIsBrokerMessage, brokerMsg.String())
+	msgCtx = context.WithValue(msgCtx, extensions.ContextKeyIsDirection, "reception")
+	msgCtx = context.WithValue(msgCtx, extensions.ContextKeyIsCorrelationID, msg.CorrelationID())
+
+	// Execute middlewares before returning
+	if err := c.executeMiddlewares(msgCtx, &brokerMsg, nil); err != nil {
+		return PongMessage{}, err
+	}
+
+	// Return the reply to the caller from the broker message that could have
+	// been modified by middlewares
+	return newPongMessageFromBrokerMessage(brokerMsg)
+}
+
 // WaitForPong will wait for a specific message by its correlation ID.
 //
 // The pub function is the publication function that should be used to send the message.
@@ -276,6 +623,9 @@ func (c *UserController) WaitForPong(ctx context.Context, publishMsg MessageWith
 
 	// Set context
 	ctx = addUserContextValues(ctx, path)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsAMQPChannelBinding, extensions.AMQPChannelBinding{})
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTOperationBinding, nil)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTMessageBinding, nil)
 
 	// Subscribe to broker channel
 	sub, err := c.broker.Subscribe(ctx, path)
@@ -311,6 +661,11 @@ func (c *UserController) WaitForPong(ctx context.Context, publishMsg MessageWith
 				return PongMessage{}, extensions.ErrSubscriptionCanceled
 			}
 
+			// Acknowledge the message as it is only read by this function
+			if err := brokerMsg.Ack(); err != nil {
+				c.logger.Error(ctx, err.Error())
+			}
+
 			// Get new message
 			msg, err := newPongMessageFromBrokerMessage(brokerMsg)
 			if err != nil {
@@ -352,11 +707,27 @@ type controller struct {
 	broker extensions.BrokerController
 	// subscriptions is a map of all subscriptions
 	subscriptions map[string]extensions.BrokerChannelSubscription
+	// subscriptionsMutex protects the subscriptions and replies maps, as
+	// subscriptions can be made and cancelled from different goroutines
+	subscriptionsMutex *sync.Mutex
+	// replies is a map of the reply dispatchers, by reply channel
+	replies map[string]*extensions.ReplyDispatcher
+	// requestTimeout is the maximum duration to wait for a reply to a request
+	requestTimeout time.Duration
 	// logger is the logger that will be used² to log operations on controller
 	logger extensions.Logger
 	// middlewares are the middlewares that will be executed when sending or
 	// receiving messages
 	middlewares []extensions.Middleware
+	// concurrency is the maximum number of messages handled simultaneously by
+	// each subscription
+	concurrency int
+	// orderingKey is the function returning the key of received messages, in
+	// order to handle messages with the same key in order
+	orderingKey extensions.OrderingKey
+	// tracker tracks the received messages being handled, in order to wait for
+	// them on shutdown
+	tracker *extensions.HandlersTracker
 }
 
 // ControllerOption is the type of the options that can be passed
@@ -377,6 +748,31 @@ func WithMiddlewares(middlewares ...extensions.Middleware) ControllerOption {
 	}
 }
 
+// WithConcurrency sets the maximum number of messages handled simultaneously by
+// each subscription. Default is 1, meaning that messages are handled one by one.
+func WithConcurrency(workers int) ControllerOption {
+	return func(controller *controller) {
+		controller.concurrency = workers
+	}
+}
+
+// WithOrderingKey sets the function returning the key of received messages:
+// messages with the same key are handled in order, while messages with
+// different keys can be handled concurrently (see WithConcurrency).
+func WithOrderingKey(key extensions.OrderingKey) ControllerOption {
+	return func(controller *controller) {
+		controller.orderingKey = key
+	}
+}
+
+// WithRequestTimeout sets the maximum duration to wait for the reply of a
+// request. Default is 0, meaning that it waits until the context is done.
+func WithRequestTimeout(timeout time.Duration) ControllerOption {
+	return func(controller *controller) {
+		controller.requestTimeout = timeout
+	}
+}
+
 type MessageWithCorrelationID interface {
 	CorrelationID() string
 	SetCorrelationID(id string)
@@ -396,7 +792,7 @@ type PingMessage struct {
 	// Headers will be used to fill the message headers
 	Headers struct {
 		// Description: Correlation ID set by user
-		CorrelationId *string `json:"correlation_id"`
+		CorrelationId *string `json:"correlationId"`
 	}
 
 	// Payload will be inserted in the message payload
@@ -485,7 +881,7 @@ type PongMessage struct {
 	// Headers will be used to fill the message headers
 	Headers struct {
 		// Description: Correlation ID set by user on corresponding request
-		CorrelationId *string `json:"correlation_id"`
+		CorrelationId *string `json:"correlationId"`
 	}
 
 	// Payload will be inserted in the message payload
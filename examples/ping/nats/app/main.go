@@ -16,7 +16,7 @@ type ServerSubscriber struct {
 	Controller *AppController
 }
 
-func (s ServerSubscriber) Ping(ctx context.Context, req PingMessage) {
+func (s ServerSubscriber) Ping(ctx context.Context, req PingMessage) error {
 	// Generate a pong message, set as a response of the request
 	resp := NewPongMessage()
 	resp.SetAsResponseFrom(&req)
@@ -25,10 +25,7 @@ func (s ServerSubscriber) Ping(ctx context.Context, req PingMessage) {
 
 	// Publish the pong message
 	// Note: it will indefinitely wait to publish as context has no timeout
-	err := s.Controller.PublishPong(ctx, resp)
-	if err != nil {
-		panic(err)
-	}
+	return s.Controller.PublishPong(ctx, resp)
 }
 
 func main() {
@@ -0,0 +1,79 @@
+package asyncapi
+
+// MessageBindings is a representation of the corresponding asyncapi object filled
+// from an asyncapi specification that will be used to generate code.
+// Source: https://www.asyncapi.com/docs/reference/specification/v2.6.0#messageBindingsObject
+type MessageBindings struct {
+	Kafka *KafkaMessageBinding `json:"kafka"`
+	MQTT  *MQTTMessageBinding  `json:"mqtt"`
+}
+
+// KafkaMessageBinding is a representation of the corresponding asyncapi object
+// filled from an asyncapi specification that will be used to generate code.
+// Source: https://github.com/asyncapi/bindings/tree/master/kafka#message-binding-object
+type KafkaMessageBinding struct {
+	Key            *Schema `json:"key"`
+	BindingVersion string  `json:"bindingVersion"`
+}
+
+// MQTTMessageBinding is a representation of the corresponding asyncapi object
+// filled from an asyncapi specification that will be used to generate code.
+// Source: https://github.com/asyncapi/bindings/tree/master/mqtt#message-binding-object
+type MQTTMessageBinding struct {
+	PayloadFormatIndicator byte   `json:"payloadFormatIndicator"`
+	ContentType            string `json:"contentType"`
+	BindingVersion         string `json:"bindingVersion"`
+}
+
+// OperationBindings is a representation of the corresponding asyncapi object
+// filled from an asyncapi specification that will be used to generate code.
+// Source: https://www.asyncapi.com/docs/reference/specification/v2.6.0#operationBindingsObject
+type OperationBindings struct {
+	MQTT *MQTTOperationBinding `json:"mqtt"`
+}
+
+// MQTTOperationBinding is a representation of the corresponding asyncapi object
+// filled from an asyncapi specification that will be used to generate code.
+// Source: https://github.com/asyncapi/bindings/tree/master/mqtt#operation-binding-object
+type MQTTOperationBinding struct {
+	QoS            int    `json:"qos"`
+	Retain         bool   `json:"retain"`
+	BindingVersion string `json:"bindingVersion"`
+}
+
+// ChannelBindings is a representation of the corresponding asyncapi object filled
+// from an asyncapi specification that will be used to generate code.
+// Source: https://www.asyncapi.com/docs/reference/specification/v2.6.0#channelBindingsObject
+type ChannelBindings struct {
+	AMQP *AMQPChannelBinding `json:"amqp"`
+}
+
+// AMQPChannelBinding is a representation of the corresponding asyncapi object
+// filled from an asyncapi specification that will be used to generate code.
+// Source: https://github.com/asyncapi/bindings/tree/master/amqp#channel-binding-object
+type AMQPChannelBinding struct {
+	Is             string        `json:"is"`
+	Exchange       *AMQPExchange `json:"exchange"`
+	Queue          *AMQPQueue    `json:"queue"`
+	BindingVersion string        `json:"bindingVersion"`
+}
+
+// AMQPExchange is the exchange of an AMQP channel binding, used when the
+// channel is a routing key.
+type AMQPExchange struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Durable    bool   `json:"durable"`
+	AutoDelete bool   `json:"autoDelete"`
+	VHost      string `json:"vhost"`
+}
+
+// AMQPQueue is the queue of an AMQP channel binding, used when the channel is
+// a queue.
+type AMQPQueue struct {
+	Name       string `json:"name"`
+	Durable    bool   `json:"durable"`
+	Exclusive  bool   `json:"exclusive"`
+	AutoDelete bool   `json:"autoDelete"`
+	VHost      string `json:"vhost"`
+}
@@ -12,12 +12,28 @@ import (
 type Channel struct {
 	Parameters map[string]*Parameter `json:"parameters"`
 
-	Subscribe *Operation `json:"subscribe"`
-	Publish   *Operation `json:"publish"`
+	Subscribe *Operation       `json:"subscribe"`
+	Publish   *Operation       `json:"publish"`
+	Bindings  *ChannelBindings `json:"bindings"`
+
+	// Extensions
+	ExtGoName       string `json:"x-go-name"`
+	ExtDeliveryMode string `json:"x-delivery-mode"`
 
 	// Non AsyncAPI fields
 	Name string `json:"-"`
 	Path string `json:"-"`
+
+	// GoName is the golang name used for the channel operations
+	GoName string `json:"-"`
+	// ParametersGoName is the golang type name of the channel parameters
+	ParametersGoName string `json:"-"`
+	// PublishReply is the channel on which the replies to the 'publish'
+	// operation messages are sent, if set with the x-reply extension
+	PublishReply *Channel `json:"-"`
+	// SubscribeReply is the channel on which the replies to the 'subscribe'
+	// operation messages are sent, if set with the x-reply extension
+	SubscribeReply *Channel `json:"-"`
 }
 
 // Process processes the Channel to make it ready for code generation.
@@ -44,6 +60,14 @@ func (c *Channel) Process(path string, spec Specification) {
 	for n, p := range c.Parameters {
 		p.Process(n, spec)
 	}
+
+	// Set reply channels
+	if c.Publish != nil && c.Publish.ExtReply != nil {
+		c.PublishReply = spec.Channels[c.Publish.ExtReply.Channel]
+	}
+	if c.Subscribe != nil && c.Subscribe.ExtReply != nil {
+		c.SubscribeReply = spec.Channels[c.Subscribe.ExtReply.Channel]
+	}
 }
 
 // GetChannelMessage will return the channel message
@@ -11,8 +11,15 @@ type Extensions struct {
 	// Setting custom Go type when generating schemas
 	ExtGoType string `json:"x-go-type"`
 
+	// Setting custom Go name when generating schemas or struct fields
+	ExtGoName string `json:"x-go-name"`
+
 	ExtCustomTag string `json:"x-custom-tag"`
 
+	// Setting additional struct tags (i.e. 'validate') or overriding generated
+	// ones when generating struct fields
+	ExtGoTags map[string]string `json:"x-go-tags"`
+
 	// Setting custom import statements for ExtGoType
 	ExtGoTypeImport *GoTypeImportExtension `json:"x-go-type-import"`
 }
@@ -50,21 +50,48 @@ const (
 // from an asyncapi specification that will be used to generate code.
 // Source: https://www.asyncapi.com/docs/reference/specification/v2.6.0#messageObject
 type Message struct {
-	Description   string         `json:"description"`
-	Headers       *Schema        `json:"headers"`
-	OneOf         []*Message     `json:"oneOf"`
-	Payload       *Schema        `json:"payload"`
-	CorrelationID *CorrelationID `json:"correlationID"`
-	Reference     string         `json:"$ref"`
+	MessageID     string           `json:"messageId"`
+	MachineName   string           `json:"name"`
+	Description   string           `json:"description"`
+	Headers       *Schema          `json:"headers"`
+	OneOf         []*Message       `json:"oneOf"`
+	Payload       *Schema          `json:"payload"`
+	CorrelationID *CorrelationID   `json:"correlationID"`
+	Bindings      *MessageBindings `json:"bindings"`
+	Reference     string           `json:"$ref"`
+
+	// --- Extensions ----------------------------------------------------------
+	ExtGoName string        `json:"x-go-name"`
+	ExtKey    *KeyExtension `json:"x-key"`
 
 	// --- Non AsyncAPI fields -------------------------------------------------
 	Name        string   `json:"-"`
 	ReferenceTo *Message `json:"-"`
 
+	// GoName is the golang type name of the message
+	GoName string `json:"-"`
+
 	// CorrelationIDLocation will indicate where the correlation id is
 	// According to: https://www.asyncapi.com/docs/reference/specification/v2.6.0#correlationIDObject
 	CorrelationIDLocation string `json:"-"`
 	CorrelationIDRequired bool   `json:"-"`
+
+	// KeyLocation will indicate where the message key is, if set with 'x-key'
+	KeyLocation string `json:"-"`
+	KeyRequired bool   `json:"-"`
+	// KeyIsString is true if the message key field is a Go string
+	KeyIsString bool `json:"-"`
+	// KeyField is true if the message key is a field of the message, as it is
+	// only set by the Kafka message binding and not with 'x-key'
+	KeyField bool `json:"-"`
+}
+
+// KeyExtension specifies the field of the message used as message key by
+// brokers supporting it (i.e. Kafka), with the same syntax as correlation IDs.
+// For example, KeyExtension{Location: "$message.payload#/id"} means that the
+// 'id' field of the payload is the message key.
+type KeyExtension struct {
+	Location string `json:"location"`
 }
 
 // Process processes the Message to make it ready for code generation.
@@ -97,6 +124,54 @@ func (msg *Message) Process(name string, spec Specification) {
 	msg.createCorrelationIDFieldIfMissing()
 	msg.CorrelationIDLocation = msg.getCorrelationIDLocation(spec)
 	msg.CorrelationIDRequired = msg.isCorrelationIDRequired()
+
+	// Process message key
+	msg.processKey(spec)
+}
+
+func (msg *Message) processKey(spec Specification) {
+	switch {
+	case msg.ExtKey != nil && msg.ExtKey.Location != "":
+		field, required := msg.FieldSchema(spec, msg.ExtKey.Location)
+		if field == nil {
+			return
+		}
+
+		msg.KeyLocation = msg.ExtKey.Location
+		msg.KeyRequired = required
+		msg.KeyIsString = field.Type == MessageTypeIsString.String() && field.Format == "" && field.ExtGoType == ""
+	case msg.Bindings != nil && msg.Bindings.Kafka != nil && msg.Bindings.Kafka.Key != nil:
+		msg.KeyField = true
+	}
+}
+
+// FieldSchema returns the schema of the message field at the location (i.e.
+// '$message.payload#/id'), following references, and if the field is required.
+// It returns nil if there is no such field.
+func (msg Message) FieldSchema(spec Specification, location string) (field *Schema, required bool) {
+	// Get the top level schema
+	var parent *Schema
+	switch {
+	case strings.HasPrefix(location, "$message.header#/"):
+		parent = msg.Headers
+	case strings.HasPrefix(location, "$message.payload#/"):
+		parent = msg.Payload
+	default:
+		return nil, false
+	}
+
+	// Go down the path to the field
+	_, path, _ := strings.Cut(location, "#/")
+	for _, name := range strings.Split(path, "/") {
+		if parent == nil {
+			return nil, false
+		}
+
+		field, required = parent.property(spec, name)
+		parent = field
+	}
+
+	return field.resolve(spec), required
 }
 
 func (msg Message) getCorrelationIDLocation(spec Specification) string {
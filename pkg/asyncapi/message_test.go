@@ -148,3 +148,36 @@ func (suite *MessageSuite) TestIsCorrelationIDRequiredWithEmptyMessage() {
 	// Check if true
 	suite.Require().False(msg.isCorrelationIDRequired())
 }
+
+func (suite *MessageSuite) TestFieldSchema() {
+	// Set message with a field from a reference, merged with 'allOf'
+	spec := Specification{
+		Components: Components{
+			Schemas: map[string]*Schema{
+				"Entity": {
+					Required:   []string{"id"},
+					Properties: map[string]*Schema{"id": {Type: "integer"}},
+				},
+			},
+		},
+	}
+	msg := Message{
+		Payload: &Schema{
+			Properties: map[string]*Schema{
+				"entity": {AllOf: []*Schema{{Reference: "#/components/schemas/Entity"}}},
+			},
+		},
+	}
+
+	// Check that the field is found
+	field, required := msg.FieldSchema(spec, "$message.payload#/entity/id")
+	suite.Require().NotNil(field)
+	suite.Require().Equal("integer", field.Type)
+	suite.Require().True(required)
+
+	// Check that missing fields are not found
+	field, _ = msg.FieldSchema(spec, "$message.payload#/entity/unknown")
+	suite.Require().Nil(field)
+	field, _ = msg.FieldSchema(spec, "$message.header#/entity")
+	suite.Require().Nil(field)
+}
@@ -4,6 +4,18 @@ package asyncapi
 // from an asyncapi specification that will be used to generate code.
 // Source: https://www.asyncapi.com/docs/reference/specification/v2.6.0#operationObject
 type Operation struct {
-	OperationID string  `json:"operationId"`
-	Message     Message `json:"message"`
+	OperationID string             `json:"operationId"`
+	Message     Message            `json:"message"`
+	Bindings    *OperationBindings `json:"bindings"`
+
+	// Extensions
+	ExtReply *ReplyExtension `json:"x-reply"`
+}
+
+// ReplyExtension specifies the channel on which the replies to the messages
+// of an operation are sent, matched by their correlation ID.
+// For example, ReplyExtension{Channel: "pong"} on the 'ping' channel operation
+// means that replies to 'ping' messages are sent on the 'pong' channel.
+type ReplyExtension struct {
+	Channel string `json:"channel"`
 }
@@ -9,9 +9,15 @@ type Parameter struct {
 	Location    string  `json:"location"`
 	Reference   string  `json:"$ref"`
 
+	// Extensions
+	ExtGoName string `json:"x-go-name"`
+
 	// Non AsyncAPI fields
 	Name        string     `json:"-"`
 	ReferenceTo *Parameter `json:"-"`
+
+	// GoName is the golang field name of the parameter
+	GoName string `json:"-"`
 }
 
 // Process processes the Parameter structure to make it ready for code generation.
@@ -24,6 +24,10 @@ type Schema struct {
 	ReferenceTo *Schema `json:"-"`
 	IsRequired  bool    `json:"-"`
 
+	// GoName is the golang name of the schema: the type name if this is a
+	// component schema, or the field name if this is a property
+	GoName string `json:"-"`
+
 	// Embedded extended fields
 	Extensions
 }
@@ -88,6 +92,42 @@ func (a Schema) IsFieldRequired(field string) bool {
 	return utils.IsInSlice(a.Required, field)
 }
 
+// resolve returns the schema referenced by the schema, or the schema itself.
+func (a *Schema) resolve(spec Specification) *Schema {
+	if a == nil || a.Reference == "" {
+		return a
+	}
+
+	if a.ReferenceTo != nil {
+		return a.ReferenceTo
+	}
+
+	return spec.ReferenceSchema(a.Reference)
+}
+
+// property returns the schema of a property, also looking into the schemas
+// that are merged with this one, and if the property is required.
+func (a *Schema) property(spec Specification, name string) (property *Schema, required bool) {
+	a = a.resolve(spec)
+	if a == nil {
+		return nil, false
+	}
+
+	if p, exists := a.Properties[name]; exists {
+		return p, a.IsFieldRequired(name)
+	}
+
+	for _, schemas := range [][]*Schema{a.AllOf, a.AnyOf, a.OneOf} {
+		for _, s := range schemas {
+			if p, required := s.property(spec, name); p != nil {
+				return p, required
+			}
+		}
+	}
+
+	return nil, false
+}
+
 func (a *Schema) referenceFrom(ref []string) *Schema {
 	if len(ref) == 0 {
 		return a
@@ -0,0 +1,48 @@
+package codegen
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// Diff generates the code in memory and compares it with the existing files,
+// without writing anything. It returns a unified diff of every file that differs
+// from what would be generated, or an empty string if everything is up to date.
+func (cg CodeGen) Diff(opt Options) (string, error) {
+	files, err := cg.render(opt)
+	if err != nil {
+		return "", err
+	}
+
+	var diff string
+	for _, f := range files {
+		// Get current content, considering a missing file as an empty one
+		current, err := os.ReadFile(f.Path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+
+		// Skip files that are up to date
+		if string(current) == f.Content {
+			continue
+		}
+
+		// Generate the diff between current and generated content
+		fileDiff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(string(current)),
+			B:        difflib.SplitLines(f.Content),
+			FromFile: f.Path,
+			ToFile:   f.Path + " (generated)",
+			Context:  3,
+		})
+		if err != nil {
+			return "", err
+		}
+		diff += fileDiff
+	}
+
+	return diff, nil
+}
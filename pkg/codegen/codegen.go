@@ -3,6 +3,7 @@ package codegen
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"runtime/debug"
 
 	"github.com/znas-io/asyncapi-codegen/pkg/asyncapi"
@@ -15,6 +16,14 @@ type CodeGen struct {
 	Specification asyncapi.Specification
 	ModulePath    string
 	ModuleVersion string
+
+	// Generators are the custom generators that will be executed in addition
+	// to the main file generation
+	Generators []Generator
+
+	// Warnings are the anomalies detected in the specification that don't
+	// prevent the code generation
+	Warnings ValidationErrors
 }
 
 // New creates a new code generation structure that can be used to generate code.
@@ -37,14 +46,78 @@ func New(spec asyncapi.Specification) CodeGen {
 	}
 }
 
+// RegisterGenerators adds custom generators that will be executed when
+// generating code, in addition to the main file generation.
+func (cg *CodeGen) RegisterGenerators(gens ...Generator) {
+	cg.Generators = append(cg.Generators, gens...)
+}
+
 // Generate generates code from the code generation structure, that have already
 // processed the AsyncAPI file when creating it.
 func (cg CodeGen) Generate(opt Options) error {
-	content, err := cg.generateImports(opt)
+	files, err := cg.render(opt)
 	if err != nil {
 		return err
 	}
 
+	for _, f := range files {
+		if err := os.WriteFile(f.Path, []byte(f.Content), 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (cg CodeGen) render(opt Options) ([]File, error) {
+	// Set golang names
+	if err := generators.ResolveNames(&cg.Specification, opt.Naming); err != nil {
+		return nil, err
+	}
+
+	// Generate main file
+	content, err := cg.generateMainFile(opt)
+	if err != nil {
+		return nil, err
+	}
+	files := []File{{Path: opt.OutputPath, Content: content}}
+
+	// Generate files from custom generators
+	customFiles, err := cg.generateCustomFiles(opt)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, customFiles...)
+
+	// Format files
+	for i, f := range files {
+		if files[i].Content, err = formatFile(f, opt); err != nil {
+			return nil, fmt.Errorf("failed to format %q: %w", f.Path, err)
+		}
+	}
+
+	return files, nil
+}
+
+func (cg CodeGen) generateMainFile(opt Options) (string, error) {
+	content, err := cg.generateImports(opt)
+	if err != nil {
+		return "", err
+	}
+
+	// Get sides for mocks, before they are consumed
+	mocksSides := make([]generators.Side, 0, 2)
+	if opt.Generate.Application {
+		mocksSides = append(mocksSides, generators.SideIsApplication)
+	}
+	if opt.Generate.User {
+		mocksSides = append(mocksSides, generators.SideIsUser)
+	}
+	if opt.Generate.Mocks && len(mocksSides) == 0 {
+		// Mocks use the controllers interfaces, generated with their side
+		return "", fmt.Errorf("%w: mocks require the application or user side to be generated", ErrMissingSide)
+	}
+
 	for remainingParts, part := true, ""; remainingParts; part = "" {
 		switch {
 		case opt.Generate.Application:
@@ -54,35 +127,71 @@ func (cg CodeGen) Generate(opt Options) error {
 			part, err = cg.generateUser()
 			opt.Generate.User = false
 		case opt.Generate.Types:
-			part, err = cg.generateTypes()
+			part, err = cg.generateTypes(opt.Tags)
 			opt.Generate.Types = false
+		case opt.Generate.Mocks:
+			part, err = cg.generateMocks(mocksSides)
+			opt.Generate.Mocks = false
 		default:
 			remainingParts = false
 		}
 
 		if err != nil {
-			return err
+			return "", err
 		}
 
 		content += part
 	}
 
-	var fileContent []byte
-	if !opt.DisableFormatting {
-		fileContent, err = imports.Process("", []byte(content), &imports.Options{
-			TabWidth:  8,
-			TabIndent: true,
-			Comments:  true,
-			Fragment:  true,
-		})
+	return content, nil
+}
+
+func (cg CodeGen) generateCustomFiles(opt Options) ([]File, error) {
+	files := make([]File, 0)
+	paths := map[string]bool{filepath.Clean(opt.OutputPath): true}
+
+	for _, gen := range cg.Generators {
+		genFiles, err := gen.Generate(cg.Specification, opt)
 		if err != nil {
-			return err
+			return nil, err
+		}
+
+		for _, f := range genFiles {
+			// Set the path relative to the main file directory
+			if !filepath.IsAbs(f.Path) {
+				f.Path = filepath.Join(filepath.Dir(opt.OutputPath), f.Path)
+			}
+
+			// Check that the file is not already generated
+			if paths[filepath.Clean(f.Path)] {
+				return nil, fmt.Errorf("%w: %q", ErrDuplicateFile, f.Path)
+			}
+			paths[filepath.Clean(f.Path)] = true
+
+			files = append(files, f)
 		}
-	} else {
-		fileContent = []byte(content)
 	}
 
-	return os.WriteFile(opt.OutputPath, fileContent, 0644)
+	return files, nil
+}
+
+func formatFile(f File, opt Options) (string, error) {
+	// Only format golang files, and only if formatting is not disabled
+	if opt.DisableFormatting || filepath.Ext(f.Path) != ".go" {
+		return f.Content, nil
+	}
+
+	content, err := imports.Process("", []byte(f.Content), &imports.Options{
+		TabWidth:  8,
+		TabIndent: true,
+		Comments:  true,
+		Fragment:  true,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return string(content), nil
 }
 
 func (cg CodeGen) generateImports(opts Options) (string, error) {
@@ -99,8 +208,12 @@ func (cg CodeGen) generateImports(opts Options) (string, error) {
 	}.Generate()
 }
 
-func (cg CodeGen) generateTypes() (string, error) {
-	return generators.TypesGenerator{Specification: cg.Specification}.Generate()
+func (cg CodeGen) generateTypes(tags generators.TagsOptions) (string, error) {
+	return generators.TypesGenerator{Specification: cg.Specification, Tags: tags}.Generate()
+}
+
+func (cg CodeGen) generateMocks(sides []generators.Side) (string, error) {
+	return generators.NewMocksGenerator(sides, cg.Specification).Generate()
 }
 
 func (cg CodeGen) generateApp() (string, error) {
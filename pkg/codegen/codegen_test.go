@@ -0,0 +1,223 @@
+package codegen
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/znas-io/asyncapi-codegen/pkg/asyncapi"
+	"github.com/znas-io/asyncapi-codegen/pkg/codegen/generators"
+)
+
+func TestCodeGenSuite(t *testing.T) {
+	suite.Run(t, new(CodeGenSuite))
+}
+
+type CodeGenSuite struct {
+	suite.Suite
+}
+
+type generatorFunc func(spec asyncapi.Specification, opt Options) ([]File, error)
+
+func (fn generatorFunc) Generate(spec asyncapi.Specification, opt Options) ([]File, error) {
+	return fn(spec, opt)
+}
+
+func (suite *CodeGenSuite) TestGenerateWithCustomGenerator() {
+	dir := suite.T().TempDir()
+
+	// Create codegen with a custom generator
+	cg := New(asyncapi.Specification{Info: asyncapi.Info{Version: "1.2.3"}})
+	cg.RegisterGenerators(generatorFunc(func(spec asyncapi.Specification, opt Options) ([]File, error) {
+		return []File{{
+			Path:    "version.gen.go",
+			Content: "package " + opt.PackageName + "\nconst Version=\"" + spec.Info.Version + "\"\n",
+		}}, nil
+	}))
+
+	// Generate code
+	err := cg.Generate(Options{
+		OutputPath:  filepath.Join(dir, "asyncapi.gen.go"),
+		PackageName: "custom",
+		Generate:    generators.Options{Types: true},
+	})
+	suite.Require().NoError(err)
+
+	// Check that the main file has been generated
+	_, err = os.Stat(filepath.Join(dir, "asyncapi.gen.go"))
+	suite.Require().NoError(err)
+
+	// Check that the custom file has been generated and formatted
+	content, err := os.ReadFile(filepath.Join(dir, "version.gen.go"))
+	suite.Require().NoError(err)
+	suite.Require().Equal("package custom\n\nconst Version = \"1.2.3\"\n", string(content))
+}
+
+func (suite *CodeGenSuite) TestGenerateWithDuplicateFile() {
+	dir := suite.T().TempDir()
+
+	// Create codegen with a custom generator that overwrites the main file
+	cg := New(asyncapi.Specification{})
+	cg.RegisterGenerators(generatorFunc(func(_ asyncapi.Specification, _ Options) ([]File, error) {
+		return []File{{Path: "asyncapi.gen.go"}}, nil
+	}))
+
+	// Generate code
+	err := cg.Generate(Options{
+		OutputPath:  filepath.Join(dir, "asyncapi.gen.go"),
+		PackageName: "custom",
+	})
+	suite.Require().ErrorIs(err, ErrDuplicateFile)
+}
+
+func (suite *CodeGenSuite) TestGenerateWithGeneratorError() {
+	expectedErr := errors.New("custom error")
+
+	// Create codegen with a custom generator that fails
+	cg := New(asyncapi.Specification{})
+	cg.RegisterGenerators(generatorFunc(func(_ asyncapi.Specification, _ Options) ([]File, error) {
+		return nil, expectedErr
+	}))
+
+	// Generate code
+	err := cg.Generate(Options{
+		OutputPath:  filepath.Join(suite.T().TempDir(), "asyncapi.gen.go"),
+		PackageName: "custom",
+	})
+	suite.Require().ErrorIs(err, expectedErr)
+}
+
+func (suite *CodeGenSuite) TestGenerateMocksWithoutSide() {
+	// Generate mocks without the controllers they implement
+	cg := New(asyncapi.Specification{})
+	err := cg.Generate(Options{
+		OutputPath:  filepath.Join(suite.T().TempDir(), "asyncapi.gen.go"),
+		PackageName: "mocks",
+		Generate:    generators.Options{Types: true, Mocks: true},
+	})
+	suite.Require().ErrorIs(err, ErrMissingSide)
+}
+
+func (suite *CodeGenSuite) TestDiff() {
+	path := filepath.Join(suite.T().TempDir(), "asyncapi.gen.go")
+	cg := New(asyncapi.Specification{Info: asyncapi.Info{Version: "1.2.3"}})
+	opt := Options{
+		OutputPath:  path,
+		PackageName: "check",
+		Generate:    generators.Options{Types: true},
+	}
+
+	// Check that a missing file is considered as stale
+	diff, err := cg.Diff(opt)
+	suite.Require().NoError(err)
+	suite.Require().Contains(diff, "+package check")
+
+	// Check that there is no diff once generated
+	suite.Require().NoError(cg.Generate(opt))
+	diff, err = cg.Diff(opt)
+	suite.Require().NoError(err)
+	suite.Require().Empty(diff)
+
+	// Check that a change in the specification is detected without writing
+	cg.Specification.Info.Version = "1.2.4"
+	diff, err = cg.Diff(opt)
+	suite.Require().NoError(err)
+	suite.Require().Contains(diff, `-const AsyncAPIVersion = "1.2.3"`)
+	suite.Require().Contains(diff, `+const AsyncAPIVersion = "1.2.4"`)
+
+	content, err := os.ReadFile(path)
+	suite.Require().NoError(err)
+	suite.Require().Contains(string(content), `"1.2.3"`)
+}
+
+func (suite *CodeGenSuite) TestGenerateWithNameCollision() {
+	cg, err := FromYAML([]byte(`asyncapi: 2.6.0
+info:
+  title: test
+  version: 1.0.0
+channels:
+  user.created:
+    publish:
+      message:
+        payload:
+          type: string
+  user/created:
+    publish:
+      message:
+        payload:
+          type: string
+`))
+	suite.Require().NoError(err)
+
+	opt := Options{
+		OutputPath:  filepath.Join(suite.T().TempDir(), "asyncapi.gen.go"),
+		PackageName: "collision",
+		Generate:    generators.Options{Types: true, Application: true},
+	}
+
+	// Check that collision is detected
+	suite.Require().ErrorIs(cg.Generate(opt), generators.ErrNameCollision)
+
+	// Check that collision is resolved with disambiguation
+	opt.Naming.Disambiguate = true
+	suite.Require().NoError(cg.Generate(opt))
+
+	content, err := os.ReadFile(opt.OutputPath)
+	suite.Require().NoError(err)
+	suite.Require().Contains(string(content), "type UserCreatedMessage2 struct")
+	suite.Require().Contains(string(content), "func (c *AppController) SubscribeUserCreated2(")
+}
+
+func (suite *CodeGenSuite) TestGenerateWithTagsOptions() {
+	cg, err := FromYAML([]byte(`asyncapi: 2.6.0
+info:
+  title: test
+  version: 1.0.0
+channels: {}
+components:
+  schemas:
+    call:
+      type: object
+      required: [callId]
+      properties:
+        callId:
+          type: string
+        callerName:
+          type: string
+          x-go-tags:
+            validate: max=64
+`))
+	suite.Require().NoError(err)
+
+	opt := Options{
+		OutputPath:  filepath.Join(suite.T().TempDir(), "asyncapi.gen.go"),
+		PackageName: "tags",
+		Generate:    generators.Options{Types: true},
+	}
+
+	// Check that property names are kept as they are by default
+	suite.Require().NoError(cg.Generate(opt))
+	content, err := os.ReadFile(opt.OutputPath)
+	suite.Require().NoError(err)
+	suite.Require().Contains(string(content), "`json:\"callId\"`")
+	suite.Require().Contains(string(content), "`json:\"callerName\" validate:\"max=64\"`")
+
+	// Check tags options
+	opt.Tags = generators.TagsOptions{
+		Casing:    generators.TagCasingIsSnake,
+		OmitEmpty: true,
+		Extra:     map[string]generators.TagCasing{"yaml": "", "db": generators.TagCasingIsCamel},
+	}
+	suite.Require().NoError(cg.Generate(opt))
+	content, err = os.ReadFile(opt.OutputPath)
+	suite.Require().NoError(err)
+	suite.Require().Contains(string(content), "`json:\"call_id\" db:\"callId\" yaml:\"call_id\"`")
+	suite.Require().Contains(string(content),
+		"`json:\"caller_name,omitempty\" db:\"callerName,omitempty\" yaml:\"caller_name,omitempty\" validate:\"max=64\"`")
+
+	// Check invalid casing
+	opt.Tags = generators.TagsOptions{Casing: "unknown"}
+	suite.Require().ErrorIs(cg.Generate(opt), generators.ErrInvalidTagCasing)
+}
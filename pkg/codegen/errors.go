@@ -12,4 +12,15 @@ var (
 
 	// ErrInvalidFileFormat is returned when using an invalid format for AsyncAPI specification.
 	ErrInvalidFileFormat = fmt.Errorf("%w: invalid file format", extensions.ErrAsyncAPI)
+
+	// ErrInvalidSpecification is returned when the AsyncAPI specification is invalid
+	// or can't be used to generate code.
+	ErrInvalidSpecification = fmt.Errorf("%w: invalid specification", extensions.ErrAsyncAPI)
+
+	// ErrDuplicateFile is returned when the same file is generated more than once.
+	ErrDuplicateFile = fmt.Errorf("%w: file generated more than once", extensions.ErrAsyncAPI)
+
+	// ErrMissingSide is returned when generating code that requires the
+	// application or user side without any of them.
+	ErrMissingSide = fmt.Errorf("%w: missing application or user side", extensions.ErrAsyncAPI)
 )
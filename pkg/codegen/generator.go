@@ -0,0 +1,28 @@
+package codegen
+
+import (
+	"github.com/znas-io/asyncapi-codegen/pkg/asyncapi"
+)
+
+// File is a file generated during the code generation, either the main file
+// or an additional file from a custom Generator.
+type File struct {
+	// Path is the path of the file. If it is relative, it will be relative to
+	// the directory of the main generated file.
+	Path string
+
+	// Content is the content of the file. If this is a golang file, it will
+	// be formatted and its imports processed as the main generated file.
+	Content string
+}
+
+// Generator is the interface that should be implemented by custom generators
+// in order to generate additional files from the AsyncAPI specification.
+//
+// Custom generators are executed in the same generation pass as the main file,
+// after the specification has been processed.
+type Generator interface {
+	// Generate generates additional files from the processed specification and
+	// the options used for the generation.
+	Generate(spec asyncapi.Specification, opt Options) ([]File, error)
+}
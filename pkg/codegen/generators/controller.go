@@ -4,6 +4,7 @@ import (
 	"bytes"
 
 	"github.com/znas-io/asyncapi-codegen/pkg/asyncapi"
+	"github.com/znas-io/asyncapi-codegen/pkg/utils"
 )
 
 // ControllerGenerator is a code generator for controllers that will turn an
@@ -14,6 +15,24 @@ type ControllerGenerator struct {
 	PublishChannels   map[string]*asyncapi.Channel
 	Prefix            string
 	Version           string
+
+	// Requests are the channels on which the controller publishes requests
+	// and receives the corresponding replies
+	Requests []RequestChannel
+	// ReplyChannels are the channels on which the controller publishes the
+	// replies to the requests it receives
+	ReplyChannels map[string]bool
+
+	// Methods are the exported methods of the controller
+	Methods []Method
+}
+
+// RequestChannel is a channel on which requests are published, with the channel
+// on which the replies are received.
+type RequestChannel struct {
+	Name    string
+	Channel *asyncapi.Channel
+	Reply   *asyncapi.Channel
 }
 
 // NewControllerGenerator will create a new controller code generator.
@@ -43,6 +62,32 @@ func NewControllerGenerator(side Side, spec asyncapi.Specification) ControllerGe
 		}
 	}
 
+	// Get channels with replies, set with the x-reply extension
+	for _, name := range utils.SortedKeys(gen.PublishChannels) {
+		channel := gen.PublishChannels[name]
+		reply := channel.PublishReply
+		if side == SideIsApplication {
+			reply = channel.SubscribeReply
+		}
+
+		if reply != nil && isSubscribeChannel(side, reply) {
+			gen.Requests = append(gen.Requests, RequestChannel{Name: name, Channel: channel, Reply: reply})
+		}
+	}
+
+	// Get channels on which replies to received requests are published
+	gen.ReplyChannels = make(map[string]bool)
+	for _, channel := range gen.SubscribeChannels {
+		reply := channel.PublishReply
+		if side == SideIsUser {
+			reply = channel.SubscribeReply
+		}
+
+		if reply != nil && isPublishChannel(side, reply) {
+			gen.ReplyChannels[reply.Path] = true
+		}
+	}
+
 	// Set generation name
 	if side == SideIsApplication {
 		gen.Prefix = "App"
@@ -53,6 +98,9 @@ func NewControllerGenerator(side Side, spec asyncapi.Specification) ControllerGe
 	// Set version
 	gen.Version = spec.Info.Version
 
+	// Set exported methods
+	gen.Methods = controllerMethods(gen)
+
 	return gen
 }
 
@@ -0,0 +1,19 @@
+package generators
+
+import (
+	"fmt"
+
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+)
+
+var (
+	// ErrNameCollision is returned when different elements of the specification
+	// would generate the same golang name.
+	ErrNameCollision = fmt.Errorf("%w: name collision", extensions.ErrAsyncAPI)
+
+	// ErrInvalidNamingStrategy is returned when using an unknown naming strategy.
+	ErrInvalidNamingStrategy = fmt.Errorf("%w: invalid naming strategy", extensions.ErrAsyncAPI)
+
+	// ErrInvalidTagCasing is returned when using an unknown struct tag casing.
+	ErrInvalidTagCasing = fmt.Errorf("%w: invalid tag casing", extensions.ErrAsyncAPI)
+)
@@ -0,0 +1,178 @@
+package generators
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/znas-io/asyncapi-codegen/pkg/asyncapi"
+	"github.com/znas-io/asyncapi-codegen/pkg/codegen/generators/templates"
+	"github.com/znas-io/asyncapi-codegen/pkg/utils"
+)
+
+// MethodArg is an argument of a generated method.
+type MethodArg struct {
+	Name string
+	Type string
+}
+
+// Field returns the name of the struct field that can hold the argument.
+func (a MethodArg) Field() string {
+	return templates.Namify(a.Name)
+}
+
+// Method describes a method of a generated interface.
+type Method struct {
+	Name    string
+	Args    []MethodArg
+	Results []string
+}
+
+// Signature returns the signature of the method, without its name.
+func (m Method) Signature() string {
+	args := make([]string, len(m.Args))
+	for i, a := range m.Args {
+		args[i] = a.Name + " " + a.Type
+	}
+
+	signature := "(" + strings.Join(args, ", ") + ")"
+	switch len(m.Results) {
+	case 0:
+		return signature
+	case 1:
+		return signature + " " + m.Results[0]
+	default:
+		return signature + " (" + strings.Join(m.Results, ", ") + ")"
+	}
+}
+
+// ArgsNames returns the arguments names separated by commas, in order to call
+// a function with the same arguments.
+func (m Method) ArgsNames() string {
+	names := make([]string, len(m.Args))
+	for i, a := range m.Args {
+		names[i] = a.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// ZeroResults returns the zero values of the results separated by commas.
+func (m Method) ZeroResults() string {
+	zeros := make([]string, len(m.Results))
+	for i, r := range m.Results {
+		if r == "error" {
+			zeros[i] = "nil"
+		} else {
+			zeros[i] = r + "{}"
+		}
+	}
+	return strings.Join(zeros, ", ")
+}
+
+func channelArgs(ch *asyncapi.Channel) []MethodArg {
+	args := []MethodArg{{Name: "ctx", Type: "context.Context"}}
+	if len(ch.Parameters) > 0 {
+		args = append(args, MethodArg{Name: "params", Type: templates.ChannelParametersTypeName(*ch)})
+	}
+	return args
+}
+
+// controllerMethods returns the exported methods of the controller.
+func controllerMethods(gen ControllerGenerator) []Method {
+	methods := []Method{{
+		Name: "Close",
+		Args: []MethodArg{{Name: "ctx", Type: "context.Context"}},
+	}, {
+		Name:    "Shutdown",
+		Args:    []MethodArg{{Name: "ctx", Type: "context.Context"}},
+		Results: []string{"error"},
+	}}
+
+	// Add methods to subscribe to all channels
+	if gen.MethodCount > 0 {
+		methods = append(methods, Method{
+			Name:    "SubscribeAll",
+			Args:    []MethodArg{{Name: "ctx", Type: "context.Context"}, {Name: "as", Type: gen.Prefix + "Subscriber"}},
+			Results: []string{"error"},
+		}, Method{
+			Name: "UnsubscribeAll",
+			Args: []MethodArg{{Name: "ctx", Type: "context.Context"}},
+		})
+	}
+
+	// Add methods for channels on which the controller subscribes
+	for _, key := range utils.SortedKeys(gen.SubscribeChannels) {
+		ch := gen.SubscribeChannels[key]
+		op, msgType := templates.OperationName(*ch), templates.ChannelToMessageTypeName(*ch)
+
+		methods = append(methods, Method{
+			Name: "Subscribe" + op,
+			Args: append(channelArgs(ch), MethodArg{
+				Name: "fn",
+				Type: fmt.Sprintf("func(ctx context.Context, msg %s) error", msgType),
+			}),
+			Results: []string{"error"},
+		}, Method{
+			Name: "Unsubscribe" + op,
+			Args: channelArgs(ch),
+		})
+	}
+
+	// Add methods for channels on which the controller publishes
+	for _, key := range utils.SortedKeys(gen.PublishChannels) {
+		ch := gen.PublishChannels[key]
+		op, msgType := templates.OperationName(*ch), templates.ChannelToMessageTypeName(*ch)
+
+		methods = append(methods, Method{
+			Name:    "Publish" + op,
+			Args:    append(channelArgs(ch), MethodArg{Name: "msg", Type: msgType}),
+			Results: []string{"error"},
+		})
+	}
+
+	// Add methods to send requests and wait for their replies
+	for _, req := range gen.Requests {
+		methods = append(methods, Method{
+			Name:    "Request" + templates.OperationName(*req.Channel),
+			Args:    append(channelArgs(req.Channel), MethodArg{Name: "msg", Type: templates.ChannelToMessageTypeName(*req.Channel)}),
+			Results: []string{templates.ChannelToMessageTypeName(*req.Reply), "error"},
+		})
+	}
+
+	// Add methods to wait for a response on user side
+	if gen.Prefix == "User" {
+		for _, key := range utils.SortedKeys(gen.SubscribeChannels) {
+			ch := gen.SubscribeChannels[key]
+			if ch.Subscribe.Message.CorrelationIDLocation == "" {
+				continue
+			}
+
+			msgType := templates.ChannelToMessageTypeName(*ch)
+			methods = append(methods, Method{
+				Name: "WaitFor" + templates.OperationName(*ch),
+				Args: append(channelArgs(ch),
+					MethodArg{Name: "publishMsg", Type: "MessageWithCorrelationID"},
+					MethodArg{Name: "pub", Type: "func(ctx context.Context) error"}),
+				Results: []string{msgType, "error"},
+			})
+		}
+	}
+
+	return methods
+}
+
+// subscriberMethods returns the methods of the subscriber interface.
+func subscriberMethods(gen SubscriberGenerator) []Method {
+	methods := make([]Method, 0, len(gen.Channels))
+	for _, key := range utils.SortedKeys(gen.Channels) {
+		ch := gen.Channels[key]
+		methods = append(methods, Method{
+			Name: templates.OperationName(*ch),
+			Args: []MethodArg{
+				{Name: "ctx", Type: "context.Context"},
+				{Name: "msg", Type: templates.ChannelToMessageTypeName(*ch)},
+			},
+			Results: []string{"error"},
+		})
+	}
+	return methods
+}
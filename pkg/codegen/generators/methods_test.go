@@ -0,0 +1,53 @@
+package generators
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestMethodSuite(t *testing.T) {
+	suite.Run(t, new(MethodSuite))
+}
+
+type MethodSuite struct {
+	suite.Suite
+}
+
+func (suite *MethodSuite) TestMethod() {
+	cases := []struct {
+		Method      Method
+		Signature   string
+		ArgsNames   string
+		ZeroResults string
+	}{
+		// Without results
+		{
+			Method:    Method{Args: []MethodArg{{Name: "ctx", Type: "context.Context"}}},
+			Signature: "(ctx context.Context)",
+			ArgsNames: "ctx",
+		},
+		// With one result
+		{
+			Method: Method{
+				Args:    []MethodArg{{Name: "ctx", Type: "context.Context"}, {Name: "msg", Type: "PingMessage"}},
+				Results: []string{"error"},
+			},
+			Signature:   "(ctx context.Context, msg PingMessage) error",
+			ArgsNames:   "ctx, msg",
+			ZeroResults: "nil",
+		},
+		// With multiple results
+		{
+			Method:      Method{Results: []string{"PongMessage", "error"}},
+			Signature:   "() (PongMessage, error)",
+			ZeroResults: "PongMessage{}, nil",
+		},
+	}
+
+	for i, c := range cases {
+		suite.Require().Equal(c.Signature, c.Method.Signature(), i)
+		suite.Require().Equal(c.ArgsNames, c.Method.ArgsNames(), i)
+		suite.Require().Equal(c.ZeroResults, c.Method.ZeroResults(), i)
+	}
+}
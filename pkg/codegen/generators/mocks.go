@@ -0,0 +1,62 @@
+package generators
+
+import (
+	"bytes"
+
+	"github.com/znas-io/asyncapi-codegen/pkg/asyncapi"
+)
+
+// Mock is a mock implementation of a generated interface.
+type Mock struct {
+	Name      string
+	Interface string
+	Methods   []Method
+}
+
+// MocksGenerator is a code generator for mocks of controllers and subscribers
+// that will turn an asyncapi specification into mocks golang code.
+type MocksGenerator struct {
+	Mocks []Mock
+}
+
+// NewMocksGenerator will create a new mocks code generator for the given sides.
+func NewMocksGenerator(sides []Side, spec asyncapi.Specification) MocksGenerator {
+	var gen MocksGenerator
+
+	for _, side := range sides {
+		// Add controller mock
+		controller := NewControllerGenerator(side, spec)
+		gen.Mocks = append(gen.Mocks, Mock{
+			Name:      controller.Prefix + "ControllerMock",
+			Interface: controller.Prefix + "ControllerInterface",
+			Methods:   controller.Methods,
+		})
+
+		// Add subscriber mock, if there is a subscriber
+		subscriber := NewSubscriberGenerator(side, spec)
+		if subscriber.MethodCount > 0 {
+			gen.Mocks = append(gen.Mocks, Mock{
+				Name:      subscriber.Prefix + "SubscriberMock",
+				Interface: subscriber.Prefix + "Subscriber",
+				Methods:   subscriberMethods(subscriber),
+			})
+		}
+	}
+
+	return gen
+}
+
+// Generate will generate the mocks code.
+func (mg MocksGenerator) Generate() (string, error) {
+	tmplt, err := loadTemplate(mocksTemplatePath)
+	if err != nil {
+		return "", err
+	}
+
+	buf := new(bytes.Buffer)
+	if err := tmplt.Execute(buf, mg); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,434 @@
+package generators
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/znas-io/asyncapi-codegen/pkg/asyncapi"
+	"github.com/znas-io/asyncapi-codegen/pkg/codegen/generators/templates"
+	"github.com/znas-io/asyncapi-codegen/pkg/utils"
+)
+
+// reservedTypeNames are the package level names used by the generated code.
+var reservedTypeNames = []string{
+	"AsyncAPIVersion", "controller", "ControllerOption", "WithLogger",
+	"WithMiddlewares", "WithConcurrency", "WithOrderingKey", "WithRequestTimeout",
+	"MessageWithCorrelationID", "Error",
+	"AppController", "UserController", "NewAppController", "NewUserController",
+	"AppSubscriber", "UserSubscriber", "addAppContextValues", "addUserContextValues",
+	"AppControllerInterface", "UserControllerInterface", "AppControllerMock", "UserControllerMock",
+	"AppSubscriberMock", "UserSubscriberMock", "MockTestingT", "MockExpectation", "mockRecorder",
+}
+
+// reservedOperationNames are the operation names that would generate controller
+// methods already used by the generated code.
+var reservedOperationNames = []string{"All"}
+
+// nameClaim is a golang name wanted by an element of the specification.
+type nameClaim struct {
+	// source describes the element, it is empty for names reserved by the
+	// generated code
+	source string
+	// name is the wanted name
+	name string
+	// prefixes are the prefixes used to generate other identifiers from the
+	// name (i.e. "New" for message constructors)
+	prefixes []string
+	// set will set the resolved name on the element
+	set func(name string)
+}
+
+func (c nameClaim) identifiers(name string) []string {
+	ids := []string{name}
+	for _, p := range c.prefixes {
+		ids = append(ids, p+name)
+	}
+	return ids
+}
+
+// nameScope is a scope where every golang name should be unique.
+type nameScope struct {
+	description string
+	claims      []nameClaim
+}
+
+func (s *nameScope) claim(c nameClaim) {
+	s.claims = append(s.claims, c)
+}
+
+func (s nameScope) resolve(disambiguate bool) []string {
+	// Sort claims to get a deterministic result: reserved names first
+	sort.SliceStable(s.claims, func(i, j int) bool {
+		if (s.claims[i].source == "") != (s.claims[j].source == "") {
+			return s.claims[i].source == ""
+		}
+		return s.claims[i].source < s.claims[j].source
+	})
+
+	owners := make(map[string]string)
+	collisions := make([]string, 0)
+	for _, c := range s.claims {
+		// Check if the name is already taken
+		name := c.name
+		owner, taken := s.owner(owners, c, name)
+		if taken && !disambiguate {
+			collisions = append(collisions, s.collision(c, owner))
+			continue
+		}
+
+		// Add a suffix until the name is available
+		for i := 2; taken; i++ {
+			name = fmt.Sprintf("%s%d", c.name, i)
+			_, taken = s.owner(owners, c, name)
+		}
+
+		// Keep the name
+		for _, id := range c.identifiers(name) {
+			owners[id] = c.source
+		}
+		if c.set != nil {
+			c.set(name)
+		}
+	}
+
+	return collisions
+}
+
+func (s nameScope) owner(owners map[string]string, c nameClaim, name string) (string, bool) {
+	for _, id := range c.identifiers(name) {
+		if owner, taken := owners[id]; taken {
+			return owner, true
+		}
+	}
+	return "", false
+}
+
+func (s nameScope) collision(c nameClaim, owner string) string {
+	if owner == "" {
+		owner = "generated code"
+	}
+
+	msg := fmt.Sprintf("%q is generated for %s and %s", c.name, owner, c.source)
+	if s.description != "" {
+		msg += " in " + s.description
+	}
+
+	return msg
+}
+
+// channelOperation is an operation of a channel with its kind.
+type channelOperation struct {
+	*asyncapi.Operation
+	kind string
+}
+
+// channelOperations returns the existing operations of a channel, in a
+// deterministic order.
+func channelOperations(ch *asyncapi.Channel) []channelOperation {
+	ops := make([]channelOperation, 0, 2)
+	if ch.Subscribe != nil {
+		ops = append(ops, channelOperation{Operation: ch.Subscribe, kind: "subscribe"})
+	}
+	if ch.Publish != nil {
+		ops = append(ops, channelOperation{Operation: ch.Publish, kind: "publish"})
+	}
+	return ops
+}
+
+// namer generates golang names from the specification.
+type namer struct {
+	opt        NamingOptions
+	scopes     []*nameScope
+	collisions []string
+}
+
+// ResolveNames sets the golang name of every element of the specification
+// that is generated, based on the naming options. It returns an error if
+// different elements would generate the same name, unless disambiguation is
+// activated.
+func ResolveNames(spec *asyncapi.Specification, opt NamingOptions) error {
+	switch opt.Strategy {
+	case "", NamingStrategyIsLegacy, NamingStrategyIsIdiomatic:
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidNamingStrategy, opt.Strategy)
+	}
+
+	// Resolve operations first, as the mocks types are named after them
+	n := namer{opt: opt}
+	n.claimOperations(spec)
+	n.resolve()
+
+	n.claimTypes(spec)
+	n.claimParameters(spec)
+	n.claimFields(spec)
+	n.resolve()
+
+	if len(n.collisions) > 0 {
+		return fmt.Errorf("%w: %s", ErrNameCollision, strings.Join(n.collisions, ", "))
+	}
+
+	return nil
+}
+
+// resolve resolves the names of the scopes claimed so far.
+func (n *namer) resolve() {
+	for _, s := range n.scopes {
+		n.collisions = append(n.collisions, s.resolve(n.opt.Disambiguate)...)
+	}
+	n.scopes = nil
+}
+
+func (n *namer) newScope(description string) *nameScope {
+	s := &nameScope{description: description}
+	n.scopes = append(n.scopes, s)
+	return s
+}
+
+func (n namer) name(sentence string) string {
+	name := templates.Namify(sentence)
+	if n.opt.Strategy == NamingStrategyIsIdiomatic {
+		name = templates.Initialisms(name)
+	}
+	return name
+}
+
+func (n namer) messageName(msg *asyncapi.Message) string {
+	// Use custom name if there is one
+	if msg.ExtGoName != "" {
+		return msg.ExtGoName
+	}
+
+	// Use 'messageId' or 'name' if the strategy allows it
+	name := msg.Name
+	if n.opt.Strategy == NamingStrategyIsIdiomatic {
+		switch {
+		case msg.MessageID != "":
+			name = msg.MessageID
+		case msg.MachineName != "":
+			name = msg.MachineName
+		}
+	}
+
+	return n.name(name) + "Message"
+}
+
+func (n *namer) claimTypes(spec *asyncapi.Specification) {
+	scope := n.newScope("")
+
+	// Reserve names used by the generated code
+	for _, name := range reservedTypeNames {
+		scope.claim(nameClaim{name: name})
+	}
+	for _, name := range mocksCallsTypeNames(spec) {
+		scope.claim(nameClaim{name: name})
+	}
+
+	// Claim channels parameters and messages
+	for _, key := range utils.SortedKeys(spec.Channels) {
+		ch := spec.Channels[key]
+
+		if len(ch.Parameters) > 0 {
+			name := templates.NamifyWithoutParams(ch.Name)
+			if n.opt.Strategy == NamingStrategyIsIdiomatic {
+				name = templates.Initialisms(name)
+			}
+			if ch.ExtGoName != "" {
+				name = ch.ExtGoName
+			}
+
+			scope.claim(nameClaim{
+				source: fmt.Sprintf("channel %q parameters", key),
+				name:   name + "Parameters",
+				set:    func(name string) { ch.ParametersGoName = name },
+			})
+		}
+
+		for _, op := range channelOperations(ch) {
+			if op.Message.Payload == nil {
+				continue
+			}
+
+			msg := &op.Message
+			scope.claim(nameClaim{
+				source:   fmt.Sprintf("channel %q %s message", key, op.kind),
+				name:     n.messageName(msg),
+				prefixes: []string{"New"},
+				set:      func(name string) { msg.GoName = name },
+			})
+		}
+	}
+
+	// Claim components messages
+	for _, key := range utils.SortedKeys(spec.Components.Messages) {
+		msg := spec.Components.Messages[key]
+		scope.claim(nameClaim{
+			source:   fmt.Sprintf("message %q", key),
+			name:     n.messageName(msg),
+			prefixes: []string{"New"},
+			set:      func(name string) { msg.GoName = name },
+		})
+	}
+
+	// Claim components schemas
+	for _, key := range utils.SortedKeys(spec.Components.Schemas) {
+		schema := spec.Components.Schemas[key]
+
+		name := n.name(key) + "Schema"
+		if schema.ExtGoName != "" {
+			name = schema.ExtGoName
+		}
+
+		scope.claim(nameClaim{
+			source: fmt.Sprintf("schema %q", key),
+			name:   name,
+			set:    func(name string) { schema.GoName = name },
+		})
+	}
+}
+
+// mocksCallsTypeNames returns the names of the types recording the calls of
+// the mocks methods, that are named after the resolved operations.
+func mocksCallsTypeNames(spec *asyncapi.Specification) []string {
+	names := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, m := range NewMocksGenerator([]Side{SideIsApplication, SideIsUser}, *spec).Mocks {
+		for _, method := range m.Methods {
+			// Ignore names generated twice by colliding operations
+			name := m.Name + method.Name + "Call"
+			if !seen[name] {
+				names = append(names, name)
+				seen[name] = true
+			}
+		}
+	}
+	return names
+}
+
+func (n *namer) claimOperations(spec *asyncapi.Specification) {
+	scope := n.newScope("operations")
+
+	// Reserve names used by the generated code
+	for _, name := range reservedOperationNames {
+		scope.claim(nameClaim{name: name})
+	}
+
+	// Claim channels operations
+	for _, key := range utils.SortedKeys(spec.Channels) {
+		ch := spec.Channels[key]
+
+		var name string
+		switch {
+		case ch.ExtGoName != "":
+			name = ch.ExtGoName
+		case ch.Publish != nil && ch.Publish.OperationID != "":
+			name = n.name(ch.Publish.OperationID)
+		case ch.Subscribe != nil && ch.Subscribe.OperationID != "":
+			name = n.name(ch.Subscribe.OperationID)
+		default:
+			name = n.name(ch.Name)
+		}
+
+		scope.claim(nameClaim{
+			source: fmt.Sprintf("channel %q", key),
+			name:   name,
+			set:    func(name string) { ch.GoName = name },
+		})
+	}
+}
+
+func (n *namer) claimParameters(spec *asyncapi.Specification) {
+	for _, key := range utils.SortedKeys(spec.Channels) {
+		ch := spec.Channels[key]
+		if len(ch.Parameters) == 0 {
+			continue
+		}
+
+		scope := n.newScope(fmt.Sprintf("channel %q parameters", key))
+		for _, pKey := range utils.SortedKeys(ch.Parameters) {
+			param := ch.Parameters[pKey]
+
+			name := n.name(pKey)
+			switch {
+			case param.ExtGoName != "":
+				name = param.ExtGoName
+			case param.ReferenceTo != nil && param.ReferenceTo.ExtGoName != "":
+				name = param.ReferenceTo.ExtGoName
+			}
+
+			scope.claim(nameClaim{
+				source: fmt.Sprintf("parameter %q", pKey),
+				name:   name,
+				set:    func(name string) { param.GoName = name },
+			})
+		}
+	}
+}
+
+func (n *namer) claimFields(spec *asyncapi.Specification) {
+	visited := make(map[*asyncapi.Schema]bool)
+
+	// Claim fields from channels messages and parameters
+	for _, key := range utils.SortedKeys(spec.Channels) {
+		ch := spec.Channels[key]
+		for _, op := range channelOperations(ch) {
+			desc := fmt.Sprintf("channel %q %s message", key, op.kind)
+			n.claimSchemaFields(desc+" headers", op.Message.Headers, visited)
+			n.claimSchemaFields(desc+" payload", op.Message.Payload, visited)
+		}
+		for _, pKey := range utils.SortedKeys(ch.Parameters) {
+			desc := fmt.Sprintf("channel %q parameter %q", key, pKey)
+			n.claimSchemaFields(desc, ch.Parameters[pKey].Schema, visited)
+		}
+	}
+
+	// Claim fields from components
+	for _, key := range utils.SortedKeys(spec.Components.Messages) {
+		msg := spec.Components.Messages[key]
+		n.claimSchemaFields(fmt.Sprintf("message %q headers", key), msg.Headers, visited)
+		n.claimSchemaFields(fmt.Sprintf("message %q payload", key), msg.Payload, visited)
+	}
+	for _, key := range utils.SortedKeys(spec.Components.Schemas) {
+		n.claimSchemaFields(fmt.Sprintf("schema %q", key), spec.Components.Schemas[key], visited)
+	}
+	for _, key := range utils.SortedKeys(spec.Components.Parameters) {
+		n.claimSchemaFields(fmt.Sprintf("parameter %q", key), spec.Components.Parameters[key].Schema, visited)
+	}
+}
+
+func (n *namer) claimSchemaFields(description string, schema *asyncapi.Schema, visited map[*asyncapi.Schema]bool) {
+	if schema == nil || visited[schema] {
+		return
+	}
+	visited[schema] = true
+
+	// Claim properties names
+	if len(schema.Properties) > 0 {
+		scope := n.newScope(description)
+		for _, key := range utils.SortedKeys(schema.Properties) {
+			prop := schema.Properties[key]
+
+			name := n.name(key)
+			if prop.ExtGoName != "" {
+				name = prop.ExtGoName
+			}
+
+			scope.claim(nameClaim{
+				source: fmt.Sprintf("property %q", key),
+				name:   name,
+				set:    func(name string) { prop.GoName = name },
+			})
+		}
+	}
+
+	// Claim sub-schemas fields
+	for _, key := range utils.SortedKeys(schema.Properties) {
+		n.claimSchemaFields(fmt.Sprintf("%s property %q", description, key), schema.Properties[key], visited)
+	}
+	n.claimSchemaFields(description+" items", schema.Items, visited)
+	for _, xxxOf := range [][]*asyncapi.Schema{schema.AllOf, schema.AnyOf, schema.OneOf} {
+		for i, s := range xxxOf {
+			n.claimSchemaFields(fmt.Sprintf("%s element %d", description, i), s, visited)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package generators
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/znas-io/asyncapi-codegen/pkg/asyncapi"
+)
+
+func TestNamingSuite(t *testing.T) {
+	suite.Run(t, new(NamingSuite))
+}
+
+type NamingSuite struct {
+	suite.Suite
+}
+
+func newStringSchema() *asyncapi.Schema {
+	return &asyncapi.Schema{Type: "string"}
+}
+
+func newChannelWithPayload(payload *asyncapi.Schema) *asyncapi.Channel {
+	return &asyncapi.Channel{
+		Publish: &asyncapi.Operation{
+			Message: asyncapi.Message{Payload: payload},
+		},
+	}
+}
+
+func (suite *NamingSuite) TestResolveNamesWithCollidingChannels() {
+	spec := asyncapi.Specification{
+		Channels: map[string]*asyncapi.Channel{
+			"user.created": newChannelWithPayload(newStringSchema()),
+			"user/created": newChannelWithPayload(newStringSchema()),
+		},
+	}
+	spec.Process()
+
+	// Check that collision is detected
+	err := ResolveNames(&spec, NamingOptions{})
+	suite.Require().ErrorIs(err, ErrNameCollision)
+	suite.Require().ErrorContains(err, `"UserCreatedMessage" is generated for `+
+		`channel "user.created" publish message and channel "user/created" publish message`)
+	suite.Require().ErrorContains(err, `"UserCreated" is generated for `+
+		`channel "user.created" and channel "user/created" in operations`)
+
+	// Check that collision is resolved with disambiguation
+	err = ResolveNames(&spec, NamingOptions{Disambiguate: true})
+	suite.Require().NoError(err)
+	suite.Require().Equal("UserCreatedMessage", spec.Channels["user.created"].Publish.Message.GoName)
+	suite.Require().Equal("UserCreatedMessage2", spec.Channels["user/created"].Publish.Message.GoName)
+	suite.Require().Equal("UserCreated", spec.Channels["user.created"].GoName)
+	suite.Require().Equal("UserCreated2", spec.Channels["user/created"].GoName)
+}
+
+func (suite *NamingSuite) TestResolveNamesWithCollidingMessages() {
+	spec := asyncapi.Specification{
+		Channels: map[string]*asyncapi.Channel{
+			"foo": newChannelWithPayload(newStringSchema()),
+		},
+		Components: asyncapi.Components{
+			Messages: map[string]*asyncapi.Message{
+				"Foo":    {Payload: newStringSchema()},
+				"NewFoo": {Payload: newStringSchema()},
+			},
+		},
+	}
+	spec.Process()
+
+	// Check that collisions are detected, including with generated constructors
+	err := ResolveNames(&spec, NamingOptions{})
+	suite.Require().ErrorIs(err, ErrNameCollision)
+	suite.Require().ErrorContains(err, `"FooMessage" is generated for channel "foo" publish message and message "Foo"`)
+	suite.Require().ErrorContains(err, `"NewFooMessage" is generated for channel "foo" publish message and message "NewFoo"`)
+
+	// Check that collisions are resolved with disambiguation
+	err = ResolveNames(&spec, NamingOptions{Disambiguate: true})
+	suite.Require().NoError(err)
+	suite.Require().Equal("FooMessage", spec.Channels["foo"].Publish.Message.GoName)
+	suite.Require().Equal("FooMessage2", spec.Components.Messages["Foo"].GoName)
+	suite.Require().Equal("NewFooMessage3", spec.Components.Messages["NewFoo"].GoName)
+}
+
+func (suite *NamingSuite) TestResolveNamesWithReservedNames() {
+	spec := asyncapi.Specification{
+		Channels: map[string]*asyncapi.Channel{
+			"all": newChannelWithPayload(newStringSchema()),
+		},
+		Components: asyncapi.Components{
+			Schemas: map[string]*asyncapi.Schema{
+				"error":       {Type: "string", Extensions: asyncapi.Extensions{ExtGoName: "Error"}},
+				"concurrency": {Type: "string", Extensions: asyncapi.Extensions{ExtGoName: "WithConcurrency"}},
+				"timeout":     {Type: "string", Extensions: asyncapi.Extensions{ExtGoName: "WithRequestTimeout"}},
+			},
+		},
+	}
+	spec.Process()
+
+	// Check that collisions with generated code are detected
+	err := ResolveNames(&spec, NamingOptions{})
+	suite.Require().ErrorIs(err, ErrNameCollision)
+	suite.Require().ErrorContains(err, `"Error" is generated for generated code and schema "error"`)
+	suite.Require().ErrorContains(err, `"WithConcurrency" is generated for generated code and schema "concurrency"`)
+	suite.Require().ErrorContains(err, `"WithRequestTimeout" is generated for generated code and schema "timeout"`)
+	suite.Require().ErrorContains(err, `"All" is generated for generated code and channel "all" in operations`)
+
+	// Check that generated code keeps its names with disambiguation
+	err = ResolveNames(&spec, NamingOptions{Disambiguate: true})
+	suite.Require().NoError(err)
+	suite.Require().Equal("Error2", spec.Components.Schemas["error"].GoName)
+	suite.Require().Equal("WithConcurrency2", spec.Components.Schemas["concurrency"].GoName)
+	suite.Require().Equal("WithRequestTimeout2", spec.Components.Schemas["timeout"].GoName)
+	suite.Require().Equal("All2", spec.Channels["all"].GoName)
+}
+
+func (suite *NamingSuite) TestResolveNamesWithMocksCalls() {
+	spec := asyncapi.Specification{
+		Channels: map[string]*asyncapi.Channel{
+			"pong": newChannelWithPayload(newStringSchema()),
+		},
+		Components: asyncapi.Components{
+			Schemas: map[string]*asyncapi.Schema{
+				"publish":   {Type: "string", Extensions: asyncapi.Extensions{ExtGoName: "UserControllerMockPublishPongCall"}},
+				"subscribe": {Type: "string", Extensions: asyncapi.Extensions{ExtGoName: "AppSubscriberMockPongCall"}},
+			},
+		},
+	}
+	spec.Process()
+
+	// Check that collisions with the mocks calls types are detected
+	err := ResolveNames(&spec, NamingOptions{})
+	suite.Require().ErrorIs(err, ErrNameCollision)
+	suite.Require().ErrorContains(err, `"UserControllerMockPublishPongCall" is generated for generated code and schema "publish"`)
+	suite.Require().ErrorContains(err, `"AppSubscriberMockPongCall" is generated for generated code and schema "subscribe"`)
+
+	// Check that the mocks calls types keep their names with disambiguation
+	err = ResolveNames(&spec, NamingOptions{Disambiguate: true})
+	suite.Require().NoError(err)
+	suite.Require().Equal("UserControllerMockPublishPongCall2", spec.Components.Schemas["publish"].GoName)
+	suite.Require().Equal("AppSubscriberMockPongCall2", spec.Components.Schemas["subscribe"].GoName)
+}
+
+func (suite *NamingSuite) TestResolveNamesWithCollidingFields() {
+	spec := asyncapi.Specification{
+		Components: asyncapi.Components{
+			Schemas: map[string]*asyncapi.Schema{
+				"user": {
+					Type: "object",
+					Properties: map[string]*asyncapi.Schema{
+						"user_id": newStringSchema(),
+						"userId":  newStringSchema(),
+					},
+				},
+			},
+		},
+	}
+	spec.Process()
+
+	// Check that collision is detected
+	err := ResolveNames(&spec, NamingOptions{})
+	suite.Require().ErrorIs(err, ErrNameCollision)
+	suite.Require().ErrorContains(err, `"UserId" is generated for property "userId" and property "user_id" in schema "user"`)
+
+	// Check that collision is resolved with disambiguation
+	err = ResolveNames(&spec, NamingOptions{Strategy: NamingStrategyIsIdiomatic, Disambiguate: true})
+	suite.Require().NoError(err)
+	suite.Require().Equal("UserID", spec.Components.Schemas["user"].Properties["userId"].GoName)
+	suite.Require().Equal("UserID2", spec.Components.Schemas["user"].Properties["user_id"].GoName)
+}
+
+func (suite *NamingSuite) TestResolveNamesWithIdiomaticStrategy() {
+	spec := asyncapi.Specification{
+		Channels: map[string]*asyncapi.Channel{
+			"user.{id}": {
+				Parameters: map[string]*asyncapi.Parameter{
+					"id": {Schema: newStringSchema()},
+				},
+				Publish: &asyncapi.Operation{
+					OperationID: "userUrl",
+					Message: asyncapi.Message{
+						MessageID: "userUrlChanged",
+						Payload: &asyncapi.Schema{
+							Type: "object",
+							Properties: map[string]*asyncapi.Schema{
+								"url":  newStringSchema(),
+								"http": {Type: "string", Extensions: asyncapi.Extensions{ExtGoName: "Protocol"}},
+							},
+						},
+					},
+				},
+			},
+		},
+		Components: asyncapi.Components{
+			Messages: map[string]*asyncapi.Message{
+				"named":  {MachineName: "json_id", Payload: newStringSchema()},
+				"custom": {MessageID: "ignored", ExtGoName: "Custom", Payload: newStringSchema()},
+			},
+		},
+	}
+	spec.Process()
+
+	err := ResolveNames(&spec, NamingOptions{Strategy: NamingStrategyIsIdiomatic})
+	suite.Require().NoError(err)
+
+	ch := spec.Channels["user.{id}"]
+	suite.Require().Equal("UserURL", ch.GoName)
+	suite.Require().Equal("UserParameters", ch.ParametersGoName)
+	suite.Require().Equal("ID", ch.Parameters["id"].GoName)
+	suite.Require().Equal("UserURLChangedMessage", ch.Publish.Message.GoName)
+	suite.Require().Equal("URL", ch.Publish.Message.Payload.Properties["url"].GoName)
+	suite.Require().Equal("Protocol", ch.Publish.Message.Payload.Properties["http"].GoName)
+	suite.Require().Equal("JSONIDMessage", spec.Components.Messages["named"].GoName)
+	suite.Require().Equal("Custom", spec.Components.Messages["custom"].GoName)
+}
+
+func (suite *NamingSuite) TestResolveNamesWithInvalidStrategy() {
+	err := ResolveNames(&asyncapi.Specification{}, NamingOptions{Strategy: "unknown"})
+	suite.Require().ErrorIs(err, ErrInvalidNamingStrategy)
+}
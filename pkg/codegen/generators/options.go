@@ -1,5 +1,13 @@
 package generators
 
+import (
+	"fmt"
+
+	"github.com/znas-io/asyncapi-codegen/pkg/asyncapi"
+	"github.com/znas-io/asyncapi-codegen/pkg/codegen/generators/templates"
+	"github.com/znas-io/asyncapi-codegen/pkg/utils"
+)
+
 // Options are the options to activate some parts of code generation.
 type Options struct {
 	// Application should be true for application code generation to be generated
@@ -8,4 +16,110 @@ type Options struct {
 	User bool
 	// Types should be true for type code (or common code) generation to be generated
 	Types bool
+	// Mocks should be true for mocks of controllers and subscribers to be generated
+	Mocks bool
+}
+
+// NamingStrategy is the strategy used to generate golang names from the
+// AsyncAPI specification.
+type NamingStrategy string
+
+const (
+	// NamingStrategyIsLegacy generates names from the specification keys, as
+	// it has always been done. This is the default strategy.
+	NamingStrategyIsLegacy NamingStrategy = "legacy"
+	// NamingStrategyIsIdiomatic generates names with golang initialisms (ID,
+	// URL, HTTP, etc) and uses the message 'messageId' or 'name' when defined.
+	NamingStrategyIsIdiomatic NamingStrategy = "idiomatic"
+)
+
+// NamingOptions are the options regarding the golang names generation.
+type NamingOptions struct {
+	// Strategy is the strategy used to generate names, legacy if empty
+	Strategy NamingStrategy
+	// Disambiguate states if colliding names should be suffixed with a number
+	// instead of returning an error
+	Disambiguate bool
+}
+
+// TagCasing is the casing used to generate struct tags from properties names.
+type TagCasing string
+
+const (
+	// TagCasingIsProperty keeps the property name as it is in the specification.
+	// This is the default casing.
+	TagCasingIsProperty TagCasing = "property"
+	// TagCasingIsSnake converts the property name to snake case (i.e. "call_id").
+	TagCasingIsSnake TagCasing = "snake"
+	// TagCasingIsCamel converts the property name to camel case (i.e. "callId").
+	TagCasingIsCamel TagCasing = "camel"
+)
+
+// TagsOptions are the options regarding the struct tags generation.
+type TagsOptions struct {
+	// Casing is the casing of the JSON tags, the property name is kept as it
+	// is if empty
+	Casing TagCasing
+	// OmitEmpty states if the 'omitempty' option should be added to the tags
+	// of optional fields
+	OmitEmpty bool
+	// Extra are additional tags to generate (i.e. "yaml" or "db") with their
+	// casing. The casing of the JSON tags is used if it is empty.
+	Extra map[string]TagCasing
+}
+
+func (opt TagsOptions) structTags() ([]templates.StructTag, error) {
+	// Add JSON tag
+	casing, err := opt.Casing.function(keepName)
+	if err != nil {
+		return nil, err
+	}
+	tags := []templates.StructTag{{Key: "json", Casing: casing}}
+
+	// Add extra tags
+	for _, key := range utils.SortedKeys(opt.Extra) {
+		if key == "json" {
+			continue
+		}
+
+		extraCasing, err := opt.Extra[key].function(casing)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, templates.StructTag{Key: key, Casing: extraCasing})
+	}
+
+	return tags, nil
+}
+
+// structTagsFunction returns the function used in templates to generate the
+// struct tags of a schema property.
+func (opt TagsOptions) structTagsFunction() (func(name string, schema asyncapi.Schema, required bool) string, error) {
+	tags, err := opt.structTags()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(name string, schema asyncapi.Schema, required bool) string {
+		return templates.StructTags(name, schema, required, tags, opt.OmitEmpty)
+	}, nil
+}
+
+func keepName(name string) string {
+	return name
+}
+
+func (c TagCasing) function(defaultFunction func(string) string) (func(string) string, error) {
+	switch c {
+	case "":
+		return defaultFunction, nil
+	case TagCasingIsProperty:
+		return keepName, nil
+	case TagCasingIsSnake:
+		return templates.SnakeCase, nil
+	case TagCasingIsCamel:
+		return templates.CamelCase, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTagCasing, c)
+	}
 }
@@ -18,6 +18,7 @@ const (
 	subscriberTemplatePath = templatesDir + "/subscriber.tmpl"
 	controllerTemplatePath = templatesDir + "/controller.tmpl"
 	parameterTemplatePath  = templatesDir + "/parameter.tmpl"
+	mocksTemplatePath      = templatesDir + "/mocks.tmpl"
 )
 
 var (
@@ -33,6 +34,9 @@ func loadTemplate(paths ...string) (*template.Template, error) {
 }
 
 func templateFunctions() template.FuncMap {
+	// Use default tags options, they can be replaced by the generators
+	structTags, _ := TagsOptions{}.structTagsFunction()
+
 	return template.FuncMap{
 		"namifyWithoutParam":             templates.NamifyWithoutParams,
 		"namify":                         templates.Namify,
@@ -48,5 +52,14 @@ func templateFunctions() template.FuncMap {
 		"multiLineComment":               templates.MultiLineComment,
 		"args":                           templates.Args,
 		"operationName":                  templates.OperationName,
+		"messageTypeName":                templates.MessageTypeName,
+		"schemaTypeName":                 templates.SchemaTypeName,
+		"channelParametersTypeName":      templates.ChannelParametersTypeName,
+		"fieldName":                      templates.FieldName,
+		"parameterName":                  templates.ParameterName,
+		"referenceTypeName":              templates.ReferenceTypeName,
+		"correlationIDAttributePath":     templates.CorrelationIDAttributePath,
+		"keyAttributePath":               templates.KeyAttributePath,
+		"structTags":                     structTags,
 	}
 }
@@ -8,6 +8,7 @@ import (
 	"unicode"
 
 	"github.com/znas-io/asyncapi-codegen/pkg/asyncapi"
+	"github.com/znas-io/asyncapi-codegen/pkg/utils"
 )
 
 // NamifyWithoutParams will convert a sentence to a golang conventional type name.
@@ -53,8 +54,35 @@ func Namify(sentence string) string {
 var (
 	matchFirstCap = regexp.MustCompile("(.)([A-Z][a-z]+)")
 	matchAllCap   = regexp.MustCompile("([a-z0-9])([A-Z])")
+	matchWord     = regexp.MustCompile("[A-Z][a-z0-9]*|[a-z0-9]+")
 )
 
+// commonInitialisms is the list of initialisms that should be upper case in
+// golang names, as described in https://go.dev/wiki/CodeReviewComments#initialisms.
+var commonInitialisms = map[string]bool{
+	"ACL": true, "API": true, "ASCII": true, "CPU": true, "CSS": true,
+	"DNS": true, "EOF": true, "GUID": true, "HTML": true, "HTTP": true,
+	"HTTPS": true, "ID": true, "IP": true, "JSON": true, "LHS": true,
+	"QPS": true, "RAM": true, "RHS": true, "RPC": true, "SLA": true,
+	"SMTP": true, "SQL": true, "SSH": true, "TCP": true, "TLS": true,
+	"TTL": true, "UDP": true, "UI": true, "UID": true, "UUID": true,
+	"URI": true, "URL": true, "UTF8": true, "VM": true, "XML": true,
+	"XMPP": true, "XSRF": true, "XSS": true,
+}
+
+// Initialisms will upper case the common initialisms contained in a golang
+// name, i.e. "UserId" will become "UserID".
+func Initialisms(name string) string {
+	return matchWord.ReplaceAllStringFunc(name, func(word string) string {
+		if upper := strings.ToUpper(word); commonInitialisms[upper] {
+			return upper
+		}
+		return word
+	})
+}
+
+// CustomTag will return the custom tag if there is one, or the JSON tag with
+// the snake case version of the sentence.
 func CustomTag(customTag string, sentence string) string {
 	if customTag == "" {
 		return fmt.Sprintf(`json:"%v"`, SnakeCase(sentence))
@@ -70,6 +98,128 @@ func SnakeCase(sentence string) string {
 	return strings.ToLower(snake)
 }
 
+// CamelCase will convert a sentence to camel case.
+func CamelCase(sentence string) string {
+	runes := []rune(Namify(sentence))
+
+	// Lower the leading upper case letters, except the last one if it starts
+	// a new word (i.e. "IDValue" will become "idValue")
+	for i := 0; i < len(runes) && unicode.IsUpper(runes[i]); i++ {
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+
+	return string(runes)
+}
+
+// StructTag is a tag generated on struct fields from the property name.
+type StructTag struct {
+	// Key is the key of the tag (i.e. "json")
+	Key string
+	// Casing transforms the property name into the tag name
+	Casing func(name string) string
+}
+
+// StructTags will generate the struct tags of a schema property. The custom tag
+// set with 'x-custom-tag' replaces every generated tag, while tags set with
+// 'x-go-tags' are added or replace generated tags with the same key.
+func StructTags(name string, schema asyncapi.Schema, required bool, tags []StructTag, omitEmpty bool) string {
+	if schema.ExtCustomTag != "" {
+		return schema.ExtCustomTag
+	}
+
+	generated := make([]string, 0, len(tags)+len(schema.ExtGoTags))
+	for _, t := range tags {
+		value, exists := schema.ExtGoTags[t.Key]
+		if !exists {
+			value = t.Casing(name)
+			if omitEmpty && !required {
+				value += ",omitempty"
+			}
+		}
+		generated = append(generated, fmt.Sprintf(`%s:"%s"`, t.Key, value))
+	}
+
+	// Add tags that have not been generated
+	for _, k := range utils.SortedKeys(schema.ExtGoTags) {
+		if !hasStructTag(tags, k) {
+			generated = append(generated, fmt.Sprintf(`%s:"%s"`, k, schema.ExtGoTags[k]))
+		}
+	}
+
+	return strings.Join(generated, " ")
+}
+
+func hasStructTag(tags []StructTag, key string) bool {
+	for _, t := range tags {
+		if t.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// MessageTypeName will return the golang type name of a message.
+func MessageTypeName(msg asyncapi.Message) string {
+	if msg.GoName != "" {
+		return msg.GoName
+	}
+
+	return Namify(msg.Name) + "Message"
+}
+
+// SchemaTypeName will return the golang type name of a component schema.
+func SchemaTypeName(name string, schema *asyncapi.Schema) string {
+	if schema != nil && schema.GoName != "" {
+		return schema.GoName
+	}
+
+	return Namify(name) + "Schema"
+}
+
+// ChannelParametersTypeName will return the golang type name of the channel
+// parameters.
+func ChannelParametersTypeName(ch asyncapi.Channel) string {
+	if ch.ParametersGoName != "" {
+		return ch.ParametersGoName
+	}
+
+	return NamifyWithoutParams(ch.Name) + "Parameters"
+}
+
+// FieldName will return the golang field name of a schema property.
+func FieldName(name string, schema *asyncapi.Schema) string {
+	if schema != nil && schema.GoName != "" {
+		return schema.GoName
+	}
+
+	return Namify(name)
+}
+
+// ParameterName will return the golang field name of a channel parameter.
+func ParameterName(param asyncapi.Parameter) string {
+	if param.GoName != "" {
+		return param.GoName
+	}
+
+	return Namify(param.Name)
+}
+
+// ReferenceTypeName will return the golang type name of the element referenced
+// by a schema.
+func ReferenceTypeName(schema asyncapi.Schema) string {
+	// Use the name of the referenced component schema if there is one
+	isComponentSchema := len(strings.Split(schema.Reference, "/")) == 4 &&
+		strings.HasPrefix(schema.Reference, "#/components/schemas/")
+	if isComponentSchema && schema.ReferenceTo != nil && schema.ReferenceTo.GoName != "" {
+		return schema.ReferenceTo.GoName
+	}
+
+	return ReferenceToTypeName(schema.Reference)
+}
+
 // ReferenceToTypeName will convert a reference to a type name in the form of
 // golang conventional type names.
 func ReferenceToTypeName(ref string) string {
@@ -111,6 +261,48 @@ func ReferenceToStructAttributePath(ref string) string {
 	return strings.Join(path, ".")
 }
 
+// CorrelationIDAttributePath will convert the correlation ID location of a
+// message to a struct attribute path in the form of "a.b.c", using the golang
+// field names of the message headers or payload.
+func CorrelationIDAttributePath(msg asyncapi.Message) string {
+	return messageAttributePath(msg, msg.CorrelationIDLocation)
+}
+
+// KeyAttributePath will convert the message key location of a message to a
+// struct attribute path in the form of "a.b.c", using the golang field names of
+// the message headers or payload.
+func KeyAttributePath(msg asyncapi.Message) string {
+	return messageAttributePath(msg, msg.KeyLocation)
+}
+
+func messageAttributePath(msg asyncapi.Message, location string) string {
+	path := referenceToSlicePath(location)
+
+	// Get the top level schema
+	var schema *asyncapi.Schema
+	if path[0] == asyncapi.MessageTypeIsHeader.String() {
+		schema, path[0] = msg.Headers, "Headers"
+	} else {
+		schema, path[0] = msg.Payload, Namify(path[0])
+	}
+
+	// Go down the path, following references
+	for i, v := range path[1:] {
+		var child *asyncapi.Schema
+		if schema != nil && schema.ReferenceTo != nil {
+			schema = schema.ReferenceTo
+		}
+		if schema != nil {
+			child = schema.Properties[v]
+		}
+
+		path[i+1] = FieldName(v, child)
+		schema = child
+	}
+
+	return strings.Join(path, ".")
+}
+
 // HasField will check if a struct has a field with the given name.
 func HasField(v any, name string) bool {
 	rv := reflect.ValueOf(v)
@@ -129,9 +321,16 @@ func ChannelToMessageTypeName(ch asyncapi.Channel) string {
 	msg := ch.GetChannelMessage()
 
 	if msg.Payload != nil || msg.OneOf != nil {
+		if msg.GoName != "" {
+			return msg.GoName
+		}
 		return Namify(ch.Name) + "Message"
 	}
 
+	if msg.ReferenceTo != nil && msg.ReferenceTo.GoName != "" {
+		return msg.ReferenceTo.GoName
+	}
+
 	return ReferenceToTypeName(msg.Reference)
 }
 
@@ -154,7 +353,13 @@ func GenerateChannelPath(ch asyncapi.Channel) string {
 
 	sprint := fmt.Sprintf("fmt.Sprintf(%q, ", format)
 	for _, m := range matches {
-		sprint += fmt.Sprintf("params.%s,", Namify(m))
+		// Use the parameter field name if it is defined
+		name := Namify(m)
+		if param, ok := ch.Parameters[strings.Trim(m, "{}")]; ok && param != nil {
+			name = ParameterName(*param)
+		}
+
+		sprint += fmt.Sprintf("params.%s,", name)
 	}
 
 	return sprint[:len(sprint)-1] + ")"
@@ -181,6 +386,11 @@ func Args(vs ...any) []any {
 // OperationName returns `operationId` value from Publish or Subscribe operation if any.
 // If no `operationID` exists — return provided default value (`name`).
 func OperationName(channel asyncapi.Channel) string {
+	// Use the name set when resolving names if there is one
+	if channel.GoName != "" {
+		return channel.GoName
+	}
+
 	var name string
 
 	switch {
@@ -65,6 +65,91 @@ func (suite *HelpersSuite) TestNamifyWithoutParams() {
 	}
 }
 
+func (suite *HelpersSuite) TestInitialisms() {
+	cases := []namifyCases{
+		// Nothing
+		{In: "", Out: ""},
+		// Without initialism
+		{In: "UserName", Out: "UserName"},
+		// With initialisms
+		{In: "UserId", Out: "UserID"},
+		{In: "HttpUrlId", Out: "HTTPURLID"},
+		{In: "Url2Id", Out: "Url2ID"},
+		// Already upper case
+		{In: "TotoIDLala", Out: "TotoIDLala"},
+		// Without initialism, but still the same letters as the initialism
+		{In: "Identity", Out: "Identity"},
+	}
+
+	for i, c := range cases {
+		suite.Require().Equal(c.Out, Initialisms(c.In), i)
+	}
+}
+
+func (suite *HelpersSuite) TestCamelCase() {
+	cases := []namifyCases{
+		// Nothing
+		{In: "", Out: ""},
+		// Snake case
+		{In: "call_id", Out: "callId"},
+		// Already camel case
+		{In: "callId", Out: "callId"},
+		// With leading acronym
+		{In: "ID", Out: "id"},
+		{In: "IDValue", Out: "idValue"},
+	}
+
+	for i, c := range cases {
+		suite.Require().Equal(c.Out, CamelCase(c.In), i)
+	}
+}
+
+func (suite *HelpersSuite) TestStructTags() {
+	keep := func(name string) string { return name }
+	tags := []StructTag{{Key: "json", Casing: keep}, {Key: "db", Casing: SnakeCase}}
+
+	cases := []struct {
+		Schema    asyncapi.Schema
+		Required  bool
+		OmitEmpty bool
+		Result    string
+	}{
+		// Required field
+		{
+			Required: true, OmitEmpty: true,
+			Result: `json:"callId" db:"call_id"`,
+		},
+		// Optional field
+		{
+			OmitEmpty: true,
+			Result:    `json:"callId,omitempty" db:"call_id,omitempty"`,
+		},
+		// Optional field without omitempty
+		{
+			Result: `json:"callId" db:"call_id"`,
+		},
+		// With additional and overridden tags
+		{
+			Schema: asyncapi.Schema{Extensions: asyncapi.Extensions{
+				ExtGoTags: map[string]string{"validate": "required", "db": "-"},
+			}},
+			Result: `json:"callId" db:"-" validate:"required"`,
+		},
+		// With custom tag
+		{
+			Schema: asyncapi.Schema{Extensions: asyncapi.Extensions{
+				ExtCustomTag: `json:"custom"`,
+				ExtGoTags:    map[string]string{"validate": "required"},
+			}},
+			Result: `json:"custom"`,
+		},
+	}
+
+	for i, c := range cases {
+		suite.Require().Equal(c.Result, StructTags("callId", c.Schema, c.Required, tags, c.OmitEmpty), i)
+	}
+}
+
 func (suite *HelpersSuite) TestIsRequired() {
 	cases := []struct {
 		Schema asyncapi.Schema
@@ -128,6 +213,17 @@ func (suite *HelpersSuite) TestOperationName() {
 			},
 			Result: "Publish",
 		},
+		// With golang name
+		{
+			Channel: asyncapi.Channel{
+				Publish: &asyncapi.Operation{
+					OperationID: "Publish",
+				},
+				Name:   "Default",
+				GoName: "GoName",
+			},
+			Result: "GoName",
+		},
 	}
 
 	for i, c := range cases {
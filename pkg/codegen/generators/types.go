@@ -2,6 +2,7 @@ package generators
 
 import (
 	"bytes"
+	"text/template"
 
 	"github.com/znas-io/asyncapi-codegen/pkg/asyncapi"
 )
@@ -10,6 +11,9 @@ import (
 // contained in an asyncapi specification to golang structures code.
 type TypesGenerator struct {
 	asyncapi.Specification
+
+	// Tags are the options regarding the struct tags generation
+	Tags TagsOptions
 }
 
 // Generate will create a new types code generator.
@@ -24,6 +28,13 @@ func (tg TypesGenerator) Generate() (string, error) {
 		return "", err
 	}
 
+	// Set struct tags generation based on options
+	structTags, err := tg.Tags.structTagsFunction()
+	if err != nil {
+		return "", err
+	}
+	tmplt.Funcs(template.FuncMap{"structTags": structTags})
+
 	buf := new(bytes.Buffer)
 	if err := tmplt.Execute(buf, tg); err != nil {
 		return "", err
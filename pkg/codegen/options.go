@@ -15,6 +15,12 @@ type Options struct {
 	// Generate contains options regarding which golang code should be generated
 	Generate generators.Options
 
+	// Naming contains options regarding how golang names should be generated
+	Naming generators.NamingOptions
+
+	// Tags contains options regarding how struct tags should be generated
+	Tags generators.TagsOptions
+
 	// DisableFormatting states if the formatting should be disabled when
 	// writing the generated code
 	DisableFormatting bool
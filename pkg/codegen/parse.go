@@ -31,54 +31,75 @@ func FromFile(path string) (CodeGen, error) {
 
 // FromYAML parses the AsyncAPI specification from a YAML file.
 func FromYAML(data []byte) (CodeGen, error) {
-	// Verify specification
-	if err := verifySpecificationData(data); err != nil {
-		return CodeGen{}, err
-	}
-
 	// Change YAML to JSON
-	data, err := yaml.YAMLToJSON(data)
+	jsonData, err := yaml.YAMLToJSON(data)
 	if err != nil {
 		return CodeGen{}, err
 	}
 
-	// Parse JSON
-	return FromJSON(data)
+	// Parse JSON, keeping YAML as source to locate anomalies
+	return fromJSON(jsonData, data)
 }
 
 // FromJSON parses the AsyncAPI specification from a JSON file.
 func FromJSON(data []byte) (CodeGen, error) {
+	return fromJSON(data, data)
+}
+
+func fromJSON(data, source []byte) (CodeGen, error) {
 	var spec asyncapi.Specification
 
 	// Verify specification
-	if err := verifySpecificationData(data); err != nil {
-		return CodeGen{}, err
-	}
-
-	// Parse JSON
-	if err := json.Unmarshal(data, &spec); err != nil {
+	warnings, err := verifySpecificationData(data, source, &spec)
+	if err != nil {
 		return CodeGen{}, err
 	}
 
 	// Process specification
 	spec.Process()
 
-	return New(spec), nil
+	cg := New(spec)
+	cg.Warnings = warnings
+	return cg, nil
 }
 
-func verifySpecificationData(data []byte) error {
+// verifySpecificationData checks the specification against the AsyncAPI schema
+// and against what can be generated, then fills the specification with the data.
+// If there is any error, it will be returned as ValidationErrors, otherwise only
+// the warnings will be returned.
+func verifySpecificationData(data, source []byte, spec *asyncapi.Specification) (ValidationErrors, error) {
 	// Create a new parser
 	p, err := parser.New()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Check the result of the parsing, and display it if there is an error
-	var b bytes.Buffer
-	err = p(bytes.NewReader(data), &b)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, " --- Anomalies detected in specification ---%s\n", b.String())
+	// Decode the document to locate anomalies
+	var document any
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	v := newValidator(newLocator(document, source))
+
+	// Check the result of the parsing against the AsyncAPI schema
+	if err := p(bytes.NewReader(data), nil); err != nil {
+		v.addParserError(err)
+		v.errors.sort()
+		return nil, v.errors
+	}
+
+	// Parse JSON
+	if err := json.Unmarshal(data, spec); err != nil {
+		return nil, err
+	}
+
+	// Check that the specification can be used to generate code
+	v.checkReferences(document, "")
+	v.checkSpecification(*spec)
+	v.errors.sort()
+	if v.errors.HasErrors() {
+		return nil, v.errors
 	}
 
-	return err
+	return v.errors, nil
 }
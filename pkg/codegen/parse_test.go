@@ -0,0 +1,358 @@
+package codegen
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestParseSuite(t *testing.T) {
+	suite.Run(t, new(ParseSuite))
+}
+
+type ParseSuite struct {
+	suite.Suite
+}
+
+func (suite *ParseSuite) requireValidationErrors(spec string) ValidationErrors {
+	_, err := FromYAML([]byte(spec))
+	suite.Require().ErrorIs(err, ErrInvalidSpecification)
+
+	var errs ValidationErrors
+	suite.Require().True(errors.As(err, &errs))
+	return errs
+}
+
+func (suite *ParseSuite) TestFromYAMLWithSchemaError() {
+	errs := suite.requireValidationErrors(`asyncapi: 2.6.0
+info:
+  title: test
+  version: 1.0.0
+channels:
+  user.signup:
+    publish:
+      unknown: true
+      message:
+        payload:
+          type: string
+`)
+
+	suite.Require().Len(errs, 1)
+	suite.Require().Equal(ValidationError{
+		Pointer:  "/channels/user.signup/publish",
+		Line:     7,
+		Column:   5,
+		Severity: SeverityError,
+		Rule:     RuleAsyncAPISchema,
+		Message:  "Additional property unknown is not allowed",
+	}, errs[0])
+}
+
+func (suite *ParseSuite) TestFromYAMLWithCodegenErrors() {
+	errs := suite.requireValidationErrors(`asyncapi: 2.6.0
+info:
+  title: test
+  version: 1.0.0
+channels:
+  first:
+    publish:
+      operationId: duplicate
+      message:
+        payload:
+          type: "null"
+  second:
+    subscribe:
+      operationId: duplicate
+      message:
+        $ref: '#/channels/first/publish/message'
+  third/{id}:
+    parameters:
+      other:
+        schema:
+          type: string
+    publish:
+      message:
+        headers:
+          type: object
+`)
+
+	suite.Require().Equal(ValidationErrors{
+		{
+			Pointer: "/channels/first/publish/message/payload/type", Line: 11, Column: 11,
+			Severity: SeverityError, Rule: RuleUnknownType,
+			Message: `type "null" can't be generated, you can use 'x-go-type' to set it`,
+		},
+		{
+			Pointer: "/channels/second/subscribe/operationId", Line: 14, Column: 7,
+			Severity: SeverityError, Rule: RuleDuplicateOperationID,
+			Message: `operationId "duplicate" is already used in "/channels/first/publish"`,
+		},
+		{
+			Pointer: "/channels/second/subscribe/message/$ref", Line: 16, Column: 9,
+			Severity: SeverityError, Rule: RuleReferenceOutsideComponents,
+			Message: `reference "#/channels/first/publish/message" should point to an element in '#/components'`,
+		},
+		{
+			Pointer: "/channels/third~1{id}", Line: 17, Column: 3,
+			Severity: SeverityError, Rule: RuleMissingParameter,
+			Message: `parameter "id" from channel "third/{id}" is not defined in its parameters`,
+		},
+		{
+			Pointer: "/channels/third~1{id}/publish/message", Line: 23, Column: 7,
+			Severity: SeverityError, Rule: RuleMissingPayload,
+			Message: "message should have a payload",
+		},
+	}, errs)
+}
+
+func (suite *ParseSuite) TestFromYAMLWithoutParameters() {
+	errs := suite.requireValidationErrors(`asyncapi: 2.6.0
+info:
+  title: test
+  version: 1.0.0
+channels:
+  orders/{id}:
+    publish:
+      message:
+        payload:
+          type: string
+`)
+
+	suite.Require().Equal(ValidationErrors{
+		{
+			Pointer: "/channels/orders~1{id}", Line: 6, Column: 3,
+			Severity: SeverityError, Rule: RuleMissingParameter,
+			Message: `parameter "id" from channel "orders/{id}" is not defined in its parameters`,
+		},
+	}, errs)
+}
+
+func (suite *ParseSuite) TestFromYAMLWithUnresolvedReference() {
+	errs := suite.requireValidationErrors(`asyncapi: 2.6.0
+info:
+  title: test
+  version: 1.0.0
+channels:
+  first:
+    publish:
+      message:
+        $ref: '#/components/messages/missing'
+`)
+
+	suite.Require().Equal(ValidationErrors{
+		{
+			Pointer: "/channels/first/publish/message/$ref", Line: 9, Column: 9,
+			Severity: SeverityError, Rule: RuleUnresolvedReference,
+			Message: `reference "#/components/messages/missing" points to an element that does not exist`,
+		},
+	}, errs)
+}
+
+func (suite *ParseSuite) TestFromYAMLWithWarnings() {
+	cg, err := FromYAML([]byte(`asyncapi: 2.6.0
+info:
+  title: test
+  version: 1.0.0
+channels:
+  first:
+    publish:
+      message:
+        payload:
+          type: integer
+          format: int8
+`))
+	suite.Require().NoError(err)
+
+	suite.Require().Len(cg.Warnings, 1)
+	suite.Require().Equal(RuleUnknownFormat, cg.Warnings[0].Rule)
+	suite.Require().Equal(SeverityWarning, cg.Warnings[0].Severity)
+	suite.Require().Equal("/channels/first/publish/message/payload/format", cg.Warnings[0].Pointer)
+	suite.Require().Equal(11, cg.Warnings[0].Line)
+}
+
+func (suite *ParseSuite) TestFromYAMLWithInvalidReplies() {
+	errs := suite.requireValidationErrors(`asyncapi: 2.6.0
+info:
+  title: test
+  version: 1.0.0
+channels:
+  missing:
+    publish:
+      x-reply:
+        channel: unknown
+      message:
+        $ref: '#/components/messages/WithID'
+  wrongOperation:
+    publish:
+      x-reply:
+        channel: missing
+      message:
+        $ref: '#/components/messages/WithID'
+  withoutID:
+    publish:
+      x-reply:
+        channel: reply
+      message:
+        payload:
+          type: string
+  reply:
+    subscribe:
+      message:
+        $ref: '#/components/messages/WithID'
+components:
+  messages:
+    WithID:
+      headers:
+        type: object
+        properties:
+          correlationId:
+            type: string
+      payload:
+        type: string
+      correlationId:
+        location: $message.header#/correlationId
+`)
+
+	suite.Require().Equal(ValidationErrors{
+		{
+			Pointer: "/channels/missing/publish/x-reply/channel", Line: 9, Column: 9,
+			Severity: SeverityError, Rule: RuleInvalidReply,
+			Message: `reply channel "unknown" does not exist`,
+		},
+		{
+			Pointer: "/channels/wrongOperation/publish/x-reply/channel", Line: 15, Column: 9,
+			Severity: SeverityError, Rule: RuleInvalidReply,
+			Message: `reply channel "missing" should have a "subscribe" operation`,
+		},
+		{
+			Pointer: "/channels/withoutID/publish/x-reply", Line: 20, Column: 7,
+			Severity: SeverityError, Rule: RuleInvalidReply,
+			Message: `messages of the operation and of the reply channel "reply" should have a correlation ID`,
+		},
+	}, errs)
+}
+
+func (suite *ParseSuite) TestFromYAMLWithInvalidKeys() {
+	errs := suite.requireValidationErrors(`asyncapi: 2.6.0
+info:
+  title: test
+  version: 1.0.0
+channels:
+  valid:
+    publish:
+      message:
+        $ref: '#/components/messages/WithKey'
+  missing:
+    publish:
+      message:
+        x-key:
+          location: $message.payload#/unknown
+        payload:
+          type: object
+          properties:
+            id:
+              type: string
+components:
+  messages:
+    WithKey:
+      x-key:
+        location: $message.payload#/item/id
+      payload:
+        type: object
+        properties:
+          item:
+            $ref: '#/components/schemas/Item'
+    WithObjectKey:
+      x-key:
+        location: $message.header#/item
+      headers:
+        type: object
+        properties:
+          item:
+            type: object
+      payload:
+        type: string
+  schemas:
+    Item:
+      type: object
+      properties:
+        id:
+          type: integer
+`)
+
+	suite.Require().Equal(ValidationErrors{
+		{
+			Pointer: "/channels/missing/publish/message/x-key/location", Line: 14, Column: 11,
+			Severity: SeverityError, Rule: RuleInvalidKey,
+			Message: `key location "$message.payload#/unknown" should point to a field of the message headers or payload`,
+		},
+		{
+			Pointer: "/components/messages/WithObjectKey/x-key/location", Line: 32, Column: 9,
+			Severity: SeverityError, Rule: RuleInvalidKey,
+			Message: `key field "$message.header#/item" should not be an object`,
+		},
+	}, errs)
+}
+
+func (suite *ParseSuite) TestFromYAMLWithInvalidDeliveryMode() {
+	errs := suite.requireValidationErrors(`asyncapi: 2.6.0
+info:
+  title: test
+  version: 1.0.0
+channels:
+  valid:
+    x-delivery-mode: broadcast
+    publish:
+      message:
+        payload:
+          type: string
+  invalid:
+    x-delivery-mode: fanout
+    publish:
+      message:
+        payload:
+          type: string
+`)
+
+	suite.Require().Equal(ValidationErrors{
+		{
+			Pointer: "/channels/invalid/x-delivery-mode", Line: 13, Column: 5,
+			Severity: SeverityError, Rule: RuleInvalidDeliveryMode,
+			Message: `delivery mode "fanout" should be "queue" or "broadcast"`,
+		},
+	}, errs)
+}
+
+func (suite *ParseSuite) TestFromYAMLWithInvalidQoS() {
+	errs := suite.requireValidationErrors(`asyncapi: 2.6.0
+info:
+  title: test
+  version: 1.0.0
+channels:
+  valid:
+    publish:
+      bindings:
+        mqtt:
+          qos: 2
+      message:
+        payload:
+          type: string
+  invalid:
+    publish:
+      bindings:
+        mqtt:
+          qos: 3
+      message:
+        payload:
+          type: string
+`)
+
+	suite.Require().Equal(ValidationErrors{
+		{
+			Pointer: "/channels/invalid/publish/bindings/mqtt/qos", Line: 18, Column: 11,
+			Severity: SeverityError, Rule: RuleInvalidQoS,
+			Message: "MQTT QoS 3 should be 0, 1 or 2",
+		},
+	}, errs)
+}
@@ -0,0 +1,620 @@
+package codegen
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/znas-io/asyncapi-codegen/pkg/asyncapi"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+	"github.com/znas-io/asyncapi-codegen/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// Severity is the severity of a validation error.
+type Severity string
+
+const (
+	// SeverityError is the severity of a validation error that prevents the
+	// code generation.
+	SeverityError Severity = "error"
+	// SeverityWarning is the severity of a validation error that does not
+	// prevent the code generation, but that may lead to unexpected results.
+	SeverityWarning Severity = "warning"
+)
+
+// ValidationRule is the identifier of the rule that raised a validation error.
+type ValidationRule string
+
+const (
+	// RuleAsyncAPISchema is raised when the specification doesn't respect the
+	// AsyncAPI schema, as reported by the AsyncAPI parser.
+	RuleAsyncAPISchema ValidationRule = "asyncapi-schema"
+	// RuleReferenceOutsideComponents is raised when a reference doesn't point
+	// to the components of the specification.
+	RuleReferenceOutsideComponents ValidationRule = "reference-outside-components"
+	// RuleUnresolvedReference is raised when a reference points to nothing.
+	RuleUnresolvedReference ValidationRule = "unresolved-reference"
+	// RuleUnknownType is raised when a schema has a type that can't be generated.
+	RuleUnknownType ValidationRule = "unknown-type"
+	// RuleUnknownFormat is raised when a schema has a format that is not
+	// handled and that will be replaced by the default one.
+	RuleUnknownFormat ValidationRule = "unknown-format"
+	// RuleMissingOperation is raised when a channel has no operation.
+	RuleMissingOperation ValidationRule = "missing-operation"
+	// RuleMissingPayload is raised when a message has no payload.
+	RuleMissingPayload ValidationRule = "missing-payload"
+	// RuleMissingParameter is raised when a channel parameter is not defined.
+	RuleMissingParameter ValidationRule = "missing-parameter"
+	// RuleMissingParameterSchema is raised when a parameter has no schema.
+	RuleMissingParameterSchema ValidationRule = "missing-parameter-schema"
+	// RuleDuplicateOperationID is raised when an operationId is used more than once.
+	RuleDuplicateOperationID ValidationRule = "duplicate-operation-id"
+	// RuleInvalidReply is raised when the reply channel set with 'x-reply' can't
+	// be used to generate request/reply methods.
+	RuleInvalidReply ValidationRule = "invalid-reply"
+	// RuleInvalidKey is raised when the message key location set with 'x-key'
+	// doesn't point to a field that can be used as message key.
+	RuleInvalidKey ValidationRule = "invalid-key"
+	// RuleInvalidDeliveryMode is raised when the delivery mode set with
+	// 'x-delivery-mode' is unknown.
+	RuleInvalidDeliveryMode ValidationRule = "invalid-delivery-mode"
+	// RuleInvalidQoS is raised when the QoS of an MQTT operation binding is
+	// not 0, 1 or 2.
+	RuleInvalidQoS ValidationRule = "invalid-qos"
+)
+
+// ValidationError is an error found in the AsyncAPI specification.
+type ValidationError struct {
+	// Pointer is the JSON pointer to the faulty element of the specification
+	Pointer string
+	// Line is the line of the faulty element in the specification file,
+	// starting at 1 (0 if unknown)
+	Line int
+	// Column is the column of the faulty element in the specification file,
+	// starting at 1 (0 if unknown)
+	Column int
+	// Severity is the severity of the error
+	Severity Severity
+	// Rule is the identifier of the rule that raised the error
+	Rule ValidationRule
+	// Message is the human readable description of the error
+	Message string
+}
+
+// Error returns the string representation of the validation error.
+func (ve ValidationError) Error() string {
+	location := ve.Pointer
+	if location == "" {
+		location = "/"
+	}
+
+	if ve.Line > 0 {
+		location = fmt.Sprintf("%d:%d (%s)", ve.Line, ve.Column, location)
+	}
+
+	return fmt.Sprintf("%s: %s: %s [%s]", location, ve.Severity, ve.Message, ve.Rule)
+}
+
+// Unwrap returns the generic error for invalid specification.
+func (ve ValidationError) Unwrap() error {
+	return ErrInvalidSpecification
+}
+
+// ValidationErrors is a list of errors found in the AsyncAPI specification.
+type ValidationErrors []ValidationError
+
+// Error returns the string representation of the validation errors, one per line.
+func (ves ValidationErrors) Error() string {
+	lines := make([]string, 0, len(ves)+1)
+	lines = append(lines, fmt.Sprintf("%s (%d anomalies detected)", ErrInvalidSpecification.Error(), len(ves)))
+	for _, ve := range ves {
+		lines = append(lines, ve.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Unwrap returns every validation error.
+func (ves ValidationErrors) Unwrap() []error {
+	errs := make([]error, 0, len(ves))
+	for _, ve := range ves {
+		errs = append(errs, ve)
+	}
+	return errs
+}
+
+// HasErrors checks if there is at least one validation error with error severity.
+func (ves ValidationErrors) HasErrors() bool {
+	for _, ve := range ves {
+		if ve.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+func (ves ValidationErrors) sort() {
+	sort.SliceStable(ves, func(i, j int) bool {
+		if ves[i].Line != ves[j].Line {
+			return ves[i].Line < ves[j].Line
+		}
+		if ves[i].Column != ves[j].Column {
+			return ves[i].Column < ves[j].Column
+		}
+		return ves[i].Pointer < ves[j].Pointer
+	})
+}
+
+// locator finds elements in the specification from their JSON pointer.
+type locator struct {
+	document any
+	source   *yaml.Node
+}
+
+func newLocator(document any, source []byte) locator {
+	// Parse the source as YAML (also compatible with JSON) to get positions
+	var node yaml.Node
+	if err := yaml.Unmarshal(source, &node); err != nil || len(node.Content) == 0 {
+		return locator{document: document}
+	}
+
+	return locator{document: document, source: node.Content[0]}
+}
+
+// position returns the line and column of the element in the source file.
+// If the element can't be found, it will return the position of the closest
+// parent found.
+func (l locator) position(pointer string) (line, column int) {
+	node := l.source
+	if node == nil {
+		return 0, 0
+	}
+	line, column = node.Line, node.Column
+
+	for _, key := range pointerToKeys(pointer) {
+		var next *yaml.Node
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == key {
+					line, column = node.Content[i].Line, node.Content[i].Column
+					next = node.Content[i+1]
+					break
+				}
+			}
+		case yaml.SequenceNode:
+			if i, err := strconv.Atoi(key); err == nil && i >= 0 && i < len(node.Content) {
+				next = node.Content[i]
+				line, column = next.Line, next.Column
+			}
+		}
+
+		if next == nil {
+			return line, column
+		}
+		node = next
+	}
+
+	return line, column
+}
+
+// resolve returns the element of the document corresponding to the JSON
+// pointer, or nil if it doesn't exist.
+func (l locator) resolve(pointer string) any {
+	node := l.document
+	for _, key := range pointerToKeys(pointer) {
+		switch n := node.(type) {
+		case map[string]any:
+			node = n[key]
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(n) {
+				return nil
+			}
+			node = n[i]
+		default:
+			return nil
+		}
+	}
+	return node
+}
+
+// dottedPathToPointer converts a path in the form of 'a.b.c', as given by the
+// AsyncAPI parser, to a JSON pointer. As keys can also contain dots, the
+// document is used to find the longest existing key at each step.
+func (l locator) dottedPathToPointer(path string) string {
+	if path == "" || path == "(root)" {
+		return ""
+	}
+
+	var pointer string
+	node := l.document
+	parts := strings.Split(path, ".")
+	for len(parts) > 0 {
+		// Find the longest key that exists in the current node
+		length := 1
+		if m, ok := node.(map[string]any); ok {
+			for i := len(parts); i > 0; i-- {
+				if _, exists := m[strings.Join(parts[:i], ".")]; exists {
+					length = i
+					break
+				}
+			}
+		}
+
+		// Go down in the document
+		key := strings.Join(parts[:length], ".")
+		pointer += "/" + escapePointerKey(key)
+		node = locator{document: node}.resolve("/" + escapePointerKey(key))
+		parts = parts[length:]
+	}
+
+	return pointer
+}
+
+func pointerToKeys(pointer string) []string {
+	pointer = strings.TrimPrefix(pointer, "#")
+	if pointer == "" || pointer == "/" {
+		return nil
+	}
+
+	keys := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	for i, k := range keys {
+		keys[i] = strings.ReplaceAll(strings.ReplaceAll(k, "~1", "/"), "~0", "~")
+	}
+	return keys
+}
+
+func escapePointerKey(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
+
+// validator checks that a specification can be used to generate code.
+type validator struct {
+	locator      locator
+	errors       ValidationErrors
+	operationIDs map[string]string
+}
+
+func newValidator(l locator) *validator {
+	return &validator{
+		locator:      l,
+		errors:       make(ValidationErrors, 0),
+		operationIDs: make(map[string]string),
+	}
+}
+
+func (v *validator) add(pointer string, severity Severity, rule ValidationRule, format string, args ...any) {
+	line, column := v.locator.position(pointer)
+	v.errors = append(v.errors, ValidationError{
+		Pointer:  pointer,
+		Line:     line,
+		Column:   column,
+		Severity: severity,
+		Rule:     rule,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// addParserError adds the errors raised by the AsyncAPI parser, that are in
+// the form of 'path.to.element: message', one per line.
+func (v *validator) addParserError(err error) {
+	// Check references first, as the parser doesn't locate the faulty ones
+	v.checkReferences(v.locator.document, "")
+	referencesErrorsCount := len(v.errors)
+
+	alreadyAdded := make(map[string]bool)
+	for _, line := range strings.Split(err.Error(), "\n") {
+		if line == "" || alreadyAdded[line] {
+			continue
+		}
+		alreadyAdded[line] = true
+
+		var pointer string
+		msg := line
+		if path, m, found := strings.Cut(line, ": "); found && !strings.Contains(path, " ") {
+			// Skip references errors if they have already been located
+			if strings.HasPrefix(path, "#") {
+				if referencesErrorsCount > 0 {
+					continue
+				}
+				path, m = "", line
+			}
+
+			pointer, msg = v.locator.dottedPathToPointer(path), m
+		}
+
+		v.add(pointer, SeverityError, RuleAsyncAPISchema, "%s", msg)
+	}
+}
+
+func (v *validator) checkReferences(node any, pointer string) {
+	switch n := node.(type) {
+	case map[string]any:
+		for _, k := range utils.SortedKeys(n) {
+			if ref, ok := n[k].(string); ok && k == "$ref" {
+				v.checkReference(ref, pointer+"/$ref")
+				continue
+			}
+			v.checkReferences(n[k], pointer+"/"+escapePointerKey(k))
+		}
+	case []any:
+		for i, c := range n {
+			v.checkReferences(c, pointer+"/"+strconv.Itoa(i))
+		}
+	}
+}
+
+func (v *validator) checkReference(ref, pointer string) {
+	if !strings.HasPrefix(ref, "#/components/") {
+		v.add(pointer, SeverityError, RuleReferenceOutsideComponents,
+			"reference %q should point to an element in '#/components'", ref)
+		return
+	}
+
+	if v.locator.resolve(ref) == nil {
+		v.add(pointer, SeverityError, RuleUnresolvedReference,
+			"reference %q points to an element that does not exist", ref)
+	}
+}
+
+func (v *validator) checkSpecification(spec asyncapi.Specification) {
+	for _, name := range utils.SortedKeys(spec.Channels) {
+		v.checkChannel(name, spec.Channels[name], "/channels/"+escapePointerKey(name))
+		v.checkReplies(spec, spec.Channels[name], "/channels/"+escapePointerKey(name))
+		v.checkKeys(spec, spec.Channels[name], "/channels/"+escapePointerKey(name))
+	}
+
+	for _, name := range utils.SortedKeys(spec.Components.Messages) {
+		v.checkMessage(spec.Components.Messages[name], "/components/messages/"+escapePointerKey(name))
+		v.checkKey(spec, spec.Components.Messages[name], "/components/messages/"+escapePointerKey(name))
+	}
+
+	for _, name := range utils.SortedKeys(spec.Components.Schemas) {
+		v.checkSchema(spec.Components.Schemas[name], "/components/schemas/"+escapePointerKey(name))
+	}
+
+	for _, name := range utils.SortedKeys(spec.Components.Parameters) {
+		v.checkParameter(spec.Components.Parameters[name], "/components/parameters/"+escapePointerKey(name))
+	}
+}
+
+var channelParameterRegexp = regexp.MustCompile("{([^{}]*)}")
+
+func (v *validator) checkChannel(name string, ch *asyncapi.Channel, pointer string) {
+	if ch == nil || (ch.Publish == nil && ch.Subscribe == nil) {
+		v.add(pointer, SeverityError, RuleMissingOperation,
+			"channel %q should have at least a 'publish' or a 'subscribe' operation", name)
+		return
+	}
+
+	if ch.Publish != nil {
+		v.checkOperation(ch.Publish, pointer+"/publish")
+	}
+	if ch.Subscribe != nil {
+		v.checkOperation(ch.Subscribe, pointer+"/subscribe")
+	}
+
+	// Check that the delivery mode is known
+	if mode := extensions.DeliveryMode(ch.ExtDeliveryMode); mode != "" && !mode.IsValid() {
+		v.add(pointer+"/x-delivery-mode", SeverityError, RuleInvalidDeliveryMode,
+			"delivery mode %q should be %q or %q", mode, extensions.DeliveryModeQueue, extensions.DeliveryModeBroadcast)
+	}
+
+	// Check that every parameter in the channel name is defined, even without
+	// parameters
+	for _, match := range channelParameterRegexp.FindAllStringSubmatch(name, -1) {
+		if _, exists := ch.Parameters[match[1]]; !exists {
+			v.add(pointer, SeverityError, RuleMissingParameter,
+				"parameter %q from channel %q is not defined in its parameters", match[1], name)
+		}
+	}
+
+	for _, n := range utils.SortedKeys(ch.Parameters) {
+		v.checkParameter(ch.Parameters[n], pointer+"/parameters/"+escapePointerKey(n))
+	}
+}
+
+func (v *validator) checkReplies(spec asyncapi.Specification, ch *asyncapi.Channel, pointer string) {
+	if ch == nil {
+		return
+	}
+
+	// Replies to messages from 'publish' operation are received by the user,
+	// and replies to 'subscribe' operation are received by the application
+	if ch.Publish != nil && ch.Publish.ExtReply != nil {
+		v.checkReply(spec, ch.Publish, pointer+"/publish", "subscribe", func(reply *asyncapi.Channel) *asyncapi.Operation {
+			return reply.Subscribe
+		})
+	}
+	if ch.Subscribe != nil && ch.Subscribe.ExtReply != nil {
+		v.checkReply(spec, ch.Subscribe, pointer+"/subscribe", "publish", func(reply *asyncapi.Channel) *asyncapi.Operation {
+			return reply.Publish
+		})
+	}
+}
+
+func (v *validator) checkReply(
+	spec asyncapi.Specification,
+	op *asyncapi.Operation,
+	pointer, replyKind string,
+	replyOperation func(reply *asyncapi.Channel) *asyncapi.Operation,
+) {
+	name := op.ExtReply.Channel
+	pointer += "/x-reply"
+
+	// Check the reply channel
+	reply, exists := spec.Channels[name]
+	switch {
+	case !exists || reply == nil:
+		v.add(pointer+"/channel", SeverityError, RuleInvalidReply,
+			"reply channel %q does not exist", name)
+		return
+	case replyOperation(reply) == nil:
+		v.add(pointer+"/channel", SeverityError, RuleInvalidReply,
+			"reply channel %q should have a %q operation", name, replyKind)
+		return
+	case len(reply.Parameters) > 0:
+		v.add(pointer+"/channel", SeverityError, RuleInvalidReply,
+			"reply channel %q should not have parameters", name)
+		return
+	}
+
+	// Check that both messages have a correlation ID to match them
+	if !hasCorrelationID(spec, &op.Message) || !hasCorrelationID(spec, &replyOperation(reply).Message) {
+		v.add(pointer, SeverityError, RuleInvalidReply,
+			"messages of the operation and of the reply channel %q should have a correlation ID", name)
+	}
+}
+
+func hasCorrelationID(spec asyncapi.Specification, msg *asyncapi.Message) bool {
+	if msg.CorrelationID != nil {
+		return msg.CorrelationID.Location != ""
+	}
+
+	// References are checked independently
+	if strings.HasPrefix(msg.Reference, "#/components/messages/") {
+		if ref := spec.ReferenceMessage(msg.Reference); ref != nil && ref.CorrelationID != nil {
+			return ref.CorrelationID.Location != ""
+		}
+	}
+
+	return false
+}
+
+func (v *validator) checkKeys(spec asyncapi.Specification, ch *asyncapi.Channel, pointer string) {
+	if ch == nil {
+		return
+	}
+
+	if ch.Publish != nil {
+		v.checkKey(spec, &ch.Publish.Message, pointer+"/publish/message")
+	}
+	if ch.Subscribe != nil {
+		v.checkKey(spec, &ch.Subscribe.Message, pointer+"/subscribe/message")
+	}
+}
+
+func (v *validator) checkKey(spec asyncapi.Specification, msg *asyncapi.Message, pointer string) {
+	// References are checked independently
+	if msg == nil || msg.Reference != "" || msg.ExtKey == nil {
+		return
+	}
+
+	// Check that the key is a field of the message that is not a structure
+	location := msg.ExtKey.Location
+	field, _ := msg.FieldSchema(spec, location)
+	switch {
+	case field == nil:
+		v.add(pointer+"/x-key/location", SeverityError, RuleInvalidKey,
+			"key location %q should point to a field of the message headers or payload", location)
+	case field.Type == asyncapi.MessageTypeIsObject.String() || field.Type == asyncapi.MessageTypeIsArray.String():
+		v.add(pointer+"/x-key/location", SeverityError, RuleInvalidKey,
+			"key field %q should not be an %s", location, field.Type)
+	}
+}
+
+func (v *validator) checkOperation(op *asyncapi.Operation, pointer string) {
+	if op.OperationID != "" {
+		if first, exists := v.operationIDs[op.OperationID]; exists {
+			v.add(pointer+"/operationId", SeverityError, RuleDuplicateOperationID,
+				"operationId %q is already used in %q", op.OperationID, first)
+		} else {
+			v.operationIDs[op.OperationID] = pointer
+		}
+	}
+
+	if op.Bindings != nil && op.Bindings.MQTT != nil && (op.Bindings.MQTT.QoS < 0 || op.Bindings.MQTT.QoS > 2) {
+		v.add(pointer+"/bindings/mqtt/qos", SeverityError, RuleInvalidQoS,
+			"MQTT QoS %d should be 0, 1 or 2", op.Bindings.MQTT.QoS)
+	}
+
+	v.checkMessage(&op.Message, pointer+"/message")
+}
+
+func (v *validator) checkMessage(msg *asyncapi.Message, pointer string) {
+	// References are checked independently
+	if msg == nil || msg.Reference != "" {
+		return
+	}
+
+	if msg.Payload == nil && len(msg.OneOf) == 0 {
+		v.add(pointer, SeverityError, RuleMissingPayload, "message should have a payload")
+	}
+
+	if msg.Headers != nil {
+		v.checkSchema(msg.Headers, pointer+"/headers")
+	}
+	if msg.Payload != nil {
+		v.checkSchema(msg.Payload, pointer+"/payload")
+	}
+	for i, m := range msg.OneOf {
+		v.checkMessage(m, pointer+"/oneOf/"+strconv.Itoa(i))
+	}
+}
+
+func (v *validator) checkParameter(param *asyncapi.Parameter, pointer string) {
+	// References are checked independently
+	if param == nil || param.Reference != "" {
+		return
+	}
+
+	if param.Schema == nil {
+		v.add(pointer, SeverityError, RuleMissingParameterSchema, "parameter should have a schema")
+		return
+	}
+
+	v.checkSchema(param.Schema, pointer+"/schema")
+}
+
+func (v *validator) checkSchema(schema *asyncapi.Schema, pointer string) {
+	if schema == nil {
+		return
+	}
+
+	v.checkSchemaTypeAndFormat(schema, pointer)
+
+	for _, n := range utils.SortedKeys(schema.Properties) {
+		v.checkSchema(schema.Properties[n], pointer+"/properties/"+escapePointerKey(n))
+	}
+
+	if schema.Items != nil {
+		v.checkSchema(schema.Items, pointer+"/items")
+	}
+
+	for i, s := range schema.AllOf {
+		v.checkSchema(s, pointer+"/allOf/"+strconv.Itoa(i))
+	}
+	for i, s := range schema.AnyOf {
+		v.checkSchema(s, pointer+"/anyOf/"+strconv.Itoa(i))
+	}
+	for i, s := range schema.OneOf {
+		v.checkSchema(s, pointer+"/oneOf/"+strconv.Itoa(i))
+	}
+}
+
+func (v *validator) checkSchemaTypeAndFormat(schema *asyncapi.Schema, pointer string) {
+	// Custom types are not checked
+	if schema.ExtGoType != "" {
+		return
+	}
+
+	switch schema.Type {
+	case "", "object", "array", "string", "boolean":
+	case "integer":
+		if schema.Format != "" && schema.Format != "int32" && schema.Format != "int64" {
+			v.add(pointer+"/format", SeverityWarning, RuleUnknownFormat,
+				"format %q is not handled for integers, 'int64' will be used", schema.Format)
+		}
+	case "number":
+		if schema.Format != "" && schema.Format != "float" && schema.Format != "double" {
+			v.add(pointer+"/format", SeverityWarning, RuleUnknownFormat,
+				"format %q is not handled for numbers, 'double' will be used", schema.Format)
+		}
+	default:
+		v.add(pointer+"/type", SeverityError, RuleUnknownType,
+			"type %q can't be generated, you can use 'x-go-type' to set it", schema.Type)
+	}
+}
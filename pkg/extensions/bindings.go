@@ -0,0 +1,66 @@
+package extensions
+
+const (
+	// AMQPChannelIsRoutingKey is the AMQP channel type of channels that are
+	// routing keys on an exchange.
+	AMQPChannelIsRoutingKey = "routingKey"
+	// AMQPChannelIsQueue is the AMQP channel type of channels that are queues.
+	AMQPChannelIsQueue = "queue"
+)
+
+// AMQPChannelBinding is the AMQP binding of a channel from the specification,
+// set by the generated code in the context of publications and subscriptions
+// with ContextKeyIsAMQPChannelBinding, for brokers supporting it (i.e.
+// RabbitMQ).
+// Source: https://github.com/asyncapi/bindings/tree/master/amqp#channel-binding-object
+type AMQPChannelBinding struct {
+	// Is is the type of the channel: AMQPChannelIsRoutingKey (the default) or
+	// AMQPChannelIsQueue.
+	Is       string
+	Exchange AMQPExchange
+	Queue    AMQPQueue
+}
+
+// AMQPExchange is the exchange of an AMQP channel binding, used when the
+// channel is a routing key. Empty fields are set by the broker.
+type AMQPExchange struct {
+	Name       string
+	Type       string
+	Durable    bool
+	AutoDelete bool
+}
+
+// AMQPQueue is the queue of an AMQP channel binding, used when the channel is
+// a queue. Empty fields are set by the broker.
+type AMQPQueue struct {
+	Name       string
+	Durable    bool
+	Exclusive  bool
+	AutoDelete bool
+}
+
+// MQTTOperationBinding is the MQTT binding of an operation from the
+// specification, set by the generated code in the context of publications and
+// subscriptions with ContextKeyIsMQTTOperationBinding, for brokers supporting
+// it (i.e. MQTT).
+// Source: https://github.com/asyncapi/bindings/tree/master/mqtt#operation-binding-object
+type MQTTOperationBinding struct {
+	// QoS is the quality of service of the messages: 0 (at most once), 1 (at
+	// least once) or 2 (exactly once).
+	QoS byte
+	// Retain is true if the broker should keep the last message of the channel
+	// for future subscriptions.
+	Retain bool
+}
+
+// MQTTMessageBinding is the MQTT binding of a message from the specification,
+// set by the generated code in the context of publications and subscriptions
+// with ContextKeyIsMQTTMessageBinding, for brokers supporting it (i.e. MQTT 5).
+// Source: https://github.com/asyncapi/bindings/tree/master/mqtt#message-binding-object
+type MQTTMessageBinding struct {
+	// PayloadFormatIndicator is 1 if the payload is UTF-8 encoded character
+	// data, 0 if it is unspecified bytes.
+	PayloadFormatIndicator byte
+	// ContentType is the content type of the payload (i.e. a MIME type).
+	ContentType string
+}
@@ -10,6 +10,7 @@ import (
 type BrokerChannelSubscription struct {
 	messages chan BrokerMessage
 	cancel   chan any
+	done     chan struct{}
 }
 
 // NewBrokerChannelSubscription creates a new broker channel subscription based
@@ -18,6 +19,7 @@ func NewBrokerChannelSubscription(messages chan BrokerMessage, cancel chan any)
 	return BrokerChannelSubscription{
 		messages: messages,
 		cancel:   cancel,
+		done:     make(chan struct{}),
 	}
 }
 
@@ -46,8 +48,10 @@ func (bcs BrokerChannelSubscription) WaitForCancellationAsync(cleanup func()) {
 		// Close messages in order to avoid new messages
 		close(bcs.messages)
 
-		// Close cancel to let listeners know that the cancellation is complete
-		close(bcs.cancel)
+		// Close done to let listeners know that the cancellation is complete
+		// Note: cancel is not used for that, as the cancellation request could
+		// be received back by the requester before being handled.
+		close(bcs.done)
 	}()
 }
 
@@ -55,12 +59,18 @@ func (bcs BrokerChannelSubscription) WaitForCancellationAsync(cleanup func()) {
 // up on broker, which will return when finished to avoid dangling resources, such
 // as non-existent queue listeners on (broker) server side.
 func (bcs BrokerChannelSubscription) Cancel(ctx context.Context) {
-	// Send a cancellation request
-	bcs.cancel <- true
+	// Send a cancellation request, if the subscription is not already canceled
+	select {
+	case bcs.cancel <- true:
+	case <-bcs.done:
+		return
+	case <-ctx.Done():
+		return
+	}
 
 	// Wait for the cancellation to be effective
 	select {
-	case <-bcs.cancel:
+	case <-bcs.done:
 	case <-ctx.Done():
 	}
 }
@@ -69,6 +79,75 @@ func (bcs BrokerChannelSubscription) Cancel(ctx context.Context) {
 type BrokerMessage struct {
 	Headers map[string][]byte
 	Payload []byte
+
+	// Metadata contains broker specific information on received messages that
+	// are not part of the headers (i.e. Kafka message key). On published
+	// messages, it can only contain the message key.
+	Metadata map[string]string
+
+	// Acknowledgment is set by brokers supporting acknowledgments on received
+	// messages. It is used by Ack() and Nack().
+	Acknowledgment BrokerAcknowledgment
+}
+
+// MetadataKeyIsMessageKey is the metadata key of the message key, that is used
+// by brokers supporting message keys (i.e. Kafka) on published and received
+// messages.
+const MetadataKeyIsMessageKey = "key"
+
+// MetadataKeyIsReplyTo is the metadata key of the channel on which the reply to
+// a received message should be sent, set by brokers supporting native
+// request/reply (i.e. the NATS reply inbox).
+const MetadataKeyIsReplyTo = "reply-to"
+
+// BrokerAcknowledgment represents the functions that should be implemented by
+// brokers supporting acknowledgments of received messages.
+type BrokerAcknowledgment interface {
+	// AckMessage acknowledges the message as successfully handled.
+	AckMessage() error
+
+	// NackMessage negatively acknowledges the message as not handled, in order
+	// to get it redelivered.
+	NackMessage() error
+}
+
+// BrokerTerminator can be implemented by the acknowledgments of brokers
+// supporting the termination of received messages.
+type BrokerTerminator interface {
+	// TermMessage terminates the message as it can't be handled, in order to
+	// not get it redelivered.
+	TermMessage() error
+}
+
+// Ack acknowledges the message as successfully handled. It does nothing if the
+// broker doesn't support acknowledgments.
+func (bm BrokerMessage) Ack() error {
+	if bm.Acknowledgment == nil {
+		return nil
+	}
+
+	return bm.Acknowledgment.AckMessage()
+}
+
+// Nack negatively acknowledges the message as not handled, in order to get it
+// redelivered. It does nothing if the broker doesn't support acknowledgments.
+func (bm BrokerMessage) Nack() error {
+	if bm.Acknowledgment == nil {
+		return nil
+	}
+
+	return bm.Acknowledgment.NackMessage()
+}
+
+// Term terminates the message as it can't be handled, in order to not get it
+// redelivered. It negatively acknowledges the message if the broker doesn't
+// support termination, and does nothing if it doesn't support acknowledgments.
+func (bm BrokerMessage) Term() error {
+	if terminator, ok := bm.Acknowledgment.(BrokerTerminator); ok {
+		return terminator.TermMessage()
+	}
+
+	return bm.Nack()
 }
 
 // IsUninitialized check if the BrokerMessage is at zero value, i.e. the
@@ -92,8 +171,50 @@ func (bm BrokerMessage) String() string {
 	}
 }
 
+// DeliveryMode is the way the messages of a channel are delivered to the
+// subscriptions of the different instances of an application (i.e. controllers
+// with the same queue group).
+type DeliveryMode string
+
+const (
+	// DeliveryModeQueue delivers each message to only one subscription of the
+	// queue group (competing consumers). This is the default mode.
+	DeliveryModeQueue DeliveryMode = "queue"
+	// DeliveryModeBroadcast delivers each message to every subscription
+	// (fan-out), i.e. for cache invalidation.
+	DeliveryModeBroadcast DeliveryMode = "broadcast"
+)
+
+// IsValid checks that the delivery mode is a known one.
+func (m DeliveryMode) IsValid() bool {
+	return m == DeliveryModeQueue || m == DeliveryModeBroadcast
+}
+
+// DeliveryModeFromContext returns the delivery mode set in the context with
+// ContextKeyIsDeliveryMode, or DeliveryModeQueue if there is none. It should
+// be used by brokers when subscribing.
+func DeliveryModeFromContext(ctx context.Context) DeliveryMode {
+	mode := DeliveryModeQueue
+	IfContextSetWith(ctx, ContextKeyIsDeliveryMode, func(value DeliveryMode) {
+		if value.IsValid() {
+			mode = value
+		}
+	})
+
+	return mode
+}
+
 // BrokerController represents the functions that should be implemented to connect
 // the broker to the application or the user.
+//
+// Controllers supporting acknowledgments should set the Acknowledgment of the
+// received messages: they will be acknowledged when successfully handled, and
+// negatively acknowledged when the handling fails.
+//
+// Controllers should subscribe with the delivery mode from the context (see
+// DeliveryModeFromContext): in queue mode, each message is received by only one
+// subscription of the queue group, while in broadcast mode it is received by
+// every subscription.
 type BrokerController interface {
 	// Publish a message to the broker
 	Publish(ctx context.Context, channel string, mw BrokerMessage) error
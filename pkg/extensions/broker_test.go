@@ -1,6 +1,7 @@
 package extensions
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/suite"
@@ -21,3 +22,88 @@ func (suite *BrokerSuite) TestIsUninitialized() {
 		Headers: make(map[string][]byte),
 	}.IsUninitialized())
 }
+
+func (suite *BrokerSuite) TestCancel() {
+	for i := 0; i < 100; i++ {
+		sub := NewBrokerChannelSubscription(make(chan BrokerMessage, 1), make(chan any, 1))
+		cleaned := false
+		sub.WaitForCancellationAsync(func() { cleaned = true })
+
+		// Check that cancel returns once the cleanup is done
+		sub.Cancel(context.Background())
+		suite.Require().True(cleaned)
+		_, open := <-sub.MessagesChannel()
+		suite.Require().False(open)
+
+		// Check that cancel can be called again
+		sub.Cancel(context.Background())
+	}
+}
+
+type acknowledgment struct {
+	acks, nacks int
+}
+
+func (a *acknowledgment) AckMessage() error {
+	a.acks++
+	return nil
+}
+
+func (a *acknowledgment) NackMessage() error {
+	a.nacks++
+	return nil
+}
+
+func (suite *BrokerSuite) TestAcknowledgment() {
+	// Check that messages without acknowledgment can be acknowledged
+	suite.Require().NoError(BrokerMessage{}.Ack())
+	suite.Require().NoError(BrokerMessage{}.Nack())
+
+	// Check that acknowledgments are passed to the broker
+	ack := &acknowledgment{}
+	bm := BrokerMessage{Acknowledgment: ack}
+	suite.Require().NoError(bm.Ack())
+	suite.Require().NoError(bm.Nack())
+	suite.Require().NoError(bm.Nack())
+	suite.Require().Equal(1, ack.acks)
+	suite.Require().Equal(2, ack.nacks)
+}
+
+type terminator struct {
+	acknowledgment
+	terms int
+}
+
+func (t *terminator) TermMessage() error {
+	t.terms++
+	return nil
+}
+
+func (suite *BrokerSuite) TestTerm() {
+	// Check that messages without acknowledgment can be terminated
+	suite.Require().NoError(BrokerMessage{}.Term())
+
+	// Check that messages are negatively acknowledged if the broker doesn't
+	// support termination
+	ack := &acknowledgment{}
+	suite.Require().NoError(BrokerMessage{Acknowledgment: ack}.Term())
+	suite.Require().Equal(1, ack.nacks)
+
+	// Check that termination is passed to the broker
+	term := &terminator{}
+	suite.Require().NoError(BrokerMessage{Acknowledgment: term}.Term())
+	suite.Require().Equal(1, term.terms)
+	suite.Require().Zero(term.nacks)
+}
+
+func (suite *BrokerSuite) TestDeliveryModeFromContext() {
+	ctx := context.Background()
+	suite.Require().Equal(DeliveryModeQueue, DeliveryModeFromContext(ctx))
+
+	ctx = context.WithValue(ctx, ContextKeyIsDeliveryMode, DeliveryModeBroadcast)
+	suite.Require().Equal(DeliveryModeBroadcast, DeliveryModeFromContext(ctx))
+
+	// Unknown modes should be ignored
+	ctx = context.WithValue(ctx, ContextKeyIsDeliveryMode, DeliveryMode("unknown"))
+	suite.Require().Equal(DeliveryModeQueue, DeliveryModeFromContext(ctx))
+}
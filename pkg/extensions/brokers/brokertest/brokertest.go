@@ -0,0 +1,504 @@
+// Package brokertest provides a test suite that checks that an implementation
+// of extensions.BrokerController behaves as expected by the generated code.
+//
+// It can be used to test any broker controller, including custom ones:
+//
+//	func TestConformance(t *testing.T) {
+//		brokertest.Run(t, func(t *testing.T, queueGroup string) extensions.BrokerController {
+//			c := mybroker.NewController("<url>", mybroker.WithQueueGroup(queueGroup))
+//			t.Cleanup(c.Close)
+//			return c
+//		})
+//	}
+package brokertest
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+)
+
+const (
+	// DefaultTimeout is the default maximum duration to wait for messages.
+	DefaultTimeout = 10 * time.Second
+	// DefaultBurstSize is the default number of messages published at once
+	// when checking that there is no message loss under load.
+	DefaultBurstSize = 1000
+	// DefaultConcurrency is the default number of subscriptions or publishers
+	// used simultaneously when checking concurrency.
+	DefaultConcurrency = 10
+)
+
+// Factory creates a new broker controller connected to the tested broker.
+//
+// Controllers created with the same queue group should share the messages of
+// a channel (each message is received by only one of them), while controllers
+// with different queue groups should all receive the messages.
+//
+// The factory is responsible for closing the controller at the end of the
+// test (i.e. with t.Cleanup()).
+type Factory func(t *testing.T, queueGroup string) extensions.BrokerController
+
+// Suite is the conformance test suite for broker controllers.
+type Suite struct {
+	newController Factory
+	timeout       time.Duration
+	burstSize     int
+	concurrency   int
+	loadBalancing bool
+	headers       bool
+
+	suite.Suite
+}
+
+// SuiteOption is a function that can be used to configure the test suite.
+// Examples: WithTimeout(), WithBurstSize(), WithoutLoadBalancing().
+type SuiteOption func(suite *Suite)
+
+// NewSuite creates a new conformance test suite that will test the controllers
+// created by the factory.
+func NewSuite(newController Factory, options ...SuiteOption) *Suite {
+	// Create default suite
+	s := &Suite{
+		newController: newController,
+		timeout:       DefaultTimeout,
+		burstSize:     DefaultBurstSize,
+		concurrency:   DefaultConcurrency,
+		loadBalancing: true,
+		headers:       true,
+	}
+
+	// Execute options
+	for _, option := range options {
+		option(s)
+	}
+
+	return s
+}
+
+// Run runs the conformance test suite on the controllers created by the factory.
+func Run(t *testing.T, newController Factory, options ...SuiteOption) {
+	suite.Run(t, NewSuite(newController, options...))
+}
+
+// WithTimeout set the maximum duration to wait for messages.
+func WithTimeout(timeout time.Duration) SuiteOption {
+	return func(suite *Suite) {
+		suite.timeout = timeout
+	}
+}
+
+// WithBurstSize set the number of messages published at once when checking
+// that there is no message loss under load.
+func WithBurstSize(size int) SuiteOption {
+	return func(suite *Suite) {
+		suite.burstSize = size
+	}
+}
+
+// WithConcurrency set the number of subscriptions or publishers used
+// simultaneously when checking concurrency.
+func WithConcurrency(concurrency int) SuiteOption {
+	return func(suite *Suite) {
+		suite.concurrency = concurrency
+	}
+}
+
+// WithoutLoadBalancing disables the check that messages are spread between
+// every member of a queue group, for brokers that can deliver every message to
+// the same member (i.e. Kafka topics with only one partition). The check that
+// each message is received by only one member is still performed.
+func WithoutLoadBalancing() SuiteOption {
+	return func(suite *Suite) {
+		suite.loadBalancing = false
+	}
+}
+
+// WithoutHeaders disables the check that headers are received as they were
+// published, for brokers that don't support headers (i.e. MQTT 3.1.1).
+func WithoutHeaders() SuiteOption {
+	return func(suite *Suite) {
+		suite.headers = false
+	}
+}
+
+// uniqueName returns a name that is not shared with other tests, to avoid
+// receiving messages from previous executions on persistent brokers.
+func uniqueName(prefix string) string {
+	return fmt.Sprintf("%s-%d", prefix, time.Now().UnixNano())
+}
+
+func (suite *Suite) controller(queueGroup string) extensions.BrokerController {
+	return suite.newController(suite.T(), queueGroup)
+}
+
+func (suite *Suite) subscribe(c extensions.BrokerController, channel string) extensions.BrokerChannelSubscription {
+	return suite.subscribeWithMode(c, channel, extensions.DeliveryModeQueue)
+}
+
+func (suite *Suite) subscribeWithMode(
+	c extensions.BrokerController,
+	channel string,
+	mode extensions.DeliveryMode,
+) extensions.BrokerChannelSubscription {
+	ctx := context.WithValue(context.Background(), extensions.ContextKeyIsDeliveryMode, mode)
+	sub, err := c.Subscribe(ctx, channel)
+	suite.Require().NoError(err)
+	suite.T().Cleanup(func() { suite.cancel(sub) })
+	return sub
+}
+
+func (suite *Suite) publish(c extensions.BrokerController, channel string, bm extensions.BrokerMessage) {
+	err := c.Publish(context.Background(), channel, bm)
+	suite.Require().NoError(err)
+}
+
+// cancel cancels the subscription, without waiting more than the timeout.
+func (suite *Suite) cancel(sub extensions.BrokerChannelSubscription) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), suite.timeout)
+	defer cancel()
+
+	sub.Cancel(ctx)
+	return ctx.Err() == nil
+}
+
+func (suite *Suite) receive(sub extensions.BrokerChannelSubscription) extensions.BrokerMessage {
+	select {
+	case msg, open := <-sub.MessagesChannel():
+		suite.Require().True(open, "subscription has been closed")
+		return msg
+	case <-time.After(suite.timeout):
+		suite.Require().FailNow("no message received before timeout")
+		return extensions.BrokerMessage{}
+	}
+}
+
+// collect receives messages until the expected count is reached on all
+// subscriptions, then waits a bit more to catch unexpected messages. It returns
+// the payloads received by each subscription.
+func (suite *Suite) collect(expected int, subs ...extensions.BrokerChannelSubscription) [][]string {
+	var mutex sync.Mutex
+	received := make([][]string, len(subs))
+	count := 0
+
+	// Receive messages on every subscription
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i, sub := range subs {
+		wg.Add(1)
+		go func(i int, sub extensions.BrokerChannelSubscription) {
+			defer wg.Done()
+			for {
+				select {
+				case msg, open := <-sub.MessagesChannel():
+					if !open {
+						return
+					}
+
+					mutex.Lock()
+					received[i] = append(received[i], string(msg.Payload))
+					count++
+					mutex.Unlock()
+				case <-done:
+					return
+				}
+			}
+		}(i, sub)
+	}
+
+	// Wait for the expected count or the timeout
+	deadline := time.Now().Add(suite.timeout)
+	for time.Now().Before(deadline) {
+		mutex.Lock()
+		c := count
+		mutex.Unlock()
+
+		if c >= expected {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Wait a bit more for unexpected messages, then stop receiving
+	time.Sleep(suite.timeout / 20)
+	close(done)
+	wg.Wait()
+
+	return received
+}
+
+// TestHeadersRoundTrip checks that headers are received as they were published.
+// It is skipped if headers are disabled with WithoutHeaders().
+func (suite *Suite) TestHeadersRoundTrip() {
+	if !suite.headers {
+		suite.T().Skip("headers are not supported by the broker")
+	}
+
+	c := suite.controller(uniqueName("brokertest"))
+	channel := uniqueName("brokertest.headers")
+	sub := suite.subscribe(c, channel)
+
+	headers := map[string][]byte{
+		"correlationId": []byte("a8b1c9e0-8d6a-4fd4-a0f4-3c1d7f5b7c2e"),
+		"application":   []byte("v1.2.3"),
+		"x-custom":      []byte("value with spaces"),
+	}
+	suite.publish(c, channel, extensions.BrokerMessage{
+		Headers: headers,
+		Payload: []byte("payload"),
+	})
+
+	msg := suite.receive(sub)
+	for k, v := range headers {
+		suite.Require().Contains(msg.Headers, k)
+		suite.Require().Equal(string(v), string(msg.Headers[k]), "header %q", k)
+	}
+}
+
+// TestPayloadIntegrity checks that payloads are received without modification,
+// including binary and large payloads.
+func (suite *Suite) TestPayloadIntegrity() {
+	c := suite.controller(uniqueName("brokertest"))
+	channel := uniqueName("brokertest.payload")
+	sub := suite.subscribe(c, channel)
+
+	// Create payloads
+	binary := make([]byte, 256)
+	for i := range binary {
+		binary[i] = byte(i)
+	}
+	large := make([]byte, 256*1024)
+	for i := range large {
+		large[i] = byte(i * 7)
+	}
+	payloads := [][]byte{
+		[]byte(`{"text":"hello, world!","unicode":"héllo 世界"}`),
+		binary,
+		large,
+	}
+
+	// Publish and receive in order
+	for _, p := range payloads {
+		suite.publish(c, channel, extensions.BrokerMessage{Payload: p})
+	}
+	for i, p := range payloads {
+		msg := suite.receive(sub)
+		suite.Require().Equal(p, msg.Payload, "payload #%d", i)
+	}
+}
+
+// TestAcknowledgment checks that received messages can be acknowledged, and
+// are not redelivered afterwards.
+func (suite *Suite) TestAcknowledgment() {
+	c := suite.controller(uniqueName("brokertest"))
+	channel := uniqueName("brokertest.ack")
+	sub := suite.subscribe(c, channel)
+
+	suite.publish(c, channel, extensions.BrokerMessage{Payload: []byte("ack")})
+	suite.Require().NoError(suite.receive(sub).Ack())
+
+	received := suite.collect(0, sub)
+	suite.Require().Empty(received[0], "acknowledged message has been redelivered")
+}
+
+// TestRequest checks that requests sent with the broker native request/reply
+// are received with a reply channel, and get the reply sent on it. It is skipped
+// if the broker doesn't implement extensions.Requester.
+func (suite *Suite) TestRequest() {
+	c := suite.controller(uniqueName("brokertest"))
+	requester, ok := c.(extensions.Requester)
+	if !ok {
+		suite.T().Skip("broker controller doesn't implement extensions.Requester")
+	}
+
+	// Reply to the request on its reply channel
+	channel := uniqueName("brokertest.request")
+	sub := suite.subscribe(c, channel)
+	go func() {
+		msg, open := <-sub.MessagesChannel()
+		if !open {
+			return
+		}
+
+		reply := extensions.BrokerMessage{
+			Headers: map[string][]byte{"request": msg.Payload},
+			Payload: []byte("reply"),
+		}
+		_ = c.Publish(context.Background(), msg.Metadata[extensions.MetadataKeyIsReplyTo], reply)
+	}()
+
+	// Send the request and wait for the reply
+	ctx, cancel := context.WithTimeout(context.Background(), suite.timeout)
+	defer cancel()
+	reply, err := requester.Request(ctx, channel, extensions.BrokerMessage{Payload: []byte("request")}, uniqueName("brokertest.reply"))
+	suite.Require().NoError(err)
+	suite.Require().Equal("reply", string(reply.Payload))
+	suite.Require().Equal("request", string(reply.Headers["request"]))
+}
+
+// TestCancel checks that cancelling a subscription returns and closes the
+// messages channel.
+func (suite *Suite) TestCancel() {
+	c := suite.controller(uniqueName("brokertest"))
+	channel := uniqueName("brokertest.cancel")
+
+	sub, err := c.Subscribe(context.Background(), channel)
+	suite.Require().NoError(err)
+
+	// Check that cancel returns before the timeout
+	suite.Require().True(suite.cancel(sub), "cancel didn't return before timeout")
+
+	// Check that the messages channel is closed
+	select {
+	case _, open := <-sub.MessagesChannel():
+		suite.Require().False(open, "message received after cancellation")
+	case <-time.After(suite.timeout):
+		suite.Require().FailNow("messages channel not closed after cancellation")
+	}
+
+	// Check that publishing on the channel still works
+	suite.publish(c, channel, extensions.BrokerMessage{Payload: []byte("after cancel")})
+}
+
+// TestQueueGroup checks that each message is received by only one member of
+// a queue group, and by every queue group.
+func (suite *Suite) TestQueueGroup() {
+	group := uniqueName("brokertest")
+	c1, c2 := suite.controller(group), suite.controller(group)
+	other := suite.controller(group + "-other")
+	channel := uniqueName("brokertest.queue")
+
+	sub1, sub2 := suite.subscribe(c1, channel), suite.subscribe(c2, channel)
+	otherSub := suite.subscribe(other, channel)
+
+	// Publish messages
+	const count = 20
+	for i := 0; i < count; i++ {
+		suite.publish(c1, channel, extensions.BrokerMessage{Payload: []byte(strconv.Itoa(i))})
+	}
+
+	// Check that messages are received once by the queue group
+	received := suite.collect(count, sub1, sub2)
+	suite.Require().ElementsMatch(expectedPayloads(count), append(received[0], received[1]...))
+	if suite.loadBalancing {
+		suite.Require().NotEmpty(received[0], "first member of the queue group didn't receive any message")
+		suite.Require().NotEmpty(received[1], "second member of the queue group didn't receive any message")
+	}
+
+	// Check that messages are received by the other queue group
+	received = suite.collect(count, otherSub)
+	suite.Require().ElementsMatch(expectedPayloads(count), received[0])
+}
+
+// TestBroadcast checks that each message is received by every broadcast
+// subscription, including the ones of the same queue group, without changing
+// the delivery of queue subscriptions.
+func (suite *Suite) TestBroadcast() {
+	group := uniqueName("brokertest")
+	c1, c2 := suite.controller(group), suite.controller(group)
+	channel := uniqueName("brokertest.broadcast")
+
+	sub1 := suite.subscribeWithMode(c1, channel, extensions.DeliveryModeBroadcast)
+	sub2 := suite.subscribeWithMode(c2, channel, extensions.DeliveryModeBroadcast)
+	queueSub := suite.subscribe(c2, channel)
+
+	// Publish messages
+	const count = 20
+	for i := 0; i < count; i++ {
+		suite.publish(c1, channel, extensions.BrokerMessage{Payload: []byte(strconv.Itoa(i))})
+	}
+
+	// Check that messages are received by every broadcast subscription
+	received := suite.collect(2*count, sub1, sub2)
+	suite.Require().ElementsMatch(expectedPayloads(count), received[0])
+	suite.Require().ElementsMatch(expectedPayloads(count), received[1])
+
+	// Check that messages are still received by the queue group
+	received = suite.collect(count, queueSub)
+	suite.Require().ElementsMatch(expectedPayloads(count), received[0])
+}
+
+// TestBurst checks that no message is lost when publishing many messages
+// simultaneously.
+func (suite *Suite) TestBurst() {
+	c := suite.controller(uniqueName("brokertest"))
+	channel := uniqueName("brokertest.burst")
+	sub := suite.subscribe(c, channel)
+
+	// Publish messages from concurrent publishers
+	errs := make(chan error, suite.burstSize)
+	var wg sync.WaitGroup
+	for p := 0; p < suite.concurrency; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := p; i < suite.burstSize; i += suite.concurrency {
+				if err := c.Publish(context.Background(), channel, extensions.BrokerMessage{
+					Payload: []byte(strconv.Itoa(i)),
+				}); err != nil {
+					errs <- err
+				}
+			}
+		}(p)
+	}
+
+	// Check that every message is received exactly once
+	received := suite.collect(suite.burstSize, sub)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		suite.Require().NoError(err)
+	}
+	suite.Require().ElementsMatch(expectedPayloads(suite.burstSize), received[0])
+}
+
+// TestConcurrentSubscriptions checks that subscriptions can be created and
+// cancelled concurrently, and that the broker still works afterwards.
+func (suite *Suite) TestConcurrentSubscriptions() {
+	c := suite.controller(uniqueName("brokertest"))
+	channel := uniqueName("brokertest.concurrent")
+
+	// Subscribe and cancel concurrently
+	errs := make(chan error, suite.concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < suite.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			sub, err := c.Subscribe(context.Background(), channel)
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			if !suite.cancel(sub) {
+				errs <- fmt.Errorf("cancel didn't return before timeout")
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		suite.Require().NoError(err)
+	}
+
+	// Check that a new subscription still receives messages
+	sub := suite.subscribe(c, channel)
+	suite.publish(c, channel, extensions.BrokerMessage{Payload: []byte("after")})
+	suite.Require().Equal("after", string(suite.receive(sub).Payload))
+}
+
+func expectedPayloads(count int) []string {
+	payloads := make([]string, count)
+	for i := range payloads {
+		payloads[i] = strconv.Itoa(i)
+	}
+	return payloads
+}
@@ -0,0 +1,634 @@
+package jetstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions/brokers"
+)
+
+// Check that it still fills the interface.
+var _ extensions.BrokerController = (*Controller)(nil)
+
+// ErrInvalidBackoff is raised when there are backoff delays without a greater
+// maximum number of deliveries, as JetStream requires it.
+var ErrInvalidBackoff = fmt.Errorf("%w: the maximum number of deliveries must be greater than the number of backoff delays",
+	extensions.ErrAsyncAPI)
+
+const (
+	// DefaultFetchBatchSize is the default maximum number of messages fetched
+	// at once by pull consumers.
+	DefaultFetchBatchSize = 64
+
+	// DefaultPublishTimeout is the default maximum duration to wait for the
+	// acknowledgment of a published message by JetStream.
+	DefaultPublishTimeout = 5 * time.Second
+
+	// fetchTimeout is the maximum duration of a fetch request of pull
+	// consumers, after which a new one is sent.
+	fetchTimeout = 5 * time.Second
+
+	// fetchMinDelay and fetchMaxDelay are the bounds of the delay before
+	// fetching again after a failed fetch request, doubled on each failure.
+	fetchMinDelay = 100 * time.Millisecond
+	fetchMaxDelay = 5 * time.Second
+
+	// broadcastInactiveThreshold is the duration after which the consumers of
+	// broadcast subscriptions are deleted by JetStream if they are not used.
+	broadcastInactiveThreshold = time.Minute
+)
+
+const (
+	// MetadataKeyIsSequence is the key of the received messages metadata that
+	// contains the sequence of the message in its stream.
+	MetadataKeyIsSequence = "jetstream-sequence"
+
+	// MetadataKeyIsDeliveries is the key of the received messages metadata that
+	// contains the number of times the message has been delivered.
+	MetadataKeyIsDeliveries = "jetstream-deliveries"
+)
+
+// Controller is the NATS JetStream implementation for asyncapi-codegen.
+//
+// Messages of a channel are persisted in the stream set with WithStream whose
+// subjects contain the channel, or in a stream named after the channel that is
+// created on first use.
+//
+// Received messages are delivered by a durable consumer per queue group and
+// channel, that is created on first use and kept when unsubscribing: messages
+// published in the meantime are received on the next subscription. Broadcast
+// subscriptions have their own consumer instead, receiving the messages
+// published while they are subscribed. Messages are
+// acknowledged when handled, negatively acknowledged to be redelivered (after
+// the backoff delay, if there is one) or terminated to not be redelivered.
+type Controller struct {
+	connection        *nats.Conn
+	connectionOptions []nats.Option
+	jetStream         nats.JetStreamContext
+	logger            extensions.Logger
+	queueGroup        string
+
+	// Streams
+	streams        []nats.StreamConfig
+	streamConfig   nats.StreamConfig
+	channelStreams map[string]string
+	mutex          sync.Mutex
+
+	// Publication only
+	publishTimeout time.Duration
+
+	// Reception only
+	push       bool
+	fetchBatch int
+	maxDeliver int
+	backoff    []time.Duration
+	ackWait    time.Duration
+}
+
+// ControllerOption is a function that can be used to configure a JetStream
+// controller.
+// Examples: WithQueueGroup(), WithStream(), WithMaxDeliver(), WithLogger().
+type ControllerOption func(controller *Controller)
+
+// NewController creates a new JetStream controller, and the streams set with
+// WithStream if they don't exist.
+func NewController(url string, options ...ControllerOption) (*Controller, error) {
+	// Creates default controller
+	controller := &Controller{
+		queueGroup:     brokers.DefaultQueueGroupID,
+		logger:         extensions.DummyLogger{},
+		streamConfig:   nats.StreamConfig{Storage: nats.FileStorage},
+		channelStreams: make(map[string]string),
+		publishTimeout: DefaultPublishTimeout,
+		fetchBatch:     DefaultFetchBatchSize,
+		maxDeliver:     -1,
+	}
+
+	// Execute options
+	for _, option := range options {
+		option(controller)
+	}
+
+	// Check the redelivery settings
+	if len(controller.backoff) > 0 && controller.maxDeliver <= len(controller.backoff) {
+		return nil, ErrInvalidBackoff
+	}
+
+	// Connect to NATS
+	nc, err := nats.Connect(url, controller.connectionOptions...)
+	if err != nil {
+		return nil, err
+	}
+	controller.connection = nc
+
+	// Get JetStream context
+	controller.jetStream, err = nc.JetStream()
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+
+	// Create the streams
+	for _, config := range controller.streams {
+		if err := controller.addStream(config); err != nil {
+			nc.Close()
+			return nil, err
+		}
+	}
+
+	return controller, nil
+}
+
+// WithQueueGroup set a custom queue group for channel subscription: members of
+// the same queue group share the durable consumer of a channel.
+func WithQueueGroup(name string) ControllerOption {
+	return func(controller *Controller) {
+		controller.queueGroup = name
+	}
+}
+
+// WithLogger set a custom logger that will log operations on broker controller.
+func WithLogger(logger extensions.Logger) ControllerOption {
+	return func(controller *Controller) {
+		controller.logger = logger
+	}
+}
+
+// WithConnectionOptions set the options of the connection to the NATS server
+// (i.e. credentials, TLS, NKeys or JWT, reconnection settings or connection
+// name), from github.com/nats-io/nats.go.
+func WithConnectionOptions(options ...nats.Option) ControllerOption {
+	return func(controller *Controller) {
+		controller.connectionOptions = append(controller.connectionOptions, options...)
+	}
+}
+
+// WithStream set a stream that is created if it doesn't exist, or updated with
+// the configuration otherwise. Channels matching its subjects are persisted in
+// it, instead of in a stream created for the channel.
+func WithStream(config nats.StreamConfig) ControllerOption {
+	return func(controller *Controller) {
+		controller.streams = append(controller.streams, config)
+	}
+}
+
+// WithStreamConfig set the configuration of the streams created for channels
+// without stream (i.e. storage, retention, maximum age or duplicates window).
+// Their name and subjects are set from the channel.
+func WithStreamConfig(config nats.StreamConfig) ControllerOption {
+	return func(controller *Controller) {
+		controller.streamConfig = config
+	}
+}
+
+// WithPublishTimeout set the maximum duration to wait for the acknowledgment of
+// a published message by JetStream, if the context has no earlier deadline.
+func WithPublishTimeout(timeout time.Duration) ControllerOption {
+	return func(controller *Controller) {
+		controller.publishTimeout = timeout
+	}
+}
+
+// WithPushConsumers set the controller to use push consumers, that deliver the
+// messages to the subscriptions as soon as possible, instead of pull consumers,
+// that fetch them by batches.
+func WithPushConsumers() ControllerOption {
+	return func(controller *Controller) {
+		controller.push = true
+	}
+}
+
+// WithFetchBatchSize set the maximum number of messages fetched at once by pull
+// consumers.
+func WithFetchBatchSize(size int) ControllerOption {
+	return func(controller *Controller) {
+		controller.fetchBatch = size
+	}
+}
+
+// WithMaxDeliver set the maximum number of times a message is delivered before
+// being dropped by the consumer.
+func WithMaxDeliver(maxDeliver int) ControllerOption {
+	return func(controller *Controller) {
+		controller.maxDeliver = maxDeliver
+	}
+}
+
+// WithBackoff set the delays before redelivering a message, for each delivery:
+// the last one is used for the next deliveries. It applies to negatively
+// acknowledged messages and to messages not acknowledged in time. The maximum
+// number of deliveries should be greater than the number of delays.
+func WithBackoff(delays ...time.Duration) ControllerOption {
+	return func(controller *Controller) {
+		controller.backoff = delays
+	}
+}
+
+// WithAckWait set the maximum duration to acknowledge a message before it is
+// redelivered, if there is no backoff.
+func WithAckWait(wait time.Duration) ControllerOption {
+	return func(controller *Controller) {
+		controller.ackWait = wait
+	}
+}
+
+// Publish a message to the broker. The correlation ID of the message from the
+// context, if there is one, is used with the channel as message ID so that
+// JetStream ignores duplicates.
+func (c *Controller) Publish(ctx context.Context, channel string, bm extensions.BrokerMessage) error {
+	// Check that the channel is persisted in a stream
+	if _, err := c.stream(channel); err != nil {
+		return err
+	}
+
+	// Set the message ID from the channel and the correlation ID, as messages
+	// of different channels can have the same one (i.e. requests and replies)
+	opts := make([]nats.PubOpt, 0, 2)
+	if id, ok := ctx.Value(extensions.ContextKeyIsCorrelationID).(string); ok && id != "" {
+		opts = append(opts, nats.MsgId(channel+":"+id))
+	}
+
+	// Publish message and wait for its acknowledgment
+	ctx, cancel := context.WithTimeout(ctx, c.publishTimeout)
+	defer cancel()
+	_, err := c.jetStream.PublishMsg(newNATSMessage(channel, bm), append(opts, nats.Context(ctx))...)
+	return err
+}
+
+// Subscribe to messages from the broker.
+func (c *Controller) Subscribe(ctx context.Context, channel string) (extensions.BrokerChannelSubscription, error) {
+	// Create the consumer of the queue group, or of the subscription if
+	// messages are broadcast
+	stream, err := c.stream(channel)
+	if err != nil {
+		return extensions.BrokerChannelSubscription{}, err
+	}
+	broadcast := extensions.DeliveryModeFromContext(ctx) == extensions.DeliveryModeBroadcast
+	var consumer string
+	if broadcast {
+		consumer, err = c.broadcastConsumer(stream, channel)
+	} else {
+		consumer, err = c.consumer(stream, channel)
+	}
+	if err != nil {
+		return extensions.BrokerChannelSubscription{}, err
+	}
+
+	// Create a new subscription
+	sub := extensions.NewBrokerChannelSubscription(
+		make(chan extensions.BrokerMessage, brokers.BrokerMessagesQueueSize),
+		make(chan any, 1),
+	)
+
+	// Receive the messages of the consumer
+	var stop func()
+	if c.push {
+		stop, err = c.consumePush(ctx, stream, channel, consumer, sub)
+	} else {
+		stop, err = c.consumePull(ctx, stream, channel, consumer, sub)
+	}
+	if err != nil {
+		if broadcast {
+			c.deleteConsumer(ctx, stream, consumer)
+		}
+		return extensions.BrokerChannelSubscription{}, err
+	}
+
+	// Wait for cancellation and stop the reception, the consumer being kept
+	// unless it is the one of the subscription
+	sub.WaitForCancellationAsync(func() {
+		stop()
+		if broadcast {
+			c.deleteConsumer(ctx, stream, consumer)
+		}
+	})
+
+	return sub, nil
+}
+
+// consumePull fetches the messages of the pull consumer until the subscription
+// is canceled. It returns a function stopping the reception.
+func (c *Controller) consumePull(
+	ctx context.Context,
+	stream, channel, consumer string,
+	sub extensions.BrokerChannelSubscription,
+) (func(), error) {
+	// Bind to the pull consumer
+	natsSub, err := c.jetStream.PullSubscribe(channel, consumer, nats.Bind(stream, consumer))
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch messages until cancellation
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		var delay time.Duration
+		for ctx.Err() == nil {
+			if err := c.fetch(ctx, natsSub, sub); err == nil {
+				delay = 0
+				continue
+			}
+
+			// Wait before fetching again, longer after each failure
+			delay = min(max(2*delay, fetchMinDelay), fetchMaxDelay)
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+		if err := natsSub.Unsubscribe(); err != nil {
+			c.logger.Error(ctx, err.Error())
+		}
+	}, nil
+}
+
+// fetch transmits a batch of messages of the pull consumer, as soon as they are
+// received. It returns an error if the fetch request failed.
+func (c *Controller) fetch(ctx context.Context, natsSub *nats.Subscription, sub extensions.BrokerChannelSubscription) error {
+	fetchCtx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+
+	// Request a batch of messages
+	batch, err := natsSub.FetchBatch(c.fetchBatch, nats.Context(fetchCtx))
+	if err != nil {
+		if ctx.Err() == nil {
+			c.logger.Warning(ctx, fmt.Sprintf("Error when fetching messages: %q", err.Error()))
+		}
+		return err
+	}
+
+	// Transmit the messages until the end of the batch
+	for msg := range batch.Messages() {
+		sub.TransmitReceivedMessage(c.newBrokerMessage(ctx, msg))
+	}
+	if err := batch.Error(); err != nil && ctx.Err() == nil && !errors.Is(err, context.DeadlineExceeded) {
+		c.logger.Warning(ctx, fmt.Sprintf("Error when fetching messages: %q", err.Error()))
+		return err
+	}
+
+	return nil
+}
+
+// consumePush receives the messages of the push consumer until the subscription
+// is canceled. It returns a function stopping the reception.
+func (c *Controller) consumePush(
+	ctx context.Context,
+	stream, channel, consumer string,
+	sub extensions.BrokerChannelSubscription,
+) (func(), error) {
+	// Messages can't be transmitted once the subscription is closed, so
+	// handlers check it and the stop waits for the running ones
+	var mutex sync.RWMutex
+	closed := false
+
+	// Bind to the push consumer
+	natsSub, err := c.jetStream.QueueSubscribe(channel, consumer, func(msg *nats.Msg) {
+		mutex.RLock()
+		defer mutex.RUnlock()
+
+		// Get the message redelivered if the subscription is closed
+		if closed {
+			if err := msg.Nak(); err != nil {
+				c.logger.Error(ctx, err.Error())
+			}
+			return
+		}
+
+		sub.TransmitReceivedMessage(c.newBrokerMessage(ctx, msg))
+	}, nats.Bind(stream, consumer), nats.ManualAck())
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		if err := natsSub.Unsubscribe(); err != nil {
+			c.logger.Error(ctx, err.Error())
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		closed = true
+	}, nil
+}
+
+// stream returns the name of the stream of the channel, creating it if there
+// is none.
+func (c *Controller) stream(channel string) (string, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Get the stream from cache
+	if name, ok := c.channelStreams[channel]; ok {
+		return name, nil
+	}
+
+	// Get the stream containing the channel, or create it
+	name, err := c.jetStream.StreamNameBySubject(channel)
+	switch {
+	case errors.Is(err, nats.ErrNoMatchingStream):
+		config := c.streamConfig
+		config.Name = validName(channel)
+		config.Subjects = []string{channel}
+		if _, err := c.jetStream.AddStream(&config); err != nil {
+			return "", err
+		}
+		name = config.Name
+	case err != nil:
+		return "", err
+	}
+
+	c.channelStreams[channel] = name
+	return name, nil
+}
+
+// addStream creates the stream, or updates it if it already exists.
+func (c *Controller) addStream(config nats.StreamConfig) error {
+	_, err := c.jetStream.AddStream(&config)
+	if errors.Is(err, nats.ErrStreamNameAlreadyInUse) {
+		_, err = c.jetStream.UpdateStream(&config)
+	}
+
+	return err
+}
+
+// consumer returns the name of the durable consumer of the channel for the
+// queue group, creating it if it doesn't exist or updating its configuration.
+func (c *Controller) consumer(stream, channel string) (string, error) {
+	config := nats.ConsumerConfig{
+		Durable:       validName(c.queueGroup + "-" + channel),
+		FilterSubject: channel,
+		DeliverPolicy: nats.DeliverAllPolicy,
+		AckPolicy:     nats.AckExplicitPolicy,
+		AckWait:       c.ackWait,
+		MaxDeliver:    c.maxDeliver,
+		BackOff:       c.backoff,
+	}
+	if c.push {
+		config.DeliverSubject = "_ASYNCAPI.deliver." + config.Durable
+		config.DeliverGroup = config.Durable
+	}
+
+	// Update the consumer if it already exists
+	_, err := c.jetStream.ConsumerInfo(stream, config.Durable)
+	switch {
+	case errors.Is(err, nats.ErrConsumerNotFound):
+		_, err = c.jetStream.AddConsumer(stream, &config)
+	case err == nil:
+		_, err = c.jetStream.UpdateConsumer(stream, &config)
+	}
+
+	return config.Durable, err
+}
+
+// broadcastConsumer creates a consumer of the channel for one subscription,
+// receiving the messages published after its creation. It is deleted when the
+// subscription is canceled, or by JetStream once inactive (i.e. if the
+// application stops without canceling it).
+func (c *Controller) broadcastConsumer(stream, channel string) (string, error) {
+	config := nats.ConsumerConfig{
+		Durable:           validName(c.queueGroup + "-" + channel + "-" + uuid.New().String()),
+		FilterSubject:     channel,
+		DeliverPolicy:     nats.DeliverNewPolicy,
+		AckPolicy:         nats.AckExplicitPolicy,
+		AckWait:           c.ackWait,
+		MaxDeliver:        c.maxDeliver,
+		BackOff:           c.backoff,
+		InactiveThreshold: broadcastInactiveThreshold,
+	}
+	if c.push {
+		config.DeliverSubject = "_ASYNCAPI.deliver." + config.Durable
+		config.DeliverGroup = config.Durable
+	}
+
+	_, err := c.jetStream.AddConsumer(stream, &config)
+	return config.Durable, err
+}
+
+// deleteConsumer deletes the consumer, logging the errors.
+func (c *Controller) deleteConsumer(ctx context.Context, stream, consumer string) {
+	if err := c.jetStream.DeleteConsumer(stream, consumer); err != nil {
+		c.logger.Error(ctx, err.Error())
+	}
+}
+
+// validName returns a stream or consumer name from a subject, replacing the
+// characters that are not allowed.
+func validName(subject string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '*', '>', '/', '\\', ' ', '\t':
+			return '_'
+		default:
+			return r
+		}
+	}, subject)
+}
+
+func newNATSMessage(subject string, bm extensions.BrokerMessage) *nats.Msg {
+	msg := nats.NewMsg(subject)
+
+	// Set message headers and content
+	for k, v := range bm.Headers {
+		msg.Header.Set(k, string(v))
+	}
+	msg.Data = bm.Payload
+
+	return msg
+}
+
+func (c *Controller) newBrokerMessage(ctx context.Context, msg *nats.Msg) extensions.BrokerMessage {
+	// Get headers
+	headers := make(map[string][]byte, len(msg.Header))
+	for k, v := range msg.Header {
+		if len(v) > 0 {
+			headers[k] = []byte(v[0])
+		}
+	}
+
+	// Get the sequence and the number of deliveries
+	metadata := make(map[string]string, 2)
+	var deliveries uint64
+	if meta, err := msg.Metadata(); err != nil {
+		c.logger.Warning(ctx, fmt.Sprintf("Error when reading message metadata: %q", err.Error()))
+	} else {
+		deliveries = meta.NumDelivered
+		metadata[MetadataKeyIsSequence] = strconv.FormatUint(meta.Sequence.Stream, 10)
+		metadata[MetadataKeyIsDeliveries] = strconv.FormatUint(deliveries, 10)
+	}
+
+	return extensions.BrokerMessage{
+		Headers:        headers,
+		Payload:        msg.Data,
+		Metadata:       metadata,
+		Acknowledgment: acknowledgment{msg: msg, delay: c.redeliveryDelay(deliveries)},
+	}
+}
+
+// redeliveryDelay returns the backoff delay before redelivering a message that
+// has been delivered the given number of times.
+func (c *Controller) redeliveryDelay(deliveries uint64) time.Duration {
+	if len(c.backoff) == 0 {
+		return 0
+	}
+
+	if deliveries < 1 {
+		deliveries = 1
+	}
+	if deliveries > uint64(len(c.backoff)) {
+		return c.backoff[len(c.backoff)-1]
+	}
+	return c.backoff[deliveries-1]
+}
+
+// Close closes everything related to the broker.
+func (c *Controller) Close() {
+	c.connection.Close()
+}
+
+// acknowledgment acknowledges the message on JetStream.
+type acknowledgment struct {
+	msg   *nats.Msg
+	delay time.Duration
+}
+
+// AckMessage acknowledges the message as successfully handled.
+func (a acknowledgment) AckMessage() error {
+	return a.msg.Ack()
+}
+
+// NackMessage negatively acknowledges the message, that is redelivered after
+// the backoff delay.
+func (a acknowledgment) NackMessage() error {
+	if a.delay > 0 {
+		return a.msg.NakWithDelay(a.delay)
+	}
+
+	return a.msg.Nak()
+}
+
+// TermMessage terminates the message, that is not redelivered.
+func (a acknowledgment) TermMessage() error {
+	return a.msg.Term()
+}
+
+// Check that it still fills the interfaces.
+var (
+	_ extensions.BrokerAcknowledgment = acknowledgment{}
+	_ extensions.BrokerTerminator     = acknowledgment{}
+)
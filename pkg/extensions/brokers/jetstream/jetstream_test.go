@@ -0,0 +1,293 @@
+package jetstream
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/suite"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions/brokers/brokertest"
+)
+
+// runServer starts an embedded NATS server with JetStream, that is stopped at
+// the end of the test, and returns its URL.
+func runServer(t *testing.T) string {
+	s, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go s.Start()
+	if !s.ReadyForConnections(10 * time.Second) {
+		t.Fatal("NATS server is not ready")
+	}
+	t.Cleanup(func() {
+		s.Shutdown()
+		s.WaitForShutdown()
+	})
+
+	return s.ClientURL()
+}
+
+func TestConformance(t *testing.T) {
+	url := runServer(t)
+
+	brokertest.Run(t, func(t *testing.T, queueGroup string) extensions.BrokerController {
+		c, err := NewController(url, WithQueueGroup(queueGroup))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(c.Close)
+		return c
+	})
+}
+
+func TestConformancePush(t *testing.T) {
+	url := runServer(t)
+
+	brokertest.Run(t, func(t *testing.T, queueGroup string) extensions.BrokerController {
+		c, err := NewController(url, WithQueueGroup(queueGroup), WithPushConsumers())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(c.Close)
+		return c
+	})
+}
+
+func TestControllerSuite(t *testing.T) {
+	suite.Run(t, new(ControllerSuite))
+}
+
+type ControllerSuite struct {
+	url     string
+	channel string
+	suite.Suite
+}
+
+func (suite *ControllerSuite) SetupSuite() {
+	suite.url = runServer(suite.T())
+}
+
+func (suite *ControllerSuite) SetupTest() {
+	suite.channel = fmt.Sprintf("jetstream.%d", time.Now().UnixNano())
+}
+
+func (suite *ControllerSuite) controller(options ...ControllerOption) *Controller {
+	c, err := NewController(suite.url, options...)
+	suite.Require().NoError(err)
+	suite.T().Cleanup(c.Close)
+	return c
+}
+
+func (suite *ControllerSuite) subscribe(c *Controller) extensions.BrokerChannelSubscription {
+	sub, err := c.Subscribe(context.Background(), suite.channel)
+	suite.Require().NoError(err)
+	suite.T().Cleanup(func() { sub.Cancel(context.Background()) })
+	return sub
+}
+
+func (suite *ControllerSuite) publish(ctx context.Context, c *Controller, payload string) {
+	err := c.Publish(ctx, suite.channel, extensions.BrokerMessage{Payload: []byte(payload)})
+	suite.Require().NoError(err)
+}
+
+func (suite *ControllerSuite) receive(sub extensions.BrokerChannelSubscription) extensions.BrokerMessage {
+	select {
+	case msg := <-sub.MessagesChannel():
+		return msg
+	case <-time.After(5 * time.Second):
+		suite.Require().FailNow("no message received")
+		return extensions.BrokerMessage{}
+	}
+}
+
+func (suite *ControllerSuite) requireNotReceived(sub extensions.BrokerChannelSubscription) {
+	select {
+	case msg := <-sub.MessagesChannel():
+		suite.Require().FailNow("unexpected message", string(msg.Payload))
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func (suite *ControllerSuite) TestPersistence() {
+	c := suite.controller()
+
+	// Messages published without subscription should be received
+	suite.publish(context.Background(), c, "before")
+	sub := suite.subscribe(c)
+	msg := suite.receive(sub)
+	suite.Require().Equal("before", string(msg.Payload))
+	suite.Require().Equal("1", msg.Metadata[MetadataKeyIsSequence])
+	suite.Require().NoError(msg.Ack())
+
+	// Messages published between subscriptions should be received by the
+	// durable consumer
+	sub.Cancel(context.Background())
+	suite.publish(context.Background(), c, "between")
+	sub = suite.subscribe(c)
+	suite.Require().Equal("between", string(suite.receive(sub).Payload))
+}
+
+func (suite *ControllerSuite) TestDeduplication() {
+	c := suite.controller()
+	sub := suite.subscribe(c)
+
+	// Messages with the same correlation ID should be received once
+	ctx := context.WithValue(context.Background(), extensions.ContextKeyIsCorrelationID, "id")
+	suite.publish(ctx, c, "first")
+	suite.publish(ctx, c, "second")
+	suite.Require().Equal("first", string(suite.receive(sub).Payload))
+	suite.requireNotReceived(sub)
+}
+
+func (suite *ControllerSuite) TestDeduplicationPerChannel() {
+	// Create a stream for requests and replies
+	c := suite.controller(WithStream(nats.StreamConfig{
+		Name:     validName(suite.channel),
+		Subjects: []string{suite.channel + ".>"},
+		Storage:  nats.MemoryStorage,
+	}))
+	requests, replies := suite.channel+".requests", suite.channel+".replies"
+	requestsSub, err := c.Subscribe(context.Background(), requests)
+	suite.Require().NoError(err)
+	defer requestsSub.Cancel(context.Background())
+	repliesSub, err := c.Subscribe(context.Background(), replies)
+	suite.Require().NoError(err)
+	defer repliesSub.Cancel(context.Background())
+
+	// Messages of different channels with the same correlation ID (i.e. a
+	// request and its reply) should all be received
+	ctx := context.WithValue(context.Background(), extensions.ContextKeyIsCorrelationID, "id")
+	suite.Require().NoError(c.Publish(ctx, requests, extensions.BrokerMessage{Payload: []byte("request")}))
+	suite.Require().NoError(c.Publish(ctx, replies, extensions.BrokerMessage{Payload: []byte("reply")}))
+	suite.Require().Equal("request", string(suite.receive(requestsSub).Payload))
+	suite.Require().Equal("reply", string(suite.receive(repliesSub).Payload))
+}
+
+func (suite *ControllerSuite) TestNackWithBackoff() {
+	c := suite.controller(WithBackoff(100*time.Millisecond, 200*time.Millisecond), WithMaxDeliver(3))
+	sub := suite.subscribe(c)
+	suite.publish(context.Background(), c, "message")
+
+	// Negatively acknowledged messages should be redelivered after the backoff
+	msg := suite.receive(sub)
+	suite.Require().NoError(msg.Nack())
+	start := time.Now()
+	msg = suite.receive(sub)
+	suite.Require().GreaterOrEqual(time.Since(start), 100*time.Millisecond)
+	suite.Require().Equal("2", msg.Metadata[MetadataKeyIsDeliveries])
+
+	// Until the maximum number of deliveries
+	suite.Require().NoError(msg.Nack())
+	msg = suite.receive(sub)
+	suite.Require().Equal("3", msg.Metadata[MetadataKeyIsDeliveries])
+	suite.Require().NoError(msg.Nack())
+	suite.requireNotReceived(sub)
+}
+
+func (suite *ControllerSuite) TestBackoffWithoutMaxDeliver() {
+	_, err := NewController(suite.url, WithBackoff(time.Second, 2*time.Second))
+	suite.Require().ErrorIs(err, ErrInvalidBackoff)
+}
+
+func (suite *ControllerSuite) TestTerm() {
+	c := suite.controller()
+	sub := suite.subscribe(c)
+	suite.publish(context.Background(), c, "message")
+
+	// Terminated messages should not be redelivered
+	suite.Require().NoError(suite.receive(sub).Term())
+	suite.requireNotReceived(sub)
+}
+
+func (suite *ControllerSuite) TestBroadcastConsumer() {
+	c := suite.controller()
+	ctx := context.WithValue(context.Background(), extensions.ContextKeyIsDeliveryMode, extensions.DeliveryModeBroadcast)
+
+	// Messages published before the subscription should not be received
+	suite.publish(context.Background(), c, "before")
+	sub, err := c.Subscribe(ctx, suite.channel)
+	suite.Require().NoError(err)
+	suite.publish(context.Background(), c, "after")
+	suite.Require().Equal("after", string(suite.receive(sub).Payload))
+
+	// The consumer of the subscription should be deleted with it
+	stream, err := c.stream(suite.channel)
+	suite.Require().NoError(err)
+	info, err := c.jetStream.StreamInfo(stream)
+	suite.Require().NoError(err)
+	suite.Require().Equal(1, info.State.Consumers)
+
+	sub.Cancel(context.Background())
+	info, err = c.jetStream.StreamInfo(stream)
+	suite.Require().NoError(err)
+	suite.Require().Zero(info.State.Consumers)
+}
+
+func (suite *ControllerSuite) TestExplicitStream() {
+	stream := fmt.Sprintf("explicit-%d", time.Now().UnixNano())
+	c := suite.controller(WithStream(nats.StreamConfig{
+		Name:     stream,
+		Subjects: []string{suite.channel + ".>"},
+		Storage:  nats.MemoryStorage,
+	}))
+
+	// Channels matching the stream subjects should be persisted in it
+	name, err := c.stream(suite.channel + ".orders")
+	suite.Require().NoError(err)
+	suite.Require().Equal(stream, name)
+
+	// Other channels should get their own stream
+	name, err = c.stream(suite.channel)
+	suite.Require().NoError(err)
+	suite.Require().Equal(validName(suite.channel), name)
+}
+
+func (suite *ControllerSuite) TestRedeliveryDelay() {
+	c := &Controller{backoff: []time.Duration{time.Second, 2 * time.Second}}
+	suite.Require().Equal(time.Second, c.redeliveryDelay(0))
+	suite.Require().Equal(time.Second, c.redeliveryDelay(1))
+	suite.Require().Equal(2*time.Second, c.redeliveryDelay(2))
+	suite.Require().Equal(2*time.Second, c.redeliveryDelay(5))
+
+	c = &Controller{}
+	suite.Require().Zero(c.redeliveryDelay(1))
+}
+
+// warningsLogger counts the logged warnings.
+type warningsLogger struct {
+	extensions.DummyLogger
+	warnings *int32
+}
+
+func (l warningsLogger) Warning(_ context.Context, _ string, _ ...extensions.LogInfo) {
+	atomic.AddInt32(l.warnings, 1)
+}
+
+func (suite *ControllerSuite) TestFetchErrorsDelayed() {
+	warnings := new(int32)
+	c := suite.controller(WithLogger(warningsLogger{warnings: warnings}))
+	suite.subscribe(c)
+
+	// Make the fetch requests fail immediately
+	c.connection.Close()
+	time.Sleep(500 * time.Millisecond)
+
+	// Failed fetch requests should be retried after a delay
+	n := atomic.LoadInt32(warnings)
+	suite.Require().Positive(n)
+	suite.Require().LessOrEqual(n, int32(4))
+}
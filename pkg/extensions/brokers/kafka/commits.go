@@ -0,0 +1,396 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+)
+
+// commitTimeout is the maximum duration of a commit of the acknowledged messages.
+const commitTimeout = 5 * time.Second
+
+// StartOffset is the offset from which consumer groups without committed offset
+// start to read messages.
+type StartOffset struct {
+	offset int64
+	time   time.Time
+}
+
+var (
+	// StartOffsetEarliest starts from the first message of the partitions.
+	StartOffsetEarliest = StartOffset{offset: kafka.FirstOffset}
+	// StartOffsetLatest starts from the messages published after the subscription.
+	StartOffsetLatest = StartOffset{offset: kafka.LastOffset}
+)
+
+// StartOffsetAt starts from the first message published at or after the time.
+func StartOffsetAt(t time.Time) StartOffset {
+	return StartOffset{time: t}
+}
+
+// setStartOffset sets the start offset on a reader without consumer group.
+func (c *Controller) setStartOffset(ctx context.Context, r *kafka.Reader) error {
+	if c.startOffset.time.IsZero() {
+		return r.SetOffset(c.startOffset.offset)
+	}
+
+	return r.SetOffsetAt(ctx, c.startOffset.time)
+}
+
+// initGroupOffsets commits the offsets corresponding to the start time for the
+// partitions of the topic that have no committed offset in the consumer group,
+// as the reader can only start from the earliest or latest message.
+func (c *Controller) initGroupOffsets(ctx context.Context, topic string) error {
+	client := c.client()
+
+	// Get the topic partitions
+	partitions, err := c.readTopicPartitions(ctx, topic)
+	if err != nil {
+		return err
+	}
+
+	// Get the partitions without committed offset
+	fetched, err := client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{
+		GroupID: c.groupID,
+		Topics:  map[string][]int{topic: partitions},
+	})
+	if err != nil {
+		return err
+	}
+	requests := make([]kafka.OffsetRequest, 0, len(partitions))
+	for _, p := range fetched.Topics[topic] {
+		if p.Error == nil && p.CommittedOffset < 0 {
+			requests = append(requests, kafka.TimeOffsetOf(p.Partition, c.startOffset.time))
+		}
+	}
+	if len(requests) == 0 {
+		return nil
+	}
+
+	// Get the offsets corresponding to the start time
+	listed, err := client.ListOffsets(ctx, &kafka.ListOffsetsRequest{
+		Topics: map[string][]kafka.OffsetRequest{topic: requests},
+	})
+	if err != nil {
+		return err
+	}
+	commits := make([]kafka.OffsetCommit, 0, len(requests))
+	for _, p := range listed.Topics[topic] {
+		// Partitions without message after start time start from the latest message
+		for offset := range p.Offsets {
+			if p.Error == nil && offset >= 0 {
+				commits = append(commits, kafka.OffsetCommit{Partition: p.Partition, Offset: offset})
+			}
+		}
+	}
+	if len(commits) == 0 {
+		return nil
+	}
+
+	// Commit them outside of any consumer group generation
+	committed, err := client.OffsetCommit(ctx, &kafka.OffsetCommitRequest{
+		GroupID:      c.groupID,
+		GenerationID: -1,
+		Topics:       map[string][]kafka.OffsetCommit{topic: commits},
+	})
+	if err != nil {
+		return err
+	}
+	for _, p := range committed.Topics[topic] {
+		if p.Error != nil {
+			c.logger.Warning(ctx, fmt.Sprintf("Error when setting start offset of partition %d of %s: %q",
+				p.Partition, topic, p.Error.Error()))
+		}
+	}
+
+	return nil
+}
+
+// client returns a client to send requests to the brokers.
+func (c *Controller) client() *kafka.Client {
+	return &kafka.Client{Addr: kafka.TCP(c.hosts...), Transport: c.transport}
+}
+
+// readTopicPartitions returns the partitions of the topic.
+func (c *Controller) readTopicPartitions(ctx context.Context, topic string) ([]int, error) {
+	metadata, err := c.client().Metadata(ctx, &kafka.MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		return nil, err
+	}
+
+	partitions := make([]int, 0)
+	for _, t := range metadata.Topics {
+		if t.Error != nil {
+			return nil, t.Error
+		}
+		for _, p := range t.Partitions {
+			partitions = append(partitions, p.ID)
+		}
+	}
+
+	return partitions, nil
+}
+
+// readLatestOffsets returns the offsets of the next messages published on the
+// partitions of the topic.
+func (c *Controller) readLatestOffsets(ctx context.Context, topic string, partitions []int) (map[int]int64, error) {
+	requests := make([]kafka.OffsetRequest, 0, len(partitions))
+	for _, p := range partitions {
+		requests = append(requests, kafka.LastOffsetOf(p))
+	}
+
+	listed, err := c.client().ListOffsets(ctx, &kafka.ListOffsetsRequest{
+		Topics: map[string][]kafka.OffsetRequest{topic: requests},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	offsets := make(map[int]int64, len(partitions))
+	for _, p := range listed.Topics[topic] {
+		if p.Error != nil {
+			return nil, p.Error
+		}
+		offsets[p.Partition] = p.LastOffset
+	}
+
+	return offsets, nil
+}
+
+// committer commits the offsets of the messages fetched by a reader, once they
+// and every previous message of their partition have been acknowledged: as
+// messages can be handled concurrently, committing the offset of a message
+// before the previous ones are handled could lose them.
+//
+// As Kafka commits offsets, negatively acknowledged messages are read again
+// from their offset, with the following messages of their partition.
+type committer struct {
+	commitMessages func(ctx context.Context, msgs ...kafka.Message) error
+	seek           func(partition int, offset int64) error
+	batchSize      int
+	interval       time.Duration
+
+	// commitMutex serializes the commits, so that offsets are committed in order,
+	// without blocking the acknowledgments
+	commitMutex sync.Mutex
+
+	mutex sync.Mutex
+	// stopped is set when the messages can't be committed anymore (i.e. when
+	// their partitions have been revoked)
+	stopped bool
+	// fetched are the fetched messages not ready to be committed yet, by
+	// partition, in fetch order
+	fetched map[int][]*fetchedMessage
+	// ready are the last messages that can be committed, by partition
+	ready      map[int]kafka.Message
+	readyCount int
+	// seeks are the offsets of the negatively acknowledged messages that should
+	// be fetched again, by partition
+	seeks map[int]int64
+}
+
+type fetchedMessage struct {
+	msg      kafka.Message
+	resolved bool
+}
+
+// newCommitter creates a committer committing the acknowledged messages by
+// batches, or at the interval if it is set (see run), and reading again the
+// negatively acknowledged messages with seek.
+func newCommitter(
+	commitMessages func(ctx context.Context, msgs ...kafka.Message) error,
+	seek func(partition int, offset int64) error,
+	batchSize int,
+	interval time.Duration,
+) *committer {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	return &committer{
+		commitMessages: commitMessages,
+		seek:           seek,
+		batchSize:      batchSize,
+		interval:       interval,
+		fetched:        make(map[int][]*fetchedMessage),
+		ready:          make(map[int]kafka.Message),
+		seeks:          make(map[int]int64),
+	}
+}
+
+// fetch adds a fetched message, returning its acknowledgment. It returns false
+// if the message should be skipped, as it has been fetched before seeking back
+// to a negatively acknowledged message.
+func (c *committer) fetch(msg kafka.Message) (acknowledgment, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Skip the messages until the negatively acknowledged one is fetched again
+	if offset, seeking := c.seeks[msg.Partition]; seeking {
+		if msg.Offset != offset {
+			return acknowledgment{}, false
+		}
+		delete(c.seeks, msg.Partition)
+	}
+
+	fm := &fetchedMessage{msg: msg}
+	c.fetched[msg.Partition] = append(c.fetched[msg.Partition], fm)
+
+	return acknowledgment{committer: c, message: fm}, true
+}
+
+// resolve sets the message as handled, and commits the messages ready to be
+// committed if there is enough of them.
+func (c *committer) resolve(fm *fetchedMessage) error {
+	c.mutex.Lock()
+
+	// Ignore messages already acknowledged, or that can't be committed anymore
+	if fm.resolved || c.stopped {
+		c.mutex.Unlock()
+		return nil
+	}
+	fm.resolved = true
+
+	// Get every resolved message of the partition, until the first unresolved one
+	partition := fm.msg.Partition
+	fetched := c.fetched[partition]
+	for len(fetched) > 0 && fetched[0].resolved {
+		c.ready[partition] = fetched[0].msg
+		c.readyCount++
+		fetched = fetched[1:]
+	}
+	c.fetched[partition] = fetched
+
+	// Commit them if there is enough of them and no commit interval
+	ready := c.interval == 0 && c.readyCount >= c.batchSize
+	c.mutex.Unlock()
+	if !ready {
+		return nil
+	}
+	return c.commit(false)
+}
+
+// reject sets the message as not handled, then seeks back to it: the following
+// messages of its partition are fetched again, and their acknowledgments are
+// ignored, so that no offset is committed past it.
+func (c *committer) reject(fm *fetchedMessage) error {
+	c.mutex.Lock()
+
+	// Ignore messages already acknowledged, or that can't be committed anymore
+	if fm.resolved || c.stopped {
+		c.mutex.Unlock()
+		return nil
+	}
+
+	// Remove the message and the following ones from the fetched messages
+	partition := fm.msg.Partition
+	fetched := c.fetched[partition]
+	for i := range fetched {
+		if fetched[i] != fm {
+			continue
+		}
+
+		for _, next := range fetched[i:] {
+			next.resolved = true
+		}
+		c.fetched[partition] = fetched[:i]
+		break
+	}
+	c.seeks[partition] = fm.msg.Offset
+	c.mutex.Unlock()
+
+	// Fetch them again, outside of the lock as the reader may be fetching
+	return c.seek(partition, fm.msg.Offset)
+}
+
+// run commits the messages ready to be committed at the interval, if it is set,
+// until the context is done. It then calls beforeStop if it is not nil, commits
+// the remaining messages and stops the committer: messages acknowledged later
+// will not be committed.
+func (c *committer) run(ctx context.Context, logger extensions.Logger, beforeStop func()) {
+	// Commit at the interval
+	if c.interval > 0 {
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+
+		for done := false; !done; {
+			select {
+			case <-ticker.C:
+				if err := c.flush(); err != nil {
+					logger.Error(ctx, fmt.Sprintf("Error when committing messages: %q", err.Error()))
+				}
+			case <-ctx.Done():
+				done = true
+			}
+		}
+	} else {
+		<-ctx.Done()
+	}
+
+	// Let the messages be acknowledged before the last commit
+	if beforeStop != nil {
+		beforeStop()
+	}
+
+	// Commit the remaining messages and stop
+	if err := c.commit(true); err != nil {
+		logger.Error(ctx, fmt.Sprintf("Error when committing messages: %q", err.Error()))
+	}
+}
+
+// flush commits the messages ready to be committed.
+func (c *committer) flush() error {
+	return c.commit(false)
+}
+
+// commit commits the messages ready to be committed, then stops the committer
+// if requested. The messages are committed outside of the lock, so that the
+// acknowledgments are not blocked by the commit.
+func (c *committer) commit(stop bool) error {
+	c.commitMutex.Lock()
+	defer c.commitMutex.Unlock()
+
+	// Get the messages ready to be committed
+	c.mutex.Lock()
+	msgs := make([]kafka.Message, 0, len(c.ready))
+	for _, msg := range c.ready {
+		msgs = append(msgs, msg)
+	}
+	c.ready = make(map[int]kafka.Message)
+	c.readyCount = 0
+	c.stopped = c.stopped || stop
+	c.mutex.Unlock()
+
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commitTimeout)
+	defer cancel()
+
+	return c.commitMessages(ctx, msgs...)
+}
+
+// acknowledgment commits the message on the reader when it is acknowledged.
+type acknowledgment struct {
+	committer *committer
+	message   *fetchedMessage
+}
+
+// AckMessage commits the message, once the previous messages are handled.
+func (a acknowledgment) AckMessage() error {
+	return a.committer.resolve(a.message)
+}
+
+// NackMessage doesn't commit the message, which is fetched again with the
+// following messages of its partition.
+func (a acknowledgment) NackMessage() error {
+	return a.committer.reject(a.message)
+}
+
+// Check that it still fills the interface.
+var _ extensions.BrokerAcknowledgment = acknowledgment{}
@@ -2,43 +2,151 @@ package kafka
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
 	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
 	"github.com/znas-io/asyncapi-codegen/pkg/extensions/brokers"
 )
 
-// Check that it still fills the interface.
-var _ extensions.BrokerController = (*Controller)(nil)
+// Check that it still fills the interfaces.
+var (
+	_ extensions.BrokerController = (*Controller)(nil)
+	_ extensions.BrokerFlusher    = (*Controller)(nil)
+)
+
+var (
+	// ErrControllerClosed is raised when publishing with a closed controller.
+	ErrControllerClosed = fmt.Errorf("%w: kafka controller is closed", extensions.ErrAsyncAPI)
+
+	// ErrUnknownTopic is raised when a topic doesn't exist and topic creation
+	// is disabled.
+	ErrUnknownTopic = fmt.Errorf("%w: kafka topic does not exist", extensions.ErrAsyncAPI)
+
+	// ErrTopicCreation is raised when a topic is still not available after the
+	// maximum number of creation attempts.
+	ErrTopicCreation = fmt.Errorf("%w: kafka topic has not been created", extensions.ErrAsyncAPI)
+)
+
+// DefaultLinger is the default duration to wait for a batch to be filled
+// before sending it.
+const DefaultLinger = 10 * time.Millisecond
+
+const (
+	// MetadataKeyIsMessageKey is the key of the messages metadata that contains
+	// the Kafka message key.
+	MetadataKeyIsMessageKey = extensions.MetadataKeyIsMessageKey
+
+	// MetadataKeyIsPartition is the key of the received messages metadata that
+	// contains the partition of the message.
+	MetadataKeyIsPartition = "kafka-partition"
+
+	// MetadataKeyIsOffset is the key of the received messages metadata that
+	// contains the offset of the message in its partition.
+	MetadataKeyIsOffset = "kafka-offset"
+
+	// MetadataKeyIsTimestamp is the key of the received messages metadata that
+	// contains the timestamp of the message, in RFC 3339 format.
+	MetadataKeyIsTimestamp = "kafka-timestamp"
+)
 
 // Controller is the Kafka implementation for asyncapi-codegen.
+//
+// Published messages are sent by one writer per topic, reused between
+// publications and closed with the controller: concurrent publications on the
+// same topic are sent in the same batches. Their partition is chosen by the
+// balancer of the topic, from the message key if there is one.
+//
+// Received messages are committed when they are acknowledged, once every
+// previous message of their partition has been handled. As Kafka commits
+// offsets, negatively acknowledged messages are redelivered with the following
+// messages of their partition, which may then be received twice.
 type Controller struct {
-	hosts     []string
-	partition int
-	maxBytes  int
+	hosts    []string
+	maxBytes int
+
+	// Connection
+	tls         *tls.Config
+	sasl        sasl.Mechanism
+	clientID    string
+	dialTimeout time.Duration
+	dialer      *kafka.Dialer
+	transport   *kafka.Transport
+
+	// Topics creation
+	topicCreation        bool
+	topicPartitions      int
+	topicReplication     int
+	topicCreationRetries int
+
+	// Publication only
+	batchSize  int
+	batchBytes int64
+	linger     time.Duration
+	async      bool
+	delivery   DeliveryCallback
+	balancer   kafka.Balancer
+	balancers  map[string]kafka.Balancer
+	writers    map[string]*kafka.Writer
+	// writersMutexes serialize the creation of the writer of each topic, so
+	// that the topic is checked outside of the controller mutex
+	writersMutexes map[string]*sync.Mutex
+	mutex          sync.Mutex
+	closed     bool
+	deliveries deliveries
 
 	// Reception only
-	groupID string
+	groupID         string
+	partitions      []int
+	onAssigned      RebalanceCallback
+	onRevoked       RebalanceCallback
+	startOffset     StartOffset
+	commitBatchSize int
+	commitInterval  time.Duration
 
 	logger extensions.Logger
 }
 
 // ControllerOption is a function that can be used to configure a Kafka controller
-// Examples: WithGroupID(), WithPartition(), WithMaxBytes(), WithLogger().
+// Examples: WithGroupID(), WithPartitions(), WithMaxBytes(), WithLogger().
 type ControllerOption func(controller *Controller)
 
+// RebalanceCallback is called with the partitions of a topic assigned to or
+// revoked from the controller when the consumer group is rebalanced.
+type RebalanceCallback func(topic string, partitions []int)
+
+// DeliveryCallback is called with the result of the delivery of messages
+// published in asynchronous mode: err is nil if they have been delivered.
+type DeliveryCallback func(channel string, msgs []extensions.BrokerMessage, err error)
+
 // NewController creates a new KafkaController that fulfill the BrokerLinker interface.
 func NewController(hosts []string, options ...ControllerOption) *Controller {
 	// Create default controller
 	controller := &Controller{
-		logger:    extensions.DummyLogger{},
-		groupID:   brokers.DefaultQueueGroupID,
-		hosts:     hosts,
-		partition: 0,
-		maxBytes:  10e6, // 10MB
+		logger:               extensions.DummyLogger{},
+		groupID:              brokers.DefaultQueueGroupID,
+		hosts:                hosts,
+		maxBytes:             10e6, // 10MB
+		dialTimeout:          10 * time.Second,
+		topicCreation:        true,
+		topicPartitions:      1,
+		topicReplication:     1,
+		topicCreationRetries: 10,
+		startOffset:          StartOffsetEarliest,
+		commitBatchSize:      1,
+		linger:               DefaultLinger,
+		balancer:             &kafka.Hash{},
+		balancers:            make(map[string]kafka.Balancer),
+		writers:              make(map[string]*kafka.Writer),
+		writersMutexes:       make(map[string]*sync.Mutex),
 	}
 
 	// Execute options
@@ -46,6 +154,21 @@ func NewController(hosts []string, options ...ControllerOption) *Controller {
 		option(controller)
 	}
 
+	// Create the connection settings shared by readers and writers
+	controller.dialer = &kafka.Dialer{
+		ClientID:      controller.clientID,
+		Timeout:       controller.dialTimeout,
+		DualStack:     true,
+		TLS:           controller.tls,
+		SASLMechanism: controller.sasl,
+	}
+	controller.transport = &kafka.Transport{
+		ClientID:    controller.clientID,
+		DialTimeout: controller.dialTimeout,
+		TLS:         controller.tls,
+		SASL:        controller.sasl,
+	}
+
 	return controller
 }
 
@@ -56,10 +179,33 @@ func WithGroupID(groupID string) ControllerOption {
 	}
 }
 
-// WithPartition set the partition to use for the topic.
+// WithPartition set the partition to read, without consumer group.
+//
+// Deprecated: use WithPartitions instead.
 func WithPartition(partition int) ControllerOption {
+	return WithPartitions(partition)
+}
+
+// WithPartitions set the partitions to read, without consumer group: the group
+// ID is ignored and received messages are not committed. If not specified,
+// every partition of the topic is read, through the consumer group if there is
+// a group ID.
+func WithPartitions(partitions ...int) ControllerOption {
+	return func(controller *Controller) {
+		controller.partitions = partitions
+	}
+}
+
+// WithRebalanceCallbacks set the functions called with the partitions of a topic
+// assigned to the controller by the consumer group, and with the same partitions
+// when they are revoked (i.e. when a member joins or leaves the group), before
+// their acknowledged messages are committed. Messages acknowledged after the
+// revocation are not committed and will be received by the new owner of their
+// partition. Callbacks can be nil.
+func WithRebalanceCallbacks(onAssigned, onRevoked RebalanceCallback) ControllerOption {
 	return func(controller *Controller) {
-		controller.partition = partition
+		controller.onAssigned = onAssigned
+		controller.onRevoked = onRevoked
 	}
 }
 
@@ -70,6 +216,141 @@ func WithMaxBytes(maxBytes int) ControllerOption {
 	}
 }
 
+// WithTLS set the TLS configuration used to connect to the brokers.
+func WithTLS(config *tls.Config) ControllerOption {
+	return func(controller *Controller) {
+		controller.tls = config
+	}
+}
+
+// WithSASL set the SASL mechanism used to authenticate to the brokers, from
+// the 'github.com/segmentio/kafka-go/sasl/plain' or
+// 'github.com/segmentio/kafka-go/sasl/scram' packages.
+func WithSASL(mechanism sasl.Mechanism) ControllerOption {
+	return func(controller *Controller) {
+		controller.sasl = mechanism
+	}
+}
+
+// WithClientID set the client ID sent to the brokers.
+func WithClientID(clientID string) ControllerOption {
+	return func(controller *Controller) {
+		controller.clientID = clientID
+	}
+}
+
+// WithDialTimeout set the maximum duration to establish a connection to a broker.
+func WithDialTimeout(timeout time.Duration) ControllerOption {
+	return func(controller *Controller) {
+		controller.dialTimeout = timeout
+	}
+}
+
+// WithoutTopicCreation disable the creation of the topics that don't exist
+// when publishing or subscribing: an error is raised instead.
+func WithoutTopicCreation() ControllerOption {
+	return func(controller *Controller) {
+		controller.topicCreation = false
+	}
+}
+
+// WithTopicConfig set the number of partitions and the replication factor of
+// the created topics.
+func WithTopicConfig(partitions, replicationFactor int) ControllerOption {
+	return func(controller *Controller) {
+		controller.topicPartitions = partitions
+		controller.topicReplication = replicationFactor
+	}
+}
+
+// WithTopicCreationRetries set the maximum number of attempts to create a topic
+// and wait for it to be available.
+func WithTopicCreationRetries(retries int) ControllerOption {
+	return func(controller *Controller) {
+		controller.topicCreationRetries = retries
+	}
+}
+
+// WithStartOffset set the offset from which consumer groups without committed
+// offset (or controllers without group ID) start to read messages. If not
+// specified, they start from the earliest message.
+func WithStartOffset(offset StartOffset) ControllerOption {
+	return func(controller *Controller) {
+		controller.startOffset = offset
+	}
+}
+
+// WithCommitBatchSize set the number of acknowledged messages that are committed
+// at once. Remaining acknowledged messages are committed when the subscription
+// is canceled.
+func WithCommitBatchSize(size int) ControllerOption {
+	return func(controller *Controller) {
+		controller.commitBatchSize = size
+	}
+}
+
+// WithCommitInterval set the interval at which the acknowledged messages are
+// committed in background. If not specified, they are committed when they are
+// acknowledged (see WithCommitBatchSize).
+func WithCommitInterval(interval time.Duration) ControllerOption {
+	return func(controller *Controller) {
+		controller.commitInterval = interval
+	}
+}
+
+// WithBatchSize set the maximum number of messages sent in one batch.
+func WithBatchSize(size int) ControllerOption {
+	return func(controller *Controller) {
+		controller.batchSize = size
+	}
+}
+
+// WithBatchBytes set the maximum size of a batch of messages, in bytes.
+func WithBatchBytes(size int64) ControllerOption {
+	return func(controller *Controller) {
+		controller.batchBytes = size
+	}
+}
+
+// WithLinger set the maximum duration to wait for a batch to be filled before
+// sending it.
+func WithLinger(linger time.Duration) ControllerOption {
+	return func(controller *Controller) {
+		controller.linger = linger
+	}
+}
+
+// WithAsync set the asynchronous mode: publications return without waiting
+// for the messages delivery, and the delivery results are given to the callback.
+// If the callback is nil, delivery errors are logged.
+func WithAsync(callback DeliveryCallback) ControllerOption {
+	return func(controller *Controller) {
+		controller.async = true
+		controller.delivery = callback
+	}
+}
+
+// WithBalancer set the balancer choosing the partition of published messages,
+// for channels without a specific balancer. For example:
+//
+//   - &kafka.Hash{} for messages with the same key to be on the same partition
+//     (default), messages without key being spread in round-robin;
+//   - kafka.Murmur2Balancer{} to be compatible with the Java client;
+//   - &kafka.RoundRobin{} to spread messages evenly, ignoring keys.
+func WithBalancer(balancer kafka.Balancer) ControllerOption {
+	return func(controller *Controller) {
+		controller.balancer = balancer
+	}
+}
+
+// WithChannelBalancer set the balancer choosing the partition of messages
+// published on the channel (see WithBalancer).
+func WithChannelBalancer(channel string, balancer kafka.Balancer) ControllerOption {
+	return func(controller *Controller) {
+		controller.balancers[channel] = balancer
+	}
+}
+
 // WithLogger set a custom logger that will log operations on broker controller.
 func WithLogger(logger extensions.Logger) ControllerOption {
 	return func(controller *Controller) {
@@ -78,26 +359,41 @@ func WithLogger(logger extensions.Logger) ControllerOption {
 }
 
 // Publish a message to the broker.
+//
+// In asynchronous mode, it returns once the message is queued, and its delivery
+// result is given to the delivery callback.
 func (c *Controller) Publish(ctx context.Context, channel string, um extensions.BrokerMessage) error {
-	// Create new writer
-	w := kafka.NewWriter(kafka.WriterConfig{
-		Brokers:  c.hosts,
-		Topic:    channel,
-		Balancer: &kafka.LeastBytes{},
-	})
+	// Get the topic writer
+	w, err := c.writer(ctx, channel)
+	if err != nil {
+		return err
+	}
 
 	// Create the message
 	msg := kafka.Message{
 		Headers: make([]kafka.Header, 0),
 	}
 
-	// Set message content and headers
+	// Set message key, content and headers
+	if key := um.Metadata[extensions.MetadataKeyIsMessageKey]; key != "" {
+		msg.Key = []byte(key)
+	}
 	msg.Value = um.Payload
 	for k, v := range um.Headers {
 		msg.Headers = append(msg.Headers, kafka.Header{Key: k, Value: v})
 	}
 
-	for {
+	// Queue message without waiting for its delivery in asynchronous mode
+	if c.async {
+		c.deliveries.add(1)
+		if err := w.WriteMessages(ctx, msg); err != nil {
+			c.deliveries.remove(1)
+			return err
+		}
+		return nil
+	}
+
+	for i := 0; ; i++ {
 		// Publish message
 		err := w.WriteMessages(ctx, msg)
 
@@ -108,7 +404,7 @@ func (c *Controller) Publish(ctx context.Context, channel string, um extensions.
 
 		// Create topic if not exists, then it means that the topic is being
 		// created, so let's retry
-		if errors.Is(err, kafka.UnknownTopicOrPartition) {
+		if errors.Is(err, kafka.UnknownTopicOrPartition) && i < c.topicCreationRetries {
 			c.logger.Warning(ctx, fmt.Sprintf("Topic %s does not exists: request creation and retry", channel))
 			if err := c.checkTopicExistOrCreateIt(ctx, channel); err != nil {
 				return err
@@ -122,103 +418,537 @@ func (c *Controller) Publish(ctx context.Context, channel string, um extensions.
 	}
 }
 
+// writer returns the writer of the topic, creating it on first use.
+func (c *Controller) writer(ctx context.Context, topic string) (*kafka.Writer, error) {
+	// Return the existing writer
+	w, err := c.existingWriter(topic)
+	if w != nil || err != nil {
+		return w, err
+	}
+
+	// Wait for the writer being created by another publication
+	c.mutex.Lock()
+	topicMutex, exists := c.writersMutexes[topic]
+	if !exists {
+		topicMutex = &sync.Mutex{}
+		c.writersMutexes[topic] = topicMutex
+	}
+	c.mutex.Unlock()
+
+	topicMutex.Lock()
+	defer topicMutex.Unlock()
+
+	if w, err := c.existingWriter(topic); w != nil || err != nil {
+		return w, err
+	}
+
+	// Check that topic exists, as the writer will not create it
+	if err := c.checkTopicExistOrCreateIt(ctx, topic); err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Check that the controller has not been closed in the meantime
+	if c.closed {
+		return nil, ErrControllerClosed
+	}
+
+	// Get the balancer of the topic
+	balancer, exists := c.balancers[topic]
+	if !exists {
+		balancer = c.balancer
+	}
+
+	// Create the writer
+	w = &kafka.Writer{
+		Addr:         kafka.TCP(c.hosts...),
+		Transport:    c.transport,
+		Topic:        topic,
+		Balancer:     balancer,
+		BatchSize:    c.batchSize,
+		BatchBytes:   c.batchBytes,
+		BatchTimeout: c.linger,
+		Async:        c.async,
+	}
+	if c.async {
+		w.Completion = func(msgs []kafka.Message, err error) {
+			c.delivered(topic, msgs, err)
+		}
+	}
+	c.writers[topic] = w
+
+	return w, nil
+}
+
+// existingWriter returns the writer of the topic if it has been created, or an
+// error if the controller is closed.
+func (c *Controller) existingWriter(topic string) (*kafka.Writer, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Check that the controller is still open
+	if c.closed {
+		return nil, ErrControllerClosed
+	}
+
+	return c.writers[topic], nil
+}
+
+func (c *Controller) delivered(topic string, msgs []kafka.Message, err error) {
+	defer c.deliveries.remove(len(msgs))
+
+	// Log errors if there is no callback
+	if c.delivery == nil {
+		if err != nil {
+			c.logger.Error(context.Background(),
+				fmt.Sprintf("Error when delivering %d message(s) on %s: %q", len(msgs), topic, err.Error()))
+		}
+		return
+	}
+
+	// Give the delivery result to the callback
+	bms := make([]extensions.BrokerMessage, 0, len(msgs))
+	for _, msg := range msgs {
+		bms = append(bms, newBrokerMessage(msg))
+	}
+	c.delivery(topic, bms, err)
+}
+
+// Flush waits for the delivery of the messages published in asynchronous mode.
+func (c *Controller) Flush(ctx context.Context) error {
+	return c.deliveries.wait(ctx)
+}
+
+// Close the controller writers, after delivering their queued messages.
+// Publications made after that will fail.
+func (c *Controller) Close() {
+	// Remove the writers
+	c.mutex.Lock()
+	writers := c.writers
+	c.writers = make(map[string]*kafka.Writer)
+	c.closed = true
+	c.mutex.Unlock()
+
+	// Close them
+	for topic, w := range writers {
+		if err := w.Close(); err != nil {
+			c.logger.Error(context.Background(), fmt.Sprintf("Error when closing writer of %s: %q", topic, err.Error()))
+		}
+	}
+	c.transport.CloseIdleConnections()
+}
+
 // Subscribe to messages from the broker.
+//
+// With a consumer group, every partition assigned to the controller is read.
+// Otherwise, the partitions set with WithPartitions are read, or every partition
+// of the topic if there is none. Broadcast subscriptions read them without
+// consumer group, from the messages published after the subscription.
 func (c *Controller) Subscribe(ctx context.Context, channel string) (extensions.BrokerChannelSubscription, error) {
 	// Check that topic exists before
 	if err := c.checkTopicExistOrCreateIt(ctx, channel); err != nil {
 		return extensions.BrokerChannelSubscription{}, err
 	}
 
-	// Create reader
-	r := kafka.NewReader(kafka.ReaderConfig{
-		Brokers:   c.hosts,
-		Topic:     channel,
-		Partition: c.partition,
-		MaxBytes:  c.maxBytes,
-		GroupID:   c.groupID,
-	})
-
 	// Create subscription
 	sub := extensions.NewBrokerChannelSubscription(
 		make(chan extensions.BrokerMessage, brokers.BrokerMessagesQueueSize),
 		make(chan any, 1),
 	)
 
-	// Handle events
-	go c.messagesHandler(ctx, r, sub)
+	// Read the partitions through the consumer group, or directly
+	broadcast := extensions.DeliveryModeFromContext(ctx) == extensions.DeliveryModeBroadcast
+	ctx, cancel := context.WithCancel(ctx)
+	var stop func()
+	var err error
+	if c.groupID != "" && len(c.partitions) == 0 && !broadcast {
+		stop, err = c.consumeGroup(ctx, channel, sub)
+	} else {
+		stop, err = c.consumePartitions(ctx, channel, sub, broadcast)
+	}
+	if err != nil {
+		cancel()
+		return extensions.BrokerChannelSubscription{}, err
+	}
 
-	// Wait for cancellation and stop the kafka listener when it happens
+	// Wait for cancellation, commit acknowledged messages and stop the kafka
+	// listeners when it happens
 	sub.WaitForCancellationAsync(func() {
-		if err := r.Close(); err != nil {
+		cancel()
+		stop()
+	})
+
+	return sub, nil
+}
+
+// consumeGroup reads the partitions assigned to the controller by the consumer
+// group, for each generation of the group. It returns a function stopping the
+// reading, that should be called once the context is done.
+func (c *Controller) consumeGroup(
+	ctx context.Context,
+	topic string,
+	sub extensions.BrokerChannelSubscription,
+) (func(), error) {
+	// Set the start time offsets of the consumer group, as the group can only
+	// start from the earliest or latest message
+	startOffset := c.startOffset.offset
+	if !c.startOffset.time.IsZero() {
+		if err := c.initGroupOffsets(ctx, topic); err != nil {
+			return nil, err
+		}
+		startOffset = kafka.LastOffset
+	}
+
+	// Join the consumer group
+	group, err := kafka.NewConsumerGroup(kafka.ConsumerGroupConfig{
+		ID:          c.groupID,
+		Brokers:     c.hosts,
+		Dialer:      c.dialer,
+		Topics:      []string{topic},
+		StartOffset: startOffset,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Handle the generations of the group
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		for {
+			gen, err := group.Next(ctx)
+			switch {
+			case err == nil:
+				c.handleGeneration(gen, topic, sub)
+			case errors.Is(err, kafka.ErrGroupClosed) || ctx.Err() != nil:
+				return
+			default:
+				c.logger.Warning(ctx, fmt.Sprintf("Error when joining consumer group %s: %q", c.groupID, err.Error()))
+			}
+		}
+	}()
+
+	return func() {
+		// Leave the group, which ends the current generation
+		if err := group.Close(); err != nil {
 			c.logger.Error(ctx, err.Error())
 		}
+		wg.Wait()
+	}, nil
+}
+
+// handleGeneration reads the partitions assigned in a generation of the consumer
+// group, until the generation ends.
+func (c *Controller) handleGeneration(gen *kafka.Generation, topic string, sub extensions.BrokerChannelSubscription) {
+	assignments := gen.Assignments[topic]
+	partitions := make([]int, 0, len(assignments))
+	for _, a := range assignments {
+		partitions = append(partitions, a.ID)
+	}
+
+	// Create a reader for each partition, from its committed offset
+	readers := make(map[int]*kafka.Reader, len(assignments))
+	for _, a := range assignments {
+		r := c.newPartitionReader(topic, a.ID)
+		if err := r.SetOffset(a.Offset); err != nil {
+			c.logger.Error(context.Background(), err.Error())
+		}
+		readers[a.ID] = r
+	}
+
+	// Commit the messages with the generation, as only its member can commit
+	// the offsets of its partitions, and read again the negatively acknowledged
+	// ones with their partition reader
+	commits := newCommitter(func(ctx context.Context, msgs ...kafka.Message) error {
+		offsets := make(map[int]int64, len(msgs))
+		for _, msg := range msgs {
+			offsets[msg.Partition] = msg.Offset + 1
+		}
+
+		// Stop waiting for the commit when the context is done, as the
+		// generation only applies the network timeout of the group
+		done := make(chan error, 1)
+		go func() { done <- gen.CommitOffsets(map[string]map[int]int64{topic: offsets}) }()
+		select {
+		case err := <-done:
+			return err
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %s", extensions.ErrContextCanceled, ctx.Err().Error())
+		}
+	}, func(partition int, offset int64) error {
+		return readers[partition].SetOffset(offset)
+	}, c.commitBatchSize, c.commitInterval)
+
+	// Notify the assignment
+	if c.onAssigned != nil {
+		c.onAssigned(topic, partitions)
+	}
+
+	// Read each partition
+	for _, r := range readers {
+		r := r
+		gen.Start(func(ctx context.Context) {
+			c.readPartition(ctx, r, commits, sub)
+		})
+	}
+
+	// Notify the revocation then commit the acknowledged messages when the
+	// generation ends
+	gen.Start(func(ctx context.Context) {
+		commits.run(ctx, c.logger, func() {
+			if c.onRevoked != nil {
+				c.onRevoked(topic, partitions)
+			}
+		})
 	})
+}
 
-	return sub, nil
+// consumePartitions reads the partitions set on the controller, or every
+// partition of the topic, without consumer group, from the start offset or from
+// the latest offsets. It returns a function waiting for the end of the reading,
+// that should be called once the context is done.
+func (c *Controller) consumePartitions(
+	ctx context.Context,
+	topic string,
+	sub extensions.BrokerChannelSubscription,
+	fromLatest bool,
+) (func(), error) {
+	// Get the partitions to read
+	partitions := c.partitions
+	if len(partitions) == 0 {
+		var err error
+		if partitions, err = c.readTopicPartitions(ctx, topic); err != nil {
+			return nil, err
+		}
+	}
+
+	// Get the latest offsets now, as readers only get them on first fetch and
+	// would miss the messages published in the meantime
+	var latest map[int]int64
+	if fromLatest {
+		var err error
+		if latest, err = c.readLatestOffsets(ctx, topic, partitions); err != nil {
+			return nil, err
+		}
+	}
+
+	// Create a reader for each partition, from the start offset
+	readers := make([]*kafka.Reader, 0, len(partitions))
+	for _, p := range partitions {
+		r := c.newPartitionReader(topic, p)
+		readers = append(readers, r)
+
+		var err error
+		if offset, ok := latest[p]; ok {
+			err = r.SetOffset(offset)
+		} else {
+			err = c.setStartOffset(ctx, r)
+		}
+		if err != nil {
+			for _, r := range readers {
+				_ = r.Close()
+			}
+			return nil, err
+		}
+	}
+
+	// Read them, without commits
+	var wg sync.WaitGroup
+	for _, r := range readers {
+		wg.Add(1)
+		go func(r *kafka.Reader) {
+			defer wg.Done()
+			c.readPartition(ctx, r, nil, sub)
+		}(r)
+	}
+
+	return wg.Wait, nil
+}
+
+// newPartitionReader creates a reader of a partition, without consumer group.
+func (c *Controller) newPartitionReader(topic string, partition int) *kafka.Reader {
+	return kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   c.hosts,
+		Dialer:    c.dialer,
+		Topic:     topic,
+		Partition: partition,
+		MaxBytes:  c.maxBytes,
+	})
 }
 
-func (c *Controller) messagesHandler(ctx context.Context, r *kafka.Reader, sub extensions.BrokerChannelSubscription) {
+// readPartition transmits the messages of the reader to the subscription until
+// the context is done, then closes the reader. Messages are acknowledged with
+// the committer, if there is one.
+func (c *Controller) readPartition(
+	ctx context.Context,
+	r *kafka.Reader,
+	commits *committer,
+	sub extensions.BrokerChannelSubscription,
+) {
+	defer func() {
+		if err := r.Close(); err != nil {
+			c.logger.Error(ctx, err.Error())
+		}
+	}()
+
 	for {
-		msg, err := r.ReadMessage(ctx)
+		msg, err := r.FetchMessage(ctx)
 		if err != nil {
-			// If the error is not io.EOF, then it is a real error
-			if !errors.Is(err, io.EOF) {
+			// If the context is not done and the error is not io.EOF, then it
+			// is a real error
+			if ctx.Err() == nil && !errors.Is(err, io.EOF) {
 				c.logger.Warning(ctx, fmt.Sprintf("Error when reading message: %q", err.Error()))
 			}
 
 			return
 		}
 
-		// Get headers
-		headers := make(map[string][]byte, len(msg.Headers))
-		for _, header := range msg.Headers {
-			headers[header.Key] = header.Value
+		// Create the message, that can only be committed with a consumer group
+		bm := newBrokerMessage(msg)
+		if commits != nil {
+			ack, ok := commits.fetch(msg)
+			if !ok {
+				continue
+			}
+			bm.Acknowledgment = ack
 		}
 
 		// Send received message
-		sub.TransmitReceivedMessage(extensions.BrokerMessage{
-			Headers: headers,
-			Payload: msg.Value,
-		})
+		sub.TransmitReceivedMessage(bm)
+	}
+}
+
+// newBrokerMessage converts a Kafka message into a broker message.
+func newBrokerMessage(msg kafka.Message) extensions.BrokerMessage {
+	// Get headers
+	headers := make(map[string][]byte, len(msg.Headers))
+	for _, header := range msg.Headers {
+		headers[header.Key] = header.Value
+	}
+
+	return extensions.BrokerMessage{
+		Headers: headers,
+		Payload: msg.Value,
+		Metadata: map[string]string{
+			MetadataKeyIsMessageKey: string(msg.Key),
+			MetadataKeyIsPartition:  strconv.Itoa(msg.Partition),
+			MetadataKeyIsOffset:     strconv.FormatInt(msg.Offset, 10),
+			MetadataKeyIsTimestamp:  msg.Time.Format(time.RFC3339Nano),
+		},
+	}
+}
+
+// deliveries counts the messages published in asynchronous mode that are not
+// delivered yet.
+type deliveries struct {
+	mutex sync.Mutex
+	count int
+	done  chan struct{}
+}
+
+func (d *deliveries) add(n int) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.count == 0 {
+		d.done = make(chan struct{})
 	}
+	d.count += n
 }
 
-func (c Controller) checkTopicExistOrCreateIt(ctx context.Context, topic string) error {
+func (d *deliveries) remove(n int) {
+	if n == 0 {
+		return
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.count -= n
+	if d.count == 0 {
+		close(d.done)
+	}
+}
+
+func (d *deliveries) wait(ctx context.Context) error {
+	// Get the channel closed when there is no more pending deliveries
+	d.mutex.Lock()
+	count, done := d.count, d.done
+	d.mutex.Unlock()
+	if count == 0 {
+		return nil
+	}
+
+	// Wait for it
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %d message(s) not delivered", extensions.ErrContextCanceled, count)
+	}
+}
+
+func (c *Controller) checkTopicExistOrCreateIt(ctx context.Context, topic string) error {
 	// Get connection to first host
-	conn, err := kafka.Dial("tcp", c.hosts[0])
+	conn, err := c.dialer.DialContext(ctx, "tcp", c.hosts[0])
 	if err != nil {
 		return err
 	}
 	defer conn.Close()
 
 	for i := 0; ; i++ {
-		// Create topic
-		topicConfigs := []kafka.TopicConfig{{
-			Topic:             topic,
-			NumPartitions:     1,
-			ReplicationFactor: 1,
-		}}
-		err = conn.CreateTopics(topicConfigs...)
-		if err != nil {
+		// Check if the topic exists
+		partitions, err := conn.ReadPartitions(topic)
+		switch {
+		case err == nil && len(partitions) > 0:
+			if i > 0 {
+				c.logger.Warning(ctx, fmt.Sprintf("Topic %s has been created.", topic))
+			}
+			return nil
+		case err != nil && !errors.Is(err, kafka.UnknownTopicOrPartition) && !errors.Is(err, kafka.LeaderNotAvailable):
 			return err
+		case !c.topicCreation:
+			return fmt.Errorf("%w: %q", ErrUnknownTopic, topic)
+		case i >= c.topicCreationRetries:
+			return fmt.Errorf("%w: %q after %d attempt(s)", ErrTopicCreation, topic, i)
 		}
 
-		// Read partitions
-		partitions, err := conn.ReadPartitions()
-		if err != nil {
-			return err
+		// Create topic
+		if err := c.createTopic(ctx, conn, topic); err != nil && !errors.Is(err, kafka.TopicAlreadyExists) {
+			c.logger.Warning(ctx, fmt.Sprintf("Error when creating topic %s: %q", topic, err.Error()))
 		}
 
-		// Get topic from partitions
-		for _, p := range partitions {
-			if topic == p.Topic {
-				if i > 0 {
-					c.logger.Warning(ctx, fmt.Sprintf("Topic %s has been created.", topic))
-				}
-				return nil
-			}
+		// Wait before checking again
+		c.logger.Warning(ctx, fmt.Sprintf("Topic %s doesn't exists yet, retrying (#%d)", topic, i))
+		select {
+		case <-ctx.Done():
+			return extensions.ErrContextCanceled
+		case <-time.After(time.Duration(i+1) * 100 * time.Millisecond):
 		}
+	}
+}
 
-		c.logger.Warning(ctx, fmt.Sprintf("Topic %s doesn't exists yet, retrying (#%d)", topic, i))
+// createTopic requests the topic creation to the controller broker.
+func (c *Controller) createTopic(ctx context.Context, conn *kafka.Conn, topic string) error {
+	// Get connection to the controller broker
+	controller, err := conn.Controller()
+	if err != nil {
+		return err
 	}
+	controllerConn, err := c.dialer.DialContext(ctx, "tcp", net.JoinHostPort(controller.Host, strconv.Itoa(controller.Port)))
+	if err != nil {
+		return err
+	}
+	defer controllerConn.Close()
+
+	// Create topic
+	return controllerConn.CreateTopics(kafka.TopicConfig{
+		Topic:             topic,
+		NumPartitions:     c.topicPartitions,
+		ReplicationFactor: c.topicReplication,
+	})
 }
@@ -0,0 +1,327 @@
+package kafka
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/stretchr/testify/suite"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+)
+
+func TestControllerSuite(t *testing.T) {
+	suite.Run(t, new(ControllerSuite))
+}
+
+type ControllerSuite struct {
+	suite.Suite
+}
+
+func (suite *ControllerSuite) TestPublishAfterClose() {
+	c := NewController([]string{"localhost:9092"})
+	c.Close()
+
+	err := c.Publish(context.Background(), "channel", extensions.BrokerMessage{})
+	suite.Require().ErrorIs(err, ErrControllerClosed)
+}
+
+func (suite *ControllerSuite) TestCloseDuringTopicCheck() {
+	// Accept connections without ever answering
+	listener, err := net.Listen("tcp", "localhost:0")
+	suite.Require().NoError(err)
+	defer listener.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			accepted <- conn
+		}
+	}()
+
+	// Publish while the topic is being checked
+	c := NewController([]string{listener.Addr().String()})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	published := make(chan error, 1)
+	go func() { published <- c.Publish(ctx, "channel", extensions.BrokerMessage{}) }()
+	conn := <-accepted
+
+	// Closing the controller should not wait for the topic check
+	closed := make(chan struct{})
+	go func() {
+		c.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		suite.Require().FailNow("controller should have been closed during the topic check")
+	}
+
+	// The publication should then fail
+	suite.Require().NoError(conn.Close())
+	suite.Require().Error(<-published)
+}
+
+func (suite *ControllerSuite) TestFlushWithoutPublication() {
+	c := NewController([]string{"localhost:9092"}, WithAsync(nil))
+	defer c.Close()
+
+	suite.Require().NoError(c.Flush(context.Background()))
+}
+
+func (suite *ControllerSuite) TestDeliveries() {
+	var d deliveries
+	d.add(2)
+
+	// Waiting should fail until every delivery is done
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	suite.Require().ErrorIs(d.wait(ctx), extensions.ErrContextCanceled)
+
+	// Waiting should return when every delivery is done
+	done := make(chan error)
+	go func() { done <- d.wait(context.Background()) }()
+	d.remove(1)
+	d.remove(1)
+	suite.Require().NoError(<-done)
+
+	// Deliveries can be added again
+	d.add(1)
+	d.remove(1)
+	suite.Require().NoError(d.wait(context.Background()))
+}
+
+func (suite *ControllerSuite) TestDeliveryCallback() {
+	var delivered []extensions.BrokerMessage
+	c := NewController([]string{"localhost:9092"}, WithAsync(func(channel string, msgs []extensions.BrokerMessage, err error) {
+		suite.Require().Equal("channel", channel)
+		suite.Require().NoError(err)
+		delivered = append(delivered, msgs...)
+	}))
+	defer c.Close()
+
+	// Simulate the delivery of published messages
+	c.deliveries.add(1)
+	c.delivered("channel", []kafka.Message{{
+		Key:     []byte("key"),
+		Value:   []byte("payload"),
+		Headers: []kafka.Header{{Key: "header", Value: []byte("value")}},
+	}}, nil)
+
+	suite.Require().NoError(c.Flush(context.Background()))
+	suite.Require().Len(delivered, 1)
+	suite.Require().Equal("payload", string(delivered[0].Payload))
+	suite.Require().Equal("value", string(delivered[0].Headers["header"]))
+	suite.Require().Equal("key", delivered[0].Metadata[MetadataKeyIsMessageKey])
+}
+
+func (suite *ControllerSuite) TestCommitAfterPreviousMessages() {
+	var committed []kafka.Message
+	c := newCommitter(func(_ context.Context, msgs ...kafka.Message) error {
+		committed = append(committed, msgs...)
+		return nil
+	}, nil, 1, 0)
+
+	// Fetch messages on two partitions
+	first := suite.fetch(c, kafka.Message{Partition: 0, Offset: 1})
+	second := suite.fetch(c, kafka.Message{Partition: 0, Offset: 2})
+	other := suite.fetch(c, kafka.Message{Partition: 1, Offset: 1})
+
+	// The second message should not be committed before the first one
+	suite.Require().NoError(second.AckMessage())
+	suite.Require().Empty(committed)
+
+	// Other partitions should not be blocked
+	suite.Require().NoError(other.AckMessage())
+	suite.Require().Equal([]kafka.Message{{Partition: 1, Offset: 1}}, committed)
+
+	// Both messages should be committed with the last offset
+	suite.Require().NoError(first.AckMessage())
+	suite.Require().Equal(kafka.Message{Partition: 0, Offset: 2}, committed[1])
+
+	// Acknowledging twice should do nothing
+	suite.Require().NoError(first.AckMessage())
+	suite.Require().Len(committed, 2)
+}
+
+func (suite *ControllerSuite) TestCommitBatches() {
+	var commits int
+	c := newCommitter(func(_ context.Context, msgs ...kafka.Message) error {
+		commits++
+		return nil
+	}, nil, 3, 0)
+
+	// Messages should be committed by batches
+	for i := int64(0); i < 4; i++ {
+		suite.Require().NoError(suite.fetch(c, kafka.Message{Offset: i}).AckMessage())
+	}
+	suite.Require().Equal(1, commits)
+
+	// Remaining messages should be committed when flushing
+	suite.Require().NoError(c.flush())
+	suite.Require().Equal(2, commits)
+	suite.Require().NoError(c.flush())
+	suite.Require().Equal(2, commits)
+}
+
+func (suite *ControllerSuite) TestNackedMessagesNotCommitted() {
+	var committed []kafka.Message
+	var seeks []int64
+	c := newCommitter(func(_ context.Context, msgs ...kafka.Message) error {
+		committed = append(committed, msgs...)
+		return nil
+	}, func(partition int, offset int64) error {
+		seeks = append(seeks, offset)
+		return nil
+	}, 1, 0)
+
+	// A negatively acknowledged message should not be committed, but fetched again
+	first := suite.fetch(c, kafka.Message{Offset: 1})
+	second := suite.fetch(c, kafka.Message{Offset: 2})
+	suite.Require().NoError(first.NackMessage())
+	suite.Require().Empty(committed)
+	suite.Require().Equal([]int64{1}, seeks)
+
+	// Nor with the next ones, as Kafka commits offsets
+	suite.Require().NoError(second.AckMessage())
+	suite.Require().Empty(committed)
+
+	// Messages fetched before seeking should be skipped
+	_, ok := c.fetch(kafka.Message{Offset: 3})
+	suite.Require().False(ok)
+
+	// The message fetched again should be committed when acknowledged
+	suite.Require().NoError(suite.fetch(c, kafka.Message{Offset: 1}).AckMessage())
+	suite.Require().Equal([]kafka.Message{{Offset: 1}}, committed)
+}
+
+func (suite *ControllerSuite) TestCommitInterval() {
+	committed := make(chan []kafka.Message, 1)
+	c := newCommitter(func(_ context.Context, msgs ...kafka.Message) error {
+		committed <- msgs
+		return nil
+	}, nil, 1, 10*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.run(ctx, extensions.DummyLogger{}, nil)
+		close(done)
+	}()
+
+	// Acknowledged messages should be committed at the interval
+	suite.Require().NoError(suite.fetch(c, kafka.Message{Offset: 1}).AckMessage())
+	suite.Require().Equal([]kafka.Message{{Offset: 1}}, <-committed)
+
+	// Remaining messages should be committed when stopping
+	second := suite.fetch(c, kafka.Message{Offset: 2})
+	last := suite.fetch(c, kafka.Message{Offset: 3})
+	suite.Require().NoError(second.AckMessage())
+	cancel()
+	<-done
+	suite.Require().Equal([]kafka.Message{{Offset: 2}}, <-committed)
+
+	// Messages acknowledged after stopping should not be committed
+	suite.Require().NoError(last.AckMessage())
+	suite.Require().NoError(c.flush())
+	suite.Require().Empty(committed)
+}
+
+func (suite *ControllerSuite) TestCommitAfterBeforeStop() {
+	var calls []string
+	c := newCommitter(func(_ context.Context, _ ...kafka.Message) error {
+		calls = append(calls, "commit")
+		return nil
+	}, nil, 10, 0)
+
+	// Stop the committer, acknowledging a message on revocation
+	msg := suite.fetch(c, kafka.Message{Offset: 1})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.run(ctx, extensions.DummyLogger{}, func() {
+		calls = append(calls, "revoked")
+		suite.Require().NoError(msg.AckMessage())
+	})
+
+	// The message acknowledged on revocation should have been committed after
+	suite.Require().Equal([]string{"revoked", "commit"}, calls)
+}
+
+func (suite *ControllerSuite) TestAckDuringCommit() {
+	committing, unblock := make(chan bool, 1), make(chan struct{})
+	c := newCommitter(func(ctx context.Context, _ ...kafka.Message) error {
+		_, hasDeadline := ctx.Deadline()
+		committing <- hasDeadline
+		<-unblock
+		return nil
+	}, nil, 1, time.Hour)
+
+	// Block a commit
+	suite.Require().NoError(suite.fetch(c, kafka.Message{Offset: 1}).AckMessage())
+	flushed := make(chan error, 1)
+	go func() { flushed <- c.flush() }()
+	suite.Require().True(<-committing, "commit should have a deadline")
+
+	// Messages should still be acknowledged during the commit
+	suite.Require().NoError(suite.fetch(c, kafka.Message{Offset: 2}).AckMessage())
+	close(unblock)
+	suite.Require().NoError(<-flushed)
+}
+
+// fetch adds the message to the committer, that should not skip it.
+func (suite *ControllerSuite) fetch(c *committer, msg kafka.Message) acknowledgment {
+	ack, ok := c.fetch(msg)
+	suite.Require().True(ok)
+	return ack
+}
+
+func (suite *ControllerSuite) TestReceivedMessageMetadata() {
+	timestamp := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	bm := newBrokerMessage(kafka.Message{
+		Key:       []byte("key"),
+		Partition: 2,
+		Offset:    42,
+		Time:      timestamp,
+	})
+
+	suite.Require().Equal(map[string]string{
+		MetadataKeyIsMessageKey: "key",
+		MetadataKeyIsPartition:  "2",
+		MetadataKeyIsOffset:     "42",
+		MetadataKeyIsTimestamp:  "2024-01-02T03:04:05.000000006Z",
+	}, bm.Metadata)
+}
+
+func (suite *ControllerSuite) TestConnectionOptions() {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	mechanism := plain.Mechanism{Username: "user", Password: "password"}
+	c := NewController([]string{"localhost:9092"},
+		WithTLS(config),
+		WithSASL(mechanism),
+		WithClientID("client"),
+		WithDialTimeout(time.Second))
+	defer c.Close()
+
+	// Readers and writers should use the same settings
+	suite.Require().Equal(config, c.dialer.TLS)
+	suite.Require().Equal(config, c.transport.TLS)
+	suite.Require().Equal(mechanism, c.dialer.SASLMechanism)
+	suite.Require().Equal(mechanism, c.transport.SASL)
+	suite.Require().Equal("client", c.dialer.ClientID)
+	suite.Require().Equal("client", c.transport.ClientID)
+	suite.Require().Equal(time.Second, c.dialer.Timeout)
+	suite.Require().Equal(time.Second, c.transport.DialTimeout)
+}
+
+func (suite *ControllerSuite) TestUnreachableBroker() {
+	c := NewController([]string{"localhost:1"}, WithDialTimeout(100*time.Millisecond))
+	defer c.Close()
+
+	// It should fail instead of retrying indefinitely
+	_, err := c.Subscribe(context.Background(), "channel")
+	suite.Require().Error(err)
+}
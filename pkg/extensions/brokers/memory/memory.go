@@ -0,0 +1,356 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions/brokers"
+)
+
+// Check that it still fills the interfaces.
+var (
+	_ extensions.BrokerController = (*Controller)(nil)
+	_ extensions.Requester        = (*Controller)(nil)
+)
+
+// PublishedMessage is a message that has been published on the in-memory broker.
+type PublishedMessage struct {
+	Channel string
+	Time    time.Time
+	extensions.BrokerMessage
+}
+
+// Controller is the in-memory Controller implementation for asyncapi-codegen.
+// Messages are exchanged in process, without any broker, which makes it
+// suitable for tests and local development.
+//
+// Negatively acknowledged messages are redelivered to the same subscription,
+// while terminated ones are not.
+//
+// Channels are NATS-like subjects: subscriptions can use '*' to match one token
+// and '>' to match one or more tokens at the end of the subject (i.e. 'a.*.c'
+// or 'a.>').
+//
+// Like NATS, it supports native request/reply: requests are received with a
+// reply inbox in their metadata, on which the reply should be published.
+type Controller struct {
+	bus        *bus
+	logger     extensions.Logger
+	queueGroup string
+	delay      time.Duration
+}
+
+// ControllerOption is a function that can be used to configure an in-memory
+// controller.
+// Examples: WithQueueGroup(), WithDeliveryDelay(), WithLogger().
+type ControllerOption func(controller *Controller)
+
+// NewController creates a new in-memory controller, with its own in-memory broker.
+func NewController(options ...ControllerOption) *Controller {
+	return newController(newBus(), options...)
+}
+
+func newController(b *bus, options ...ControllerOption) *Controller {
+	// Creates default controller
+	controller := &Controller{
+		bus:        b,
+		queueGroup: brokers.DefaultQueueGroupID,
+		logger:     extensions.DummyLogger{},
+	}
+
+	// Execute options
+	for _, option := range options {
+		option(controller)
+	}
+
+	return controller
+}
+
+// Connect creates a new controller connected to the same in-memory broker,
+// with its own options (i.e. to simulate another service with a different
+// queue group).
+func (c *Controller) Connect(options ...ControllerOption) *Controller {
+	return newController(c.bus, options...)
+}
+
+// WithQueueGroup set a custom queue group for channel subscription. Each
+// message is delivered to only one subscription of a queue group. If the name
+// is empty, every subscription will receive every message (fan-out).
+func WithQueueGroup(name string) ControllerOption {
+	return func(controller *Controller) {
+		controller.queueGroup = name
+	}
+}
+
+// WithDeliveryDelay set a delay between the publication of a message and its
+// delivery to subscriptions.
+func WithDeliveryDelay(delay time.Duration) ControllerOption {
+	return func(controller *Controller) {
+		controller.delay = delay
+	}
+}
+
+// WithLogger set a custom logger that will log operations on broker controller.
+func WithLogger(logger extensions.Logger) ControllerOption {
+	return func(controller *Controller) {
+		controller.logger = logger
+	}
+}
+
+// Publish a message to the broker.
+func (c *Controller) Publish(_ context.Context, channel string, bm extensions.BrokerMessage) error {
+	// Metadata is only set by the broker on received messages, except the key
+	bm.Metadata = publishedMetadata(bm)
+
+	c.bus.publish(channel, bm, c.delay)
+	return nil
+}
+
+// Request publishes a message with a reply inbox in its metadata, and waits for
+// the reply published on this inbox. The reply channel is not used, as the reply
+// is sent directly to the inbox by the receiver.
+func (c *Controller) Request(
+	ctx context.Context,
+	channel string,
+	bm extensions.BrokerMessage,
+	_ string,
+) (extensions.BrokerMessage, error) {
+	// Subscribe to a new inbox
+	inbox := "_INBOX." + uuid.New().String()
+	messages := make(chan extensions.BrokerMessage, 1)
+	s := newSubscription(c, inbox, messages)
+	c.bus.add(s)
+	defer func() {
+		c.bus.remove(s)
+		s.stop()
+	}()
+
+	// Publish the request with the inbox
+	bm.Metadata = publishedMetadata(bm)
+	bm.Metadata[extensions.MetadataKeyIsReplyTo] = inbox
+	c.bus.publish(channel, bm, c.delay)
+
+	// Wait for the reply
+	select {
+	case reply := <-messages:
+		return reply, nil
+	case <-ctx.Done():
+		return extensions.BrokerMessage{}, fmt.Errorf("%w: %s", extensions.ErrContextCanceled, ctx.Err().Error())
+	}
+}
+
+// Subscribe to messages from the broker.
+func (c *Controller) Subscribe(ctx context.Context, channel string) (extensions.BrokerChannelSubscription, error) {
+	// Create a new subscription
+	messages := make(chan extensions.BrokerMessage, brokers.BrokerMessagesQueueSize)
+	sub := extensions.NewBrokerChannelSubscription(messages, make(chan any, 1))
+
+	// Add it to the broker, with the queue group unless messages are broadcast
+	s := newSubscription(c, channel, messages)
+	if extensions.DeliveryModeFromContext(ctx) == extensions.DeliveryModeBroadcast {
+		s.queueGroup = ""
+	}
+	c.bus.add(s)
+
+	// Wait for cancellation and remove the subscription from the broker
+	sub.WaitForCancellationAsync(func() {
+		c.bus.remove(s)
+		s.stop()
+		c.logger.Info(ctx, "Subscription to '"+channel+"' canceled")
+	})
+
+	return sub, nil
+}
+
+// PublishedMessages returns every message published on the in-memory broker,
+// by this controller or any other connected controller, in publication order.
+func (c *Controller) PublishedMessages() []PublishedMessage {
+	return c.bus.publishedMessages()
+}
+
+// ResetPublishedMessages removes the messages returned by PublishedMessages.
+func (c *Controller) ResetPublishedMessages() {
+	c.bus.resetPublishedMessages()
+}
+
+// publishedMetadata returns the metadata of a published message, that only
+// keeps the message key.
+func publishedMetadata(bm extensions.BrokerMessage) map[string]string {
+	metadata := make(map[string]string)
+	if key, exists := bm.Metadata[extensions.MetadataKeyIsMessageKey]; exists {
+		metadata[extensions.MetadataKeyIsMessageKey] = key
+	}
+	return metadata
+}
+
+// Close closes everything related to the broker: the subscriptions of this
+// controller will not receive messages anymore.
+func (c *Controller) Close() {
+	for _, s := range c.bus.removeController(c) {
+		s.stop()
+	}
+}
+
+// bus is the in-memory broker shared by connected controllers.
+type bus struct {
+	mutex         sync.Mutex
+	subscriptions []*subscription
+	published     []PublishedMessage
+	// nextMember is the index of the next queue group member to deliver to,
+	// by queue group
+	nextMember map[string]int
+}
+
+func newBus() *bus {
+	return &bus{
+		nextMember: make(map[string]int),
+	}
+}
+
+func (b *bus) add(s *subscription) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.subscriptions = append(b.subscriptions, s)
+}
+
+func (b *bus) remove(s *subscription) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for i, v := range b.subscriptions {
+		if v == s {
+			b.subscriptions = append(b.subscriptions[:i], b.subscriptions[i+1:]...)
+			return
+		}
+	}
+}
+
+func (b *bus) removeController(c *Controller) []*subscription {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	removed := make([]*subscription, 0)
+	kept := make([]*subscription, 0, len(b.subscriptions))
+	for _, s := range b.subscriptions {
+		if s.controller == c {
+			removed = append(removed, s)
+		} else {
+			kept = append(kept, s)
+		}
+	}
+	b.subscriptions = kept
+
+	return removed
+}
+
+func (b *bus) publish(channel string, bm extensions.BrokerMessage, delay time.Duration) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	// Keep the message for inspection
+	now := time.Now()
+	b.published = append(b.published, PublishedMessage{
+		Channel:       channel,
+		Time:          now,
+		BrokerMessage: copyMessage(bm),
+	})
+
+	// Get matching subscriptions, grouped by queue group
+	groups := make(map[string][]*subscription)
+	order := make([]string, 0)
+	for _, s := range b.subscriptions {
+		if !matchChannel(s.channel, channel) {
+			continue
+		}
+
+		// Deliver directly when there is no queue group
+		if s.queueGroup == "" {
+			s.push(copyMessage(bm), now.Add(delay))
+			continue
+		}
+
+		if _, exists := groups[s.queueGroup]; !exists {
+			order = append(order, s.queueGroup)
+		}
+		groups[s.queueGroup] = append(groups[s.queueGroup], s)
+	}
+
+	// Deliver to one member of each queue group, in turn
+	for _, name := range order {
+		members := groups[name]
+		member := members[b.nextMember[name]%len(members)]
+		b.nextMember[name]++
+		member.push(copyMessage(bm), now.Add(delay))
+	}
+}
+
+func (b *bus) publishedMessages() []PublishedMessage {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	msgs := make([]PublishedMessage, len(b.published))
+	for i, m := range b.published {
+		msgs[i] = m
+		msgs[i].BrokerMessage = copyMessage(m.BrokerMessage)
+	}
+
+	return msgs
+}
+
+func (b *bus) resetPublishedMessages() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.published = nil
+}
+
+// matchChannel checks if a published channel matches a subscription channel
+// that can contain wildcards.
+func matchChannel(pattern, channel string) bool {
+	patternTokens := strings.Split(pattern, ".")
+	channelTokens := strings.Split(channel, ".")
+
+	for i, p := range patternTokens {
+		switch {
+		case p == ">":
+			// Match one or more remaining tokens
+			return i < len(channelTokens)
+		case i >= len(channelTokens):
+			return false
+		case p != "*" && p != channelTokens[i]:
+			return false
+		}
+	}
+
+	return len(patternTokens) == len(channelTokens)
+}
+
+func copyMessage(bm extensions.BrokerMessage) extensions.BrokerMessage {
+	// Always set headers, so the message is never considered as uninitialized
+	cp := extensions.BrokerMessage{
+		Headers: make(map[string][]byte, len(bm.Headers)),
+	}
+
+	for k, v := range bm.Headers {
+		cp.Headers[k] = append([]byte(nil), v...)
+	}
+
+	if bm.Payload != nil {
+		cp.Payload = append([]byte{}, bm.Payload...)
+	}
+
+	if bm.Metadata != nil {
+		cp.Metadata = make(map[string]string, len(bm.Metadata))
+		for k, v := range bm.Metadata {
+			cp.Metadata[k] = v
+		}
+	}
+
+	return cp
+}
@@ -0,0 +1,214 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions/brokers/brokertest"
+)
+
+func TestControllerSuite(t *testing.T) {
+	suite.Run(t, new(ControllerSuite))
+}
+
+func TestConformance(t *testing.T) {
+	broker := NewController()
+	brokertest.Run(t, func(t *testing.T, queueGroup string) extensions.BrokerController {
+		c := broker.Connect(WithQueueGroup(queueGroup))
+		t.Cleanup(c.Close)
+		return c
+	})
+}
+
+type ControllerSuite struct {
+	suite.Suite
+}
+
+func (suite *ControllerSuite) subscribe(c *Controller, channel string) extensions.BrokerChannelSubscription {
+	sub, err := c.Subscribe(context.Background(), channel)
+	suite.Require().NoError(err)
+	suite.T().Cleanup(func() { sub.Cancel(context.Background()) })
+	return sub
+}
+
+func (suite *ControllerSuite) publish(c *Controller, channel string, payload string) {
+	err := c.Publish(context.Background(), channel, extensions.BrokerMessage{Payload: []byte(payload)})
+	suite.Require().NoError(err)
+}
+
+func (suite *ControllerSuite) requireReceived(sub extensions.BrokerChannelSubscription, payload string) {
+	select {
+	case msg := <-sub.MessagesChannel():
+		suite.Require().Equal(payload, string(msg.Payload))
+	case <-time.After(time.Second):
+		suite.Require().FailNow("no message received", "expected %q", payload)
+	}
+}
+
+func (suite *ControllerSuite) requireNotReceived(sub extensions.BrokerChannelSubscription) {
+	select {
+	case msg := <-sub.MessagesChannel():
+		suite.Require().FailNow("unexpected message received", "%s", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func (suite *ControllerSuite) TestQueueGroup() {
+	c := NewController()
+	sub1, sub2 := suite.subscribe(c, "channel"), suite.subscribe(c, "channel")
+
+	// Check that each message is received by only one subscription, in turn
+	suite.publish(c, "channel", "1")
+	suite.publish(c, "channel", "2")
+	suite.requireReceived(sub1, "1")
+	suite.requireReceived(sub2, "2")
+	suite.requireNotReceived(sub1)
+	suite.requireNotReceived(sub2)
+
+	// Check that another queue group receives every message
+	sub3 := suite.subscribe(c.Connect(WithQueueGroup("other")), "channel")
+	suite.publish(c, "channel", "3")
+	suite.requireReceived(sub1, "3")
+	suite.requireReceived(sub3, "3")
+}
+
+func (suite *ControllerSuite) TestFanOut() {
+	c := NewController(WithQueueGroup(""))
+	sub1, sub2 := suite.subscribe(c, "channel"), suite.subscribe(c, "channel")
+
+	// Check that each message is received by every subscription
+	suite.publish(c, "channel", "1")
+	suite.requireReceived(sub1, "1")
+	suite.requireReceived(sub2, "1")
+}
+
+func (suite *ControllerSuite) TestWildcards() {
+	c := NewController(WithQueueGroup(""))
+	exact := suite.subscribe(c, "a.b.c")
+	token := suite.subscribe(c, "a.*.c")
+	tail := suite.subscribe(c, "a.>")
+
+	suite.publish(c, "a.b.c", "abc")
+	suite.requireReceived(exact, "abc")
+	suite.requireReceived(token, "abc")
+	suite.requireReceived(tail, "abc")
+
+	suite.publish(c, "a.x.c", "axc")
+	suite.requireReceived(token, "axc")
+	suite.requireReceived(tail, "axc")
+	suite.requireNotReceived(exact)
+
+	suite.publish(c, "a.b.c.d", "abcd")
+	suite.requireReceived(tail, "abcd")
+	suite.requireNotReceived(token)
+
+	suite.publish(c, "a", "a")
+	suite.requireNotReceived(tail)
+}
+
+func (suite *ControllerSuite) TestDeliveryDelay() {
+	c := NewController(WithDeliveryDelay(100 * time.Millisecond))
+	sub := suite.subscribe(c, "channel")
+
+	start := time.Now()
+	suite.publish(c, "channel", "1")
+	suite.publish(c, "channel", "2")
+	suite.requireReceived(sub, "1")
+	suite.requireReceived(sub, "2")
+	suite.Require().GreaterOrEqual(time.Since(start), 100*time.Millisecond)
+}
+
+func (suite *ControllerSuite) TestPublishedMessages() {
+	c := NewController()
+
+	// Publish without subscription and from another controller
+	suite.publish(c, "a", "1")
+	suite.publish(c.Connect(), "b", "2")
+
+	msgs := c.PublishedMessages()
+	suite.Require().Len(msgs, 2)
+	suite.Require().Equal("a", msgs[0].Channel)
+	suite.Require().Equal("1", string(msgs[0].Payload))
+	suite.Require().Equal("b", msgs[1].Channel)
+	suite.Require().Equal("2", string(msgs[1].Payload))
+
+	c.ResetPublishedMessages()
+	suite.Require().Empty(c.PublishedMessages())
+}
+
+func (suite *ControllerSuite) TestCancelAndClose() {
+	c := NewController(WithQueueGroup(""))
+
+	// Check that a canceled subscription has its channel closed
+	sub, err := c.Subscribe(context.Background(), "channel")
+	suite.Require().NoError(err)
+	sub.Cancel(context.Background())
+	_, open := <-sub.MessagesChannel()
+	suite.Require().False(open)
+
+	// Check that closed controller subscriptions don't receive messages anymore
+	closed := c.Connect(WithQueueGroup(""))
+	closedSub := suite.subscribe(closed, "channel")
+	openSub := suite.subscribe(c, "channel")
+	closed.Close()
+	suite.publish(c, "channel", "1")
+	suite.requireReceived(openSub, "1")
+	suite.requireNotReceived(closedSub)
+}
+
+func (suite *ControllerSuite) TestAcknowledgment() {
+	c := NewController()
+	sub := suite.subscribe(c, "channel")
+	suite.publish(c, "channel", "1")
+
+	// Check that a negatively acknowledged message is redelivered only once
+	msg := <-sub.MessagesChannel()
+	suite.Require().NoError(msg.Nack())
+	suite.Require().NoError(msg.Nack())
+	msg = <-sub.MessagesChannel()
+	suite.Require().Equal("1", string(msg.Payload))
+	suite.requireNotReceived(sub)
+
+	// Check that an acknowledged message is not redelivered
+	suite.Require().NoError(msg.Ack())
+	suite.Require().NoError(msg.Nack())
+	suite.requireNotReceived(sub)
+}
+
+func (suite *ControllerSuite) TestTerm() {
+	c := NewController()
+	sub := suite.subscribe(c, "channel")
+	suite.publish(c, "channel", "1")
+
+	// Check that a terminated message is not redelivered
+	msg := <-sub.MessagesChannel()
+	suite.Require().NoError(msg.Term())
+	suite.Require().NoError(msg.Nack())
+	suite.requireNotReceived(sub)
+}
+
+func (suite *ControllerSuite) TestRequest() {
+	c := NewController()
+	sub := suite.subscribe(c, "requests")
+
+	// Reply to the request on its reply inbox
+	go func() {
+		msg := <-sub.MessagesChannel()
+		replyTo := msg.Metadata[extensions.MetadataKeyIsReplyTo]
+		_ = c.Publish(context.Background(), replyTo, extensions.BrokerMessage{Payload: []byte("reply to " + string(msg.Payload))})
+	}()
+
+	// Send the request and wait for the reply
+	reply, err := c.Request(context.Background(), "requests", extensions.BrokerMessage{Payload: []byte("request")}, "replies")
+	suite.Require().NoError(err)
+	suite.Require().Equal("reply to request", string(reply.Payload))
+
+	// Without reply, the request should fail when the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = c.Request(ctx, "requests", extensions.BrokerMessage{Payload: []byte("request")}, "replies")
+	suite.Require().ErrorIs(err, extensions.ErrContextCanceled)
+}
@@ -0,0 +1,142 @@
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+)
+
+// delivery is a message waiting to be delivered to a subscription.
+type delivery struct {
+	msg       extensions.BrokerMessage
+	deliverAt time.Time
+}
+
+// subscription delivers messages in publication order to a broker channel
+// subscription, from its own goroutine so publication never blocks.
+type subscription struct {
+	controller *Controller
+	channel    string
+	queueGroup string
+	messages   chan extensions.BrokerMessage
+
+	mutex   sync.Mutex
+	pending []delivery
+	notify  chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+func newSubscription(c *Controller, channel string, messages chan extensions.BrokerMessage) *subscription {
+	s := &subscription{
+		controller: c,
+		channel:    channel,
+		queueGroup: c.queueGroup,
+		messages:   messages,
+		notify:     make(chan struct{}, 1),
+		done:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+
+	go s.run()
+
+	return s
+}
+
+func (s *subscription) push(msg extensions.BrokerMessage, deliverAt time.Time) {
+	s.mutex.Lock()
+	s.pending = append(s.pending, delivery{msg: msg, deliverAt: deliverAt})
+	s.mutex.Unlock()
+
+	// Notify the delivery goroutine without blocking
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (s *subscription) next() (delivery, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.pending) == 0 {
+		return delivery{}, false
+	}
+
+	d := s.pending[0]
+	s.pending = s.pending[1:]
+	return d, true
+}
+
+func (s *subscription) run() {
+	defer close(s.stopped)
+
+	for {
+		// Get next message or wait for one
+		d, ok := s.next()
+		if !ok {
+			select {
+			case <-s.notify:
+				continue
+			case <-s.done:
+				return
+			}
+		}
+
+		// Wait for the delivery time
+		if wait := time.Until(d.deliverAt); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-s.done:
+				timer.Stop()
+				return
+			}
+		}
+
+		// Deliver the message, with its acknowledgment
+		msg := d.msg
+		msg.Acknowledgment = &acknowledgment{subscription: s, msg: d.msg}
+		select {
+		case s.messages <- msg:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// stop stops the deliveries and waits for the delivery goroutine to end.
+func (s *subscription) stop() {
+	s.once.Do(func() { close(s.done) })
+	<-s.stopped
+}
+
+// acknowledgment redelivers the message to its subscription when it is
+// negatively acknowledged. Only the first acknowledgment is taken into account.
+type acknowledgment struct {
+	subscription *subscription
+	msg          extensions.BrokerMessage
+	once         sync.Once
+}
+
+// AckMessage acknowledges the message as successfully handled.
+func (a *acknowledgment) AckMessage() error {
+	a.once.Do(func() {})
+	return nil
+}
+
+// NackMessage redelivers the message to the subscription.
+func (a *acknowledgment) NackMessage() error {
+	a.once.Do(func() {
+		a.subscription.push(a.msg, time.Now().Add(a.subscription.controller.delay))
+	})
+	return nil
+}
+
+// TermMessage terminates the message, that is not redelivered.
+func (a *acknowledgment) TermMessage() error {
+	a.once.Do(func() {})
+	return nil
+}
@@ -0,0 +1,39 @@
+package mqtt
+
+import "context"
+
+// client is the MQTT client of a protocol version, that is either publishing
+// messages or subscribed to a topic filter. It reconnects when the connection
+// is lost, and then subscribes again to its topic filter.
+type client interface {
+	publish(ctx context.Context, topic string, p publication) error
+	subscribe(ctx context.Context) error
+	disconnect()
+}
+
+// publication is a message published by a client.
+type publication struct {
+	qos     byte
+	retain  bool
+	headers map[string][]byte
+	payload []byte
+
+	// MQTT 5 only
+	contentType     string
+	payloadFormat   byte
+	correlationData []byte
+}
+
+// reception is a message received by a client.
+type reception struct {
+	topic    string
+	retained bool
+	headers  map[string][]byte
+	payload  []byte
+
+	// ack acknowledges the message to the broker, it is nil with QoS 0
+	ack func() error
+}
+
+// keepAlive is the keep alive interval of the connections, in seconds.
+const keepAlive = 30
@@ -0,0 +1,127 @@
+package mqtt
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	pahov3 "github.com/eclipse/paho.mqtt.golang"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+)
+
+// clientV3 is the MQTT 3.1.1 client, based on
+// github.com/eclipse/paho.mqtt.golang.
+type clientV3 struct {
+	controller *Controller
+	filter     *filter
+	connection pahov3.Client
+
+	// connected is true once the first connection has been made
+	connected atomic.Bool
+}
+
+func newClientV3(c *Controller, clientID string, f *filter) (*clientV3, error) {
+	cl := &clientV3{controller: c, filter: f}
+
+	// Set the client options
+	opts := pahov3.NewClientOptions().
+		AddBroker(c.url.String()).
+		SetProtocolVersion(uint(ProtocolVersion311)).
+		SetClientID(clientID).
+		SetUsername(c.username).
+		SetPassword(c.password).
+		SetTLSConfig(c.tlsConfig).
+		SetKeepAlive(keepAlive * time.Second).
+		SetCleanSession(true).
+		SetConnectTimeout(c.connectTimeout).
+		SetAutoReconnect(true).
+		SetMaxReconnectInterval(c.reconnectWait).
+		SetDefaultPublishHandler(cl.receive).
+		SetAutoAckDisabled(true).
+		SetOnConnectHandler(func(pahov3.Client) {
+			if !cl.connected.Swap(true) {
+				return
+			}
+
+			c.logger.Info(context.Background(), "Reconnected to MQTT broker")
+			cl.resubscribe()
+		}).
+		SetConnectionLostHandler(func(_ pahov3.Client, err error) {
+			c.logger.Warning(context.Background(), fmt.Sprintf("Disconnected from MQTT broker: %q", err.Error()))
+		})
+
+	// Connect
+	cl.connection = pahov3.NewClient(opts)
+	token := cl.connection.Connect()
+	if !token.WaitTimeout(c.connectTimeout) {
+		cl.connection.Disconnect(0)
+		return nil, fmt.Errorf("%w: connection timeout", extensions.ErrContextCanceled)
+	}
+	if err := token.Error(); err != nil {
+		return nil, err
+	}
+
+	return cl, nil
+}
+
+// resubscribe subscribes again to the topic filter of the client, if any.
+func (cl *clientV3) resubscribe() {
+	if cl.filter == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cl.controller.connectTimeout)
+	defer cancel()
+
+	if err := cl.subscribe(ctx); err != nil {
+		cl.controller.logger.Error(ctx, fmt.Sprintf("Error when subscribing again to %q: %q", cl.filter.name, err.Error()))
+	}
+}
+
+func (cl *clientV3) publish(ctx context.Context, topic string, p publication) error {
+	return wait(ctx, cl.connection.Publish(topic, p.qos, p.retain, p.payload))
+}
+
+func (cl *clientV3) receive(_ pahov3.Client, msg pahov3.Message) {
+	if cl.filter == nil {
+		msg.Ack()
+		return
+	}
+
+	r := reception{
+		topic:    msg.Topic(),
+		retained: msg.Retained(),
+		headers:  make(map[string][]byte),
+		payload:  msg.Payload(),
+	}
+
+	// Acknowledge the message once handled
+	if msg.Qos() > 0 {
+		r.ack = func() error {
+			msg.Ack()
+			return nil
+		}
+	}
+
+	cl.controller.dispatch(cl.filter, r)
+}
+
+func (cl *clientV3) subscribe(ctx context.Context) error {
+	// Without callback, messages are received by the default handler
+	return wait(ctx, cl.connection.Subscribe(cl.filter.name, cl.filter.qos, nil))
+}
+
+func (cl *clientV3) disconnect() {
+	cl.connection.Disconnect(uint(cl.controller.connectTimeout.Milliseconds()))
+}
+
+// wait waits for the operation of the token to be completed.
+func wait(ctx context.Context, token pahov3.Token) error {
+	select {
+	case <-token.Done():
+		return token.Error()
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %s", extensions.ErrContextCanceled, ctx.Err().Error())
+	}
+}
@@ -0,0 +1,193 @@
+package mqtt
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/url"
+	"sync/atomic"
+
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+)
+
+// clientV5 is the MQTT 5 client, based on github.com/eclipse/paho.golang.
+type clientV5 struct {
+	controller *Controller
+	filter     *filter
+	connection *autopaho.ConnectionManager
+
+	// connected is true once the first connection has been made
+	connected atomic.Bool
+}
+
+func newClientV5(c *Controller, clientID string, f *filter) (*clientV5, error) {
+	cl := &clientV5{controller: c, filter: f}
+	up := make(chan struct{})
+	errs := make(chan error, 1)
+
+	// Set the client configuration
+	config := autopaho.ClientConfig{
+		ServerUrls:                    []*url.URL{c.url},
+		TlsCfg:                        c.tlsConfig,
+		KeepAlive:                     keepAlive,
+		CleanStartOnInitialConnection: true,
+		ConnectRetryDelay:             c.reconnectWait,
+		ConnectTimeout:                c.connectTimeout,
+		ConnectUsername:               c.username,
+		ConnectPacketBuilder: func(cp *paho.Connect, _ *url.URL) *paho.Connect {
+			// Limit the messages received and not acknowledged yet, keeping the
+			// default properties (i.e. problem information requested)
+			if cp.Properties == nil {
+				cp.Properties = &paho.ConnectProperties{RequestProblemInfo: true}
+			}
+			receiveMaximum := uint16(min(c.maxPending, math.MaxUint16))
+			cp.Properties.ReceiveMaximum = &receiveMaximum
+			return cp
+		},
+		OnConnectionUp: func(*autopaho.ConnectionManager, *paho.Connack) {
+			if !cl.connected.Swap(true) {
+				close(up)
+				return
+			}
+
+			c.logger.Info(context.Background(), "Reconnected to MQTT broker")
+			cl.resubscribe()
+		},
+		OnConnectError: func(err error) {
+			if !cl.connected.Load() {
+				select {
+				case errs <- err:
+				default:
+				}
+				return
+			}
+
+			c.logger.Warning(context.Background(), fmt.Sprintf("Error when reconnecting to MQTT broker: %q", err.Error()))
+		},
+		ClientConfig: paho.ClientConfig{
+			ClientID:                   clientID,
+			OnPublishReceived:          []func(paho.PublishReceived) (bool, error){cl.receive},
+			EnableManualAcknowledgment: true,
+			OnClientError: func(err error) {
+				c.logger.Warning(context.Background(), fmt.Sprintf("Disconnected from MQTT broker: %q", err.Error()))
+			},
+			OnServerDisconnect: func(d *paho.Disconnect) {
+				c.logger.Warning(context.Background(), fmt.Sprintf("Disconnected by MQTT broker: reason code %d", d.ReasonCode))
+			},
+		},
+	}
+	if c.password != "" {
+		config.ConnectPassword = []byte(c.password)
+	}
+
+	// Connect, and fail if the first connection fails
+	cm, err := autopaho.NewConnection(context.Background(), config)
+	if err != nil {
+		return nil, err
+	}
+	cl.connection = cm
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.connectTimeout)
+	defer cancel()
+	select {
+	case <-up:
+		return cl, nil
+	case err = <-errs:
+	case <-ctx.Done():
+		err = fmt.Errorf("%w: %s", extensions.ErrContextCanceled, ctx.Err().Error())
+	}
+	cl.disconnect()
+
+	return nil, err
+}
+
+// resubscribe subscribes again to the topic filter of the client, if any.
+func (cl *clientV5) resubscribe() {
+	if cl.filter == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cl.controller.connectTimeout)
+	defer cancel()
+
+	if err := cl.subscribe(ctx); err != nil {
+		cl.controller.logger.Error(ctx, fmt.Sprintf("Error when subscribing again to %q: %q", cl.filter.name, err.Error()))
+	}
+}
+
+func (cl *clientV5) publish(ctx context.Context, topic string, p publication) error {
+	// Wait for the connection
+	if err := cl.connection.AwaitConnection(ctx); err != nil {
+		return fmt.Errorf("%w: %s", extensions.ErrContextCanceled, err.Error())
+	}
+
+	// Set the message, with headers as user properties
+	msg := &paho.Publish{
+		QoS:     p.qos,
+		Retain:  p.retain,
+		Topic:   topic,
+		Payload: p.payload,
+		Properties: &paho.PublishProperties{
+			ContentType:     p.contentType,
+			CorrelationData: p.correlationData,
+		},
+	}
+	if p.payloadFormat != 0 {
+		msg.Properties.PayloadFormat = &p.payloadFormat
+	}
+	for k, v := range p.headers {
+		msg.Properties.User.Add(k, string(v))
+	}
+
+	_, err := cl.connection.Publish(ctx, msg)
+	return err
+}
+
+func (cl *clientV5) receive(pr paho.PublishReceived) (bool, error) {
+	if cl.filter == nil {
+		return true, pr.Client.Ack(pr.Packet)
+	}
+
+	msg := pr.Packet
+	r := reception{
+		topic:    msg.Topic,
+		retained: msg.Retain,
+		headers:  make(map[string][]byte),
+		payload:  msg.Payload,
+	}
+
+	// Get headers from user properties
+	if msg.Properties != nil {
+		for _, p := range msg.Properties.User {
+			r.headers[p.Key] = []byte(p.Value)
+		}
+	}
+
+	// Acknowledge the message once handled
+	if msg.QoS > 0 {
+		r.ack = func() error {
+			return pr.Client.Ack(msg)
+		}
+	}
+
+	cl.controller.dispatch(cl.filter, r)
+	return true, nil
+}
+
+func (cl *clientV5) subscribe(ctx context.Context) error {
+	_, err := cl.connection.Subscribe(ctx, &paho.Subscribe{
+		Subscriptions: []paho.SubscribeOptions{{Topic: cl.filter.name, QoS: cl.filter.qos}},
+	})
+	return err
+}
+
+func (cl *clientV5) disconnect() {
+	ctx, cancel := context.WithTimeout(context.Background(), cl.controller.connectTimeout)
+	defer cancel()
+
+	if err := cl.connection.Disconnect(ctx); err != nil {
+		cl.controller.logger.Error(ctx, err.Error())
+	}
+}
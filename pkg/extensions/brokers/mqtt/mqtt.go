@@ -0,0 +1,496 @@
+package mqtt
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions/brokers"
+)
+
+// Check that it still fills the interface.
+var _ extensions.BrokerController = (*Controller)(nil)
+
+var (
+	// ErrUnsupportedProtocolVersion is raised when the MQTT protocol version
+	// set with WithProtocolVersion is neither MQTT 3.1.1 nor MQTT 5.
+	ErrUnsupportedProtocolVersion = fmt.Errorf("%w: unsupported MQTT protocol version", extensions.ErrAsyncAPI)
+
+	// ErrInvalidQoS is raised when a QoS is not 0, 1 or 2.
+	ErrInvalidQoS = fmt.Errorf("%w: MQTT QoS should be 0, 1 or 2", extensions.ErrAsyncAPI)
+
+	// ErrInvalidMaxPendingMessages is raised when the maximum number of
+	// pending messages is not positive.
+	ErrInvalidMaxPendingMessages = fmt.Errorf("%w: MQTT maximum pending messages should be positive", extensions.ErrAsyncAPI)
+)
+
+// ProtocolVersion is the version of the MQTT protocol used by the controller.
+type ProtocolVersion byte
+
+const (
+	// ProtocolVersion311 is MQTT 3.1.1.
+	ProtocolVersion311 ProtocolVersion = 4
+	// ProtocolVersion5 is MQTT 5.
+	ProtocolVersion5 ProtocolVersion = 5
+)
+
+const (
+	// DefaultQoS is the default QoS of channels without MQTT operation binding:
+	// messages are delivered at least once.
+	DefaultQoS = 1
+
+	// DefaultConnectTimeout is the default maximum duration to wait for the
+	// connection to the MQTT broker.
+	DefaultConnectTimeout = 10 * time.Second
+
+	// DefaultPublishTimeout is the default maximum duration to wait for the
+	// acknowledgment of a published message by the MQTT broker.
+	DefaultPublishTimeout = 5 * time.Second
+
+	// DefaultReconnectWait is the default duration to wait between attempts to
+	// reconnect to the MQTT broker.
+	DefaultReconnectWait = time.Second
+
+	// DefaultMaxPendingMessages is the default maximum number of received
+	// messages waiting to be read by a subscription.
+	DefaultMaxPendingMessages = 1024
+)
+
+const (
+	// MetadataKeyIsTopic is the key of the received messages metadata that
+	// contains their topic (i.e. the channel on wildcard subscriptions).
+	MetadataKeyIsTopic = "mqtt-topic"
+
+	// MetadataKeyIsRetained is the key of the received messages metadata that
+	// is "true" if the message has been retained by the broker.
+	MetadataKeyIsRetained = "mqtt-retained"
+)
+
+// Controller is the MQTT implementation for asyncapi-codegen, supporting MQTT
+// 3.1.1 and MQTT 5.
+//
+// Channels are topics, subscribed with the QoS of their MQTT operation binding
+// (or DefaultQoS), through a shared subscription of the queue group unless
+// messages are broadcast. Channels can contain the NATS-like wildcards of the
+// other brokers, that are converted to the MQTT ones.
+//
+// Headers are transmitted as MQTT 5 user properties: they are not supported
+// with MQTT 3.1.1.
+//
+// Messages are published on the controller connection, and each topic filter
+// is subscribed on its own connection, shared by its subscriptions.
+//
+// Received messages with QoS 1 or 2 are acknowledged to the broker once handled,
+// either successfully or not, as MQTT doesn't redeliver them. They are never
+// dropped, as the broker limits the number of unacknowledged messages it sends:
+// to the maximum number of pending messages of a subscription with MQTT 5, and
+// to its own maximum of in-flight messages with MQTT 3.1.1. Messages with QoS 0
+// received while the queue of a subscription is full are dropped.
+//
+// Disconnections and reconnections are logged with the controller logger. On
+// reconnection, the subscriptions are re-established.
+type Controller struct {
+	url             *url.URL
+	logger          extensions.Logger
+	queueGroup      string
+	protocolVersion ProtocolVersion
+	clientID        string
+	username        string
+	password        string
+	tlsConfig       *tls.Config
+	qos             byte
+	connectTimeout  time.Duration
+	publishTimeout  time.Duration
+	reconnectWait   time.Duration
+	maxPending      int
+
+	// client is the client publishing messages
+	client client
+
+	// subscriptionsMutex serializes the subscriptions on the broker
+	subscriptionsMutex sync.Mutex
+
+	// filters are the topic filters subscribed on the broker, by name
+	mutex         sync.Mutex
+	filters       map[string]*filter
+	lastFilterID  int
+	subscriptions map[*subscription]*filter
+}
+
+// ControllerOption is a function that can be used to configure a MQTT
+// controller.
+// Examples: WithQueueGroup(), WithProtocolVersion(), WithLogger().
+type ControllerOption func(controller *Controller)
+
+// NewController creates a new MQTT controller connected to the broker.
+func NewController(brokerURL string, options ...ControllerOption) (*Controller, error) {
+	u, err := url.Parse(brokerURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// Creates default controller
+	controller := &Controller{
+		url:             u,
+		logger:          extensions.DummyLogger{},
+		queueGroup:      brokers.DefaultQueueGroupID,
+		protocolVersion: ProtocolVersion5,
+		clientID:        brokers.DefaultQueueGroupID + "-" + uuid.New().String(),
+		qos:             DefaultQoS,
+		connectTimeout:  DefaultConnectTimeout,
+		publishTimeout:  DefaultPublishTimeout,
+		reconnectWait:   DefaultReconnectWait,
+		maxPending:      DefaultMaxPendingMessages,
+		filters:         make(map[string]*filter),
+		subscriptions:   make(map[*subscription]*filter),
+	}
+
+	// Execute options
+	for _, option := range options {
+		option(controller)
+	}
+
+	// Check options
+	if controller.qos > 2 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidQoS, controller.qos)
+	}
+	if controller.maxPending < 1 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidMaxPendingMessages, controller.maxPending)
+	}
+
+	// Connect to the broker
+	if controller.client, err = controller.newClient(controller.clientID, nil); err != nil {
+		return nil, err
+	}
+
+	return controller, nil
+}
+
+// WithQueueGroup set a custom queue group for channel subscription: members of
+// the same queue group share a subscription. If the name is empty, every
+// subscription will receive every message (fan-out).
+func WithQueueGroup(name string) ControllerOption {
+	return func(controller *Controller) {
+		controller.queueGroup = name
+	}
+}
+
+// WithLogger set a custom logger that will log operations on broker controller.
+func WithLogger(logger extensions.Logger) ControllerOption {
+	return func(controller *Controller) {
+		controller.logger = logger
+	}
+}
+
+// WithProtocolVersion set the version of the MQTT protocol: ProtocolVersion5
+// (the default) or ProtocolVersion311.
+func WithProtocolVersion(version ProtocolVersion) ControllerOption {
+	return func(controller *Controller) {
+		controller.protocolVersion = version
+	}
+}
+
+// WithClientID set the client identifier of the connection. It should be unique
+// on the broker. The connections of the subscriptions use it with a suffix.
+func WithClientID(id string) ControllerOption {
+	return func(controller *Controller) {
+		controller.clientID = id
+	}
+}
+
+// WithCredentials set the username and password of the connection.
+func WithCredentials(username, password string) ControllerOption {
+	return func(controller *Controller) {
+		controller.username = username
+		controller.password = password
+	}
+}
+
+// WithTLSConfig set the TLS configuration of the connection, used with the
+// 'mqtts', 'ssl', 'tls' and 'wss' URL schemes.
+func WithTLSConfig(config *tls.Config) ControllerOption {
+	return func(controller *Controller) {
+		controller.tlsConfig = config
+	}
+}
+
+// WithQoS set the QoS of channels without MQTT operation binding.
+func WithQoS(qos byte) ControllerOption {
+	return func(controller *Controller) {
+		controller.qos = qos
+	}
+}
+
+// WithConnectTimeout set the maximum duration to wait for the connection to
+// the broker.
+func WithConnectTimeout(timeout time.Duration) ControllerOption {
+	return func(controller *Controller) {
+		controller.connectTimeout = timeout
+	}
+}
+
+// WithPublishTimeout set the maximum duration to wait for the acknowledgment of
+// a published message by the broker.
+func WithPublishTimeout(timeout time.Duration) ControllerOption {
+	return func(controller *Controller) {
+		controller.publishTimeout = timeout
+	}
+}
+
+// WithReconnectWait set the duration to wait between reconnection attempts.
+func WithReconnectWait(wait time.Duration) ControllerOption {
+	return func(controller *Controller) {
+		controller.reconnectWait = wait
+	}
+}
+
+// WithMaxPendingMessages set the maximum number of received messages with QoS 0
+// waiting to be read by a subscription, the next ones being dropped. With MQTT
+// 5, it is also the maximum number of messages with QoS 1 or 2 sent by the
+// broker without being acknowledged. With MQTT 3.1.1, the number of these
+// messages is only limited by the maximum of in-flight messages of the broker.
+func WithMaxPendingMessages(max int) ControllerOption {
+	return func(controller *Controller) {
+		controller.maxPending = max
+	}
+}
+
+// Publish a message to the broker.
+func (c *Controller) Publish(ctx context.Context, channel string, bm extensions.BrokerMessage) error {
+	// Wait for the acknowledgment until the timeout
+	ctx, cancel := context.WithTimeout(ctx, c.publishTimeout)
+	defer cancel()
+
+	return c.client.publish(ctx, channel, c.newPublication(ctx, bm))
+}
+
+// Subscribe to messages from the broker.
+func (c *Controller) Subscribe(ctx context.Context, channel string) (extensions.BrokerChannelSubscription, error) {
+	// Create a new subscription
+	sub := newSubscription(c.maxPending)
+
+	// Get the topic filter, shared by the queue group unless messages are broadcast
+	topic := topicFilter(channel)
+	name := topic
+	if c.queueGroup != "" && extensions.DeliveryModeFromContext(ctx) != extensions.DeliveryModeBroadcast {
+		name = "$share/" + c.queueGroup + "/" + topic
+	}
+
+	// Subscribe to the topic filter, unless it is already subscribed
+	if err := c.subscribe(ctx, name, topic, c.operationBinding(ctx).QoS, sub); err != nil {
+		return extensions.BrokerChannelSubscription{}, err
+	}
+	go sub.run()
+
+	// Wait for cancellation and unsubscribe
+	sub.WaitForCancellationAsync(func() {
+		c.unsubscribe(sub)
+	})
+
+	return sub.BrokerChannelSubscription, nil
+}
+
+// newClient connects a client of the protocol version to the broker, that
+// transmits its received messages to the topic filter (if any).
+func (c *Controller) newClient(clientID string, f *filter) (client, error) {
+	switch c.protocolVersion {
+	case ProtocolVersion5:
+		return newClientV5(c, clientID, f)
+	case ProtocolVersion311:
+		return newClientV3(c, clientID, f)
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedProtocolVersion, c.protocolVersion)
+	}
+}
+
+// subscribe adds the subscription to the topic filter, which is subscribed on
+// the broker with a new connection if it is new.
+func (c *Controller) subscribe(ctx context.Context, name, topic string, qos byte, sub *subscription) error {
+	c.subscriptionsMutex.Lock()
+	defer c.subscriptionsMutex.Unlock()
+
+	// Add the subscription to the topic filter, if it exists
+	c.mutex.Lock()
+	if f, exists := c.filters[name]; exists {
+		f.subscriptions = append(f.subscriptions, sub)
+		c.subscriptions[sub] = f
+		c.mutex.Unlock()
+		return nil
+	}
+	c.mutex.Unlock()
+
+	// Subscribe to the topic filter on its own connection, as brokers can
+	// merge the messages of overlapping topic filters of a connection
+	c.lastFilterID++
+	f := &filter{
+		id:            c.lastFilterID,
+		name:          name,
+		topic:         topic,
+		qos:           qos,
+		shared:        name != topic,
+		subscriptions: []*subscription{sub},
+	}
+	cl, err := c.newClient(c.clientID+"-"+strconv.Itoa(f.id), f)
+	if err != nil {
+		return err
+	}
+	if err := cl.subscribe(ctx); err != nil {
+		cl.disconnect()
+		return err
+	}
+	f.client = cl
+
+	// Add the topic filter
+	c.mutex.Lock()
+	c.filters[name] = f
+	c.subscriptions[sub] = f
+	c.mutex.Unlock()
+
+	return nil
+}
+
+// unsubscribe removes the subscription from its topic filter, whose
+// connection is closed if it has no subscription left.
+func (c *Controller) unsubscribe(sub *subscription) {
+	c.subscriptionsMutex.Lock()
+	defer c.subscriptionsMutex.Unlock()
+
+	// Remove the subscription, so it doesn't receive messages anymore
+	c.mutex.Lock()
+	f := c.subscriptions[sub]
+	delete(c.subscriptions, sub)
+	f.remove(sub)
+	closing := len(f.subscriptions) == 0 && c.filters[f.name] == f
+	if closing {
+		delete(c.filters, f.name)
+	}
+	c.mutex.Unlock()
+
+	// Acknowledge the messages that will not be read, as the next ones are
+	// acknowledged to the broker in order
+	for _, bm := range sub.stop() {
+		c.ack(bm)
+	}
+
+	// Close the connection of the topic filter, which unsubscribes from it
+	if closing {
+		f.client.disconnect()
+	}
+}
+
+// dispatch transmits a message received on the topic filter to its
+// subscriptions. It is acknowledged to the broker once every subscription has
+// handled it, or immediately if none is receiving it.
+func (c *Controller) dispatch(f *filter, r reception) {
+	bm := newBrokerMessage(r)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Get the subscriptions receiving the message, and count the
+	// acknowledgment of the dispatch itself so it is sent when none is
+	subs := f.recipients()
+	if r.ack != nil {
+		bm.Acknowledgment = newAcknowledgment(len(subs)+1, r.ack)
+	}
+
+	// Transmit the message. Messages to acknowledge are always queued, as the
+	// broker limits them, but the others are dropped if the queue is full
+	for _, sub := range subs {
+		if !sub.push(bm, r.ack != nil) {
+			c.logger.Warning(context.Background(), fmt.Sprintf("Message dropped on %q: too many pending messages", r.topic))
+		}
+	}
+
+	// Release the acknowledgment of the dispatch
+	c.ack(bm)
+}
+
+// ack acknowledges a received message that will not be handled.
+func (c *Controller) ack(bm extensions.BrokerMessage) {
+	if err := bm.Ack(); err != nil {
+		c.logger.Error(context.Background(), fmt.Sprintf("Error when acknowledging message: %q", err.Error()))
+	}
+}
+
+// operationBinding returns the MQTT operation binding from the specification,
+// or the default one if there is none.
+func (c *Controller) operationBinding(ctx context.Context) extensions.MQTTOperationBinding {
+	binding := extensions.MQTTOperationBinding{QoS: c.qos}
+	extensions.IfContextSetWith(ctx, extensions.ContextKeyIsMQTTOperationBinding, func(value extensions.MQTTOperationBinding) {
+		binding = value
+	})
+
+	return binding
+}
+
+func (c *Controller) newPublication(ctx context.Context, bm extensions.BrokerMessage) publication {
+	binding := c.operationBinding(ctx)
+	p := publication{
+		qos:     binding.QoS,
+		retain:  binding.Retain,
+		headers: bm.Headers,
+		payload: bm.Payload,
+	}
+
+	// Set the MQTT 5 properties of the message binding, if there is one
+	extensions.IfContextSetWith(ctx, extensions.ContextKeyIsMQTTMessageBinding, func(value extensions.MQTTMessageBinding) {
+		p.contentType = value.ContentType
+		p.payloadFormat = value.PayloadFormatIndicator
+	})
+
+	// Set the correlation ID, if there is one
+	if id, ok := ctx.Value(extensions.ContextKeyIsCorrelationID).(string); ok {
+		p.correlationData = []byte(id)
+	}
+
+	return p
+}
+
+func newBrokerMessage(r reception) extensions.BrokerMessage {
+	return extensions.BrokerMessage{
+		Headers: r.headers,
+		Payload: r.payload,
+		Metadata: map[string]string{
+			MetadataKeyIsTopic:    r.topic,
+			MetadataKeyIsRetained: strconv.FormatBool(r.retained),
+		},
+	}
+}
+
+// Close closes everything related to the broker.
+func (c *Controller) Close() {
+	// Close the connections of the topic filters
+	c.mutex.Lock()
+	filters := c.filters
+	c.filters = make(map[string]*filter)
+	c.mutex.Unlock()
+	for _, f := range filters {
+		f.client.disconnect()
+	}
+
+	c.client.disconnect()
+}
+
+// topicFilter returns the MQTT topic filter of a channel, with the NATS-like
+// '*' and '>' wildcards replaced by the MQTT '+' and '#' ones.
+func topicFilter(channel string) string {
+	levels := strings.Split(channel, "/")
+	for i, level := range levels {
+		switch {
+		case level == "*":
+			levels[i] = "+"
+		case level == ">" && i == len(levels)-1:
+			levels[i] = "#"
+		}
+	}
+
+	return strings.Join(levels, "/")
+}
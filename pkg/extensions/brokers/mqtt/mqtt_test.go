@@ -0,0 +1,197 @@
+package mqtt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+)
+
+func TestControllerSuite(t *testing.T) {
+	suite.Run(t, new(ControllerSuite))
+}
+
+type ControllerSuite struct {
+	suite.Suite
+	controller *Controller
+}
+
+func (suite *ControllerSuite) SetupTest() {
+	// Create a controller without connection, as the tests don't use it
+	suite.controller = &Controller{
+		qos:           DefaultQoS,
+		logger:        extensions.DummyLogger{},
+		filters:       make(map[string]*filter),
+		subscriptions: make(map[*subscription]*filter),
+	}
+}
+
+func (suite *ControllerSuite) TestConnectError() {
+	for _, version := range []ProtocolVersion{ProtocolVersion5, ProtocolVersion311} {
+		_, err := NewController("mqtt://127.0.0.1:1",
+			WithProtocolVersion(version),
+			WithConnectTimeout(time.Second))
+		suite.Require().Error(err, version)
+	}
+}
+
+func (suite *ControllerSuite) TestInvalidOptions() {
+	_, err := NewController("mqtt://127.0.0.1:1", WithQoS(3))
+	suite.Require().ErrorIs(err, ErrInvalidQoS)
+
+	_, err = NewController("mqtt://127.0.0.1:1", WithProtocolVersion(3))
+	suite.Require().ErrorIs(err, ErrUnsupportedProtocolVersion)
+
+	_, err = NewController("mqtt://127.0.0.1:1", WithMaxPendingMessages(0))
+	suite.Require().ErrorIs(err, ErrInvalidMaxPendingMessages)
+}
+
+func (suite *ControllerSuite) TestTopicFilter() {
+	suite.Require().Equal("user/signup", topicFilter("user/signup"))
+	suite.Require().Equal("user/+/signup", topicFilter("user/*/signup"))
+	suite.Require().Equal("user/#", topicFilter("user/>"))
+	suite.Require().Equal("user/+/#", topicFilter("user/+/#"))
+	suite.Require().Equal("user/>/signup", topicFilter("user/>/signup"))
+}
+
+func (suite *ControllerSuite) TestPublicationDefault() {
+	p := suite.controller.newPublication(context.Background(), extensions.BrokerMessage{
+		Headers: map[string][]byte{"key": []byte("value")},
+		Payload: []byte("payload"),
+	})
+	suite.Require().Equal(publication{
+		qos:     DefaultQoS,
+		headers: map[string][]byte{"key": []byte("value")},
+		payload: []byte("payload"),
+	}, p)
+}
+
+func (suite *ControllerSuite) TestPublicationFromContext() {
+	ctx := context.WithValue(context.Background(), extensions.ContextKeyIsMQTTOperationBinding, extensions.MQTTOperationBinding{
+		QoS:    2,
+		Retain: true,
+	})
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTMessageBinding, extensions.MQTTMessageBinding{
+		PayloadFormatIndicator: 1,
+		ContentType:            "application/json",
+	})
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsCorrelationID, "1234")
+
+	p := suite.controller.newPublication(ctx, extensions.BrokerMessage{Payload: []byte("payload")})
+	suite.Require().Equal(byte(2), p.qos)
+	suite.Require().True(p.retain)
+	suite.Require().Equal(byte(1), p.payloadFormat)
+	suite.Require().Equal("application/json", p.contentType)
+	suite.Require().Equal([]byte("1234"), p.correlationData)
+}
+
+func (suite *ControllerSuite) TestBrokerMessage() {
+	bm := newBrokerMessage(reception{
+		topic:    "user/signup",
+		retained: true,
+		headers:  map[string][]byte{"key": []byte("value")},
+		payload:  []byte("payload"),
+	})
+	suite.Require().Equal(map[string][]byte{"key": []byte("value")}, bm.Headers)
+	suite.Require().Equal("payload", string(bm.Payload))
+	suite.Require().Equal("user/signup", bm.Metadata[MetadataKeyIsTopic])
+	suite.Require().Equal("true", bm.Metadata[MetadataKeyIsRetained])
+	suite.Require().Nil(bm.Acknowledgment)
+}
+
+func (suite *ControllerSuite) TestDispatchShared() {
+	sub1, sub2 := newSubscription(10), newSubscription(10)
+	f := &filter{name: "$share/group/user/+", topic: "user/+", shared: true, subscriptions: []*subscription{sub1, sub2}}
+
+	// Messages are transmitted to one subscription at a time
+	for i := 0; i < 3; i++ {
+		suite.controller.dispatch(f, reception{topic: "user/signup"})
+	}
+	suite.Require().Len(sub1.pending, 2)
+	suite.Require().Len(sub2.pending, 1)
+}
+
+func (suite *ControllerSuite) TestDispatchNotShared() {
+	sub1, sub2 := newSubscription(10), newSubscription(10)
+	f := &filter{name: "user/+", topic: "user/+", subscriptions: []*subscription{sub1, sub2}}
+
+	// Messages are transmitted to every subscription
+	for i := 0; i < 3; i++ {
+		suite.controller.dispatch(f, reception{topic: "user/signup"})
+	}
+	suite.Require().Len(sub1.pending, 3)
+	suite.Require().Len(sub2.pending, 3)
+}
+
+// countingAck returns an acknowledgment function counting its calls.
+func countingAck(acks *int) func() error {
+	return func() error {
+		*acks++
+		return nil
+	}
+}
+
+func (suite *ControllerSuite) TestAckOnceHandledBySubscriptions() {
+	sub1, sub2 := newSubscription(10), newSubscription(10)
+	f := &filter{name: "user/+", topic: "user/+", subscriptions: []*subscription{sub1, sub2}}
+
+	// The message should be acknowledged once handled by every subscription
+	acks := 0
+	suite.controller.dispatch(f, reception{topic: "user/signup", ack: countingAck(&acks)})
+	suite.Require().Zero(acks)
+	suite.Require().NoError(sub1.pending[0].Ack())
+	suite.Require().Zero(acks)
+	suite.Require().NoError(sub2.pending[0].Nack())
+	suite.Require().Equal(1, acks)
+}
+
+func (suite *ControllerSuite) TestAckWithoutSubscription() {
+	f := &filter{name: "user/+", topic: "user/+"}
+
+	// The message should be acknowledged as it will not be handled
+	acks := 0
+	suite.controller.dispatch(f, reception{topic: "user/signup", ack: countingAck(&acks)})
+	suite.Require().Equal(1, acks)
+}
+
+func (suite *ControllerSuite) TestDispatchFullQueue() {
+	for _, version := range []ProtocolVersion{ProtocolVersion5, ProtocolVersion311} {
+		suite.controller.protocolVersion = version
+		sub := newSubscription(1)
+		f := &filter{name: "user/+", topic: "user/+", subscriptions: []*subscription{sub}}
+
+		// The messages to acknowledge should be queued even if the queue is full,
+		// without being acknowledged, as the broker limits them
+		acks := 0
+		suite.controller.dispatch(f, reception{topic: "user/signup", ack: countingAck(&acks)})
+		suite.controller.dispatch(f, reception{topic: "user/signin", ack: countingAck(&acks)})
+		suite.Require().Len(sub.pending, 2, version)
+		suite.Require().Zero(acks, version)
+
+		// But the messages without acknowledgment (QoS 0) should be dropped
+		suite.controller.dispatch(f, reception{topic: "user/signout"})
+		suite.Require().Len(sub.pending, 2, version)
+		suite.Require().Equal("user/signin", sub.pending[1].Metadata[MetadataKeyIsTopic], version)
+	}
+}
+
+func (suite *ControllerSuite) TestAckUnreadOnUnsubscribe() {
+	sub1, sub2 := newSubscription(10), newSubscription(10)
+	f := &filter{name: "user/+", topic: "user/+", subscriptions: []*subscription{sub1, sub2}}
+	suite.controller.filters[f.name] = f
+	suite.controller.subscriptions[sub1] = f
+	suite.controller.subscriptions[sub2] = f
+	go sub1.run()
+
+	// Receive a message that is not read by the first subscription
+	acks := 0
+	suite.controller.dispatch(f, reception{topic: "user/signup", ack: countingAck(&acks)})
+	suite.Require().NoError(sub2.pending[0].Ack())
+	suite.Require().Zero(acks)
+
+	// Unsubscribing should acknowledge it
+	suite.controller.unsubscribe(sub1)
+	suite.Require().Equal(1, acks)
+}
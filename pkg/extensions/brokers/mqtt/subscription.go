@@ -0,0 +1,188 @@
+package mqtt
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions/brokers"
+)
+
+// filter is a topic filter subscribed on the broker, shared by the
+// subscriptions to the same channel with the same delivery mode.
+type filter struct {
+	// id is the identifier of the topic filter in the controller
+	id int
+	// name is the topic filter subscribed on the broker, with the shared
+	// subscription prefix if it is shared
+	name string
+	// topic is the topic filter matched by the received messages
+	topic  string
+	qos    byte
+	shared bool
+
+	// client is the client subscribed to the topic filter
+	client client
+
+	subscriptions []*subscription
+	next          int
+}
+
+// recipients returns the subscriptions of the topic filter that should receive
+// a message: all of them, or one of them in turn if it is shared.
+func (f *filter) recipients() []*subscription {
+	if len(f.subscriptions) == 0 {
+		return nil
+	}
+
+	if f.shared {
+		sub := f.subscriptions[f.next%len(f.subscriptions)]
+		f.next++
+		return []*subscription{sub}
+	}
+
+	return append([]*subscription(nil), f.subscriptions...)
+}
+
+// remove removes the subscription from the topic filter.
+func (f *filter) remove(sub *subscription) {
+	for i, s := range f.subscriptions {
+		if s == sub {
+			f.subscriptions = append(f.subscriptions[:i], f.subscriptions[i+1:]...)
+			return
+		}
+	}
+}
+
+// acknowledgment acknowledges a received message to the broker once it has
+// been acknowledged by each of its recipients.
+type acknowledgment struct {
+	remaining atomic.Int32
+	ack       func() error
+}
+
+func newAcknowledgment(recipients int, ack func() error) *acknowledgment {
+	a := &acknowledgment{ack: ack}
+	a.remaining.Store(int32(recipients))
+	return a
+}
+
+// AckMessage acknowledges the message as successfully handled.
+func (a *acknowledgment) AckMessage() error {
+	if a.remaining.Add(-1) != 0 {
+		return nil
+	}
+
+	return a.ack()
+}
+
+// NackMessage acknowledges the message too, as MQTT doesn't redeliver it.
+func (a *acknowledgment) NackMessage() error {
+	return a.AckMessage()
+}
+
+// subscription queues the received messages until they are read, so the
+// reception of the other messages (and of the broker acknowledgments) is not
+// blocked by a slow subscriber. The queue is limited to maxPending messages,
+// except for the messages that the broker limits itself.
+type subscription struct {
+	extensions.BrokerChannelSubscription
+	messages chan extensions.BrokerMessage
+
+	mutex      sync.Mutex
+	pending    []extensions.BrokerMessage
+	maxPending int
+	signal     chan struct{}
+
+	stopping chan struct{}
+	stopped  chan struct{}
+}
+
+func newSubscription(maxPending int) *subscription {
+	messages := make(chan extensions.BrokerMessage, brokers.BrokerMessagesQueueSize)
+
+	return &subscription{
+		BrokerChannelSubscription: extensions.NewBrokerChannelSubscription(messages, make(chan any, 1)),
+		messages:                  messages,
+		maxPending:                maxPending,
+		signal:                    make(chan struct{}, 1),
+		stopping:                  make(chan struct{}),
+		stopped:                   make(chan struct{}),
+	}
+}
+
+// push adds the message to the queue, without blocking. It returns false if
+// the queue is full, unless the message is unlimited.
+func (s *subscription) push(bm extensions.BrokerMessage, unlimited bool) bool {
+	s.mutex.Lock()
+	if !unlimited && len(s.pending) >= s.maxPending {
+		s.mutex.Unlock()
+		return false
+	}
+	s.pending = append(s.pending, bm)
+	s.mutex.Unlock()
+
+	select {
+	case s.signal <- struct{}{}:
+	default:
+	}
+
+	return true
+}
+
+// run transmits the queued messages until the subscription is stopped.
+func (s *subscription) run() {
+	defer close(s.stopped)
+
+	for {
+		// Wait for new messages
+		select {
+		case <-s.signal:
+		case <-s.stopping:
+			return
+		}
+
+		// Transmit them, keeping each one queued until it is transmitted so
+		// it is returned by stop otherwise
+		for {
+			s.mutex.Lock()
+			if len(s.pending) == 0 {
+				s.mutex.Unlock()
+				break
+			}
+			bm := s.pending[0]
+			s.mutex.Unlock()
+
+			select {
+			case s.messages <- bm:
+			case <-s.stopping:
+				return
+			}
+
+			s.mutex.Lock()
+			s.pending = s.pending[1:]
+			s.mutex.Unlock()
+		}
+	}
+}
+
+// stop stops the transmission of the messages, and waits for it to be
+// effective. It returns the messages that have not been read.
+func (s *subscription) stop() []extensions.BrokerMessage {
+	close(s.stopping)
+	<-s.stopped
+
+	s.mutex.Lock()
+	unread := s.pending
+	s.pending = nil
+	s.mutex.Unlock()
+
+	for {
+		select {
+		case bm := <-s.messages:
+			unread = append(unread, bm)
+		default:
+			return unread
+		}
+	}
+}
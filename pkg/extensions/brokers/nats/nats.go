@@ -2,6 +2,7 @@ package nats
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/nats-io/nats.go"
 	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
@@ -9,30 +10,85 @@ import (
 )
 
 // Check that it still fills the interface.
-var _ extensions.BrokerController = (*Controller)(nil)
+var (
+	_ extensions.BrokerController = (*Controller)(nil)
+	_ extensions.BrokerFlusher    = (*Controller)(nil)
+	_ extensions.Requester        = (*Controller)(nil)
+)
 
 // Controller is the Controller implementation for asyncapi-codegen.
+//
+// Disconnections and reconnections are logged with the controller logger. On
+// reconnection, the subscriptions are re-established by the NATS client and
+// the messages published during the disconnection are sent.
 type Controller struct {
-	connection *nats.Conn
-	logger     extensions.Logger
-	queueGroup string
+	connection        *nats.Conn
+	ownConnection     bool
+	connectionOptions []nats.Option
+	logger            extensions.Logger
+	queueGroup        string
 }
 
 // ControllerOption is a function that can be used to configure a NATS controller
-// Examples: WithQueueGroup(), WithLogger().
+// Examples: WithQueueGroup(), WithLogger(), WithConnectionOptions().
 type ControllerOption func(controller *Controller)
 
-// NewController creates a new NATS controller.
+// NewController creates a new NATS controller connected to the NATS server.
+// It panics if the connection fails: use Connect to get the error instead.
 func NewController(url string, options ...ControllerOption) *Controller {
-	// Connect to NATS
-	nc, err := nats.Connect(url)
+	controller, err := Connect(url, options...)
 	if err != nil {
 		panic(err)
 	}
 
+	return controller
+}
+
+// Connect connects to the NATS server and creates a new NATS controller. The
+// connection is closed with the controller, and reconnects indefinitely by
+// default.
+func Connect(url string, options ...ControllerOption) (*Controller, error) {
+	controller := newController(options...)
+
+	// Set connection options, then log the connection events in addition to
+	// the handlers set by these options
+	connectionOptions := append([]nats.Option{nats.MaxReconnects(-1)}, controller.connectionOptions...)
+	connectionOptions = append(connectionOptions, func(opts *nats.Options) error {
+		controller.logConnectionEvents(opts)
+		return nil
+	})
+
+	// Connect to NATS
+	nc, err := nats.Connect(url, connectionOptions...)
+	if err != nil {
+		return nil, err
+	}
+	controller.connection = nc
+	controller.ownConnection = true
+
+	return controller, nil
+}
+
+// NewControllerWithConn creates a new NATS controller using an existing
+// connection, which is not closed with the controller. Connection options set
+// with WithConnectionOptions are ignored.
+func NewControllerWithConn(nc *nats.Conn, options ...ControllerOption) *Controller {
+	controller := newController(options...)
+	controller.connection = nc
+
+	// Log the connection events in addition to the existing handlers
+	opts := nc.Opts
+	controller.logConnectionEvents(&opts)
+	nc.SetDisconnectErrHandler(opts.DisconnectedErrCB)
+	nc.SetReconnectHandler(opts.ReconnectedCB)
+	nc.SetClosedHandler(opts.ClosedCB)
+
+	return controller
+}
+
+func newController(options ...ControllerOption) *Controller {
 	// Creates default controller
 	controller := &Controller{
-		connection: nc,
 		queueGroup: brokers.DefaultQueueGroupID,
 		logger:     extensions.DummyLogger{},
 	}
@@ -45,6 +101,56 @@ func NewController(url string, options ...ControllerOption) *Controller {
 	return controller
 }
 
+// logConnectionEvents sets the connection handlers to log disconnections,
+// reconnections and closure, before calling the existing handlers.
+func (c *Controller) logConnectionEvents(opts *nats.Options) {
+	disconnected, disconnectedErr := opts.DisconnectedCB, opts.DisconnectedErrCB
+	opts.DisconnectedErrCB = func(nc *nats.Conn, err error) {
+		if err != nil {
+			c.logger.Warning(context.Background(), fmt.Sprintf("Disconnected from NATS server: %q", err.Error()))
+		} else {
+			c.logger.Warning(context.Background(), "Disconnected from NATS server")
+		}
+
+		if disconnectedErr != nil {
+			disconnectedErr(nc, err)
+		} else if disconnected != nil {
+			disconnected(nc)
+		}
+	}
+
+	reconnected := opts.ReconnectedCB
+	opts.ReconnectedCB = func(nc *nats.Conn) {
+		c.logger.Info(context.Background(), fmt.Sprintf("Reconnected to NATS server %s", nc.ConnectedUrlRedacted()))
+
+		if reconnected != nil {
+			reconnected(nc)
+		}
+	}
+
+	closed := opts.ClosedCB
+	opts.ClosedCB = func(nc *nats.Conn) {
+		if err := nc.LastError(); err != nil {
+			c.logger.Error(context.Background(), fmt.Sprintf("NATS connection closed: %q", err.Error()))
+		} else {
+			c.logger.Info(context.Background(), "NATS connection closed")
+		}
+
+		if closed != nil {
+			closed(nc)
+		}
+	}
+}
+
+// WithConnectionOptions set the options of the connection to the NATS server
+// (i.e. credentials, TLS, NKeys or JWT, reconnection settings or connection
+// name), from github.com/nats-io/nats.go.
+func WithConnectionOptions(options ...nats.Option) ControllerOption {
+	return func(controller *Controller) {
+		controller.connectionOptions = append(controller.connectionOptions, options...)
+	}
+}
+
 // WithQueueGroup set a custom queue group for channel subscription.
 func WithQueueGroup(name string) ControllerOption {
 	return func(controller *Controller) {
@@ -61,23 +167,42 @@ func WithLogger(logger extensions.Logger) ControllerOption {
 
 // Publish a message to the broker.
 func (c *Controller) Publish(_ context.Context, channel string, bm extensions.BrokerMessage) error {
-	msg := nats.NewMsg(channel)
-
-	// Set message headers and content
-	for k, v := range bm.Headers {
-		msg.Header.Set(k, string(v))
-	}
-	msg.Data = bm.Payload
+	msg := newNATSMessage(channel, bm)
 
 	// Publish message
 	if err := c.connection.PublishMsg(msg); err != nil {
 		return err
 	}
 
-	// Flush the queue
+	// Flush the queue, unless reconnecting: the message is then sent on
+	// reconnection
+	if c.connection.IsReconnecting() {
+		return nil
+	}
 	return c.connection.Flush()
 }
 
+// Request publishes a message to the broker with a reply inbox, and waits for
+// the reply sent on this inbox. The reply channel is not used, as the reply is
+// sent directly to the inbox by the receiver.
+func (c *Controller) Request(
+	ctx context.Context,
+	channel string,
+	bm extensions.BrokerMessage,
+	_ string,
+) (extensions.BrokerMessage, error) {
+	// Send the request and wait for the reply
+	reply, err := c.connection.RequestMsgWithContext(ctx, newNATSMessage(channel, bm))
+	if err != nil {
+		if ctx.Err() != nil {
+			return extensions.BrokerMessage{}, fmt.Errorf("%w: %s", extensions.ErrContextCanceled, err.Error())
+		}
+		return extensions.BrokerMessage{}, err
+	}
+
+	return newBrokerMessage(reply), nil
+}
+
 // Subscribe to messages from the broker.
 func (c *Controller) Subscribe(ctx context.Context, channel string) (extensions.BrokerChannelSubscription, error) {
 	// Create a new subscription
@@ -86,12 +211,26 @@ func (c *Controller) Subscribe(ctx context.Context, channel string) (extensions.
 		make(chan any, 1),
 	)
 
-	// Subscribe on subject
-	natsSub, err := c.connection.QueueSubscribe(channel, c.queueGroup, messagesHandler(sub))
+	// Subscribe on subject, with the queue group unless messages are broadcast
+	queueGroup := c.queueGroup
+	if extensions.DeliveryModeFromContext(ctx) == extensions.DeliveryModeBroadcast {
+		queueGroup = ""
+	}
+	natsSub, err := c.connection.QueueSubscribe(channel, queueGroup, messagesHandler(sub))
 	if err != nil {
 		return extensions.BrokerChannelSubscription{}, err
 	}
 
+	// Flush the queue, so the subscription is effective on the server before
+	// returning (i.e. for messages published from another connection), unless
+	// reconnecting: the subscription is then re-established on reconnection
+	if !c.connection.IsReconnecting() {
+		if err := c.connection.Flush(); err != nil {
+			_ = natsSub.Unsubscribe()
+			return extensions.BrokerChannelSubscription{}, err
+		}
+	}
+
 	// Wait for cancellation and drain the NATS subscription
 	sub.WaitForCancellationAsync(func() {
 		if err := natsSub.Drain(); err != nil {
@@ -104,23 +243,54 @@ func (c *Controller) Subscribe(ctx context.Context, channel string) (extensions.
 
 func messagesHandler(sub extensions.BrokerChannelSubscription) nats.MsgHandler {
 	return func(msg *nats.Msg) {
-		// Get headers
-		headers := make(map[string][]byte, len(msg.Header))
-		for k, v := range msg.Header {
-			if len(v) > 0 {
-				headers[k] = []byte(v[0])
-			}
+		// Create and transmit message to user
+		sub.TransmitReceivedMessage(newBrokerMessage(msg))
+	}
+}
+
+func newNATSMessage(subject string, bm extensions.BrokerMessage) *nats.Msg {
+	msg := nats.NewMsg(subject)
+
+	// Set message headers and content
+	for k, v := range bm.Headers {
+		msg.Header.Set(k, string(v))
+	}
+	msg.Data = bm.Payload
+
+	return msg
+}
+
+func newBrokerMessage(msg *nats.Msg) extensions.BrokerMessage {
+	// Get headers
+	headers := make(map[string][]byte, len(msg.Header))
+	for k, v := range msg.Header {
+		if len(v) > 0 {
+			headers[k] = []byte(v[0])
 		}
+	}
 
-		// Create and transmit message to user
-		sub.TransmitReceivedMessage(extensions.BrokerMessage{
-			Headers: headers,
-			Payload: msg.Data,
-		})
+	// Set the reply inbox, if the message has been sent as a request
+	var metadata map[string]string
+	if msg.Reply != "" {
+		metadata = map[string]string{extensions.MetadataKeyIsReplyTo: msg.Reply}
 	}
+
+	return extensions.BrokerMessage{
+		Headers:  headers,
+		Payload:  msg.Data,
+		Metadata: metadata,
+	}
+}
+
+// Flush sends the buffered published messages to the NATS server.
+func (c *Controller) Flush(ctx context.Context) error {
+	return c.connection.FlushWithContext(ctx)
 }
 
-// Close closes everything related to the broker.
+// Close closes everything related to the broker, including the connection
+// unless it has been given with NewControllerWithConn.
 func (c *Controller) Close() {
-	c.connection.Close()
+	if c.ownConnection {
+		c.connection.Close()
+	}
 }
@@ -0,0 +1,146 @@
+package nats
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/suite"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+)
+
+func TestControllerSuite(t *testing.T) {
+	suite.Run(t, new(ControllerSuite))
+}
+
+type ControllerSuite struct {
+	suite.Suite
+}
+
+// runServer starts an embedded NATS server on the port (or a random one if it
+// is -1), that is stopped at the end of the test.
+func (suite *ControllerSuite) runServer(port int) *server.Server {
+	s, err := server.NewServer(&server.Options{
+		Host:   "127.0.0.1",
+		Port:   port,
+		NoLog:  true,
+		NoSigs: true,
+	})
+	suite.Require().NoError(err)
+
+	go s.Start()
+	suite.Require().True(s.ReadyForConnections(10*time.Second), "NATS server is not ready")
+	suite.T().Cleanup(s.Shutdown)
+
+	return s
+}
+
+// eventsLogger sends the logged messages on a channel, without blocking if it
+// is full.
+type eventsLogger struct {
+	extensions.DummyLogger
+	events chan string
+}
+
+func (l eventsLogger) Info(_ context.Context, msg string, _ ...extensions.LogInfo) {
+	l.send(msg)
+}
+
+func (l eventsLogger) Warning(_ context.Context, msg string, _ ...extensions.LogInfo) {
+	l.send(msg)
+}
+
+func (l eventsLogger) send(msg string) {
+	select {
+	case l.events <- msg:
+	default:
+	}
+}
+
+func (l eventsLogger) requireEvent(suite *ControllerSuite, prefix string) {
+	for {
+		select {
+		case event := <-l.events:
+			if strings.HasPrefix(event, prefix) {
+				return
+			}
+		case <-time.After(10 * time.Second):
+			suite.Require().FailNow("event not logged", prefix)
+		}
+	}
+}
+
+func (suite *ControllerSuite) TestConnectError() {
+	_, err := Connect("nats://127.0.0.1:1", WithConnectionOptions(nats.Timeout(100*time.Millisecond)))
+	suite.Require().Error(err)
+}
+
+func (suite *ControllerSuite) TestConnectionOptions() {
+	s := suite.runServer(-1)
+
+	c, err := Connect(s.ClientURL(), WithConnectionOptions(nats.Name("asyncapi-tests")))
+	suite.Require().NoError(err)
+	defer c.Close()
+
+	suite.Require().Equal("asyncapi-tests", c.connection.Opts.Name)
+}
+
+func (suite *ControllerSuite) TestExistingConnection() {
+	s := suite.runServer(-1)
+	nc, err := nats.Connect(s.ClientURL())
+	suite.Require().NoError(err)
+	defer nc.Close()
+
+	// Existing handlers should still be called
+	closed := make(chan struct{})
+	nc.SetClosedHandler(func(_ *nats.Conn) { close(closed) })
+
+	// The connection should not be closed with the controller
+	c := NewControllerWithConn(nc)
+	c.Close()
+	suite.Require().False(nc.IsClosed())
+
+	nc.Close()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		suite.Require().FailNow("closed handler has not been called")
+	}
+}
+
+func (suite *ControllerSuite) TestReconnection() {
+	s := suite.runServer(-1)
+	port := s.Addr().(*net.TCPAddr).Port
+	logger := eventsLogger{events: make(chan string, 64)}
+
+	c, err := Connect(s.ClientURL(),
+		WithLogger(logger),
+		WithConnectionOptions(nats.ReconnectWait(50*time.Millisecond)))
+	suite.Require().NoError(err)
+	defer c.Close()
+
+	sub, err := c.Subscribe(context.Background(), "reconnection")
+	suite.Require().NoError(err)
+	defer sub.Cancel(context.Background())
+
+	// Restart the server
+	s.Shutdown()
+	logger.requireEvent(suite, "Disconnected from NATS server")
+	suite.runServer(port)
+	logger.requireEvent(suite, "Reconnected to NATS server")
+
+	// The subscription should be re-established
+	suite.Require().NoError(c.Publish(context.Background(), "reconnection", extensions.BrokerMessage{
+		Payload: []byte("after reconnection"),
+	}))
+	select {
+	case msg := <-sub.MessagesChannel():
+		suite.Require().Equal("after reconnection", string(msg.Payload))
+	case <-time.After(5 * time.Second):
+		suite.Require().FailNow("message not received after reconnection")
+	}
+}
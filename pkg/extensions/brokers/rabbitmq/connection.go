@@ -0,0 +1,188 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+)
+
+// connection is a connection to RabbitMQ, that is replaced when it is lost.
+type connection struct {
+	*amqp.Connection
+
+	// lost is closed when the connection is closed, by RabbitMQ or by the
+	// controller
+	lost chan struct{}
+	// declared are the exchanges and queues declared for publications on this
+	// connection
+	declared sync.Map
+}
+
+// declareOnce executes the declaration if it has not been successfully
+// executed yet on the connection for the key.
+func (conn *connection) declareOnce(key string, declare func() error) error {
+	if _, exists := conn.declared.Load(key); exists {
+		return nil
+	}
+
+	if err := declare(); err != nil {
+		return err
+	}
+	conn.declared.Store(key, true)
+
+	return nil
+}
+
+// connect connects to RabbitMQ, and reconnects when the connection is lost.
+func (c *Controller) connect() error {
+	conn, err := amqp.DialConfig(c.url, c.config)
+	if err != nil {
+		return err
+	}
+
+	if !c.setConnection(conn) {
+		_ = conn.Close()
+		return ErrClosed
+	}
+
+	return nil
+}
+
+// setConnection sets the connection as the current one and watches it, unless
+// the controller has been closed.
+func (c *Controller) setConnection(amqpConn *amqp.Connection) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return false
+	}
+
+	conn := &connection{Connection: amqpConn, lost: make(chan struct{})}
+	c.connection = conn
+	close(c.connected)
+	go c.watch(conn)
+
+	return true
+}
+
+// watch waits for the connection to be closed, and reconnects if it has been
+// lost.
+func (c *Controller) watch(conn *connection) {
+	// Wait for the connection to be closed, the notification being closed
+	// without error when it is closed by the controller
+	amqpErr, lost := <-conn.NotifyClose(make(chan *amqp.Error, 1))
+
+	// Remove the connection, so new operations wait for the reconnection
+	c.mutex.Lock()
+	c.connection = nil
+	c.connected = make(chan struct{})
+	c.mutex.Unlock()
+	close(conn.lost)
+
+	if !lost || amqpErr == nil {
+		return
+	}
+	c.logger.Warning(context.Background(), fmt.Sprintf("Disconnected from RabbitMQ: %q", amqpErr.Error()))
+
+	// Reconnect until it succeeds or the controller is closed
+	for {
+		select {
+		case <-time.After(c.reconnectWait):
+		case <-c.done:
+			return
+		}
+
+		err := c.connect()
+		switch {
+		case err == nil:
+			c.logger.Info(context.Background(), "Reconnected to RabbitMQ")
+			return
+		case errors.Is(err, ErrClosed):
+			return
+		default:
+			c.logger.Warning(context.Background(), fmt.Sprintf("Error when reconnecting to RabbitMQ: %q", err.Error()))
+		}
+	}
+}
+
+// channel opens a new channel on the current connection, waiting for the
+// reconnection if the connection is lost.
+func (c *Controller) channel(ctx context.Context) (*amqp.Channel, *connection, error) {
+	for {
+		// Get the current connection
+		c.mutex.Lock()
+		conn, connected, closed := c.connection, c.connected, c.closed
+		c.mutex.Unlock()
+		if closed {
+			return nil, nil, ErrClosed
+		}
+
+		// Open a channel on it, unless it has been lost
+		wait := connected
+		if conn != nil {
+			ch, err := conn.Channel()
+			if err == nil || !conn.IsClosed() {
+				return ch, conn, err
+			}
+			wait = conn.lost
+		}
+
+		// Wait for the connection
+		select {
+		case <-wait:
+		case <-c.done:
+			return nil, nil, ErrClosed
+		case <-ctx.Done():
+			return nil, nil, fmt.Errorf("%w: %s", extensions.ErrContextCanceled, ctx.Err().Error())
+		}
+	}
+}
+
+// publisher returns the channel used for publications, in confirm mode, that
+// is opened on first use on the current connection.
+func (c *Controller) publisher(ctx context.Context) (*amqp.Channel, *connection, error) {
+	c.publisherMutex.Lock()
+	defer c.publisherMutex.Unlock()
+
+	// Use the existing channel if it is still open
+	if c.publisherChannel != nil && !c.publisherChannel.IsClosed() {
+		return c.publisherChannel, c.publisherConnection, nil
+	}
+
+	// Open a new one, with publisher confirms
+	ch, conn, err := c.channel(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := ch.Confirm(false); err != nil {
+		_ = ch.Close()
+		return nil, nil, err
+	}
+	c.publisherChannel, c.publisherConnection = ch, conn
+
+	return ch, conn, nil
+}
+
+// close closes the connection, without reconnection.
+func (c *Controller) close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return
+	}
+	c.closed = true
+	close(c.done)
+
+	if c.connection != nil {
+		if err := c.connection.Close(); err != nil {
+			c.logger.Error(context.Background(), err.Error())
+		}
+	}
+}
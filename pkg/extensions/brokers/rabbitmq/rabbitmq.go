@@ -0,0 +1,445 @@
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions/brokers"
+)
+
+// Check that it still fills the interface.
+var _ extensions.BrokerController = (*Controller)(nil)
+
+var (
+	// ErrNotConfirmed is raised when a published message is negatively
+	// confirmed by RabbitMQ (i.e. because of an internal error).
+	ErrNotConfirmed = fmt.Errorf("%w: message not confirmed by RabbitMQ", extensions.ErrAsyncAPI)
+
+	// ErrClosed is raised when the controller is used after being closed.
+	ErrClosed = fmt.Errorf("%w: RabbitMQ controller closed", extensions.ErrAsyncAPI)
+)
+
+const (
+	// DefaultExchange is the name of the exchange of channels without AMQP
+	// binding, or whose binding has no exchange name.
+	DefaultExchange = "asyncapi"
+
+	// DefaultPrefetch is the default maximum number of messages received by a
+	// subscription without being acknowledged.
+	DefaultPrefetch = brokers.BrokerMessagesQueueSize
+
+	// DefaultPublishTimeout is the default maximum duration to wait for the
+	// confirmation of a published message by RabbitMQ.
+	DefaultPublishTimeout = 5 * time.Second
+
+	// DefaultReconnectWait is the default duration to wait between attempts to
+	// reconnect to RabbitMQ.
+	DefaultReconnectWait = time.Second
+)
+
+const (
+	// MetadataKeyIsRoutingKey is the key of the received messages metadata that
+	// contains their routing key (i.e. the channel on wildcard subscriptions).
+	MetadataKeyIsRoutingKey = "rabbitmq-routing-key"
+
+	// MetadataKeyIsRedelivered is the key of the received messages metadata
+	// that is "true" if the message has already been delivered.
+	MetadataKeyIsRedelivered = "rabbitmq-redelivered"
+)
+
+// Controller is the RabbitMQ (AMQP 0-9-1) implementation for asyncapi-codegen.
+//
+// Channels are routing keys on the exchange of their AMQP binding, or on the
+// default topic exchange, unless their AMQP binding sets them as queues. The
+// exchanges, queues and bindings are declared on first use.
+//
+// Published messages are confirmed by RabbitMQ. Received messages are consumed
+// from a durable queue per queue group and channel, or from an exclusive queue
+// for broadcast subscriptions. They are acknowledged when handled, negatively
+// acknowledged to be requeued, or rejected to not be redelivered.
+//
+// Disconnections and reconnections are logged with the controller logger. On
+// reconnection, the subscriptions are re-established.
+type Controller struct {
+	url           string
+	config        amqp.Config
+	logger        extensions.Logger
+	queueGroup    string
+	exchange      extensions.AMQPExchange
+	bindings      map[string]extensions.AMQPChannelBinding
+	reconnectWait time.Duration
+
+	// Publication only
+	publishTimeout time.Duration
+
+	// Reception only
+	prefetch int
+
+	// Connection, replaced on reconnection
+	mutex      sync.Mutex
+	connection *connection
+	connected  chan struct{}
+	done       chan struct{}
+	closed     bool
+
+	// Publication channel, opened on first publication on the connection
+	publisherMutex      sync.Mutex
+	publisherChannel    *amqp.Channel
+	publisherConnection *connection
+}
+
+// ControllerOption is a function that can be used to configure a RabbitMQ
+// controller.
+// Examples: WithQueueGroup(), WithChannelBinding(), WithPrefetch(), WithLogger().
+type ControllerOption func(controller *Controller)
+
+// NewController creates a new RabbitMQ controller connected to the server.
+func NewController(url string, options ...ControllerOption) (*Controller, error) {
+	// Creates default controller
+	controller := &Controller{
+		url:        url,
+		logger:     extensions.DummyLogger{},
+		queueGroup: brokers.DefaultQueueGroupID,
+		exchange: extensions.AMQPExchange{
+			Name:    DefaultExchange,
+			Type:    amqp.ExchangeTopic,
+			Durable: true,
+		},
+		bindings:       make(map[string]extensions.AMQPChannelBinding),
+		reconnectWait:  DefaultReconnectWait,
+		publishTimeout: DefaultPublishTimeout,
+		prefetch:       DefaultPrefetch,
+		connected:      make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+
+	// Execute options
+	for _, option := range options {
+		option(controller)
+	}
+
+	// Connect to RabbitMQ
+	if err := controller.connect(); err != nil {
+		return nil, err
+	}
+
+	return controller, nil
+}
+
+// WithQueueGroup set a custom queue group for channel subscription: members of
+// the same queue group share the queue of a channel. If the name is empty,
+// every subscription will receive every message (fan-out).
+func WithQueueGroup(name string) ControllerOption {
+	return func(controller *Controller) {
+		controller.queueGroup = name
+	}
+}
+
+// WithLogger set a custom logger that will log operations on broker controller.
+func WithLogger(logger extensions.Logger) ControllerOption {
+	return func(controller *Controller) {
+		controller.logger = logger
+	}
+}
+
+// WithConnectionConfig set the configuration of the connection to RabbitMQ
+// (i.e. credentials, TLS, virtual host or heartbeat), from
+// github.com/rabbitmq/amqp091-go.
+func WithConnectionConfig(config amqp.Config) ControllerOption {
+	return func(controller *Controller) {
+		controller.config = config
+	}
+}
+
+// WithExchange set the exchange of channels without AMQP binding, or whose
+// binding has no exchange name.
+func WithExchange(exchange extensions.AMQPExchange) ControllerOption {
+	return func(controller *Controller) {
+		controller.exchange = exchange
+	}
+}
+
+// WithChannelBinding set the AMQP binding of a channel, overriding the one from
+// the specification.
+func WithChannelBinding(channel string, binding extensions.AMQPChannelBinding) ControllerOption {
+	return func(controller *Controller) {
+		controller.bindings[channel] = binding
+	}
+}
+
+// WithPrefetch set the maximum number of messages received by a subscription
+// without being acknowledged.
+func WithPrefetch(count int) ControllerOption {
+	return func(controller *Controller) {
+		controller.prefetch = count
+	}
+}
+
+// WithPublishTimeout set the maximum duration to wait for the confirmation of
+// a published message by RabbitMQ (including the reconnection, if the
+// connection is lost), if the context has no earlier deadline.
+func WithPublishTimeout(timeout time.Duration) ControllerOption {
+	return func(controller *Controller) {
+		controller.publishTimeout = timeout
+	}
+}
+
+// WithReconnectWait set the duration to wait between attempts to reconnect to
+// RabbitMQ when the connection is lost.
+func WithReconnectWait(wait time.Duration) ControllerOption {
+	return func(controller *Controller) {
+		controller.reconnectWait = wait
+	}
+}
+
+// Publish a message to the broker, and wait for its confirmation.
+func (c *Controller) Publish(ctx context.Context, channel string, bm extensions.BrokerMessage) error {
+	ctx, cancel := context.WithTimeout(ctx, c.publishTimeout)
+	defer cancel()
+
+	// Get the publication channel, waiting for the connection if needed
+	ch, conn, err := c.publisher(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Declare the exchange or the queue of the channel
+	exchange, key, err := c.declareDestination(ch, conn, channel, c.binding(ctx, channel))
+	if err != nil {
+		return err
+	}
+
+	// Publish the message and wait for its confirmation
+	confirmation, err := ch.PublishWithDeferredConfirmWithContext(ctx, exchange, key, false, false, newPublishing(ctx, bm))
+	if err != nil {
+		return err
+	}
+	confirmed, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %s", extensions.ErrContextCanceled, err.Error())
+	} else if !confirmed {
+		return ErrNotConfirmed
+	}
+
+	return nil
+}
+
+// Subscribe to messages from the broker.
+func (c *Controller) Subscribe(ctx context.Context, channel string) (extensions.BrokerChannelSubscription, error) {
+	// Create a new subscription
+	sub := extensions.NewBrokerChannelSubscription(
+		make(chan extensions.BrokerMessage, brokers.BrokerMessagesQueueSize),
+		make(chan any, 1),
+	)
+
+	// Consume the queue of the channel, the subscription being re-established
+	// on reconnection
+	s := newSubscription(ctx, c, channel, sub)
+	deliveries, err := s.consume()
+	if err != nil {
+		s.cancel()
+		return extensions.BrokerChannelSubscription{}, err
+	}
+	go s.run(deliveries)
+
+	// Wait for cancellation and stop the consumption
+	sub.WaitForCancellationAsync(s.stop)
+
+	return sub, nil
+}
+
+// binding returns the AMQP binding of the channel, set with WithChannelBinding
+// or from the specification, with the default exchange if it has none. A zero
+// binding in the context means that the channel has no binding.
+func (c *Controller) binding(ctx context.Context, channel string) extensions.AMQPChannelBinding {
+	// Get the binding
+	binding, exists := c.bindings[channel]
+	if !exists {
+		extensions.IfContextSetWith(ctx, extensions.ContextKeyIsAMQPChannelBinding, func(value extensions.AMQPChannelBinding) {
+			if value != (extensions.AMQPChannelBinding{}) {
+				binding = value
+			}
+		})
+	}
+
+	// Channels on the AMQP default exchange are queues with the same name
+	if binding.Exchange.Type == "default" {
+		return extensions.AMQPChannelBinding{
+			Is:    extensions.AMQPChannelIsQueue,
+			Queue: extensions.AMQPQueue{Name: channel, Durable: binding.Exchange.Durable},
+		}
+	}
+
+	// Set default values
+	if binding.Is == extensions.AMQPChannelIsQueue {
+		if binding.Queue.Name == "" {
+			binding.Queue.Name = channel
+		}
+		return binding
+	}
+	binding.Is = extensions.AMQPChannelIsRoutingKey
+	if binding.Exchange.Name == "" {
+		binding.Exchange = c.exchange
+	} else if binding.Exchange.Type == "" {
+		binding.Exchange.Type = amqp.ExchangeTopic
+	}
+
+	return binding
+}
+
+// declareDestination declares the exchange or the queue of the channel on the
+// connection, if it has not been declared yet, and returns the exchange and the
+// routing key on which its messages should be published.
+func (c *Controller) declareDestination(
+	ch *amqp.Channel,
+	conn *connection,
+	channel string,
+	binding extensions.AMQPChannelBinding,
+) (exchange, key string, err error) {
+	if binding.Is == extensions.AMQPChannelIsQueue {
+		q := binding.Queue
+		return "", q.Name, conn.declareOnce("queue:"+q.Name, func() error {
+			_, err := ch.QueueDeclare(q.Name, q.Durable, q.AutoDelete, q.Exclusive, false, nil)
+			return err
+		})
+	}
+
+	e := binding.Exchange
+	return e.Name, channel, conn.declareOnce("exchange:"+e.Name, func() error {
+		return ch.ExchangeDeclare(e.Name, e.Type, e.Durable, e.AutoDelete, false, false, nil)
+	})
+}
+
+// declareSource declares the queue consumed by a subscription to the channel,
+// with its exchange and binding if the channel is a routing key, and returns
+// its name. Broadcast subscriptions have their own exclusive queue, deleted
+// with them.
+func (c *Controller) declareSource(
+	ch *amqp.Channel,
+	channel string,
+	binding extensions.AMQPChannelBinding,
+	broadcast bool,
+) (string, error) {
+	// Declare the queue of the channel
+	if binding.Is == extensions.AMQPChannelIsQueue {
+		q := binding.Queue
+		_, err := ch.QueueDeclare(q.Name, q.Durable, q.AutoDelete, q.Exclusive, false, nil)
+		return q.Name, err
+	}
+
+	// Declare the exchange
+	e := binding.Exchange
+	if err := ch.ExchangeDeclare(e.Name, e.Type, e.Durable, e.AutoDelete, false, false, nil); err != nil {
+		return "", err
+	}
+
+	// Declare the queue of the queue group, or of the subscription
+	var q amqp.Queue
+	var err error
+	if broadcast || c.queueGroup == "" {
+		q, err = ch.QueueDeclare("", false, true, true, false, nil)
+	} else {
+		q, err = ch.QueueDeclare(c.queueGroup+"."+channel, true, false, false, false, nil)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// Bind it to the exchange
+	return q.Name, ch.QueueBind(q.Name, bindingKey(channel), e.Name, false, nil)
+}
+
+// bindingKey returns the binding key of a channel, with the NATS-like '>'
+// wildcard replaced by the AMQP '#' one.
+func bindingKey(channel string) string {
+	tokens := strings.Split(channel, ".")
+	if tokens[len(tokens)-1] == ">" {
+		tokens[len(tokens)-1] = "#"
+	}
+
+	return strings.Join(tokens, ".")
+}
+
+func newPublishing(ctx context.Context, bm extensions.BrokerMessage) amqp.Publishing {
+	msg := amqp.Publishing{
+		Headers:      make(amqp.Table, len(bm.Headers)),
+		DeliveryMode: amqp.Persistent,
+		Timestamp:    time.Now(),
+		Body:         bm.Payload,
+	}
+
+	// Set message headers
+	for k, v := range bm.Headers {
+		msg.Headers[k] = string(v)
+	}
+
+	// Set the correlation ID, if there is one
+	if id, ok := ctx.Value(extensions.ContextKeyIsCorrelationID).(string); ok {
+		msg.CorrelationId = id
+	}
+
+	return msg
+}
+
+func newBrokerMessage(d amqp.Delivery) extensions.BrokerMessage {
+	// Get headers
+	headers := make(map[string][]byte, len(d.Headers))
+	for k, v := range d.Headers {
+		switch value := v.(type) {
+		case string:
+			headers[k] = []byte(value)
+		case []byte:
+			headers[k] = value
+		default:
+			headers[k] = []byte(fmt.Sprint(value))
+		}
+	}
+
+	return extensions.BrokerMessage{
+		Headers: headers,
+		Payload: d.Body,
+		Metadata: map[string]string{
+			MetadataKeyIsRoutingKey:  d.RoutingKey,
+			MetadataKeyIsRedelivered: strconv.FormatBool(d.Redelivered),
+		},
+		Acknowledgment: acknowledgment{delivery: d},
+	}
+}
+
+// Close closes everything related to the broker.
+func (c *Controller) Close() {
+	c.close()
+}
+
+// acknowledgment acknowledges the message on RabbitMQ.
+type acknowledgment struct {
+	delivery amqp.Delivery
+}
+
+// AckMessage acknowledges the message as successfully handled.
+func (a acknowledgment) AckMessage() error {
+	return a.delivery.Ack(false)
+}
+
+// NackMessage negatively acknowledges the message, that is requeued.
+func (a acknowledgment) NackMessage() error {
+	return a.delivery.Nack(false, true)
+}
+
+// TermMessage rejects the message, that is not redelivered (it is dead
+// lettered if the queue has a dead letter exchange).
+func (a acknowledgment) TermMessage() error {
+	return a.delivery.Reject(false)
+}
+
+// Check that it still fills the interfaces.
+var (
+	_ extensions.BrokerAcknowledgment = acknowledgment{}
+	_ extensions.BrokerTerminator     = acknowledgment{}
+)
@@ -0,0 +1,165 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+)
+
+// subscription consumes the queue of a channel and transmits its messages to a
+// broker channel subscription, consuming it again when the connection is lost.
+type subscription struct {
+	controller *Controller
+	channel    string
+	binding    extensions.AMQPChannelBinding
+	broadcast  bool
+	tag        string
+	sub        extensions.BrokerChannelSubscription
+
+	// ctx is canceled when the subscription is stopped
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mutex   sync.Mutex
+	ch      *amqp.Channel
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func newSubscription(
+	ctx context.Context,
+	c *Controller,
+	channel string,
+	sub extensions.BrokerChannelSubscription,
+) *subscription {
+	// The subscription is kept after the subscription request
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+
+	return &subscription{
+		controller: c,
+		channel:    channel,
+		binding:    c.binding(ctx, channel),
+		broadcast:  extensions.DeliveryModeFromContext(ctx) == extensions.DeliveryModeBroadcast,
+		tag:        uuid.New().String(),
+		sub:        sub,
+		ctx:        ctx,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+}
+
+// consume opens a channel, declares the queue of the subscription and starts
+// consuming it.
+func (s *subscription) consume() (<-chan amqp.Delivery, error) {
+	// Open a channel, waiting for the connection if needed
+	ch, _, err := s.controller.channel(s.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Declare the queue and consume it
+	deliveries, err := func() (<-chan amqp.Delivery, error) {
+		if err := ch.Qos(s.controller.prefetch, 0, false); err != nil {
+			return nil, err
+		}
+
+		queue, err := s.controller.declareSource(ch, s.channel, s.binding, s.broadcast)
+		if err != nil {
+			return nil, err
+		}
+
+		return ch.Consume(queue, s.tag, false, false, false, false, nil)
+	}()
+	if err != nil {
+		_ = ch.Close()
+		return nil, err
+	}
+
+	// Keep the channel to cancel the consumption, unless it has been stopped
+	// in the meantime
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	select {
+	case <-s.done:
+		_ = ch.Close()
+		return nil, extensions.ErrSubscriptionCanceled
+	default:
+		s.ch = ch
+	}
+
+	return deliveries, nil
+}
+
+// run transmits the deliveries until the subscription is stopped, consuming
+// the queue again if the consumption stops (i.e. when the connection is lost).
+func (s *subscription) run(deliveries <-chan amqp.Delivery) {
+	defer close(s.stopped)
+
+	for {
+		// Transmit the deliveries, or get them requeued once stopped
+		for d := range deliveries {
+			select {
+			case <-s.done:
+				_ = d.Nack(false, true)
+			default:
+				s.sub.TransmitReceivedMessage(newBrokerMessage(d))
+			}
+		}
+
+		// Consume again, until it succeeds or the subscription is stopped
+		for deliveries = nil; deliveries == nil; {
+			select {
+			case <-s.done:
+				return
+			default:
+			}
+
+			var err error
+			deliveries, err = s.consume()
+			switch {
+			case err == nil:
+				s.controller.logger.Info(s.ctx, "Subscription to '"+s.channel+"' re-established")
+			case errors.Is(err, ErrClosed), errors.Is(err, extensions.ErrSubscriptionCanceled), s.ctx.Err() != nil:
+				return
+			default:
+				s.controller.logger.Warning(s.ctx, fmt.Sprintf("Error when subscribing to '%s': %q", s.channel, err.Error()))
+				select {
+				case <-time.After(s.controller.reconnectWait):
+				case <-s.done:
+					return
+				}
+			}
+		}
+	}
+}
+
+// stop cancels the consumption, and waits for the pending deliveries to be
+// transmitted or requeued before closing the channel.
+func (s *subscription) stop() {
+	s.mutex.Lock()
+	close(s.done)
+	ch := s.ch
+	s.mutex.Unlock()
+	s.cancel()
+
+	// Cancel the consumption, which closes the deliveries and deletes the queue
+	// of broadcast subscriptions
+	if err := ch.Cancel(s.tag, false); err != nil && !ch.IsClosed() {
+		s.controller.logger.Error(s.ctx, err.Error())
+		_ = ch.Close()
+	}
+	<-s.stopped
+
+	// Close the channel
+	if err := ch.Close(); err != nil && !errors.Is(err, amqp.ErrClosed) {
+		s.controller.logger.Error(s.ctx, err.Error())
+	}
+	s.controller.logger.Info(s.ctx, "Subscription to '"+s.channel+"' canceled")
+}
@@ -24,6 +24,22 @@ const (
 	ContextKeyIsBrokerMessage ContextKey = Prefix + "broker-message"
 	// ContextKeyIsCorrelationID is the correlation ID of the message.
 	ContextKeyIsCorrelationID ContextKey = Prefix + "correlationID"
+	// ContextKeyIsReplyTo is the channel on which the reply to the received
+	// message should be sent, when the request has been sent with the broker
+	// native request/reply (see Requester).
+	ContextKeyIsReplyTo ContextKey = Prefix + "reply-to"
+	// ContextKeyIsDeliveryMode is the delivery mode of the messages of the
+	// channel subscribed with this context (see DeliveryMode).
+	ContextKeyIsDeliveryMode ContextKey = Prefix + "delivery-mode"
+	// ContextKeyIsAMQPChannelBinding is the AMQP binding of the channel from
+	// the specification, if there is one (see AMQPChannelBinding).
+	ContextKeyIsAMQPChannelBinding ContextKey = Prefix + "amqp-channel-binding"
+	// ContextKeyIsMQTTOperationBinding is the MQTT binding of the operation
+	// from the specification, if there is one (see MQTTOperationBinding).
+	ContextKeyIsMQTTOperationBinding ContextKey = Prefix + "mqtt-operation-binding"
+	// ContextKeyIsMQTTMessageBinding is the MQTT binding of the message from
+	// the specification, if there is one (see MQTTMessageBinding).
+	ContextKeyIsMQTTMessageBinding ContextKey = Prefix + "mqtt-message-binding"
 )
 
 // String returns the string representation of the key.
@@ -25,6 +25,19 @@ var (
 	// or more without unsubscribing.
 	ErrAlreadySubscribedChannel = fmt.Errorf("%w: the channel has already been subscribed", ErrAsyncAPI)
 
+	// ErrMessagesDropped is raised when received messages have not been handled
+	// before the end of a controller shutdown.
+	ErrMessagesDropped = fmt.Errorf("%w: received messages have not been handled", ErrAsyncAPI)
+
+	// ErrDuplicateCorrelationID is raised when a request is sent with the same
+	// correlation ID as another pending request.
+	ErrDuplicateCorrelationID = fmt.Errorf("%w: a pending request already has this correlation ID", ErrAsyncAPI)
+
+	// ErrUnrecoverable can be wrapped by errors returned by subscription callbacks
+	// when a message can't be handled, even if it is redelivered (i.e. invalid
+	// content): the message is then terminated instead of being redelivered.
+	ErrUnrecoverable = fmt.Errorf("%w: unrecoverable error", ErrAsyncAPI)
+
 	// ErrSubscriptionCanceled is raised when expecting something and the subscription has been canceled before it happens.
 	ErrSubscriptionCanceled = fmt.Errorf("%w: the subscription has been canceled", ErrAsyncAPI)
 )
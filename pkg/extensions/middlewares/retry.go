@@ -0,0 +1,216 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+)
+
+const (
+	// DeadLetterErrorHeaderKey is the header key of the last error that
+	// happened when handling a message sent to the dead-letter channel.
+	DeadLetterErrorHeaderKey = "dead-letter-error"
+	// DeadLetterAttemptsHeaderKey is the header key of the number of attempts
+	// to handle a message sent to the dead-letter channel.
+	DeadLetterAttemptsHeaderKey = "dead-letter-attempts"
+	// DeadLetterChannelHeaderKey is the header key of the channel on which a
+	// message sent to the dead-letter channel has been received.
+	DeadLetterChannelHeaderKey = "dead-letter-channel"
+)
+
+// RetryOption is a function that can be used to configure the Retry middleware.
+// Examples: WithMaxAttempts(), WithBackoff(), WithJitter(), WithDeadLetterChannel().
+type RetryOption func(r *retry)
+
+type retry struct {
+	maxAttempts  int
+	initialDelay time.Duration
+	maxDelay     time.Duration
+	multiplier   float64
+	jitter       float64
+	logger       extensions.Logger
+
+	// Dead-letter channel
+	broker  extensions.BrokerController
+	channel string
+}
+
+// WithMaxAttempts set the maximum number of attempts to handle a message,
+// including the first one.
+func WithMaxAttempts(attempts int) RetryOption {
+	return func(r *retry) {
+		r.maxAttempts = attempts
+	}
+}
+
+// WithBackoff set the delay before the first retry, that will be multiplied by
+// the multiplier for each following retry without exceeding the maximum delay.
+func WithBackoff(initial, max time.Duration, multiplier float64) RetryOption {
+	return func(r *retry) {
+		r.initialDelay = initial
+		r.maxDelay = max
+		r.multiplier = multiplier
+	}
+}
+
+// WithJitter set the ratio of the delay that will be randomly added or removed
+// from each delay (i.e. 0.1 for +/-10%), to avoid simultaneous retries.
+func WithJitter(ratio float64) RetryOption {
+	return func(r *retry) {
+		r.jitter = ratio
+	}
+}
+
+// WithDeadLetterChannel set the channel on which the messages will be published,
+// with the broker, when every attempt failed.
+func WithDeadLetterChannel(broker extensions.BrokerController, channel string) RetryOption {
+	return func(r *retry) {
+		r.broker = broker
+		r.channel = channel
+	}
+}
+
+// WithRetryLogger set a custom logger that will log failed attempts.
+func WithRetryLogger(logger extensions.Logger) RetryOption {
+	return func(r *retry) {
+		r.logger = logger
+	}
+}
+
+// Retry is a middleware that retries the handling of received messages, by the
+// middlewares coming after it and user code from subscription, when it returns
+// an error. The delay between each attempt grows exponentially.
+//
+// When every attempt failed, the original message is published on the
+// dead-letter channel (if set) with the error, the number of attempts and the
+// original channel as headers. It is then considered as handled.
+func Retry(options ...RetryOption) extensions.Middleware {
+	// Create default retry
+	r := retry{
+		maxAttempts:  3,
+		initialDelay: 100 * time.Millisecond,
+		maxDelay:     10 * time.Second,
+		multiplier:   2,
+		jitter:       0.1,
+		logger:       extensions.DummyLogger{},
+	}
+
+	// Execute options
+	for _, option := range options {
+		option(&r)
+	}
+
+	return func(ctx context.Context, msg *extensions.BrokerMessage, next extensions.NextMiddleware) error {
+		// Only retry received messages
+		if ctx.Value(extensions.ContextKeyIsDirection) != "reception" {
+			return next(ctx)
+		}
+
+		// Keep the original message, as it can be modified by next middlewares
+		original := copyBrokerMessage(*msg)
+
+		// Try to handle the message
+		var err error
+		attempts := 0
+		for delay := r.initialDelay; attempts < r.maxAttempts; delay = r.nextDelay(delay) {
+			// Wait before retrying with the original message
+			if attempts > 0 {
+				select {
+				case <-time.After(r.withJitter(delay)):
+				case <-ctx.Done():
+					return err
+				}
+				*msg = copyBrokerMessage(original)
+			}
+
+			// Execute next middlewares and user code
+			attempts++
+			if err = next(ctx); err == nil {
+				return nil
+			}
+			r.logger.Warning(ctx, fmt.Sprintf("Attempt #%d failed: %s", attempts, err.Error()))
+
+			// Don't retry messages that can't be handled
+			if errors.Is(err, extensions.ErrUnrecoverable) {
+				break
+			}
+		}
+
+		// Return the error if there is no dead-letter channel
+		if r.broker == nil {
+			return err
+		}
+
+		// Publish the original message on the dead-letter channel
+		return r.publishDeadLetter(ctx, original, err, attempts)
+	}
+}
+
+func (r retry) nextDelay(delay time.Duration) time.Duration {
+	next := time.Duration(float64(delay) * r.multiplier)
+	if next > r.maxDelay {
+		return r.maxDelay
+	}
+	return next
+}
+
+func (r retry) withJitter(delay time.Duration) time.Duration {
+	if r.jitter <= 0 {
+		return delay
+	}
+
+	// Add or remove a random part of the delay
+	return delay + time.Duration((rand.Float64()*2-1)*r.jitter*float64(delay))
+}
+
+func (r retry) publishDeadLetter(ctx context.Context, msg extensions.BrokerMessage, err error, attempts int) error {
+	// Get the original channel
+	var channel string
+	extensions.IfContextSetWith(ctx, extensions.ContextKeyIsChannel, func(value string) {
+		channel = value
+	})
+
+	// Add information on the failure
+	msg.Headers[DeadLetterErrorHeaderKey] = []byte(err.Error())
+	msg.Headers[DeadLetterAttemptsHeaderKey] = []byte(strconv.Itoa(attempts))
+	msg.Headers[DeadLetterChannelHeaderKey] = []byte(channel)
+
+	// Publish the message, without the bindings of the original channel
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsAMQPChannelBinding, extensions.AMQPChannelBinding{})
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTOperationBinding, nil)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTMessageBinding, nil)
+	if pubErr := r.broker.Publish(ctx, r.channel, msg); pubErr != nil {
+		r.logger.Error(ctx, fmt.Sprintf("Publication on dead-letter channel %q failed: %s", r.channel, pubErr.Error()))
+		return err
+	}
+
+	r.logger.Warning(ctx, fmt.Sprintf("Message published on dead-letter channel %q after %d attempts", r.channel, attempts))
+	return nil
+}
+
+func copyBrokerMessage(msg extensions.BrokerMessage) extensions.BrokerMessage {
+	cp := extensions.BrokerMessage{
+		Headers:        make(map[string][]byte, len(msg.Headers)),
+		Payload:        append([]byte(nil), msg.Payload...),
+		Acknowledgment: msg.Acknowledgment,
+	}
+
+	for k, v := range msg.Headers {
+		cp.Headers[k] = append([]byte(nil), v...)
+	}
+
+	// Keep the metadata, such as the message key used on dead-letter channel
+	if msg.Metadata != nil {
+		cp.Metadata = make(map[string]string, len(msg.Metadata))
+		for k, v := range msg.Metadata {
+			cp.Metadata[k] = v
+		}
+	}
+
+	return cp
+}
@@ -0,0 +1,205 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions/brokers/memory"
+)
+
+func TestRetrySuite(t *testing.T) {
+	suite.Run(t, new(RetrySuite))
+}
+
+type RetrySuite struct {
+	suite.Suite
+}
+
+func receptionContext() context.Context {
+	ctx := context.WithValue(context.Background(), extensions.ContextKeyIsDirection, "reception")
+	return context.WithValue(ctx, extensions.ContextKeyIsChannel, "orders")
+}
+
+// failingNext returns a next function that fails the given number of times,
+// and modifies the message on each call to check that it is restored.
+func failingNext(msg *extensions.BrokerMessage, failures int, calls *int) extensions.NextMiddleware {
+	return func(_ context.Context) error {
+		*calls++
+		if _, modified := msg.Headers["modified"]; modified {
+			return errors.New("message has not been restored")
+		}
+		msg.Headers["modified"] = []byte("true")
+		if *calls <= failures {
+			return errors.New("handling failed")
+		}
+		return nil
+	}
+}
+
+func (suite *RetrySuite) TestSuccessAfterRetries() {
+	m := Retry(WithMaxAttempts(3), WithBackoff(time.Millisecond, 5*time.Millisecond, 2))
+	msg := &extensions.BrokerMessage{Headers: map[string][]byte{}, Payload: []byte("order")}
+
+	calls := 0
+	err := m(receptionContext(), msg, failingNext(msg, 2, &calls))
+	suite.Require().NoError(err)
+	suite.Require().Equal(3, calls)
+}
+
+func (suite *RetrySuite) TestErrorWhenAttemptsAreExhausted() {
+	m := Retry(WithMaxAttempts(2), WithBackoff(time.Millisecond, time.Millisecond, 1))
+	msg := &extensions.BrokerMessage{Headers: map[string][]byte{}, Payload: []byte("order")}
+
+	calls := 0
+	err := m(receptionContext(), msg, failingNext(msg, 5, &calls))
+	suite.Require().ErrorContains(err, "handling failed")
+	suite.Require().Equal(2, calls)
+}
+
+func (suite *RetrySuite) TestNoRetryOnUnrecoverableError() {
+	m := Retry(WithMaxAttempts(3), WithBackoff(time.Millisecond, time.Millisecond, 1))
+	msg := &extensions.BrokerMessage{Headers: map[string][]byte{}}
+
+	calls := 0
+	err := m(receptionContext(), msg, func(_ context.Context) error {
+		calls++
+		return fmt.Errorf("%w: invalid order", extensions.ErrUnrecoverable)
+	})
+	suite.Require().ErrorIs(err, extensions.ErrUnrecoverable)
+	suite.Require().Equal(1, calls)
+}
+
+func (suite *RetrySuite) TestNoRetryOnPublication() {
+	m := Retry(WithMaxAttempts(3))
+	msg := &extensions.BrokerMessage{Headers: map[string][]byte{}}
+	ctx := context.WithValue(context.Background(), extensions.ContextKeyIsDirection, "publication")
+
+	calls := 0
+	err := m(ctx, msg, failingNext(msg, 5, &calls))
+	suite.Require().Error(err)
+	suite.Require().Equal(1, calls)
+}
+
+func (suite *RetrySuite) TestDeadLetterChannel() {
+	broker := memory.NewController()
+	defer broker.Close()
+
+	m := Retry(
+		WithMaxAttempts(3),
+		WithBackoff(time.Millisecond, time.Millisecond, 1),
+		WithJitter(0.5),
+		WithDeadLetterChannel(broker, "orders.dead-letter"))
+	msg := &extensions.BrokerMessage{
+		Headers: map[string][]byte{"key": []byte("value")},
+		Payload: []byte("order"),
+	}
+
+	// Check that the message is considered as handled
+	calls := 0
+	err := m(receptionContext(), msg, failingNext(msg, 5, &calls))
+	suite.Require().NoError(err)
+	suite.Require().Equal(3, calls)
+
+	// Check the original message has been published with failure information
+	published := broker.PublishedMessages()
+	suite.Require().Len(published, 1)
+	suite.Require().Equal("orders.dead-letter", published[0].Channel)
+	suite.Require().Equal("order", string(published[0].Payload))
+	suite.Require().Equal(map[string][]byte{
+		"key":                       []byte("value"),
+		DeadLetterErrorHeaderKey:    []byte("handling failed"),
+		DeadLetterAttemptsHeaderKey: []byte("3"),
+		DeadLetterChannelHeaderKey:  []byte("orders"),
+	}, published[0].Headers)
+}
+
+func (suite *RetrySuite) TestDeadLetterChannelWithKey() {
+	broker := memory.NewController()
+	defer broker.Close()
+
+	m := Retry(
+		WithMaxAttempts(2),
+		WithBackoff(time.Millisecond, time.Millisecond, 1),
+		WithDeadLetterChannel(broker, "orders.dead-letter"))
+	msg := &extensions.BrokerMessage{
+		Headers: map[string][]byte{},
+		Payload: []byte("order"),
+		Metadata: map[string]string{
+			extensions.MetadataKeyIsMessageKey: "customer-1",
+			"kafka-partition":                  "3",
+		},
+	}
+
+	// Handle the message, with a handler modifying its metadata
+	calls := 0
+	err := m(receptionContext(), msg, func(_ context.Context) error {
+		calls++
+		if msg.Metadata[extensions.MetadataKeyIsMessageKey] != "customer-1" {
+			return errors.New("metadata has not been restored")
+		}
+		msg.Metadata[extensions.MetadataKeyIsMessageKey] = "modified"
+		return errors.New("handling failed")
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal(2, calls)
+
+	// Check the message has been published with its original key
+	published := broker.PublishedMessages()
+	suite.Require().Len(published, 1)
+	suite.Require().Equal("customer-1", published[0].Metadata[extensions.MetadataKeyIsMessageKey])
+	suite.Require().Equal("handling failed", string(published[0].Headers[DeadLetterErrorHeaderKey]))
+}
+
+// contextBroker records the context of the last publication.
+type contextBroker struct {
+	*memory.Controller
+	ctx context.Context
+}
+
+func (b *contextBroker) Publish(ctx context.Context, channel string, bm extensions.BrokerMessage) error {
+	b.ctx = ctx
+	return b.Controller.Publish(ctx, channel, bm)
+}
+
+func (suite *RetrySuite) TestDeadLetterChannelWithoutOriginalBindings() {
+	broker := &contextBroker{Controller: memory.NewController()}
+	defer broker.Close()
+
+	m := Retry(
+		WithMaxAttempts(1),
+		WithDeadLetterChannel(broker, "orders.dead-letter"))
+	msg := &extensions.BrokerMessage{Headers: map[string][]byte{}, Payload: []byte("order")}
+
+	// Handle the message received on a channel with an AMQP binding
+	ctx := context.WithValue(receptionContext(), extensions.ContextKeyIsAMQPChannelBinding, extensions.AMQPChannelBinding{
+		Is:    extensions.AMQPChannelIsQueue,
+		Queue: extensions.AMQPQueue{Name: "orders"},
+	})
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTOperationBinding, extensions.MQTTOperationBinding{QoS: 2})
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTMessageBinding, extensions.MQTTMessageBinding{ContentType: "text/plain"})
+	calls := 0
+	suite.Require().NoError(m(ctx, msg, failingNext(msg, 5, &calls)))
+
+	// Check that the bindings are not used for the dead-letter channel
+	suite.Require().Equal(extensions.AMQPChannelBinding{}, broker.ctx.Value(extensions.ContextKeyIsAMQPChannelBinding))
+	suite.Require().Nil(broker.ctx.Value(extensions.ContextKeyIsMQTTOperationBinding))
+	suite.Require().Nil(broker.ctx.Value(extensions.ContextKeyIsMQTTMessageBinding))
+}
+
+func (suite *RetrySuite) TestContextCanceled() {
+	m := Retry(WithMaxAttempts(3), WithBackoff(time.Hour, time.Hour, 1))
+	msg := &extensions.BrokerMessage{Headers: map[string][]byte{}}
+
+	ctx, cancel := context.WithCancel(receptionContext())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	calls := 0
+	err := m(ctx, msg, failingNext(msg, 5, &calls))
+	suite.Require().Error(err)
+	suite.Require().Equal(1, calls)
+}
@@ -0,0 +1,210 @@
+package extensions
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Requester is the interface that can be implemented by broker controllers
+// supporting a native request/reply mechanism (i.e. NATS reply inboxes). When
+// it is implemented, generated request methods use it instead of a reply
+// subscription with correlation ID matching.
+type Requester interface {
+	// Request publishes the message on the channel and waits for its reply.
+	// The reply channel is the channel on which replies are sent without
+	// native request/reply, it can be ignored by the broker.
+	//
+	// Received messages should have the channel on which their reply should be
+	// sent in their metadata, with MetadataKeyIsReplyTo key.
+	Request(ctx context.Context, channel string, msg BrokerMessage, replyChannel string) (BrokerMessage, error)
+}
+
+// ReplyChannel returns the channel on which a reply with the correlation ID
+// should be published: the reply channel set in context by a request received
+// with the broker native request/reply, if the reply corresponds to this
+// request, or the given channel otherwise.
+func ReplyChannel(ctx context.Context, channel, correlationID string) string {
+	// Get the reply channel and the correlation ID of the received request
+	var replyTo, requestID string
+	IfContextSetWith(ctx, ContextKeyIsReplyTo, func(value string) {
+		replyTo = value
+	})
+	IfContextSetWith(ctx, ContextKeyIsCorrelationID, func(value string) {
+		requestID = value
+	})
+
+	// Use the reply channel only for the reply to the request
+	if replyTo == "" || requestID != correlationID {
+		return channel
+	}
+
+	return replyTo
+}
+
+// CorrelationIDGetter is the signature of the function that returns the
+// correlation ID of a received message.
+type CorrelationIDGetter func(msg BrokerMessage) (string, error)
+
+// ReplyDispatcher dispatches the messages received on a long-lived reply
+// subscription to the pending requests, based on their correlation ID.
+// This avoids to subscribe to the reply channel for each request.
+type ReplyDispatcher struct {
+	ctx           context.Context
+	subscription  BrokerChannelSubscription
+	correlationID CorrelationIDGetter
+	logger        Logger
+
+	mutex   sync.Mutex
+	pending map[string]chan BrokerMessage
+	closed  chan struct{}
+}
+
+// NewReplyDispatcher creates a new reply dispatcher that will dispatch the
+// messages received on the subscription until it is canceled.
+func NewReplyDispatcher(
+	ctx context.Context,
+	sub BrokerChannelSubscription,
+	correlationID CorrelationIDGetter,
+	logger Logger,
+) *ReplyDispatcher {
+	if logger == nil {
+		logger = DummyLogger{}
+	}
+
+	// Create the dispatcher
+	d := &ReplyDispatcher{
+		ctx:           ctx,
+		subscription:  sub,
+		correlationID: correlationID,
+		logger:        logger,
+		pending:       make(map[string]chan BrokerMessage),
+		closed:        make(chan struct{}),
+	}
+
+	// Dispatch received messages
+	go d.run()
+
+	return d
+}
+
+func (d *ReplyDispatcher) run() {
+	defer close(d.closed)
+
+	for msg := range d.subscription.MessagesChannel() {
+		// Acknowledge the message as it is only read by the dispatcher
+		if err := msg.Ack(); err != nil {
+			d.logger.Error(d.ctx, err.Error())
+		}
+
+		// Get the correlation ID of the reply
+		id, err := d.correlationID(msg)
+		if err != nil {
+			d.logger.Error(d.ctx, err.Error())
+			continue
+		}
+
+		// Get the corresponding request and remove it, as it has its reply
+		d.mutex.Lock()
+		ch, exists := d.pending[id]
+		delete(d.pending, id)
+		d.mutex.Unlock()
+		if !exists {
+			d.logger.Warning(d.ctx, fmt.Sprintf("Reply with correlation ID %q has no pending request", id))
+			continue
+		}
+
+		// Send the reply to the request
+		ch <- msg
+	}
+}
+
+// Expect registers a pending request with the correlation ID, in order to get
+// the corresponding reply. It should be called before sending the request, to
+// not miss the reply.
+func (d *ReplyDispatcher) Expect(correlationID string) (*PendingReply, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	// Check that the dispatcher is still running
+	if d.Closed() {
+		return nil, ErrSubscriptionCanceled
+	}
+
+	// Check that there is no other request with this correlation ID
+	if _, exists := d.pending[correlationID]; exists {
+		return nil, fmt.Errorf("%w: %q", ErrDuplicateCorrelationID, correlationID)
+	}
+
+	// Add the pending request
+	ch := make(chan BrokerMessage, 1)
+	d.pending[correlationID] = ch
+
+	return &PendingReply{
+		dispatcher:    d,
+		correlationID: correlationID,
+		reply:         ch,
+	}, nil
+}
+
+// Pending returns the number of requests waiting for their reply.
+func (d *ReplyDispatcher) Pending() int {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return len(d.pending)
+}
+
+// Closed returns true if the dispatcher does not receive replies anymore.
+func (d *ReplyDispatcher) Closed() bool {
+	select {
+	case <-d.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close cancels the reply subscription. Pending requests will then fail.
+func (d *ReplyDispatcher) Close(ctx context.Context) {
+	d.subscription.Cancel(ctx)
+}
+
+// PendingReply is a request waiting for its reply.
+type PendingReply struct {
+	dispatcher    *ReplyDispatcher
+	correlationID string
+	reply         chan BrokerMessage
+}
+
+// Wait waits for the reply until the context is done or the dispatcher is closed.
+// The pending request is removed when it returns.
+func (r *PendingReply) Wait(ctx context.Context) (BrokerMessage, error) {
+	defer r.Cancel()
+
+	select {
+	case msg := <-r.reply:
+		return msg, nil
+	case <-ctx.Done():
+		return BrokerMessage{}, ErrContextCanceled
+	case <-r.dispatcher.closed:
+		// The reply may have been received before the dispatcher was closed
+		select {
+		case msg := <-r.reply:
+			return msg, nil
+		default:
+			return BrokerMessage{}, ErrSubscriptionCanceled
+		}
+	}
+}
+
+// Cancel removes the pending request from the dispatcher, if it is still waiting
+// for its reply.
+func (r *PendingReply) Cancel() {
+	r.dispatcher.mutex.Lock()
+	defer r.dispatcher.mutex.Unlock()
+
+	if r.dispatcher.pending[r.correlationID] == r.reply {
+		delete(r.dispatcher.pending, r.correlationID)
+	}
+}
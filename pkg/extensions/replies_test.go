@@ -0,0 +1,165 @@
+package extensions
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestRepliesSuite(t *testing.T) {
+	suite.Run(t, new(RepliesSuite))
+}
+
+type RepliesSuite struct {
+	messages   chan BrokerMessage
+	dispatcher *ReplyDispatcher
+	suite.Suite
+}
+
+func (suite *RepliesSuite) SetupTest() {
+	suite.messages = make(chan BrokerMessage, 1)
+	sub := NewBrokerChannelSubscription(suite.messages, make(chan any, 1))
+	sub.WaitForCancellationAsync(func() {})
+
+	suite.dispatcher = NewReplyDispatcher(context.Background(), sub,
+		func(msg BrokerMessage) (string, error) {
+			return string(msg.Headers["correlationId"]), nil
+		}, nil)
+}
+
+func (suite *RepliesSuite) TearDownTest() {
+	suite.dispatcher.Close(context.Background())
+}
+
+func reply(id string) BrokerMessage {
+	return BrokerMessage{
+		Headers: map[string][]byte{"correlationId": []byte(id)},
+		Payload: []byte(id),
+	}
+}
+
+func (suite *RepliesSuite) TestDispatchByCorrelationID() {
+	// Expect replies for several requests
+	const count = 20
+	pendings := make([]*PendingReply, count)
+	for i := range pendings {
+		pending, err := suite.dispatcher.Expect(strconv.Itoa(i))
+		suite.Require().NoError(err)
+		pendings[i] = pending
+	}
+
+	// Send replies in reverse order, with an unexpected one
+	go func() {
+		suite.messages <- reply("unexpected")
+		for i := count - 1; i >= 0; i-- {
+			suite.messages <- reply(strconv.Itoa(i))
+		}
+	}()
+
+	// Each request should get its own reply
+	var wg sync.WaitGroup
+	for i, pending := range pendings {
+		wg.Add(1)
+		go func(i int, pending *PendingReply) {
+			defer wg.Done()
+			msg, err := pending.Wait(context.Background())
+			suite.NoError(err)
+			suite.Equal(strconv.Itoa(i), string(msg.Payload))
+		}(i, pending)
+	}
+	wg.Wait()
+
+	suite.Require().Equal(0, suite.dispatcher.Pending())
+}
+
+func (suite *RepliesSuite) TestDuplicateCorrelationID() {
+	_, err := suite.dispatcher.Expect("id")
+	suite.Require().NoError(err)
+
+	_, err = suite.dispatcher.Expect("id")
+	suite.Require().ErrorIs(err, ErrDuplicateCorrelationID)
+}
+
+func (suite *RepliesSuite) TestTimeout() {
+	pending, err := suite.dispatcher.Expect("id")
+	suite.Require().NoError(err)
+
+	// Wait without reply
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = pending.Wait(ctx)
+	suite.Require().ErrorIs(err, ErrContextCanceled)
+
+	// Request should have been removed
+	suite.Require().Equal(0, suite.dispatcher.Pending())
+}
+
+func (suite *RepliesSuite) TestCancel() {
+	pending, err := suite.dispatcher.Expect("id")
+	suite.Require().NoError(err)
+
+	// Cancel the request, the correlation ID should be usable again
+	pending.Cancel()
+	suite.Require().Equal(0, suite.dispatcher.Pending())
+	_, err = suite.dispatcher.Expect("id")
+	suite.Require().NoError(err)
+}
+
+func (suite *RepliesSuite) TestClose() {
+	pending, err := suite.dispatcher.Expect("id")
+	suite.Require().NoError(err)
+
+	// Close the dispatcher while the request is waiting
+	suite.dispatcher.Close(context.Background())
+	_, err = pending.Wait(context.Background())
+	suite.Require().True(errors.Is(err, ErrSubscriptionCanceled))
+
+	// New requests should fail
+	suite.Require().True(suite.dispatcher.Closed())
+	_, err = suite.dispatcher.Expect("other")
+	suite.Require().ErrorIs(err, ErrSubscriptionCanceled)
+}
+
+func (suite *RepliesSuite) TestReplyBeforeClose() {
+	// Repeat as the reply and the closing are both ready when waiting
+	for i := 0; i < 20; i++ {
+		messages := make(chan BrokerMessage, 1)
+		sub := NewBrokerChannelSubscription(messages, make(chan any, 1))
+		sub.WaitForCancellationAsync(func() {})
+		dispatcher := NewReplyDispatcher(context.Background(), sub,
+			func(msg BrokerMessage) (string, error) {
+				return string(msg.Headers["correlationId"]), nil
+			}, nil)
+
+		// Receive the reply, then close the dispatcher
+		pending, err := dispatcher.Expect("id")
+		suite.Require().NoError(err)
+		messages <- reply("id")
+		suite.Require().Eventually(func() bool { return dispatcher.Pending() == 0 }, time.Second, time.Millisecond)
+		dispatcher.Close(context.Background())
+		suite.Require().Eventually(dispatcher.Closed, time.Second, time.Millisecond)
+
+		// The reply should still be returned
+		msg, err := pending.Wait(context.Background())
+		suite.Require().NoError(err)
+		suite.Require().Equal("id", string(msg.Payload))
+	}
+}
+
+func (suite *RepliesSuite) TestReplyChannel() {
+	// Without reply channel in context
+	suite.Require().Equal("pong", ReplyChannel(context.Background(), "pong", "id"))
+
+	// With reply channel of a request in context
+	ctx := context.WithValue(context.Background(), ContextKeyIsReplyTo, "_INBOX.1")
+	ctx = context.WithValue(ctx, ContextKeyIsCorrelationID, "id")
+	suite.Require().Equal("_INBOX.1", ReplyChannel(ctx, "pong", "id"))
+
+	// With a message that is not a reply to the request
+	suite.Require().Equal("pong", ReplyChannel(ctx, "pong", "other"))
+}
@@ -0,0 +1,151 @@
+package extensions
+
+import (
+	"context"
+	"sync"
+)
+
+// BrokerFlusher is the interface that can be implemented by broker controllers
+// that buffer published messages, in order to send them on controllers shutdown.
+type BrokerFlusher interface {
+	// Flush sends the buffered messages to the broker.
+	Flush(ctx context.Context) error
+}
+
+// HandlersTracker tracks the subscriptions and the received messages handled by
+// a controller, in order to wait for them on shutdown.
+type HandlersTracker struct {
+	mutex         sync.Mutex
+	subscriptions int
+	pending       int
+	drops         int
+
+	// changed is closed when a subscription ends or a message has been handled
+	changed chan struct{}
+
+	// dropping is closed when the shutdown deadline is reached, and recreated
+	// when a subscription starts after it
+	dropping chan struct{}
+	dropped  bool
+}
+
+// NewHandlersTracker creates a new handlers tracker.
+func NewHandlersTracker() *HandlersTracker {
+	return &HandlersTracker{
+		changed:  make(chan struct{}),
+		dropping: make(chan struct{}),
+	}
+}
+
+// StartSubscription should be called when a subscription starts to receive messages.
+// If it starts after a shutdown that reached its deadline, its messages are not
+// dropped anymore.
+func (t *HandlersTracker) StartSubscription() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.dropped {
+		t.dropping = make(chan struct{})
+		t.dropped = false
+	}
+	t.subscriptions++
+}
+
+// EndSubscription should be called when a subscription will not receive
+// messages anymore.
+func (t *HandlersTracker) EndSubscription() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.subscriptions--
+	t.notify()
+}
+
+// Accept should be called when a received message will be handled.
+func (t *HandlersTracker) Accept() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.pending++
+}
+
+// Done should be called when an accepted message has been handled.
+func (t *HandlersTracker) Done() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.pending--
+	t.notify()
+}
+
+// AddDropped should be called when a received message will not be handled
+// because of the shutdown.
+func (t *HandlersTracker) AddDropped() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.drops++
+}
+
+// Dropping returns a channel that is closed when the remaining messages should
+// be dropped, as the shutdown deadline has been reached.
+func (t *HandlersTracker) Dropping() <-chan struct{} {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.dropping
+}
+
+// Wait waits for the subscriptions to end and for the received messages to be
+// handled. If the context is done before, the remaining messages are dropped.
+//
+// It returns the number of messages that were not handled: the dropped ones
+// and the ones that are still being handled.
+func (t *HandlersTracker) Wait(ctx context.Context) int {
+	// Wait for the end or drop the remaining messages
+	if !t.waitUntil(ctx, func() bool { return t.subscriptions == 0 && t.pending == 0 }) {
+		t.mutex.Lock()
+		if !t.dropped {
+			close(t.dropping)
+			t.dropped = true
+		}
+		t.mutex.Unlock()
+
+		t.waitUntil(context.Background(), func() bool { return t.subscriptions == 0 })
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	// Reset the dropped messages, that are only reported once
+	notHandled := t.drops + t.pending
+	t.drops = 0
+
+	return notHandled
+}
+
+// waitUntil waits for the condition, checked with the mutex locked, to be true.
+// It returns false if the context is done before.
+func (t *HandlersTracker) waitUntil(ctx context.Context, condition func() bool) bool {
+	for {
+		t.mutex.Lock()
+		ok, changed := condition(), t.changed
+		t.mutex.Unlock()
+
+		if ok {
+			return true
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// notify wakes up the waiters, and should be called with the mutex locked.
+func (t *HandlersTracker) notify() {
+	close(t.changed)
+	t.changed = make(chan struct{})
+}
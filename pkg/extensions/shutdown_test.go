@@ -0,0 +1,93 @@
+package extensions
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestShutdownSuite(t *testing.T) {
+	suite.Run(t, new(ShutdownSuite))
+}
+
+type ShutdownSuite struct {
+	suite.Suite
+}
+
+func (suite *ShutdownSuite) TestWaitForHandlers() {
+	tracker := NewHandlersTracker()
+
+	// Start a subscription with a handler that will end shortly
+	tracker.StartSubscription()
+	tracker.Accept()
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		tracker.Done()
+		tracker.EndSubscription()
+	}()
+
+	// Wait for the handler
+	suite.Require().Equal(0, tracker.Wait(context.Background()))
+
+	// Dropping should not have been triggered
+	select {
+	case <-tracker.Dropping():
+		suite.Require().FailNow("dropping should not be closed")
+	default:
+	}
+}
+
+func (suite *ShutdownSuite) TestDropOnDeadline() {
+	tracker := NewHandlersTracker()
+
+	// Start a subscription with a blocked handler, that drops a message when
+	// the deadline is reached
+	release := make(chan struct{})
+	tracker.StartSubscription()
+	tracker.Accept()
+	go func() {
+		defer tracker.Done()
+		<-release
+	}()
+	go func() {
+		defer tracker.EndSubscription()
+		<-tracker.Dropping()
+		tracker.AddDropped()
+	}()
+
+	// Wait with a short deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	suite.Require().Equal(2, tracker.Wait(ctx))
+
+	close(release)
+}
+
+func (suite *ShutdownSuite) TestSubscriptionAfterDeadline() {
+	tracker := NewHandlersTracker()
+
+	// Reach the deadline with a subscription dropping a message
+	tracker.StartSubscription()
+	go func() {
+		defer tracker.EndSubscription()
+		<-tracker.Dropping()
+		tracker.AddDropped()
+	}()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	suite.Require().Equal(1, tracker.Wait(ctx))
+
+	// A new subscription should not drop messages
+	tracker.StartSubscription()
+	select {
+	case <-tracker.Dropping():
+		suite.Require().FailNow("dropping should not be closed")
+	default:
+	}
+
+	// Next wait should not report the previously dropped messages
+	tracker.EndSubscription()
+	suite.Require().Equal(0, tracker.Wait(context.Background()))
+}
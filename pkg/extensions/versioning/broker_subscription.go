@@ -94,6 +94,7 @@ func (bs *brokerSubscription) launchListener(ctx context.Context) {
 				} else {
 					ctx = context.WithValue(ctx, extensions.ContextKeyIsBrokerMessage, msg)
 					bs.parent.logger.Error(ctx, "no version in the message and no default version")
+					bs.ackIgnoredMessage(ctx, msg)
 					continue
 				}
 			}
@@ -110,6 +111,8 @@ func (bs *brokerSubscription) launchListener(ctx context.Context) {
 
 				// Log the error
 				bs.parent.logger.Error(ctx, fmt.Sprintf("version %q is not registered", version))
+				bs.versionsMutex.Unlock()
+				bs.ackIgnoredMessage(ctx, msg)
 				continue
 			}
 
@@ -121,3 +124,11 @@ func (bs *brokerSubscription) launchListener(ctx context.Context) {
 		}
 	}()
 }
+
+// ackIgnoredMessage acknowledges a message that will not be transmitted to any
+// version, as it will never be handled.
+func (bs *brokerSubscription) ackIgnoredMessage(ctx context.Context, msg extensions.BrokerMessage) {
+	if err := msg.Ack(); err != nil {
+		bs.parent.logger.Error(ctx, err.Error())
+	}
+}
@@ -0,0 +1,130 @@
+package extensions
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+)
+
+// OrderingKey is the signature of the function that returns the key of a
+// received message: messages with the same key are handled in order, while
+// messages with different keys can be handled concurrently. Messages with an
+// empty key can be handled by any worker.
+type OrderingKey func(ctx context.Context, msg BrokerMessage) string
+
+// OrderingKeyFromHeader returns the value of a header as ordering key.
+func OrderingKeyFromHeader(name string) OrderingKey {
+	return func(_ context.Context, msg BrokerMessage) string {
+		return string(msg.Headers[name])
+	}
+}
+
+// OrderingKeyFromMetadata returns the value of a broker metadata as ordering
+// key (i.e. the Kafka message key).
+func OrderingKeyFromMetadata(name string) OrderingKey {
+	return func(_ context.Context, msg BrokerMessage) string {
+		return msg.Metadata[name]
+	}
+}
+
+// OrderingKeyFromCorrelationID returns the correlation ID of the message as
+// ordering key.
+func OrderingKeyFromCorrelationID() OrderingKey {
+	return func(ctx context.Context, _ BrokerMessage) string {
+		var key string
+		IfContextSetWith(ctx, ContextKeyIsCorrelationID, func(value string) {
+			key = value
+		})
+		return key
+	}
+}
+
+// WorkerPool executes functions concurrently with a bounded number of workers.
+// Functions submitted with the same key are executed in order by the same worker.
+type WorkerPool struct {
+	shared chan func()
+	keyed  []chan func()
+	wg     sync.WaitGroup
+}
+
+// NewWorkerPool creates a new worker pool with the given number of workers (at
+// least one).
+func NewWorkerPool(size int) *WorkerPool {
+	if size < 1 {
+		size = 1
+	}
+
+	// Create the pool
+	pool := &WorkerPool{
+		shared: make(chan func()),
+		keyed:  make([]chan func(), size),
+	}
+
+	// Start the workers
+	for i := range pool.keyed {
+		pool.keyed[i] = make(chan func())
+		pool.wg.Add(1)
+		go pool.work(pool.keyed[i])
+	}
+
+	return pool
+}
+
+func (p *WorkerPool) work(keyed chan func()) {
+	defer p.wg.Done()
+
+	shared := p.shared
+	for keyed != nil || shared != nil {
+		select {
+		case fn, open := <-keyed:
+			if !open {
+				keyed = nil
+				continue
+			}
+			fn()
+		case fn, open := <-shared:
+			if !open {
+				shared = nil
+				continue
+			}
+			fn()
+		}
+	}
+}
+
+// Submit executes the function on a worker, waiting for one to be available.
+// Functions with the same non-empty key are executed in submission order.
+func (p *WorkerPool) Submit(key string, fn func()) {
+	p.SubmitUntil(nil, key, fn)
+}
+
+// SubmitUntil executes the function like Submit, unless the done channel is
+// closed before a worker is available. It returns true if the function will be
+// executed.
+func (p *WorkerPool) SubmitUntil(done <-chan struct{}, key string, fn func()) bool {
+	// Get the channel corresponding to the key, or any available worker
+	ch := p.shared
+	if key != "" {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(key))
+		ch = p.keyed[h.Sum32()%uint32(len(p.keyed))]
+	}
+
+	select {
+	case ch <- fn:
+		return true
+	case <-done:
+		return false
+	}
+}
+
+// Close stops the workers after the execution of submitted functions, and waits
+// for them to finish. No function should be submitted afterwards.
+func (p *WorkerPool) Close() {
+	close(p.shared)
+	for _, ch := range p.keyed {
+		close(ch)
+	}
+
+	p.wg.Wait()
+}
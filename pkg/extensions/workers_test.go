@@ -0,0 +1,81 @@
+package extensions
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestWorkersSuite(t *testing.T) {
+	suite.Run(t, new(WorkersSuite))
+}
+
+type WorkersSuite struct {
+	suite.Suite
+}
+
+func (suite *WorkersSuite) TestBoundedConcurrency() {
+	pool := NewWorkerPool(3)
+
+	// Submit blocking functions and track the maximum concurrency
+	var current, max int32
+	for i := 0; i < 20; i++ {
+		pool.Submit("", func() {
+			n := atomic.AddInt32(&current, 1)
+			for {
+				m := atomic.LoadInt32(&max)
+				if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&current, -1)
+		})
+	}
+	pool.Close()
+
+	suite.Require().Equal(int32(3), max)
+}
+
+func (suite *WorkersSuite) TestOrderingByKey() {
+	pool := NewWorkerPool(4)
+
+	// Submit functions for several keys
+	var mutex sync.Mutex
+	executed := make(map[string][]int)
+	for i := 0; i < 100; i++ {
+		key, i := strconv.Itoa(i%5), i
+		pool.Submit(key, func() {
+			time.Sleep(time.Duration(i%3) * time.Millisecond)
+			mutex.Lock()
+			executed[key] = append(executed[key], i)
+			mutex.Unlock()
+		})
+	}
+	pool.Close()
+
+	// Check that functions have been executed in order for each key
+	suite.Require().Len(executed, 5)
+	for key, values := range executed {
+		suite.Require().Len(values, 20, key)
+		suite.Require().IsIncreasing(values, key)
+	}
+}
+
+func (suite *WorkersSuite) TestOrderingKeys() {
+	ctx := context.WithValue(context.Background(), ContextKeyIsCorrelationID, "id")
+	msg := BrokerMessage{
+		Headers:  map[string][]byte{"key": []byte("header")},
+		Metadata: map[string]string{"key": "metadata"},
+	}
+
+	suite.Require().Equal("header", OrderingKeyFromHeader("key")(ctx, msg))
+	suite.Require().Equal("metadata", OrderingKeyFromMetadata("key")(ctx, msg))
+	suite.Require().Equal("id", OrderingKeyFromCorrelationID()(ctx, msg))
+	suite.Require().Equal("", OrderingKeyFromCorrelationID()(context.Background(), msg))
+}
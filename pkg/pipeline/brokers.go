@@ -17,7 +17,10 @@ func Brokers(client *dagger.Client) map[string]*dagger.Service {
 	brokers := make(map[string]*dagger.Service)
 
 	brokers["kafka"] = BrokerKafka(client)
+	brokers["kafka-secure"] = BrokerKafkaSecure(client)
 	brokers["nats"] = BrokerNATS(client)
+	brokers["rabbitmq"] = BrokerRabbitMQ(client)
+	brokers["mosquitto"] = BrokerMosquitto(client)
 
 	return brokers
 }
@@ -47,6 +50,42 @@ func BrokerKafka(client *dagger.Client) *dagger.Service {
 		AsService()
 }
 
+// BrokerKafkaSecure returns a service for the Kafka broker with SASL
+// authentication over TLS, using the test certificates.
+func BrokerKafkaSecure(client *dagger.Client) *dagger.Service {
+	return client.Container().
+		//	Set container image
+		From(KafkaImage).
+
+		// Add test certificates
+		WithMountedDirectory("/opt/bitnami/kafka/config/certs", client.Host().Directory(KafkaCertificatesPath)).
+
+		// Add environment variables
+		WithEnvVariable("KAFKA_CFG_NODE_ID", "0").
+		WithEnvVariable("KAFKA_CFG_PROCESS_ROLES", "controller,broker").
+		WithEnvVariable("KAFKA_CFG_LISTENERS", "SASL_SSL://:9092,CONTROLLER://:9093").
+		WithEnvVariable("KAFKA_CFG_ADVERTISED_LISTENERS", "SASL_SSL://kafka-secure:9092").
+		WithEnvVariable("KAFKA_CFG_LISTENER_SECURITY_PROTOCOL_MAP", "CONTROLLER:PLAINTEXT,SASL_SSL:SASL_SSL").
+		WithEnvVariable("KAFKA_CFG_CONTROLLER_QUORUM_VOTERS", "0@:9093").
+		WithEnvVariable("KAFKA_CFG_CONTROLLER_LISTENER_NAMES", "CONTROLLER").
+		WithEnvVariable("KAFKA_CFG_INTER_BROKER_LISTENER_NAME", "SASL_SSL").
+		WithEnvVariable("KAFKA_CFG_SASL_ENABLED_MECHANISMS", "PLAIN,SCRAM-SHA-512").
+		WithEnvVariable("KAFKA_CFG_SASL_MECHANISM_INTER_BROKER_PROTOCOL", "PLAIN").
+		WithEnvVariable("KAFKA_CLIENT_USERS", KafkaSecureUser).
+		WithEnvVariable("KAFKA_CLIENT_PASSWORDS", KafkaSecurePassword).
+		WithEnvVariable("KAFKA_INTER_BROKER_USER", KafkaSecureUser).
+		WithEnvVariable("KAFKA_INTER_BROKER_PASSWORD", KafkaSecurePassword).
+		WithEnvVariable("KAFKA_TLS_TYPE", "PEM").
+		WithEnvVariable("KAFKA_TLS_CLIENT_AUTH", "none").
+
+		// Add exposed ports
+		WithExposedPort(9092).
+		WithExposedPort(9093).
+
+		// Return container as a service
+		AsService()
+}
+
 // BrokerNATS returns a service for the NATS broker.
 func BrokerNATS(client *dagger.Client) *dagger.Service {
 	return client.Container().
@@ -57,3 +96,29 @@ func BrokerNATS(client *dagger.Client) *dagger.Service {
 		// Return container as a service
 		AsService()
 }
+
+// BrokerRabbitMQ returns a service for the RabbitMQ broker.
+func BrokerRabbitMQ(client *dagger.Client) *dagger.Service {
+	return client.Container().
+		// Add base image
+		From(RabbitMQImage).
+		// Add exposed ports
+		WithExposedPort(5672).
+		// Return container as a service
+		AsService()
+}
+
+// BrokerMosquitto returns a service for the Mosquitto (MQTT) broker.
+func BrokerMosquitto(client *dagger.Client) *dagger.Service {
+	return client.Container().
+		// Add base image
+		From(MosquittoImage).
+		// Listen on all interfaces, as Mosquitto only listens locally by default
+		WithNewFile("/mosquitto/config/mosquitto.conf", dagger.ContainerWithNewFileOpts{
+			Contents: "listener 1883\nallow_anonymous true\n",
+		}).
+		// Add exposed ports
+		WithExposedPort(1883).
+		// Return container as a service
+		AsService()
+}
@@ -3,10 +3,21 @@ package pipeline
 const (
 	// KafkaImage is the image used for kafka.
 	KafkaImage = "bitnami/kafka:3.5.1"
+	// KafkaCertificatesPath is the path of the certificates used by the Kafka
+	// broker with TLS.
+	KafkaCertificatesPath = "./test/brokers/kafka/testdata"
+	// KafkaSecureUser is the SASL user of the Kafka broker with TLS.
+	KafkaSecureUser = "asyncapi"
+	// KafkaSecurePassword is the SASL password of the Kafka broker with TLS.
+	KafkaSecurePassword = "asyncapi-password"
 	// GolangImage is the image used for golang execution.
 	GolangImage = "golang:1.21.4"
 	// LinterImage is the image used for linter.
 	LinterImage = "golangci/golangci-lint:v1.55"
+	// MosquittoImage is the image used for Mosquitto (MQTT).
+	MosquittoImage = "eclipse-mosquitto:2"
 	// NATSImage is the image used for NATS.
 	NATSImage = "nats:2.10"
+	// RabbitMQImage is the image used for RabbitMQ.
+	RabbitMQImage = "rabbitmq:3.12"
 )
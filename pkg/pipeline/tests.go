@@ -24,7 +24,7 @@ func Tests(client *dagger.Client, brokers map[string]*dagger.Service) map[string
 			// Set brokers as dependencies of app and user
 			With(BindBrokers(brokers)).
 			// Execute command
-			WithExec([]string{"go", "test", testsPath + p})
+			WithExec([]string{"go", "test", "-race", testsPath + p})
 
 		// Add user containers to containers
 		containers[p] = t
@@ -1,5 +1,7 @@
 package utils
 
+import "sort"
+
 // MapToList will change a map to a list.
 func MapToList[T1 comparable, T2 any](m map[T1]T2) []T2 {
 	l := make([]T2, 0, len(m))
@@ -8,3 +10,13 @@ func MapToList[T1 comparable, T2 any](m map[T1]T2) []T2 {
 	}
 	return l
 }
+
+// SortedKeys will return the keys of a map sorted in ascending order.
+func SortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -22,3 +22,16 @@ func TestMapToListWithString(t *testing.T) {
 	less := func(a, b string) bool { return a < b }
 	assert.Equal(t, cmp.Diff(expectedOutput, MapToList(input), cmpopts.SortSlices(less)), "")
 }
+
+func TestSortedKeys(t *testing.T) {
+	// Parameters
+	input := map[string]int{
+		"c": 3,
+		"a": 1,
+		"d": 4,
+		"b": 2,
+	}
+	expectedOutput := []string{"a", "b", "c", "d"}
+
+	assert.Equal(t, expectedOutput, SortedKeys(input))
+}
@@ -7,11 +7,13 @@ import (
 
 	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
 	"github.com/znas-io/asyncapi-codegen/pkg/extensions/brokers/kafka"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions/brokers/memory"
 	"github.com/znas-io/asyncapi-codegen/pkg/extensions/brokers/nats"
 )
 
 // BrokerControllers returns a list of BrokerController to test based on the
-// docker-compose file of the project.
+// docker-compose file of the project. Only the in-memory broker is returned
+// when running tests in short mode.
 func BrokerControllers(t *testing.T) (brokers []extensions.BrokerController, cleanup func()) {
 	t.Helper() // Set this function as a helper
 
@@ -21,6 +23,15 @@ func BrokerControllers(t *testing.T) (brokers []extensions.BrokerController, cle
 	// Set a specific queueGroupeID to avoid collision between tests
 	queueGroupID := fmt.Sprintf("test-%d", time.Now().UnixNano())
 
+	// Add in-memory broker
+	mb := memory.NewController(memory.WithQueueGroup(queueGroupID))
+	brokers = append(brokers, mb)
+
+	// Only use the in-memory broker in short mode
+	if testing.Short() {
+		return brokers, mb.Close
+	}
+
 	// Add NATS broker
 	nb := nats.NewController("nats://nats:4222", nats.WithQueueGroup(queueGroupID))
 	brokers = append(brokers, nb)
@@ -31,7 +42,13 @@ func BrokerControllers(t *testing.T) (brokers []extensions.BrokerController, cle
 
 	// Return brokers with their cleanup functions
 	return brokers, func() {
+		// Clean up in-memory
+		mb.Close()
+
 		// Clean up NATS
 		nb.Close()
+
+		// Clean up Kafka
+		kb.Close()
 	}
 }
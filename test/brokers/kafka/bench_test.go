@@ -0,0 +1,58 @@
+package kafka_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions/brokers/kafka"
+)
+
+func benchmarkPublish(b *testing.B, options ...kafka.ControllerOption) {
+	if testing.Short() {
+		b.Skip("Kafka broker is not available in short mode")
+	}
+
+	c := kafka.NewController([]string{"kafka:9092"}, options...)
+	defer c.Close()
+
+	// Create the topic before measuring
+	topic := fmt.Sprintf("benchmark.%d", time.Now().UnixNano())
+	msg := extensions.BrokerMessage{Payload: make([]byte, 256)}
+	if err := c.Publish(context.Background(), topic, msg); err != nil {
+		b.Fatal(err)
+	}
+
+	// Publish from parallel goroutines
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if err := c.Publish(context.Background(), topic, msg); err != nil {
+				b.Error(err)
+			}
+		}
+	})
+
+	// Wait for asynchronous deliveries
+	if err := c.Flush(context.Background()); err != nil {
+		b.Fatal(err)
+	}
+}
+
+func BenchmarkPublish(b *testing.B) {
+	benchmarkPublish(b)
+}
+
+func BenchmarkPublishShortLinger(b *testing.B) {
+	benchmarkPublish(b, kafka.WithLinger(time.Millisecond))
+}
+
+func BenchmarkPublishAsync(b *testing.B) {
+	benchmarkPublish(b, kafka.WithAsync(nil))
+}
+
+func BenchmarkPublishAsyncLargeBatches(b *testing.B) {
+	benchmarkPublish(b, kafka.WithAsync(nil), kafka.WithBatchSize(1000), kafka.WithLinger(50*time.Millisecond))
+}
@@ -0,0 +1,195 @@
+package kafka_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions/brokers/kafka"
+)
+
+const (
+	// partitionsCount is the number of partitions of the topics of this suite.
+	partitionsCount = 3
+	// messagesCount is the number of messages published on each topic, with
+	// different keys to be on every partition.
+	messagesCount = 30
+)
+
+func TestPartitionsSuite(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Kafka broker is not available in short mode")
+	}
+
+	suite.Run(t, new(PartitionsSuite))
+}
+
+type PartitionsSuite struct {
+	topic string
+	suite.Suite
+}
+
+func (suite *PartitionsSuite) SetupTest() {
+	suite.topic = fmt.Sprintf("partitions.%d", time.Now().UnixNano())
+}
+
+func (suite *PartitionsSuite) newController(options ...kafka.ControllerOption) *kafka.Controller {
+	options = append([]kafka.ControllerOption{
+		kafka.WithTopicConfig(partitionsCount, 1),
+	}, options...)
+
+	c := kafka.NewController([]string{"kafka:9092"}, options...)
+	suite.T().Cleanup(c.Close)
+	return c
+}
+
+func (suite *PartitionsSuite) subscribe(c *kafka.Controller) extensions.BrokerChannelSubscription {
+	sub, err := c.Subscribe(context.Background(), suite.topic)
+	suite.Require().NoError(err)
+	suite.T().Cleanup(func() { sub.Cancel(context.Background()) })
+	return sub
+}
+
+func (suite *PartitionsSuite) publish(c *kafka.Controller) {
+	for i := 0; i < messagesCount; i++ {
+		err := c.Publish(context.Background(), suite.topic, extensions.BrokerMessage{
+			Payload: []byte(fmt.Sprintf("message-%d", i)),
+			Metadata: map[string]string{
+				kafka.MetadataKeyIsMessageKey: fmt.Sprintf("key-%d", i),
+			},
+		})
+		suite.Require().NoError(err)
+	}
+}
+
+// receive returns the number of received messages by partition.
+func (suite *PartitionsSuite) receive(sub extensions.BrokerChannelSubscription, count int) map[string]int {
+	partitions := make(map[string]int)
+	for i := 0; i < count; i++ {
+		select {
+		case bm := <-sub.MessagesChannel():
+			suite.Require().NotEmpty(bm.Metadata[kafka.MetadataKeyIsOffset])
+			_, err := time.Parse(time.RFC3339Nano, bm.Metadata[kafka.MetadataKeyIsTimestamp])
+			suite.Require().NoError(err)
+			suite.Require().NoError(bm.Ack())
+
+			partitions[bm.Metadata[kafka.MetadataKeyIsPartition]]++
+		case <-time.After(30 * time.Second):
+			suite.Require().FailNow("timeout", "received %d messages on %d", i, count)
+		}
+	}
+
+	return partitions
+}
+
+// notify sends the partitions without blocking the consumer group, as there
+// can be several rebalances.
+func notify(ch chan []int, partitions []int) {
+	select {
+	case ch <- partitions:
+	default:
+	}
+}
+
+func (suite *PartitionsSuite) TestConsumerGroup() {
+	assigned := make(chan []int, 1)
+	revoked := make(chan []int, 1)
+	c := suite.newController(
+		kafka.WithGroupID(suite.topic),
+		kafka.WithRebalanceCallbacks(
+			func(_ string, partitions []int) { notify(assigned, partitions) },
+			func(_ string, partitions []int) { notify(revoked, partitions) }))
+
+	// Every partition should be assigned to the only member of the group
+	sub := suite.subscribe(c)
+	select {
+	case partitions := <-assigned:
+		suite.Require().ElementsMatch([]int{0, 1, 2}, partitions)
+	case <-time.After(30 * time.Second):
+		suite.Require().FailNow("partitions have not been assigned")
+	}
+
+	// Messages from every partition should be received
+	suite.publish(c)
+	suite.Require().Len(suite.receive(sub, messagesCount), partitionsCount)
+
+	// Partitions should be revoked when another member joins the group
+	suite.subscribe(suite.newController(kafka.WithGroupID(suite.topic)))
+	select {
+	case partitions := <-revoked:
+		suite.Require().ElementsMatch([]int{0, 1, 2}, partitions)
+	case <-time.After(30 * time.Second):
+		suite.Require().FailNow("partitions have not been revoked")
+	}
+}
+
+func (suite *PartitionsSuite) TestWithoutConsumerGroup() {
+	c := suite.newController(kafka.WithGroupID(""))
+
+	// Messages from every partition should be received
+	sub := suite.subscribe(c)
+	suite.publish(c)
+	suite.Require().Len(suite.receive(sub, messagesCount), partitionsCount)
+}
+
+func (suite *PartitionsSuite) TestExplicitPartitions() {
+	c := suite.newController(kafka.WithPartitions(0, 2))
+
+	// Only messages from the partitions should be received
+	suite.publish(c)
+	sub := suite.subscribe(c)
+	var received int
+	for done := false; !done; {
+		select {
+		case bm := <-sub.MessagesChannel():
+			suite.Require().NotEqual("1", bm.Metadata[kafka.MetadataKeyIsPartition])
+			received++
+		case <-time.After(5 * time.Second):
+			done = true
+		}
+	}
+	suite.Require().Positive(received)
+	suite.Require().Less(received, messagesCount)
+}
+
+func (suite *PartitionsSuite) TestRedeliveryAfterNack() {
+	c := suite.newController(kafka.WithGroupID(suite.topic), kafka.WithStartOffset(kafka.StartOffsetEarliest))
+
+	// Publish messages on the same partition
+	for _, payload := range []string{"nacked", "acked"} {
+		err := c.Publish(context.Background(), suite.topic, extensions.BrokerMessage{
+			Payload:  []byte(payload),
+			Metadata: map[string]string{kafka.MetadataKeyIsMessageKey: "key"},
+		})
+		suite.Require().NoError(err)
+	}
+
+	sub := suite.subscribe(c)
+	next := func() extensions.BrokerMessage {
+		select {
+		case bm := <-sub.MessagesChannel():
+			return bm
+		case <-time.After(30 * time.Second):
+			suite.Require().FailNow("timeout")
+			return extensions.BrokerMessage{}
+		}
+	}
+
+	// Negatively acknowledge the first message, then acknowledge the next one
+	nacked := next()
+	suite.Require().Equal("nacked", string(nacked.Payload))
+	acked := next()
+	suite.Require().Equal("acked", string(acked.Payload))
+	suite.Require().NoError(nacked.Nack())
+	suite.Require().NoError(acked.Ack())
+
+	// The first message should be redelivered, with the following one
+	redelivered := next()
+	suite.Require().Equal("nacked", string(redelivered.Payload))
+	suite.Require().Equal(nacked.Metadata[kafka.MetadataKeyIsOffset], redelivered.Metadata[kafka.MetadataKeyIsOffset])
+	suite.Require().NoError(redelivered.Ack())
+	suite.Require().Equal("acked", string(next().Payload))
+}
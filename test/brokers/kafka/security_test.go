@@ -0,0 +1,95 @@
+package kafka_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/stretchr/testify/suite"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions/brokers/brokertest"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions/brokers/kafka"
+)
+
+const (
+	// secureHost is the Kafka broker with SASL authentication over TLS, with
+	// the user set in the CI pipeline.
+	secureHost     = "kafka-secure:9092"
+	secureUser     = "asyncapi"
+	securePassword = "asyncapi-password"
+)
+
+func TestSecuritySuite(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Kafka broker is not available in short mode")
+	}
+
+	suite.Run(t, new(SecuritySuite))
+}
+
+type SecuritySuite struct {
+	tls *tls.Config
+	suite.Suite
+}
+
+func (suite *SecuritySuite) SetupSuite() {
+	// Trust the test certificate authority
+	ca, err := os.ReadFile("testdata/ca.pem")
+	suite.Require().NoError(err)
+	pool := x509.NewCertPool()
+	suite.Require().True(pool.AppendCertsFromPEM(ca))
+	suite.tls = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+}
+
+func (suite *SecuritySuite) newController(options ...kafka.ControllerOption) *kafka.Controller {
+	options = append([]kafka.ControllerOption{
+		kafka.WithTLS(suite.tls),
+		kafka.WithSASL(plain.Mechanism{Username: secureUser, Password: securePassword}),
+		kafka.WithClientID("asyncapi-codegen-tests"),
+		kafka.WithDialTimeout(5 * time.Second),
+		kafka.WithTopicCreationRetries(3),
+	}, options...)
+
+	c := kafka.NewController([]string{secureHost}, options...)
+	suite.T().Cleanup(c.Close)
+	return c
+}
+
+func (suite *SecuritySuite) TestConformance() {
+	brokertest.Run(suite.T(), func(t *testing.T, queueGroup string) extensions.BrokerController {
+		return suite.newController(kafka.WithGroupID(queueGroup))
+	},
+		brokertest.WithoutLoadBalancing(),
+		brokertest.WithTimeout(30*time.Second),
+		brokertest.WithBurstSize(100))
+}
+
+func (suite *SecuritySuite) TestWrongPassword() {
+	c := suite.newController(kafka.WithSASL(plain.Mechanism{Username: secureUser, Password: "wrong"}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := c.Subscribe(ctx, fmt.Sprintf("security.%d", time.Now().UnixNano()))
+	suite.Require().Error(err)
+}
+
+func (suite *SecuritySuite) TestWithoutTLS() {
+	c := suite.newController(kafka.WithTLS(nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := c.Subscribe(ctx, fmt.Sprintf("security.%d", time.Now().UnixNano()))
+	suite.Require().Error(err)
+}
+
+func (suite *SecuritySuite) TestWithoutTopicCreation() {
+	c := suite.newController(kafka.WithoutTopicCreation())
+
+	err := c.Publish(context.Background(), fmt.Sprintf("security.%d", time.Now().UnixNano()), extensions.BrokerMessage{})
+	suite.Require().ErrorIs(err, kafka.ErrUnknownTopic)
+}
@@ -0,0 +1,42 @@
+package kafka_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions/brokers/brokertest"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions/brokers/kafka"
+)
+
+func TestConformance(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Kafka broker is not available in short mode")
+	}
+
+	brokertest.Run(t, func(t *testing.T, queueGroup string) extensions.BrokerController {
+		c := kafka.NewController([]string{"kafka:9092"}, kafka.WithGroupID(queueGroup))
+		t.Cleanup(c.Close)
+		return c
+	},
+		// Topics are created with only one partition, and consumer groups can
+		// take some time to be balanced
+		brokertest.WithoutLoadBalancing(),
+		brokertest.WithTimeout(30*time.Second),
+		// Each publication waits for the writer batch timeout
+		brokertest.WithBurstSize(100))
+}
+
+func TestConformanceAsync(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Kafka broker is not available in short mode")
+	}
+
+	brokertest.Run(t, func(t *testing.T, queueGroup string) extensions.BrokerController {
+		c := kafka.NewController([]string{"kafka:9092"}, kafka.WithGroupID(queueGroup), kafka.WithAsync(nil))
+		t.Cleanup(c.Close)
+		return c
+	},
+		brokertest.WithoutLoadBalancing(),
+		brokertest.WithTimeout(30*time.Second))
+}
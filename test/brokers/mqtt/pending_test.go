@@ -0,0 +1,49 @@
+package mqtt_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions/brokers/mqtt"
+)
+
+func TestPendingMessagesV311(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Mosquitto broker is not available in short mode")
+	}
+
+	broker, err := mqtt.NewController("mqtt://mosquitto:1883",
+		mqtt.WithProtocolVersion(mqtt.ProtocolVersion311),
+		mqtt.WithQueueGroup(fmt.Sprintf("pending-%d", time.Now().UnixNano())),
+		mqtt.WithMaxPendingMessages(1))
+	require.NoError(t, err)
+	defer broker.Close()
+
+	channel := fmt.Sprintf("pending/%d", time.Now().UnixNano())
+	sub, err := broker.Subscribe(context.Background(), channel)
+	require.NoError(t, err)
+	defer sub.Cancel(context.Background())
+
+	// Publish more messages than the subscription queues, without reading them
+	const count = 100
+	for i := 0; i < count; i++ {
+		err := broker.Publish(context.Background(), channel, extensions.BrokerMessage{Payload: []byte(fmt.Sprint(i))})
+		require.NoError(t, err)
+	}
+
+	// Every message should be received in order, as the messages with QoS 1
+	// are not dropped when the queue is full
+	for i := 0; i < count; i++ {
+		select {
+		case bm := <-sub.MessagesChannel():
+			require.Equal(t, fmt.Sprint(i), string(bm.Payload))
+			require.NoError(t, bm.Ack())
+		case <-time.After(10 * time.Second):
+			require.FailNow(t, "message not received", "received %d messages on %d", i, count)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package mqtt_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions/brokers/brokertest"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions/brokers/mqtt"
+)
+
+func TestConformance(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Mosquitto broker is not available in short mode")
+	}
+
+	brokertest.Run(t, func(t *testing.T, queueGroup string) extensions.BrokerController {
+		c, err := mqtt.NewController("mqtt://mosquitto:1883", mqtt.WithQueueGroup(queueGroup))
+		require.NoError(t, err)
+		t.Cleanup(c.Close)
+		return c
+	})
+}
+
+func TestConformanceV311(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Mosquitto broker is not available in short mode")
+	}
+
+	brokertest.Run(t, func(t *testing.T, queueGroup string) extensions.BrokerController {
+		c, err := mqtt.NewController("mqtt://mosquitto:1883",
+			mqtt.WithQueueGroup(queueGroup),
+			mqtt.WithProtocolVersion(mqtt.ProtocolVersion311))
+		require.NoError(t, err)
+		t.Cleanup(c.Close)
+		return c
+	}, brokertest.WithoutHeaders())
+}
@@ -0,0 +1,67 @@
+package mqtt_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions/brokers/mqtt"
+	"github.com/znas-io/asyncapi-codegen/test/features/bindings"
+)
+
+func TestWildcardParameters(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Mosquitto broker is not available in short mode")
+	}
+
+	for i, wildcard := range []string{"+", "*"} {
+		t.Run(wildcard, func(t *testing.T) {
+			broker, err := mqtt.NewController("mqtt://mosquitto:1883", mqtt.WithQueueGroup(fmt.Sprintf("wildcards-%d", i)))
+			require.NoError(t, err)
+			defer broker.Close()
+
+			// Get the topic of the received messages from their metadata
+			topics := make(chan string, 1)
+			app, err := bindings.NewAppController(broker, bindings.WithMiddlewares(
+				func(ctx context.Context, msg *extensions.BrokerMessage, next extensions.NextMiddleware) error {
+					extensions.IfContextValueEquals(ctx, extensions.ContextKeyIsDirection, "reception", func() {
+						topics <- msg.Metadata[mqtt.MetadataKeyIsTopic]
+					})
+					return next(ctx)
+				}))
+			require.NoError(t, err)
+			defer app.Close(context.Background())
+			user, err := bindings.NewUserController(broker)
+			require.NoError(t, err)
+			defer user.Close(context.Background())
+
+			// Subscribe to the channel of every sensor
+			received := make(chan string, 1)
+			err = app.SubscribeSensorsSensorIdTemperature(context.Background(),
+				bindings.SensorsTemperatureParameters{SensorId: wildcard},
+				func(_ context.Context, msg bindings.SensorsSensorIdTemperatureMessage) error {
+					received <- msg.Payload
+					return nil
+				})
+			require.NoError(t, err)
+
+			// Publish on the channel of a sensor
+			err = user.PublishSensorsSensorIdTemperature(context.Background(),
+				bindings.SensorsTemperatureParameters{SensorId: "kitchen"},
+				bindings.SensorsSensorIdTemperatureMessage{Payload: "21.5"})
+			require.NoError(t, err)
+
+			// The message should be received with its topic
+			select {
+			case payload := <-received:
+				require.Equal(t, "21.5", payload)
+				require.Equal(t, "sensors/kitchen/temperature", <-topics)
+			case <-time.After(5 * time.Second):
+				require.FailNow(t, "no message received")
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package nats_test
+
+import (
+	"testing"
+
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions/brokers/brokertest"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions/brokers/nats"
+)
+
+func TestConformance(t *testing.T) {
+	if testing.Short() {
+		t.Skip("NATS broker is not available in short mode")
+	}
+
+	brokertest.Run(t, func(t *testing.T, queueGroup string) extensions.BrokerController {
+		c := nats.NewController("nats://nats:4222", nats.WithQueueGroup(queueGroup))
+		t.Cleanup(c.Close)
+		return c
+	})
+}
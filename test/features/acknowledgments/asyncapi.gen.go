@@ -0,0 +1,681 @@
+// Package "acknowledgments" provides primitives to interact with the AsyncAPI specification.
+//
+// Code generated by github.com/znas-io/asyncapi-codegen version (devel) DO NOT EDIT.
+package acknowledgments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+)
+
+// AppSubscriber represents all handlers that are expecting messages for App
+type AppSubscriber interface {
+	// Acknowledgments subscribes to messages placed on the 'acknowledgments' channel.
+	// If an error is returned, the message will be negatively acknowledged.
+	Acknowledgments(ctx context.Context, msg AcknowledgmentsMessage) error
+}
+
+// AppControllerInterface is the interface of AppController, that
+// can be used to replace it in tests (i.e. with AppControllerMock).
+type AppControllerInterface interface {
+	Close(ctx context.Context)
+	Shutdown(ctx context.Context) error
+	SubscribeAll(ctx context.Context, as AppSubscriber) error
+	UnsubscribeAll(ctx context.Context)
+	SubscribeAcknowledgments(ctx context.Context, fn func(ctx context.Context, msg AcknowledgmentsMessage) error) error
+	UnsubscribeAcknowledgments(ctx context.Context)
+}
+
+var _ AppControllerInterface = (*AppController)(nil)
+
+// AppController is the structure that provides publishing capabilities to the
+// developer and and connect the broker with the App
+type AppController struct {
+	controller
+}
+
+// NewAppController links the App to the broker
+func NewAppController(bc extensions.BrokerController, options ...ControllerOption) (*AppController, error) {
+	// Check if broker controller has been provided
+	if bc == nil {
+		return nil, extensions.ErrNilBrokerController
+	}
+
+	// Create default controller
+	controller := controller{
+		broker:             bc,
+		subscriptions:      make(map[string]extensions.BrokerChannelSubscription),
+		subscriptionsMutex: &sync.Mutex{},
+		replies:            make(map[string]*extensions.ReplyDispatcher),
+		logger:             extensions.DummyLogger{},
+		middlewares:        make([]extensions.Middleware, 0),
+		concurrency:        1,
+		tracker:            extensions.NewHandlersTracker(),
+	}
+
+	// Apply options
+	for _, option := range options {
+		option(&controller)
+	}
+
+	return &AppController{controller: controller}, nil
+}
+
+func (c AppController) wrapMiddlewares(
+	middlewares []extensions.Middleware,
+	callback extensions.NextMiddleware,
+) func(ctx context.Context, msg *extensions.BrokerMessage) error {
+	// If there is no more middleware
+	if len(middlewares) == 0 {
+		return func(ctx context.Context, msg *extensions.BrokerMessage) error {
+			// Call the callback if it exists
+			if callback != nil {
+				return callback(ctx)
+			}
+
+			return nil
+		}
+	}
+
+	// Get the next function to call from next middlewares or callback
+	next := c.wrapMiddlewares(middlewares[1:], callback)
+
+	// Wrap middleware into a check function that will call execute the middleware
+	// and call the next wrapped middleware if it has not been called by the
+	// middleware. Note: the middleware can call it several times (i.e. to retry).
+	return func(ctx context.Context, msg *extensions.BrokerMessage) error {
+		// Create the next call with the context and the message
+		var called bool
+		nextWithArgs := func(ctx context.Context) error {
+			called = true
+			return next(ctx, msg)
+		}
+
+		// Call the middleware
+		if err := middlewares[0](ctx, msg, nextWithArgs); err != nil {
+			return err
+		}
+
+		// If next has already been called in middleware, it should not be executed again
+		if called {
+			return nil
+		}
+
+		return nextWithArgs(ctx)
+	}
+}
+
+func (c AppController) executeMiddlewares(ctx context.Context, msg *extensions.BrokerMessage, callback extensions.NextMiddleware) error {
+	// Wrap middleware to have 'next' function when calling them
+	wrapped := c.wrapMiddlewares(c.middlewares, callback)
+
+	// Execute wrapped middlewares
+	return wrapped(ctx, msg)
+}
+
+// dropMessage negatively acknowledges a received message that will not be
+// handled because of the controller shutdown.
+func (c AppController) dropMessage(ctx context.Context, msg extensions.BrokerMessage) {
+	c.logger.Warning(ctx, "Message dropped on shutdown")
+	if err := msg.Nack(); err != nil {
+		c.logger.Error(ctx, err.Error())
+	}
+	c.tracker.AddDropped()
+}
+
+func addAppContextValues(ctx context.Context, path string) context.Context {
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsVersion, "1.0.0")
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsProvider, "app")
+	return context.WithValue(ctx, extensions.ContextKeyIsChannel, path)
+}
+
+// Close will clean up any existing resources on the controller
+func (c *AppController) Close(ctx context.Context) {
+	// Unsubscribing remaining channels
+	c.UnsubscribeAll(ctx)
+
+	c.logger.Info(ctx, "Closed app controller")
+}
+
+// Shutdown will gracefully stop the controller: subscriptions stop receiving new
+// messages, then it waits for the already received messages to be handled and
+// flushes the messages published on the broker (if supported).
+//
+// If the context is done before every received message has been handled, the
+// remaining messages are dropped and an error wrapping extensions.ErrMessagesDropped
+// is returned with the number of messages that were not handled.
+func (c *AppController) Shutdown(ctx context.Context) error {
+	// Remove every subscription
+	c.subscriptionsMutex.Lock()
+	subscriptions := make([]extensions.BrokerChannelSubscription, 0, len(c.subscriptions))
+	for path, sub := range c.subscriptions {
+		subscriptions = append(subscriptions, sub)
+		delete(c.subscriptions, path)
+	}
+	c.subscriptionsMutex.Unlock()
+
+	// Stop receiving new messages
+	for _, sub := range subscriptions {
+		sub.Cancel(ctx)
+	}
+
+	// Wait for received messages to be handled
+	dropped := c.tracker.Wait(ctx)
+
+	// Flush published messages
+	if flusher, ok := c.broker.(extensions.BrokerFlusher); ok {
+		if err := flusher.Flush(ctx); err != nil {
+			c.logger.Error(ctx, err.Error())
+			return err
+		}
+	}
+
+	// Report dropped messages
+	if dropped > 0 {
+		err := fmt.Errorf("%w: %d message(s) not handled before shutdown", extensions.ErrMessagesDropped, dropped)
+		c.logger.Error(ctx, err.Error())
+		return err
+	}
+
+	c.logger.Info(ctx, "Shut down app controller")
+	return nil
+}
+
+// SubscribeAll will subscribe to channels without parameters on which the app is expecting messages.
+// For channels with parameters, they should be subscribed independently.
+func (c *AppController) SubscribeAll(ctx context.Context, as AppSubscriber) error {
+	if as == nil {
+		return extensions.ErrNilAppSubscriber
+	}
+
+	if err := c.SubscribeAcknowledgments(ctx, as.Acknowledgments); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UnsubscribeAll will unsubscribe all remaining subscribed channels
+func (c *AppController) UnsubscribeAll(ctx context.Context) {
+	c.UnsubscribeAcknowledgments(ctx)
+}
+
+// SubscribeAcknowledgments will subscribe to new messages from 'acknowledgments' channel.
+//
+// Callback function 'fn' will be called each time a new message is received.
+// The message will be acknowledged if it returns no error, or negatively
+// acknowledged to be redelivered otherwise (if supported by the broker).
+func (c *AppController) SubscribeAcknowledgments(ctx context.Context, fn func(ctx context.Context, msg AcknowledgmentsMessage) error) error {
+	// Get channel path
+	path := "acknowledgments"
+
+	// Set context
+	ctx = addAppContextValues(ctx, path)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsDirection, "reception")
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsAMQPChannelBinding, extensions.AMQPChannelBinding{})
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTOperationBinding, nil)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTMessageBinding, nil)
+
+	// Lock the subscriptions until this one is added, to avoid a concurrent
+	// subscription on the same channel
+	c.subscriptionsMutex.Lock()
+	defer c.subscriptionsMutex.Unlock()
+
+	// Check if there is already a subscription
+	_, exists := c.subscriptions[path]
+	if exists {
+		err := fmt.Errorf("%w: %q channel is already subscribed", extensions.ErrAlreadySubscribedChannel, path)
+		c.logger.Error(ctx, err.Error())
+		return err
+	}
+
+	// Subscribe to broker channel
+	sub, err := c.broker.Subscribe(ctx, path)
+	if err != nil {
+		c.logger.Error(ctx, err.Error())
+		return err
+	}
+	c.logger.Info(ctx, "Subscribed to channel")
+
+	// Handle a received message through middlewares and subscription function
+	handle := func(ctx context.Context, brokerMsg extensions.BrokerMessage) {
+		// Execute middlewares before handling the message
+		if err := c.executeMiddlewares(ctx, &brokerMsg, func(ctx context.Context) error {
+			// Process message
+			msg, err := newAcknowledgmentsMessageFromBrokerMessage(brokerMsg)
+			if err != nil {
+				return err
+			}
+
+			// Execute the subscription function
+			return fn(ctx, msg)
+		}); err != nil {
+			c.logger.Error(ctx, err.Error())
+
+			// Terminate the message if it can't be handled, or negatively
+			// acknowledge it to get it redelivered
+			nack := brokerMsg.Nack
+			if errors.Is(err, extensions.ErrUnrecoverable) {
+				nack = brokerMsg.Term
+			}
+			if err := nack(); err != nil {
+				c.logger.Error(ctx, err.Error())
+			}
+			return
+		}
+
+		// Acknowledge the message as it has been successfully handled
+		if err := brokerMsg.Ack(); err != nil {
+			c.logger.Error(ctx, err.Error())
+		}
+	}
+
+	// Asynchronously listen to new messages and pass them to app subscriber
+	c.tracker.StartSubscription()
+	go func() {
+		// Handle messages with workers, and wait for them before leaving
+		workers := extensions.NewWorkerPool(c.concurrency)
+		defer workers.Close()
+		defer c.tracker.EndSubscription()
+
+		for {
+			// Wait for next message, or drop the remaining ones on shutdown
+			var brokerMsg extensions.BrokerMessage
+			var open bool
+			select {
+			case brokerMsg, open = <-sub.MessagesChannel():
+			case <-c.tracker.Dropping():
+				for {
+					select {
+					case brokerMsg, open := <-sub.MessagesChannel():
+						if open {
+							c.dropMessage(ctx, brokerMsg)
+							continue
+						}
+					default:
+					}
+					return
+				}
+			}
+
+			// If subscription is closed and there is no more message
+			// (i.e. uninitialized message), then exit the function
+			if !open && brokerMsg.IsUninitialized() {
+				return
+			}
+
+			// Set broker message to context
+			msgCtx := context.WithValue(ctx, extensions.ContextKeyIsBrokerMessage, brokerMsg.String())
+
+			// Get the ordering key of the message
+			var key string
+			if c.orderingKey != nil {
+				key = c.orderingKey(msgCtx, brokerMsg)
+			}
+
+			// Handle the message on a worker, in order with messages with the same key
+			c.tracker.Accept()
+			if !workers.SubmitUntil(c.tracker.Dropping(), key, func() {
+				defer c.tracker.Done()
+				handle(msgCtx, brokerMsg)
+			}) {
+				c.tracker.Done()
+				c.dropMessage(msgCtx, brokerMsg)
+			}
+		}
+	}()
+
+	// Add the cancel channel to the inside map
+	c.subscriptions[path] = sub
+
+	return nil
+}
+
+// UnsubscribeAcknowledgments will unsubscribe messages from 'acknowledgments' channel.
+// A timeout can be set in context to avoid blocking operation, if needed.
+func (c *AppController) UnsubscribeAcknowledgments(ctx context.Context) {
+	// Get channel path
+	path := "acknowledgments"
+
+	// Check if there subscribers for this channel and remove it from the
+	// subscribers, so it is cancelled only once
+	c.subscriptionsMutex.Lock()
+	sub, exists := c.subscriptions[path]
+	delete(c.subscriptions, path)
+	c.subscriptionsMutex.Unlock()
+	if !exists {
+		return
+	}
+
+	// Set context
+	ctx = addAppContextValues(ctx, path)
+
+	// Stop the subscription
+	sub.Cancel(ctx)
+
+	c.logger.Info(ctx, "Unsubscribed from channel")
+}
+
+// UserControllerInterface is the interface of UserController, that
+// can be used to replace it in tests (i.e. with UserControllerMock).
+type UserControllerInterface interface {
+	Close(ctx context.Context)
+	Shutdown(ctx context.Context) error
+	PublishAcknowledgments(ctx context.Context, msg AcknowledgmentsMessage) error
+}
+
+var _ UserControllerInterface = (*UserController)(nil)
+
+// UserController is the structure that provides publishing capabilities to the
+// developer and and connect the broker with the User
+type UserController struct {
+	controller
+}
+
+// NewUserController links the User to the broker
+func NewUserController(bc extensions.BrokerController, options ...ControllerOption) (*UserController, error) {
+	// Check if broker controller has been provided
+	if bc == nil {
+		return nil, extensions.ErrNilBrokerController
+	}
+
+	// Create default controller
+	controller := controller{
+		broker:             bc,
+		subscriptions:      make(map[string]extensions.BrokerChannelSubscription),
+		subscriptionsMutex: &sync.Mutex{},
+		replies:            make(map[string]*extensions.ReplyDispatcher),
+		logger:             extensions.DummyLogger{},
+		middlewares:        make([]extensions.Middleware, 0),
+		concurrency:        1,
+		tracker:            extensions.NewHandlersTracker(),
+	}
+
+	// Apply options
+	for _, option := range options {
+		option(&controller)
+	}
+
+	return &UserController{controller: controller}, nil
+}
+
+func (c UserController) wrapMiddlewares(
+	middlewares []extensions.Middleware,
+	callback extensions.NextMiddleware,
+) func(ctx context.Context, msg *extensions.BrokerMessage) error {
+	// If there is no more middleware
+	if len(middlewares) == 0 {
+		return func(ctx context.Context, msg *extensions.BrokerMessage) error {
+			// Call the callback if it exists
+			if callback != nil {
+				return callback(ctx)
+			}
+
+			return nil
+		}
+	}
+
+	// Get the next function to call from next middlewares or callback
+	next := c.wrapMiddlewares(middlewares[1:], callback)
+
+	// Wrap middleware into a check function that will call execute the middleware
+	// and call the next wrapped middleware if it has not been called by the
+	// middleware. Note: the middleware can call it several times (i.e. to retry).
+	return func(ctx context.Context, msg *extensions.BrokerMessage) error {
+		// Create the next call with the context and the message
+		var called bool
+		nextWithArgs := func(ctx context.Context) error {
+			called = true
+			return next(ctx, msg)
+		}
+
+		// Call the middleware
+		if err := middlewares[0](ctx, msg, nextWithArgs); err != nil {
+			return err
+		}
+
+		// If next has already been called in middleware, it should not be executed again
+		if called {
+			return nil
+		}
+
+		return nextWithArgs(ctx)
+	}
+}
+
+func (c UserController) executeMiddlewares(ctx context.Context, msg *extensions.BrokerMessage, callback extensions.NextMiddleware) error {
+	// Wrap middleware to have 'next' function when calling them
+	wrapped := c.wrapMiddlewares(c.middlewares, callback)
+
+	// Execute wrapped middlewares
+	return wrapped(ctx, msg)
+}
+
+// dropMessage negatively acknowledges a received message that will not be
+// handled because of the controller shutdown.
+func (c UserController) dropMessage(ctx context.Context, msg extensions.BrokerMessage) {
+	c.logger.Warning(ctx, "Message dropped on shutdown")
+	if err := msg.Nack(); err != nil {
+		c.logger.Error(ctx, err.Error())
+	}
+	c.tracker.AddDropped()
+}
+
+func addUserContextValues(ctx context.Context, path string) context.Context {
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsVersion, "1.0.0")
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsProvider, "user")
+	return context.WithValue(ctx, extensions.ContextKeyIsChannel, path)
+}
+
+// Close will clean up any existing resources on the controller
+func (c *UserController) Close(ctx context.Context) {
+	// Unsubscribing remaining channels
+}
+
+// Shutdown will gracefully stop the controller: subscriptions stop receiving new
+// messages, then it waits for the already received messages to be handled and
+// flushes the messages published on the broker (if supported).
+//
+// If the context is done before every received message has been handled, the
+// remaining messages are dropped and an error wrapping extensions.ErrMessagesDropped
+// is returned with the number of messages that were not handled.
+func (c *UserController) Shutdown(ctx context.Context) error {
+	// Remove every subscription
+	c.subscriptionsMutex.Lock()
+	subscriptions := make([]extensions.BrokerChannelSubscription, 0, len(c.subscriptions))
+	for path, sub := range c.subscriptions {
+		subscriptions = append(subscriptions, sub)
+		delete(c.subscriptions, path)
+	}
+	c.subscriptionsMutex.Unlock()
+
+	// Stop receiving new messages
+	for _, sub := range subscriptions {
+		sub.Cancel(ctx)
+	}
+
+	// Wait for received messages to be handled
+	dropped := c.tracker.Wait(ctx)
+
+	// Flush published messages
+	if flusher, ok := c.broker.(extensions.BrokerFlusher); ok {
+		if err := flusher.Flush(ctx); err != nil {
+			c.logger.Error(ctx, err.Error())
+			return err
+		}
+	}
+
+	// Report dropped messages
+	if dropped > 0 {
+		err := fmt.Errorf("%w: %d message(s) not handled before shutdown", extensions.ErrMessagesDropped, dropped)
+		c.logger.Error(ctx, err.Error())
+		return err
+	}
+
+	c.logger.Info(ctx, "Shut down user controller")
+	return nil
+}
+
+// PublishAcknowledgments will publish messages to 'acknowledgments' channel
+func (c *UserController) PublishAcknowledgments(ctx context.Context, msg AcknowledgmentsMessage) error {
+	// Get channel path
+	path := "acknowledgments"
+
+	// Set context
+	ctx = addUserContextValues(ctx, path)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsDirection, "publication")
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsAMQPChannelBinding, extensions.AMQPChannelBinding{})
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTOperationBinding, nil)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTMessageBinding, nil)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsCorrelationID, nil)
+
+	// Convert to BrokerMessage
+	brokerMsg, err := msg.toBrokerMessage()
+	if err != nil {
+		return err
+	}
+
+	// Set broker message to context
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsBrokerMessage, brokerMsg.String())
+
+	// Publish the message on event-broker through middlewares
+	return c.executeMiddlewares(ctx, &brokerMsg, func(ctx context.Context) error {
+		return c.broker.Publish(ctx, path, brokerMsg)
+	})
+}
+
+// AsyncAPIVersion is the version of the used AsyncAPI document
+const AsyncAPIVersion = "1.0.0"
+
+// controller is the controller that will be used to communicate with the broker
+// It will be used internally by AppController and UserController
+type controller struct {
+	// broker is the broker controller that will be used to communicate
+	broker extensions.BrokerController
+	// subscriptions is a map of all subscriptions
+	subscriptions map[string]extensions.BrokerChannelSubscription
+	// subscriptionsMutex protects the subscriptions and replies maps, as
+	// subscriptions can be made and cancelled from different goroutines
+	subscriptionsMutex *sync.Mutex
+	// replies is a map of the reply dispatchers, by reply channel
+	replies map[string]*extensions.ReplyDispatcher
+	// requestTimeout is the maximum duration to wait for a reply to a request
+	requestTimeout time.Duration
+	// logger is the logger that will be used² to log operations on controller
+	logger extensions.Logger
+	// middlewares are the middlewares that will be executed when sending or
+	// receiving messages
+	middlewares []extensions.Middleware
+	// concurrency is the maximum number of messages handled simultaneously by
+	// each subscription
+	concurrency int
+	// orderingKey is the function returning the key of received messages, in
+	// order to handle messages with the same key in order
+	orderingKey extensions.OrderingKey
+	// tracker tracks the received messages being handled, in order to wait for
+	// them on shutdown
+	tracker *extensions.HandlersTracker
+}
+
+// ControllerOption is the type of the options that can be passed
+// when creating a new Controller
+type ControllerOption func(controller *controller)
+
+// WithLogger attaches a logger to the controller
+func WithLogger(logger extensions.Logger) ControllerOption {
+	return func(controller *controller) {
+		controller.logger = logger
+	}
+}
+
+// WithMiddlewares attaches middlewares that will be executed when sending or receiving messages
+func WithMiddlewares(middlewares ...extensions.Middleware) ControllerOption {
+	return func(controller *controller) {
+		controller.middlewares = middlewares
+	}
+}
+
+// WithConcurrency sets the maximum number of messages handled simultaneously by
+// each subscription. Default is 1, meaning that messages are handled one by one.
+func WithConcurrency(workers int) ControllerOption {
+	return func(controller *controller) {
+		controller.concurrency = workers
+	}
+}
+
+// WithOrderingKey sets the function returning the key of received messages:
+// messages with the same key are handled in order, while messages with
+// different keys can be handled concurrently (see WithConcurrency).
+func WithOrderingKey(key extensions.OrderingKey) ControllerOption {
+	return func(controller *controller) {
+		controller.orderingKey = key
+	}
+}
+
+// WithRequestTimeout sets the maximum duration to wait for the reply of a
+// request. Default is 0, meaning that it waits until the context is done.
+func WithRequestTimeout(timeout time.Duration) ControllerOption {
+	return func(controller *controller) {
+		controller.requestTimeout = timeout
+	}
+}
+
+type MessageWithCorrelationID interface {
+	CorrelationID() string
+	SetCorrelationID(id string)
+}
+
+type Error struct {
+	Channel string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("channel %q: err %v", e.Channel, e.Err)
+}
+
+// AcknowledgmentsMessage is the message expected for 'Acknowledgments' channel
+type AcknowledgmentsMessage struct {
+	// Payload will be inserted in the message payload
+	Payload string
+}
+
+func NewAcknowledgmentsMessage() AcknowledgmentsMessage {
+	var msg AcknowledgmentsMessage
+
+	return msg
+}
+
+// newAcknowledgmentsMessageFromBrokerMessage will fill a new AcknowledgmentsMessage with data from generic broker message
+func newAcknowledgmentsMessageFromBrokerMessage(bMsg extensions.BrokerMessage) (AcknowledgmentsMessage, error) {
+	var msg AcknowledgmentsMessage
+
+	// Convert to string
+	payload := string(bMsg.Payload)
+	msg.Payload = payload // No need for type conversion to reference
+
+	// TODO: run checks on msg type
+
+	return msg, nil
+}
+
+// toBrokerMessage will generate a generic broker message from AcknowledgmentsMessage data
+func (msg AcknowledgmentsMessage) toBrokerMessage() (extensions.BrokerMessage, error) {
+	// TODO: implement checks on message
+
+	// Convert to []byte
+	payload := []byte(msg.Payload)
+
+	// There is no headers here
+	headers := make(map[string][]byte, 0)
+
+	return extensions.BrokerMessage{
+		Headers: headers,
+		Payload: payload,
+	}, nil
+}
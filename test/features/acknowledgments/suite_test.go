@@ -0,0 +1,153 @@
+//go:generate go run ../../../cmd/asyncapi-codegen -p acknowledgments -i ./asyncapi.yaml -o ./asyncapi.gen.go
+
+package acknowledgments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions/brokers/memory"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions/middlewares"
+)
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(Suite))
+}
+
+type Suite struct {
+	broker *memory.Controller
+	app    *AppController
+	user   *UserController
+	suite.Suite
+}
+
+func (suite *Suite) SetupTest() {
+	// Use in-memory broker, as it redelivers negatively acknowledged messages
+	suite.broker = memory.NewController()
+
+	// Create app
+	app, err := NewAppController(suite.broker)
+	suite.Require().NoError(err)
+	suite.app = app
+
+	// Create user
+	user, err := NewUserController(suite.broker)
+	suite.Require().NoError(err)
+	suite.user = user
+}
+
+func (suite *Suite) TearDownTest() {
+	suite.app.Close(context.Background())
+	suite.user.Close(context.Background())
+	suite.broker.Close()
+}
+
+func (suite *Suite) TestRedeliveryOnError() {
+	received := make(chan string, 8)
+
+	// Fail the first time the message is handled
+	attempts := 0
+	err := suite.app.SubscribeAcknowledgments(context.Background(), func(_ context.Context, msg AcknowledgmentsMessage) error {
+		received <- msg.Payload
+
+		attempts++
+		if attempts == 1 {
+			return errors.New("handling failed")
+		}
+		return nil
+	})
+	suite.Require().NoError(err)
+
+	// Publish the message
+	err = suite.user.PublishAcknowledgments(context.Background(), AcknowledgmentsMessage{Payload: "hello"})
+	suite.Require().NoError(err)
+
+	// Check that the message is redelivered once after the error
+	for i := 0; i < 2; i++ {
+		select {
+		case payload := <-received:
+			suite.Require().Equal("hello", payload)
+		case <-time.After(time.Second):
+			suite.Require().FailNow("message has not been redelivered")
+		}
+	}
+
+	select {
+	case payload := <-received:
+		suite.Require().FailNow("acknowledged message has been redelivered", payload)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func (suite *Suite) TestNoRedeliveryOnUnrecoverableError() {
+	received := make(chan string, 8)
+
+	// Fail with an unrecoverable error
+	err := suite.app.SubscribeAcknowledgments(context.Background(), func(_ context.Context, msg AcknowledgmentsMessage) error {
+		received <- msg.Payload
+		return fmt.Errorf("%w: invalid message", extensions.ErrUnrecoverable)
+	})
+	suite.Require().NoError(err)
+
+	// Publish the message
+	err = suite.user.PublishAcknowledgments(context.Background(), AcknowledgmentsMessage{Payload: "hello"})
+	suite.Require().NoError(err)
+
+	// Check that the message is terminated instead of being redelivered
+	select {
+	case payload := <-received:
+		suite.Require().Equal("hello", payload)
+	case <-time.After(time.Second):
+		suite.Require().FailNow("message has not been received")
+	}
+
+	select {
+	case payload := <-received:
+		suite.Require().FailNow("terminated message has been redelivered", payload)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func (suite *Suite) TestRetryAndDeadLetter() {
+	// Create an app that retries and then publishes on a dead-letter channel
+	app, err := NewAppController(suite.broker, WithMiddlewares(middlewares.Retry(
+		middlewares.WithMaxAttempts(3),
+		middlewares.WithBackoff(time.Millisecond, time.Millisecond, 1),
+		middlewares.WithDeadLetterChannel(suite.broker, "acknowledgments.dead-letter"),
+	)))
+	suite.Require().NoError(err)
+	defer app.Close(context.Background())
+
+	// Subscribe to dead-letter channel
+	deadLetters, err := suite.broker.Subscribe(context.Background(), "acknowledgments.dead-letter")
+	suite.Require().NoError(err)
+	defer deadLetters.Cancel(context.Background())
+
+	// Always fail when handling the message
+	received := make(chan string, 8)
+	err = app.SubscribeAcknowledgments(context.Background(), func(_ context.Context, msg AcknowledgmentsMessage) error {
+		received <- msg.Payload
+		return errors.New("handling failed")
+	})
+	suite.Require().NoError(err)
+
+	// Publish the message
+	err = suite.user.PublishAcknowledgments(context.Background(), AcknowledgmentsMessage{Payload: "hello"})
+	suite.Require().NoError(err)
+
+	// Check that the message is published on dead-letter channel after the attempts
+	select {
+	case msg := <-deadLetters.MessagesChannel():
+		suite.Require().Equal("3", string(msg.Headers[middlewares.DeadLetterAttemptsHeaderKey]))
+		suite.Require().Equal("acknowledgments", string(msg.Headers[middlewares.DeadLetterChannelHeaderKey]))
+		suite.Require().Equal("handling failed", string(msg.Headers[middlewares.DeadLetterErrorHeaderKey]))
+	case <-time.After(time.Second):
+		suite.Require().FailNow("message has not been published on dead-letter channel")
+	}
+	suite.Require().Len(received, 3)
+}
@@ -0,0 +1,1923 @@
+// Package "bindings" provides primitives to interact with the AsyncAPI specification.
+//
+// Code generated by github.com/znas-io/asyncapi-codegen version (devel) DO NOT EDIT.
+package bindings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+)
+
+// AppSubscriber represents all handlers that are expecting messages for App
+type AppSubscriber interface {
+	// Invalidations subscribes to messages placed on the 'invalidations' channel.
+	// If an error is returned, the message will be negatively acknowledged.
+	Invalidations(ctx context.Context, msg InvalidationsMessage) error
+
+	// Orders subscribes to messages placed on the 'orders' channel.
+	// If an error is returned, the message will be negatively acknowledged.
+	Orders(ctx context.Context, msg OrdersMessage) error
+
+	// SensorsTemperature subscribes to messages placed on the 'sensors/temperature' channel.
+	// If an error is returned, the message will be negatively acknowledged.
+	SensorsTemperature(ctx context.Context, msg SensorsTemperatureMessage) error
+
+	// SensorsSensorIdTemperature subscribes to messages placed on the 'sensors/{sensorId}/temperature' channel.
+	// If an error is returned, the message will be negatively acknowledged.
+	SensorsSensorIdTemperature(ctx context.Context, msg SensorsSensorIdTemperatureMessage) error
+
+	// UserSignup subscribes to messages placed on the 'user.signup' channel.
+	// If an error is returned, the message will be negatively acknowledged.
+	UserSignup(ctx context.Context, msg UserSignupMessage) error
+}
+
+// AppControllerInterface is the interface of AppController, that
+// can be used to replace it in tests (i.e. with AppControllerMock).
+type AppControllerInterface interface {
+	Close(ctx context.Context)
+	Shutdown(ctx context.Context) error
+	SubscribeAll(ctx context.Context, as AppSubscriber) error
+	UnsubscribeAll(ctx context.Context)
+	SubscribeInvalidations(ctx context.Context, fn func(ctx context.Context, msg InvalidationsMessage) error) error
+	UnsubscribeInvalidations(ctx context.Context)
+	SubscribeOrders(ctx context.Context, fn func(ctx context.Context, msg OrdersMessage) error) error
+	UnsubscribeOrders(ctx context.Context)
+	SubscribeSensorsTemperature(ctx context.Context, fn func(ctx context.Context, msg SensorsTemperatureMessage) error) error
+	UnsubscribeSensorsTemperature(ctx context.Context)
+	SubscribeSensorsSensorIdTemperature(ctx context.Context, params SensorsTemperatureParameters, fn func(ctx context.Context, msg SensorsSensorIdTemperatureMessage) error) error
+	UnsubscribeSensorsSensorIdTemperature(ctx context.Context, params SensorsTemperatureParameters)
+	SubscribeUserSignup(ctx context.Context, fn func(ctx context.Context, msg UserSignupMessage) error) error
+	UnsubscribeUserSignup(ctx context.Context)
+	PublishNotifications(ctx context.Context, msg NotificationsMessage) error
+}
+
+var _ AppControllerInterface = (*AppController)(nil)
+
+// AppController is the structure that provides publishing capabilities to the
+// developer and and connect the broker with the App
+type AppController struct {
+	controller
+}
+
+// NewAppController links the App to the broker
+func NewAppController(bc extensions.BrokerController, options ...ControllerOption) (*AppController, error) {
+	// Check if broker controller has been provided
+	if bc == nil {
+		return nil, extensions.ErrNilBrokerController
+	}
+
+	// Create default controller
+	controller := controller{
+		broker:             bc,
+		subscriptions:      make(map[string]extensions.BrokerChannelSubscription),
+		subscriptionsMutex: &sync.Mutex{},
+		replies:            make(map[string]*extensions.ReplyDispatcher),
+		logger:             extensions.DummyLogger{},
+		middlewares:        make([]extensions.Middleware, 0),
+		concurrency:        1,
+		tracker:            extensions.NewHandlersTracker(),
+	}
+
+	// Apply options
+	for _, option := range options {
+		option(&controller)
+	}
+
+	return &AppController{controller: controller}, nil
+}
+
+func (c AppController) wrapMiddlewares(
+	middlewares []extensions.Middleware,
+	callback extensions.NextMiddleware,
+) func(ctx context.Context, msg *extensions.BrokerMessage) error {
+	// If there is no more middleware
+	if len(middlewares) == 0 {
+		return func(ctx context.Context, msg *extensions.BrokerMessage) error {
+			// Call the callback if it exists
+			if callback != nil {
+				return callback(ctx)
+			}
+
+			return nil
+		}
+	}
+
+	// Get the next function to call from next middlewares or callback
+	next := c.wrapMiddlewares(middlewares[1:], callback)
+
+	// Wrap middleware into a check function that will call execute the middleware
+	// and call the next wrapped middleware if it has not been called by the
+	// middleware. Note: the middleware can call it several times (i.e. to retry).
+	return func(ctx context.Context, msg *extensions.BrokerMessage) error {
+		// Create the next call with the context and the message
+		var called bool
+		nextWithArgs := func(ctx context.Context) error {
+			called = true
+			return next(ctx, msg)
+		}
+
+		// Call the middleware
+		if err := middlewares[0](ctx, msg, nextWithArgs); err != nil {
+			return err
+		}
+
+		// If next has already been called in middleware, it should not be executed again
+		if called {
+			return nil
+		}
+
+		return nextWithArgs(ctx)
+	}
+}
+
+func (c AppController) executeMiddlewares(ctx context.Context, msg *extensions.BrokerMessage, callback extensions.NextMiddleware) error {
+	// Wrap middleware to have 'next' function when calling them
+	wrapped := c.wrapMiddlewares(c.middlewares, callback)
+
+	// Execute wrapped middlewares
+	return wrapped(ctx, msg)
+}
+
+// dropMessage negatively acknowledges a received message that will not be
+// handled because of the controller shutdown.
+func (c AppController) dropMessage(ctx context.Context, msg extensions.BrokerMessage) {
+	c.logger.Warning(ctx, "Message dropped on shutdown")
+	if err := msg.Nack(); err != nil {
+		c.logger.Error(ctx, err.Error())
+	}
+	c.tracker.AddDropped()
+}
+
+func addAppContextValues(ctx context.Context, path string) context.Context {
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsVersion, "1.0.0")
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsProvider, "app")
+	return context.WithValue(ctx, extensions.ContextKeyIsChannel, path)
+}
+
+// Close will clean up any existing resources on the controller
+func (c *AppController) Close(ctx context.Context) {
+	// Unsubscribing remaining channels
+	c.UnsubscribeAll(ctx)
+
+	c.logger.Info(ctx, "Closed app controller")
+}
+
+// Shutdown will gracefully stop the controller: subscriptions stop receiving new
+// messages, then it waits for the already received messages to be handled and
+// flushes the messages published on the broker (if supported).
+//
+// If the context is done before every received message has been handled, the
+// remaining messages are dropped and an error wrapping extensions.ErrMessagesDropped
+// is returned with the number of messages that were not handled.
+func (c *AppController) Shutdown(ctx context.Context) error {
+	// Remove every subscription
+	c.subscriptionsMutex.Lock()
+	subscriptions := make([]extensions.BrokerChannelSubscription, 0, len(c.subscriptions))
+	for path, sub := range c.subscriptions {
+		subscriptions = append(subscriptions, sub)
+		delete(c.subscriptions, path)
+	}
+	c.subscriptionsMutex.Unlock()
+
+	// Stop receiving new messages
+	for _, sub := range subscriptions {
+		sub.Cancel(ctx)
+	}
+
+	// Wait for received messages to be handled
+	dropped := c.tracker.Wait(ctx)
+
+	// Flush published messages
+	if flusher, ok := c.broker.(extensions.BrokerFlusher); ok {
+		if err := flusher.Flush(ctx); err != nil {
+			c.logger.Error(ctx, err.Error())
+			return err
+		}
+	}
+
+	// Report dropped messages
+	if dropped > 0 {
+		err := fmt.Errorf("%w: %d message(s) not handled before shutdown", extensions.ErrMessagesDropped, dropped)
+		c.logger.Error(ctx, err.Error())
+		return err
+	}
+
+	c.logger.Info(ctx, "Shut down app controller")
+	return nil
+}
+
+// SubscribeAll will subscribe to channels without parameters on which the app is expecting messages.
+// For channels with parameters, they should be subscribed independently.
+func (c *AppController) SubscribeAll(ctx context.Context, as AppSubscriber) error {
+	if as == nil {
+		return extensions.ErrNilAppSubscriber
+	}
+
+	if err := c.SubscribeInvalidations(ctx, as.Invalidations); err != nil {
+		return err
+	}
+	if err := c.SubscribeOrders(ctx, as.Orders); err != nil {
+		return err
+	}
+	if err := c.SubscribeSensorsTemperature(ctx, as.SensorsTemperature); err != nil {
+		return err
+	}
+	if err := c.SubscribeUserSignup(ctx, as.UserSignup); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UnsubscribeAll will unsubscribe all remaining subscribed channels
+func (c *AppController) UnsubscribeAll(ctx context.Context) {
+	c.UnsubscribeInvalidations(ctx)
+	c.UnsubscribeOrders(ctx)
+	c.UnsubscribeSensorsTemperature(ctx)
+	c.UnsubscribeUserSignup(ctx)
+}
+
+// SubscribeInvalidations will subscribe to new messages from 'invalidations' channel.
+//
+// Callback function 'fn' will be called each time a new message is received.
+// The message will be acknowledged if it returns no error, or negatively
+// acknowledged to be redelivered otherwise (if supported by the broker).
+func (c *AppController) SubscribeInvalidations(ctx context.Context, fn func(ctx context.Context, msg InvalidationsMessage) error) error {
+	// Get channel path
+	path := "invalidations"
+
+	// Set context
+	ctx = addAppContextValues(ctx, path)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsDirection, "reception")
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsAMQPChannelBinding, extensions.AMQPChannelBinding{})
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTOperationBinding, nil)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTMessageBinding, nil)
+
+	// Lock the subscriptions until this one is added, to avoid a concurrent
+	// subscription on the same channel
+	c.subscriptionsMutex.Lock()
+	defer c.subscriptionsMutex.Unlock()
+
+	// Check if there is already a subscription
+	_, exists := c.subscriptions[path]
+	if exists {
+		err := fmt.Errorf("%w: %q channel is already subscribed", extensions.ErrAlreadySubscribedChannel, path)
+		c.logger.Error(ctx, err.Error())
+		return err
+	}
+
+	// Subscribe to broker channel
+	sub, err := c.broker.Subscribe(ctx, path)
+	if err != nil {
+		c.logger.Error(ctx, err.Error())
+		return err
+	}
+	c.logger.Info(ctx, "Subscribed to channel")
+
+	// Handle a received message through middlewares and subscription function
+	handle := func(ctx context.Context, brokerMsg extensions.BrokerMessage) {
+		// Execute middlewares before handling the message
+		if err := c.executeMiddlewares(ctx, &brokerMsg, func(ctx context.Context) error {
+			// Process message
+			msg, err := newInvalidationsMessageFromBrokerMessage(brokerMsg)
+			if err != nil {
+				return err
+			}
+
+			// Execute the subscription function
+			return fn(ctx, msg)
+		}); err != nil {
+			c.logger.Error(ctx, err.Error())
+
+			// Terminate the message if it can't be handled, or negatively
+			// acknowledge it to get it redelivered
+			nack := brokerMsg.Nack
+			if errors.Is(err, extensions.ErrUnrecoverable) {
+				nack = brokerMsg.Term
+			}
+			if err := nack(); err != nil {
+				c.logger.Error(ctx, err.Error())
+			}
+			return
+		}
+
+		// Acknowledge the message as it has been successfully handled
+		if err := brokerMsg.Ack(); err != nil {
+			c.logger.Error(ctx, err.Error())
+		}
+	}
+
+	// Asynchronously listen to new messages and pass them to app subscriber
+	c.tracker.StartSubscription()
+	go func() {
+		// Handle messages with workers, and wait for them before leaving
+		workers := extensions.NewWorkerPool(c.concurrency)
+		defer workers.Close()
+		defer c.tracker.EndSubscription()
+
+		for {
+			// Wait for next message, or drop the remaining ones on shutdown
+			var brokerMsg extensions.BrokerMessage
+			var open bool
+			select {
+			case brokerMsg, open = <-sub.MessagesChannel():
+			case <-c.tracker.Dropping():
+				for {
+					select {
+					case brokerMsg, open := <-sub.MessagesChannel():
+						if open {
+							c.dropMessage(ctx, brokerMsg)
+							continue
+						}
+					default:
+					}
+					return
+				}
+			}
+
+			// If subscription is closed and there is no more message
+			// (i.e. uninitialized message), then exit the function
+			if !open && brokerMsg.IsUninitialized() {
+				return
+			}
+
+			// Set broker message to context
+			msgCtx := context.WithValue(ctx, extensions.ContextKeyIsBrokerMessage, brokerMsg.String())
+
+			// Get the ordering key of the message
+			var key string
+			if c.orderingKey != nil {
+				key = c.orderingKey(msgCtx, brokerMsg)
+			}
+
+			// Handle the message on a worker, in order with messages with the same key
+			c.tracker.Accept()
+			if !workers.SubmitUntil(c.tracker.Dropping(), key, func() {
+				defer c.tracker.Done()
+				handle(msgCtx, brokerMsg)
+			}) {
+				c.tracker.Done()
+				c.dropMessage(msgCtx, brokerMsg)
+			}
+		}
+	}()
+
+	// Add the cancel channel to the inside map
+	c.subscriptions[path] = sub
+
+	return nil
+}
+
+// UnsubscribeInvalidations will unsubscribe messages from 'invalidations' channel.
+// A timeout can be set in context to avoid blocking operation, if needed.
+func (c *AppController) UnsubscribeInvalidations(ctx context.Context) {
+	// Get channel path
+	path := "invalidations"
+
+	// Check if there subscribers for this channel and remove it from the
+	// subscribers, so it is cancelled only once
+	c.subscriptionsMutex.Lock()
+	sub, exists := c.subscriptions[path]
+	delete(c.subscriptions, path)
+	c.subscriptionsMutex.Unlock()
+	if !exists {
+		return
+	}
+
+	// Set context
+	ctx = addAppContextValues(ctx, path)
+
+	// Stop the subscription
+	sub.Cancel(ctx)
+
+	c.logger.Info(ctx, "Unsubscribed from channel")
+} // SubscribeOrders will subscribe to new messages from 'orders' channel.
+// Callback function 'fn' will be called each time a new message is received.
+// The message will be acknowledged if it returns no error, or negatively
+// acknowledged to be redelivered otherwise (if supported by the broker).
+func (c *AppController) SubscribeOrders(ctx context.Context, fn func(ctx context.Context, msg OrdersMessage) error) error {
+	// Get channel path
+	path := "orders"
+
+	// Set context
+	ctx = addAppContextValues(ctx, path)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsDirection, "reception")
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsAMQPChannelBinding, extensions.AMQPChannelBinding{
+		Is: "queue",
+		Queue: extensions.AMQPQueue{
+			Name:       "orders-queue",
+			Durable:    true,
+			Exclusive:  false,
+			AutoDelete: false,
+		},
+	})
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTOperationBinding, nil)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTMessageBinding, nil)
+
+	// Lock the subscriptions until this one is added, to avoid a concurrent
+	// subscription on the same channel
+	c.subscriptionsMutex.Lock()
+	defer c.subscriptionsMutex.Unlock()
+
+	// Check if there is already a subscription
+	_, exists := c.subscriptions[path]
+	if exists {
+		err := fmt.Errorf("%w: %q channel is already subscribed", extensions.ErrAlreadySubscribedChannel, path)
+		c.logger.Error(ctx, err.Error())
+		return err
+	}
+
+	// Subscribe to broker channel
+	sub, err := c.broker.Subscribe(ctx, path)
+	if err != nil {
+		c.logger.Error(ctx, err.Error())
+		return err
+	}
+	c.logger.Info(ctx, "Subscribed to channel")
+
+	// Handle a received message through middlewares and subscription function
+	handle := func(ctx context.Context, brokerMsg extensions.BrokerMessage) {
+		// Execute middlewares before handling the message
+		if err := c.executeMiddlewares(ctx, &brokerMsg, func(ctx context.Context) error {
+			// Process message
+			msg, err := newOrdersMessageFromBrokerMessage(brokerMsg)
+			if err != nil {
+				return err
+			}
+
+			// Execute the subscription function
+			return fn(ctx, msg)
+		}); err != nil {
+			c.logger.Error(ctx, err.Error())
+
+			// Terminate the message if it can't be handled, or negatively
+			// acknowledge it to get it redelivered
+			nack := brokerMsg.Nack
+			if errors.Is(err, extensions.ErrUnrecoverable) {
+				nack = brokerMsg.Term
+			}
+			if err := nack(); err != nil {
+				c.logger.Error(ctx, err.Error())
+			}
+			return
+		}
+
+		// Acknowledge the message as it has been successfully handled
+		if err := brokerMsg.Ack(); err != nil {
+			c.logger.Error(ctx, err.Error())
+		}
+	}
+
+	// Asynchronously listen to new messages and pass them to app subscriber
+	c.tracker.StartSubscription()
+	go func() {
+		// Handle messages with workers, and wait for them before leaving
+		workers := extensions.NewWorkerPool(c.concurrency)
+		defer workers.Close()
+		defer c.tracker.EndSubscription()
+
+		for {
+			// Wait for next message, or drop the remaining ones on shutdown
+			var brokerMsg extensions.BrokerMessage
+			var open bool
+			select {
+			case brokerMsg, open = <-sub.MessagesChannel():
+			case <-c.tracker.Dropping():
+				for {
+					select {
+					case brokerMsg, open := <-sub.MessagesChannel():
+						if open {
+							c.dropMessage(ctx, brokerMsg)
+							continue
+						}
+					default:
+					}
+					return
+				}
+			}
+
+			// If subscription is closed and there is no more message
+			// (i.e. uninitialized message), then exit the function
+			if !open && brokerMsg.IsUninitialized() {
+				return
+			}
+
+			// Set broker message to context
+			msgCtx := context.WithValue(ctx, extensions.ContextKeyIsBrokerMessage, brokerMsg.String())
+
+			// Get the ordering key of the message
+			var key string
+			if c.orderingKey != nil {
+				key = c.orderingKey(msgCtx, brokerMsg)
+			}
+
+			// Handle the message on a worker, in order with messages with the same key
+			c.tracker.Accept()
+			if !workers.SubmitUntil(c.tracker.Dropping(), key, func() {
+				defer c.tracker.Done()
+				handle(msgCtx, brokerMsg)
+			}) {
+				c.tracker.Done()
+				c.dropMessage(msgCtx, brokerMsg)
+			}
+		}
+	}()
+
+	// Add the cancel channel to the inside map
+	c.subscriptions[path] = sub
+
+	return nil
+}
+
+// UnsubscribeOrders will unsubscribe messages from 'orders' channel.
+// A timeout can be set in context to avoid blocking operation, if needed.
+func (c *AppController) UnsubscribeOrders(ctx context.Context) {
+	// Get channel path
+	path := "orders"
+
+	// Check if there subscribers for this channel and remove it from the
+	// subscribers, so it is cancelled only once
+	c.subscriptionsMutex.Lock()
+	sub, exists := c.subscriptions[path]
+	delete(c.subscriptions, path)
+	c.subscriptionsMutex.Unlock()
+	if !exists {
+		return
+	}
+
+	// Set context
+	ctx = addAppContextValues(ctx, path)
+
+	// Stop the subscription
+	sub.Cancel(ctx)
+
+	c.logger.Info(ctx, "Unsubscribed from channel")
+} // SubscribeSensorsTemperature will subscribe to new messages from 'sensors/temperature' channel.
+// Callback function 'fn' will be called each time a new message is received.
+// The message will be acknowledged if it returns no error, or negatively
+// acknowledged to be redelivered otherwise (if supported by the broker).
+func (c *AppController) SubscribeSensorsTemperature(ctx context.Context, fn func(ctx context.Context, msg SensorsTemperatureMessage) error) error {
+	// Get channel path
+	path := "sensors/temperature"
+
+	// Set context
+	ctx = addAppContextValues(ctx, path)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsDirection, "reception")
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsAMQPChannelBinding, extensions.AMQPChannelBinding{})
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTOperationBinding, extensions.MQTTOperationBinding{
+		QoS:    2,
+		Retain: true,
+	})
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTMessageBinding, extensions.MQTTMessageBinding{
+		PayloadFormatIndicator: 1,
+		ContentType:            "text/plain",
+	})
+
+	// Lock the subscriptions until this one is added, to avoid a concurrent
+	// subscription on the same channel
+	c.subscriptionsMutex.Lock()
+	defer c.subscriptionsMutex.Unlock()
+
+	// Check if there is already a subscription
+	_, exists := c.subscriptions[path]
+	if exists {
+		err := fmt.Errorf("%w: %q channel is already subscribed", extensions.ErrAlreadySubscribedChannel, path)
+		c.logger.Error(ctx, err.Error())
+		return err
+	}
+
+	// Subscribe to broker channel
+	sub, err := c.broker.Subscribe(ctx, path)
+	if err != nil {
+		c.logger.Error(ctx, err.Error())
+		return err
+	}
+	c.logger.Info(ctx, "Subscribed to channel")
+
+	// Handle a received message through middlewares and subscription function
+	handle := func(ctx context.Context, brokerMsg extensions.BrokerMessage) {
+		// Execute middlewares before handling the message
+		if err := c.executeMiddlewares(ctx, &brokerMsg, func(ctx context.Context) error {
+			// Process message
+			msg, err := newSensorsTemperatureMessageFromBrokerMessage(brokerMsg)
+			if err != nil {
+				return err
+			}
+
+			// Execute the subscription function
+			return fn(ctx, msg)
+		}); err != nil {
+			c.logger.Error(ctx, err.Error())
+
+			// Terminate the message if it can't be handled, or negatively
+			// acknowledge it to get it redelivered
+			nack := brokerMsg.Nack
+			if errors.Is(err, extensions.ErrUnrecoverable) {
+				nack = brokerMsg.Term
+			}
+			if err := nack(); err != nil {
+				c.logger.Error(ctx, err.Error())
+			}
+			return
+		}
+
+		// Acknowledge the message as it has been successfully handled
+		if err := brokerMsg.Ack(); err != nil {
+			c.logger.Error(ctx, err.Error())
+		}
+	}
+
+	// Asynchronously listen to new messages and pass them to app subscriber
+	c.tracker.StartSubscription()
+	go func() {
+		// Handle messages with workers, and wait for them before leaving
+		workers := extensions.NewWorkerPool(c.concurrency)
+		defer workers.Close()
+		defer c.tracker.EndSubscription()
+
+		for {
+			// Wait for next message, or drop the remaining ones on shutdown
+			var brokerMsg extensions.BrokerMessage
+			var open bool
+			select {
+			case brokerMsg, open = <-sub.MessagesChannel():
+			case <-c.tracker.Dropping():
+				for {
+					select {
+					case brokerMsg, open := <-sub.MessagesChannel():
+						if open {
+							c.dropMessage(ctx, brokerMsg)
+							continue
+						}
+					default:
+					}
+					return
+				}
+			}
+
+			// If subscription is closed and there is no more message
+			// (i.e. uninitialized message), then exit the function
+			if !open && brokerMsg.IsUninitialized() {
+				return
+			}
+
+			// Set broker message to context
+			msgCtx := context.WithValue(ctx, extensions.ContextKeyIsBrokerMessage, brokerMsg.String())
+
+			// Get the ordering key of the message
+			var key string
+			if c.orderingKey != nil {
+				key = c.orderingKey(msgCtx, brokerMsg)
+			}
+
+			// Handle the message on a worker, in order with messages with the same key
+			c.tracker.Accept()
+			if !workers.SubmitUntil(c.tracker.Dropping(), key, func() {
+				defer c.tracker.Done()
+				handle(msgCtx, brokerMsg)
+			}) {
+				c.tracker.Done()
+				c.dropMessage(msgCtx, brokerMsg)
+			}
+		}
+	}()
+
+	// Add the cancel channel to the inside map
+	c.subscriptions[path] = sub
+
+	return nil
+}
+
+// UnsubscribeSensorsTemperature will unsubscribe messages from 'sensors/temperature' channel.
+// A timeout can be set in context to avoid blocking operation, if needed.
+func (c *AppController) UnsubscribeSensorsTemperature(ctx context.Context) {
+	// Get channel path
+	path := "sensors/temperature"
+
+	// Check if there subscribers for this channel and remove it from the
+	// subscribers, so it is cancelled only once
+	c.subscriptionsMutex.Lock()
+	sub, exists := c.subscriptions[path]
+	delete(c.subscriptions, path)
+	c.subscriptionsMutex.Unlock()
+	if !exists {
+		return
+	}
+
+	// Set context
+	ctx = addAppContextValues(ctx, path)
+
+	// Stop the subscription
+	sub.Cancel(ctx)
+
+	c.logger.Info(ctx, "Unsubscribed from channel")
+} // SubscribeSensorsSensorIdTemperature will subscribe to new messages from 'sensors/{sensorId}/temperature' channel.
+// Callback function 'fn' will be called each time a new message is received.
+// The message will be acknowledged if it returns no error, or negatively
+// acknowledged to be redelivered otherwise (if supported by the broker).
+func (c *AppController) SubscribeSensorsSensorIdTemperature(ctx context.Context, params SensorsTemperatureParameters, fn func(ctx context.Context, msg SensorsSensorIdTemperatureMessage) error) error {
+	// Get channel path
+	path := fmt.Sprintf("sensors/%v/temperature", params.SensorId)
+
+	// Set context
+	ctx = addAppContextValues(ctx, path)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsDirection, "reception")
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsAMQPChannelBinding, extensions.AMQPChannelBinding{})
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTOperationBinding, extensions.MQTTOperationBinding{
+		QoS:    1,
+		Retain: false,
+	})
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTMessageBinding, nil)
+
+	// Lock the subscriptions until this one is added, to avoid a concurrent
+	// subscription on the same channel
+	c.subscriptionsMutex.Lock()
+	defer c.subscriptionsMutex.Unlock()
+
+	// Check if there is already a subscription
+	_, exists := c.subscriptions[path]
+	if exists {
+		err := fmt.Errorf("%w: %q channel is already subscribed", extensions.ErrAlreadySubscribedChannel, path)
+		c.logger.Error(ctx, err.Error())
+		return err
+	}
+
+	// Subscribe to broker channel
+	sub, err := c.broker.Subscribe(ctx, path)
+	if err != nil {
+		c.logger.Error(ctx, err.Error())
+		return err
+	}
+	c.logger.Info(ctx, "Subscribed to channel")
+
+	// Handle a received message through middlewares and subscription function
+	handle := func(ctx context.Context, brokerMsg extensions.BrokerMessage) {
+		// Execute middlewares before handling the message
+		if err := c.executeMiddlewares(ctx, &brokerMsg, func(ctx context.Context) error {
+			// Process message
+			msg, err := newSensorsSensorIdTemperatureMessageFromBrokerMessage(brokerMsg)
+			if err != nil {
+				return err
+			}
+
+			// Execute the subscription function
+			return fn(ctx, msg)
+		}); err != nil {
+			c.logger.Error(ctx, err.Error())
+
+			// Terminate the message if it can't be handled, or negatively
+			// acknowledge it to get it redelivered
+			nack := brokerMsg.Nack
+			if errors.Is(err, extensions.ErrUnrecoverable) {
+				nack = brokerMsg.Term
+			}
+			if err := nack(); err != nil {
+				c.logger.Error(ctx, err.Error())
+			}
+			return
+		}
+
+		// Acknowledge the message as it has been successfully handled
+		if err := brokerMsg.Ack(); err != nil {
+			c.logger.Error(ctx, err.Error())
+		}
+	}
+
+	// Asynchronously listen to new messages and pass them to app subscriber
+	c.tracker.StartSubscription()
+	go func() {
+		// Handle messages with workers, and wait for them before leaving
+		workers := extensions.NewWorkerPool(c.concurrency)
+		defer workers.Close()
+		defer c.tracker.EndSubscription()
+
+		for {
+			// Wait for next message, or drop the remaining ones on shutdown
+			var brokerMsg extensions.BrokerMessage
+			var open bool
+			select {
+			case brokerMsg, open = <-sub.MessagesChannel():
+			case <-c.tracker.Dropping():
+				for {
+					select {
+					case brokerMsg, open := <-sub.MessagesChannel():
+						if open {
+							c.dropMessage(ctx, brokerMsg)
+							continue
+						}
+					default:
+					}
+					return
+				}
+			}
+
+			// If subscription is closed and there is no more message
+			// (i.e. uninitialized message), then exit the function
+			if !open && brokerMsg.IsUninitialized() {
+				return
+			}
+
+			// Set broker message to context
+			msgCtx := context.WithValue(ctx, extensions.ContextKeyIsBrokerMessage, brokerMsg.String())
+
+			// Get the ordering key of the message
+			var key string
+			if c.orderingKey != nil {
+				key = c.orderingKey(msgCtx, brokerMsg)
+			}
+
+			// Handle the message on a worker, in order with messages with the same key
+			c.tracker.Accept()
+			if !workers.SubmitUntil(c.tracker.Dropping(), key, func() {
+				defer c.tracker.Done()
+				handle(msgCtx, brokerMsg)
+			}) {
+				c.tracker.Done()
+				c.dropMessage(msgCtx, brokerMsg)
+			}
+		}
+	}()
+
+	// Add the cancel channel to the inside map
+	c.subscriptions[path] = sub
+
+	return nil
+}
+
+// UnsubscribeSensorsSensorIdTemperature will unsubscribe messages from 'sensors/{sensorId}/temperature' channel.
+// A timeout can be set in context to avoid blocking operation, if needed.
+func (c *AppController) UnsubscribeSensorsSensorIdTemperature(ctx context.Context, params SensorsTemperatureParameters) {
+	// Get channel path
+	path := fmt.Sprintf("sensors/%v/temperature", params.SensorId)
+
+	// Check if there subscribers for this channel and remove it from the
+	// subscribers, so it is cancelled only once
+	c.subscriptionsMutex.Lock()
+	sub, exists := c.subscriptions[path]
+	delete(c.subscriptions, path)
+	c.subscriptionsMutex.Unlock()
+	if !exists {
+		return
+	}
+
+	// Set context
+	ctx = addAppContextValues(ctx, path)
+
+	// Stop the subscription
+	sub.Cancel(ctx)
+
+	c.logger.Info(ctx, "Unsubscribed from channel")
+} // SubscribeUserSignup will subscribe to new messages from 'user.signup' channel.
+// Callback function 'fn' will be called each time a new message is received.
+// The message will be acknowledged if it returns no error, or negatively
+// acknowledged to be redelivered otherwise (if supported by the broker).
+func (c *AppController) SubscribeUserSignup(ctx context.Context, fn func(ctx context.Context, msg UserSignupMessage) error) error {
+	// Get channel path
+	path := "user.signup"
+
+	// Set context
+	ctx = addAppContextValues(ctx, path)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsDirection, "reception")
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsAMQPChannelBinding, extensions.AMQPChannelBinding{
+		Is: "routingKey",
+		Exchange: extensions.AMQPExchange{
+			Name:       "users",
+			Type:       "topic",
+			Durable:    true,
+			AutoDelete: false,
+		},
+	})
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTOperationBinding, nil)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTMessageBinding, nil)
+
+	// Lock the subscriptions until this one is added, to avoid a concurrent
+	// subscription on the same channel
+	c.subscriptionsMutex.Lock()
+	defer c.subscriptionsMutex.Unlock()
+
+	// Check if there is already a subscription
+	_, exists := c.subscriptions[path]
+	if exists {
+		err := fmt.Errorf("%w: %q channel is already subscribed", extensions.ErrAlreadySubscribedChannel, path)
+		c.logger.Error(ctx, err.Error())
+		return err
+	}
+
+	// Subscribe to broker channel
+	sub, err := c.broker.Subscribe(ctx, path)
+	if err != nil {
+		c.logger.Error(ctx, err.Error())
+		return err
+	}
+	c.logger.Info(ctx, "Subscribed to channel")
+
+	// Handle a received message through middlewares and subscription function
+	handle := func(ctx context.Context, brokerMsg extensions.BrokerMessage) {
+		// Execute middlewares before handling the message
+		if err := c.executeMiddlewares(ctx, &brokerMsg, func(ctx context.Context) error {
+			// Process message
+			msg, err := newUserSignupMessageFromBrokerMessage(brokerMsg)
+			if err != nil {
+				return err
+			}
+
+			// Execute the subscription function
+			return fn(ctx, msg)
+		}); err != nil {
+			c.logger.Error(ctx, err.Error())
+
+			// Terminate the message if it can't be handled, or negatively
+			// acknowledge it to get it redelivered
+			nack := brokerMsg.Nack
+			if errors.Is(err, extensions.ErrUnrecoverable) {
+				nack = brokerMsg.Term
+			}
+			if err := nack(); err != nil {
+				c.logger.Error(ctx, err.Error())
+			}
+			return
+		}
+
+		// Acknowledge the message as it has been successfully handled
+		if err := brokerMsg.Ack(); err != nil {
+			c.logger.Error(ctx, err.Error())
+		}
+	}
+
+	// Asynchronously listen to new messages and pass them to app subscriber
+	c.tracker.StartSubscription()
+	go func() {
+		// Handle messages with workers, and wait for them before leaving
+		workers := extensions.NewWorkerPool(c.concurrency)
+		defer workers.Close()
+		defer c.tracker.EndSubscription()
+
+		for {
+			// Wait for next message, or drop the remaining ones on shutdown
+			var brokerMsg extensions.BrokerMessage
+			var open bool
+			select {
+			case brokerMsg, open = <-sub.MessagesChannel():
+			case <-c.tracker.Dropping():
+				for {
+					select {
+					case brokerMsg, open := <-sub.MessagesChannel():
+						if open {
+							c.dropMessage(ctx, brokerMsg)
+							continue
+						}
+					default:
+					}
+					return
+				}
+			}
+
+			// If subscription is closed and there is no more message
+			// (i.e. uninitialized message), then exit the function
+			if !open && brokerMsg.IsUninitialized() {
+				return
+			}
+
+			// Set broker message to context
+			msgCtx := context.WithValue(ctx, extensions.ContextKeyIsBrokerMessage, brokerMsg.String())
+
+			// Get the ordering key of the message
+			var key string
+			if c.orderingKey != nil {
+				key = c.orderingKey(msgCtx, brokerMsg)
+			}
+
+			// Handle the message on a worker, in order with messages with the same key
+			c.tracker.Accept()
+			if !workers.SubmitUntil(c.tracker.Dropping(), key, func() {
+				defer c.tracker.Done()
+				handle(msgCtx, brokerMsg)
+			}) {
+				c.tracker.Done()
+				c.dropMessage(msgCtx, brokerMsg)
+			}
+		}
+	}()
+
+	// Add the cancel channel to the inside map
+	c.subscriptions[path] = sub
+
+	return nil
+}
+
+// UnsubscribeUserSignup will unsubscribe messages from 'user.signup' channel.
+// A timeout can be set in context to avoid blocking operation, if needed.
+func (c *AppController) UnsubscribeUserSignup(ctx context.Context) {
+	// Get channel path
+	path := "user.signup"
+
+	// Check if there subscribers for this channel and remove it from the
+	// subscribers, so it is cancelled only once
+	c.subscriptionsMutex.Lock()
+	sub, exists := c.subscriptions[path]
+	delete(c.subscriptions, path)
+	c.subscriptionsMutex.Unlock()
+	if !exists {
+		return
+	}
+
+	// Set context
+	ctx = addAppContextValues(ctx, path)
+
+	// Stop the subscription
+	sub.Cancel(ctx)
+
+	c.logger.Info(ctx, "Unsubscribed from channel")
+}
+
+// PublishNotifications will publish messages to 'notifications' channel
+func (c *AppController) PublishNotifications(ctx context.Context, msg NotificationsMessage) error {
+	// Get channel path
+	path := "notifications"
+
+	// Set context
+	ctx = addAppContextValues(ctx, path)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsDirection, "publication")
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsAMQPChannelBinding, extensions.AMQPChannelBinding{})
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTOperationBinding, nil)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTMessageBinding, nil)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsCorrelationID, nil)
+
+	// Convert to BrokerMessage
+	brokerMsg, err := msg.toBrokerMessage()
+	if err != nil {
+		return err
+	}
+
+	// Set broker message to context
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsBrokerMessage, brokerMsg.String())
+
+	// Publish the message on event-broker through middlewares
+	return c.executeMiddlewares(ctx, &brokerMsg, func(ctx context.Context) error {
+		return c.broker.Publish(ctx, path, brokerMsg)
+	})
+}
+
+// UserSubscriber represents all handlers that are expecting messages for User
+type UserSubscriber interface {
+	// Notifications subscribes to messages placed on the 'notifications' channel.
+	// If an error is returned, the message will be negatively acknowledged.
+	Notifications(ctx context.Context, msg NotificationsMessage) error
+}
+
+// UserControllerInterface is the interface of UserController, that
+// can be used to replace it in tests (i.e. with UserControllerMock).
+type UserControllerInterface interface {
+	Close(ctx context.Context)
+	Shutdown(ctx context.Context) error
+	SubscribeAll(ctx context.Context, as UserSubscriber) error
+	UnsubscribeAll(ctx context.Context)
+	SubscribeNotifications(ctx context.Context, fn func(ctx context.Context, msg NotificationsMessage) error) error
+	UnsubscribeNotifications(ctx context.Context)
+	PublishInvalidations(ctx context.Context, msg InvalidationsMessage) error
+	PublishOrders(ctx context.Context, msg OrdersMessage) error
+	PublishSensorsTemperature(ctx context.Context, msg SensorsTemperatureMessage) error
+	PublishSensorsSensorIdTemperature(ctx context.Context, params SensorsTemperatureParameters, msg SensorsSensorIdTemperatureMessage) error
+	PublishUserSignup(ctx context.Context, msg UserSignupMessage) error
+}
+
+var _ UserControllerInterface = (*UserController)(nil)
+
+// UserController is the structure that provides publishing capabilities to the
+// developer and and connect the broker with the User
+type UserController struct {
+	controller
+}
+
+// NewUserController links the User to the broker
+func NewUserController(bc extensions.BrokerController, options ...ControllerOption) (*UserController, error) {
+	// Check if broker controller has been provided
+	if bc == nil {
+		return nil, extensions.ErrNilBrokerController
+	}
+
+	// Create default controller
+	controller := controller{
+		broker:             bc,
+		subscriptions:      make(map[string]extensions.BrokerChannelSubscription),
+		subscriptionsMutex: &sync.Mutex{},
+		replies:            make(map[string]*extensions.ReplyDispatcher),
+		logger:             extensions.DummyLogger{},
+		middlewares:        make([]extensions.Middleware, 0),
+		concurrency:        1,
+		tracker:            extensions.NewHandlersTracker(),
+	}
+
+	// Apply options
+	for _, option := range options {
+		option(&controller)
+	}
+
+	return &UserController{controller: controller}, nil
+}
+
+func (c UserController) wrapMiddlewares(
+	middlewares []extensions.Middleware,
+	callback extensions.NextMiddleware,
+) func(ctx context.Context, msg *extensions.BrokerMessage) error {
+	// If there is no more middleware
+	if len(middlewares) == 0 {
+		return func(ctx context.Context, msg *extensions.BrokerMessage) error {
+			// Call the callback if it exists
+			if callback != nil {
+				return callback(ctx)
+			}
+
+			return nil
+		}
+	}
+
+	// Get the next function to call from next middlewares or callback
+	next := c.wrapMiddlewares(middlewares[1:], callback)
+
+	// Wrap middleware into a check function that will call execute the middleware
+	// and call the next wrapped middleware if it has not been called by the
+	// middleware. Note: the middleware can call it several times (i.e. to retry).
+	return func(ctx context.Context, msg *extensions.BrokerMessage) error {
+		// Create the next call with the context and the message
+		var called bool
+		nextWithArgs := func(ctx context.Context) error {
+			called = true
+			return next(ctx, msg)
+		}
+
+		// Call the middleware
+		if err := middlewares[0](ctx, msg, nextWithArgs); err != nil {
+			return err
+		}
+
+		// If next has already been called in middleware, it should not be executed again
+		if called {
+			return nil
+		}
+
+		return nextWithArgs(ctx)
+	}
+}
+
+func (c UserController) executeMiddlewares(ctx context.Context, msg *extensions.BrokerMessage, callback extensions.NextMiddleware) error {
+	// Wrap middleware to have 'next' function when calling them
+	wrapped := c.wrapMiddlewares(c.middlewares, callback)
+
+	// Execute wrapped middlewares
+	return wrapped(ctx, msg)
+}
+
+// dropMessage negatively acknowledges a received message that will not be
+// handled because of the controller shutdown.
+func (c UserController) dropMessage(ctx context.Context, msg extensions.BrokerMessage) {
+	c.logger.Warning(ctx, "Message dropped on shutdown")
+	if err := msg.Nack(); err != nil {
+		c.logger.Error(ctx, err.Error())
+	}
+	c.tracker.AddDropped()
+}
+
+func addUserContextValues(ctx context.Context, path string) context.Context {
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsVersion, "1.0.0")
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsProvider, "user")
+	return context.WithValue(ctx, extensions.ContextKeyIsChannel, path)
+}
+
+// Close will clean up any existing resources on the controller
+func (c *UserController) Close(ctx context.Context) {
+	// Unsubscribing remaining channels
+	c.UnsubscribeAll(ctx)
+
+	c.logger.Info(ctx, "Closed user controller")
+}
+
+// Shutdown will gracefully stop the controller: subscriptions stop receiving new
+// messages, then it waits for the already received messages to be handled and
+// flushes the messages published on the broker (if supported).
+//
+// If the context is done before every received message has been handled, the
+// remaining messages are dropped and an error wrapping extensions.ErrMessagesDropped
+// is returned with the number of messages that were not handled.
+func (c *UserController) Shutdown(ctx context.Context) error {
+	// Remove every subscription
+	c.subscriptionsMutex.Lock()
+	subscriptions := make([]extensions.BrokerChannelSubscription, 0, len(c.subscriptions))
+	for path, sub := range c.subscriptions {
+		subscriptions = append(subscriptions, sub)
+		delete(c.subscriptions, path)
+	}
+	c.subscriptionsMutex.Unlock()
+
+	// Stop receiving new messages
+	for _, sub := range subscriptions {
+		sub.Cancel(ctx)
+	}
+
+	// Wait for received messages to be handled
+	dropped := c.tracker.Wait(ctx)
+
+	// Flush published messages
+	if flusher, ok := c.broker.(extensions.BrokerFlusher); ok {
+		if err := flusher.Flush(ctx); err != nil {
+			c.logger.Error(ctx, err.Error())
+			return err
+		}
+	}
+
+	// Report dropped messages
+	if dropped > 0 {
+		err := fmt.Errorf("%w: %d message(s) not handled before shutdown", extensions.ErrMessagesDropped, dropped)
+		c.logger.Error(ctx, err.Error())
+		return err
+	}
+
+	c.logger.Info(ctx, "Shut down user controller")
+	return nil
+}
+
+// SubscribeAll will subscribe to channels without parameters on which the app is expecting messages.
+// For channels with parameters, they should be subscribed independently.
+func (c *UserController) SubscribeAll(ctx context.Context, as UserSubscriber) error {
+	if as == nil {
+		return extensions.ErrNilUserSubscriber
+	}
+
+	if err := c.SubscribeNotifications(ctx, as.Notifications); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UnsubscribeAll will unsubscribe all remaining subscribed channels
+func (c *UserController) UnsubscribeAll(ctx context.Context) {
+	c.UnsubscribeNotifications(ctx)
+}
+
+// SubscribeNotifications will subscribe to new messages from 'notifications' channel.
+//
+// Callback function 'fn' will be called each time a new message is received.
+// The message will be acknowledged if it returns no error, or negatively
+// acknowledged to be redelivered otherwise (if supported by the broker).
+func (c *UserController) SubscribeNotifications(ctx context.Context, fn func(ctx context.Context, msg NotificationsMessage) error) error {
+	// Get channel path
+	path := "notifications"
+
+	// Set context
+	ctx = addUserContextValues(ctx, path)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsDirection, "reception")
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsAMQPChannelBinding, extensions.AMQPChannelBinding{})
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTOperationBinding, nil)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTMessageBinding, nil)
+
+	// Lock the subscriptions until this one is added, to avoid a concurrent
+	// subscription on the same channel
+	c.subscriptionsMutex.Lock()
+	defer c.subscriptionsMutex.Unlock()
+
+	// Check if there is already a subscription
+	_, exists := c.subscriptions[path]
+	if exists {
+		err := fmt.Errorf("%w: %q channel is already subscribed", extensions.ErrAlreadySubscribedChannel, path)
+		c.logger.Error(ctx, err.Error())
+		return err
+	}
+
+	// Subscribe to broker channel
+	sub, err := c.broker.Subscribe(ctx, path)
+	if err != nil {
+		c.logger.Error(ctx, err.Error())
+		return err
+	}
+	c.logger.Info(ctx, "Subscribed to channel")
+
+	// Handle a received message through middlewares and subscription function
+	handle := func(ctx context.Context, brokerMsg extensions.BrokerMessage) {
+		// Execute middlewares before handling the message
+		if err := c.executeMiddlewares(ctx, &brokerMsg, func(ctx context.Context) error {
+			// Process message
+			msg, err := newNotificationsMessageFromBrokerMessage(brokerMsg)
+			if err != nil {
+				return err
+			}
+
+			// Execute the subscription function
+			return fn(ctx, msg)
+		}); err != nil {
+			c.logger.Error(ctx, err.Error())
+
+			// Terminate the message if it can't be handled, or negatively
+			// acknowledge it to get it redelivered
+			nack := brokerMsg.Nack
+			if errors.Is(err, extensions.ErrUnrecoverable) {
+				nack = brokerMsg.Term
+			}
+			if err := nack(); err != nil {
+				c.logger.Error(ctx, err.Error())
+			}
+			return
+		}
+
+		// Acknowledge the message as it has been successfully handled
+		if err := brokerMsg.Ack(); err != nil {
+			c.logger.Error(ctx, err.Error())
+		}
+	}
+
+	// Asynchronously listen to new messages and pass them to app subscriber
+	c.tracker.StartSubscription()
+	go func() {
+		// Handle messages with workers, and wait for them before leaving
+		workers := extensions.NewWorkerPool(c.concurrency)
+		defer workers.Close()
+		defer c.tracker.EndSubscription()
+
+		for {
+			// Wait for next message, or drop the remaining ones on shutdown
+			var brokerMsg extensions.BrokerMessage
+			var open bool
+			select {
+			case brokerMsg, open = <-sub.MessagesChannel():
+			case <-c.tracker.Dropping():
+				for {
+					select {
+					case brokerMsg, open := <-sub.MessagesChannel():
+						if open {
+							c.dropMessage(ctx, brokerMsg)
+							continue
+						}
+					default:
+					}
+					return
+				}
+			}
+
+			// If subscription is closed and there is no more message
+			// (i.e. uninitialized message), then exit the function
+			if !open && brokerMsg.IsUninitialized() {
+				return
+			}
+
+			// Set broker message to context
+			msgCtx := context.WithValue(ctx, extensions.ContextKeyIsBrokerMessage, brokerMsg.String())
+
+			// Get the ordering key of the message
+			var key string
+			if c.orderingKey != nil {
+				key = c.orderingKey(msgCtx, brokerMsg)
+			}
+
+			// Handle the message on a worker, in order with messages with the same key
+			c.tracker.Accept()
+			if !workers.SubmitUntil(c.tracker.Dropping(), key, func() {
+				defer c.tracker.Done()
+				handle(msgCtx, brokerMsg)
+			}) {
+				c.tracker.Done()
+				c.dropMessage(msgCtx, brokerMsg)
+			}
+		}
+	}()
+
+	// Add the cancel channel to the inside map
+	c.subscriptions[path] = sub
+
+	return nil
+}
+
+// UnsubscribeNotifications will unsubscribe messages from 'notifications' channel.
+// A timeout can be set in context to avoid blocking operation, if needed.
+func (c *UserController) UnsubscribeNotifications(ctx context.Context) {
+	// Get channel path
+	path := "notifications"
+
+	// Check if there subscribers for this channel and remove it from the
+	// subscribers, so it is cancelled only once
+	c.subscriptionsMutex.Lock()
+	sub, exists := c.subscriptions[path]
+	delete(c.subscriptions, path)
+	c.subscriptionsMutex.Unlock()
+	if !exists {
+		return
+	}
+
+	// Set context
+	ctx = addUserContextValues(ctx, path)
+
+	// Stop the subscription
+	sub.Cancel(ctx)
+
+	c.logger.Info(ctx, "Unsubscribed from channel")
+}
+
+// PublishInvalidations will publish messages to 'invalidations' channel
+func (c *UserController) PublishInvalidations(ctx context.Context, msg InvalidationsMessage) error {
+	// Get channel path
+	path := "invalidations"
+
+	// Set context
+	ctx = addUserContextValues(ctx, path)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsDirection, "publication")
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsAMQPChannelBinding, extensions.AMQPChannelBinding{})
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTOperationBinding, nil)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTMessageBinding, nil)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsCorrelationID, nil)
+
+	// Convert to BrokerMessage
+	brokerMsg, err := msg.toBrokerMessage()
+	if err != nil {
+		return err
+	}
+
+	// Set broker message to context
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsBrokerMessage, brokerMsg.String())
+
+	// Publish the message on event-broker through middlewares
+	return c.executeMiddlewares(ctx, &brokerMsg, func(ctx context.Context) error {
+		return c.broker.Publish(ctx, path, brokerMsg)
+	})
+}
+
+// PublishOrders will publish messages to 'orders' channel
+func (c *UserController) PublishOrders(ctx context.Context, msg OrdersMessage) error {
+	// Get channel path
+	path := "orders"
+
+	// Set context
+	ctx = addUserContextValues(ctx, path)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsDirection, "publication")
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsAMQPChannelBinding, extensions.AMQPChannelBinding{
+		Is: "queue",
+		Queue: extensions.AMQPQueue{
+			Name:       "orders-queue",
+			Durable:    true,
+			Exclusive:  false,
+			AutoDelete: false,
+		},
+	})
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTOperationBinding, nil)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTMessageBinding, nil)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsCorrelationID, nil)
+
+	// Convert to BrokerMessage
+	brokerMsg, err := msg.toBrokerMessage()
+	if err != nil {
+		return err
+	}
+
+	// Set broker message to context
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsBrokerMessage, brokerMsg.String())
+
+	// Publish the message on event-broker through middlewares
+	return c.executeMiddlewares(ctx, &brokerMsg, func(ctx context.Context) error {
+		return c.broker.Publish(ctx, path, brokerMsg)
+	})
+}
+
+// PublishSensorsTemperature will publish messages to 'sensors/temperature' channel
+func (c *UserController) PublishSensorsTemperature(ctx context.Context, msg SensorsTemperatureMessage) error {
+	// Get channel path
+	path := "sensors/temperature"
+
+	// Set context
+	ctx = addUserContextValues(ctx, path)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsDirection, "publication")
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsAMQPChannelBinding, extensions.AMQPChannelBinding{})
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTOperationBinding, extensions.MQTTOperationBinding{
+		QoS:    2,
+		Retain: true,
+	})
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTMessageBinding, extensions.MQTTMessageBinding{
+		PayloadFormatIndicator: 1,
+		ContentType:            "text/plain",
+	})
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsCorrelationID, nil)
+
+	// Convert to BrokerMessage
+	brokerMsg, err := msg.toBrokerMessage()
+	if err != nil {
+		return err
+	}
+
+	// Set broker message to context
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsBrokerMessage, brokerMsg.String())
+
+	// Publish the message on event-broker through middlewares
+	return c.executeMiddlewares(ctx, &brokerMsg, func(ctx context.Context) error {
+		return c.broker.Publish(ctx, path, brokerMsg)
+	})
+}
+
+// PublishSensorsSensorIdTemperature will publish messages to 'sensors/{sensorId}/temperature' channel
+func (c *UserController) PublishSensorsSensorIdTemperature(ctx context.Context, params SensorsTemperatureParameters, msg SensorsSensorIdTemperatureMessage) error {
+	// Get channel path
+	path := fmt.Sprintf("sensors/%v/temperature", params.SensorId)
+
+	// Set context
+	ctx = addUserContextValues(ctx, path)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsDirection, "publication")
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsAMQPChannelBinding, extensions.AMQPChannelBinding{})
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTOperationBinding, extensions.MQTTOperationBinding{
+		QoS:    1,
+		Retain: false,
+	})
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTMessageBinding, nil)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsCorrelationID, nil)
+
+	// Convert to BrokerMessage
+	brokerMsg, err := msg.toBrokerMessage()
+	if err != nil {
+		return err
+	}
+
+	// Set broker message to context
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsBrokerMessage, brokerMsg.String())
+
+	// Publish the message on event-broker through middlewares
+	return c.executeMiddlewares(ctx, &brokerMsg, func(ctx context.Context) error {
+		return c.broker.Publish(ctx, path, brokerMsg)
+	})
+}
+
+// PublishUserSignup will publish messages to 'user.signup' channel
+func (c *UserController) PublishUserSignup(ctx context.Context, msg UserSignupMessage) error {
+	// Get channel path
+	path := "user.signup"
+
+	// Set context
+	ctx = addUserContextValues(ctx, path)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsDirection, "publication")
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsAMQPChannelBinding, extensions.AMQPChannelBinding{
+		Is: "routingKey",
+		Exchange: extensions.AMQPExchange{
+			Name:       "users",
+			Type:       "topic",
+			Durable:    true,
+			AutoDelete: false,
+		},
+	})
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTOperationBinding, nil)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTMessageBinding, nil)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsCorrelationID, nil)
+
+	// Convert to BrokerMessage
+	brokerMsg, err := msg.toBrokerMessage()
+	if err != nil {
+		return err
+	}
+
+	// Set broker message to context
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsBrokerMessage, brokerMsg.String())
+
+	// Publish the message on event-broker through middlewares
+	return c.executeMiddlewares(ctx, &brokerMsg, func(ctx context.Context) error {
+		return c.broker.Publish(ctx, path, brokerMsg)
+	})
+}
+
+// AsyncAPIVersion is the version of the used AsyncAPI document
+const AsyncAPIVersion = "1.0.0"
+
+// controller is the controller that will be used to communicate with the broker
+// It will be used internally by AppController and UserController
+type controller struct {
+	// broker is the broker controller that will be used to communicate
+	broker extensions.BrokerController
+	// subscriptions is a map of all subscriptions
+	subscriptions map[string]extensions.BrokerChannelSubscription
+	// subscriptionsMutex protects the subscriptions and replies maps, as
+	// subscriptions can be made and cancelled from different goroutines
+	subscriptionsMutex *sync.Mutex
+	// replies is a map of the reply dispatchers, by reply channel
+	replies map[string]*extensions.ReplyDispatcher
+	// requestTimeout is the maximum duration to wait for a reply to a request
+	requestTimeout time.Duration
+	// logger is the logger that will be used² to log operations on controller
+	logger extensions.Logger
+	// middlewares are the middlewares that will be executed when sending or
+	// receiving messages
+	middlewares []extensions.Middleware
+	// concurrency is the maximum number of messages handled simultaneously by
+	// each subscription
+	concurrency int
+	// orderingKey is the function returning the key of received messages, in
+	// order to handle messages with the same key in order
+	orderingKey extensions.OrderingKey
+	// tracker tracks the received messages being handled, in order to wait for
+	// them on shutdown
+	tracker *extensions.HandlersTracker
+}
+
+// ControllerOption is the type of the options that can be passed
+// when creating a new Controller
+type ControllerOption func(controller *controller)
+
+// WithLogger attaches a logger to the controller
+func WithLogger(logger extensions.Logger) ControllerOption {
+	return func(controller *controller) {
+		controller.logger = logger
+	}
+}
+
+// WithMiddlewares attaches middlewares that will be executed when sending or receiving messages
+func WithMiddlewares(middlewares ...extensions.Middleware) ControllerOption {
+	return func(controller *controller) {
+		controller.middlewares = middlewares
+	}
+}
+
+// WithConcurrency sets the maximum number of messages handled simultaneously by
+// each subscription. Default is 1, meaning that messages are handled one by one.
+func WithConcurrency(workers int) ControllerOption {
+	return func(controller *controller) {
+		controller.concurrency = workers
+	}
+}
+
+// WithOrderingKey sets the function returning the key of received messages:
+// messages with the same key are handled in order, while messages with
+// different keys can be handled concurrently (see WithConcurrency).
+func WithOrderingKey(key extensions.OrderingKey) ControllerOption {
+	return func(controller *controller) {
+		controller.orderingKey = key
+	}
+}
+
+// WithRequestTimeout sets the maximum duration to wait for the reply of a
+// request. Default is 0, meaning that it waits until the context is done.
+func WithRequestTimeout(timeout time.Duration) ControllerOption {
+	return func(controller *controller) {
+		controller.requestTimeout = timeout
+	}
+}
+
+type MessageWithCorrelationID interface {
+	CorrelationID() string
+	SetCorrelationID(id string)
+}
+
+type Error struct {
+	Channel string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("channel %q: err %v", e.Channel, e.Err)
+}
+
+// InvalidationsMessage is the message expected for 'Invalidations' channel
+type InvalidationsMessage struct {
+	// Payload will be inserted in the message payload
+	Payload string
+}
+
+func NewInvalidationsMessage() InvalidationsMessage {
+	var msg InvalidationsMessage
+
+	return msg
+}
+
+// newInvalidationsMessageFromBrokerMessage will fill a new InvalidationsMessage with data from generic broker message
+func newInvalidationsMessageFromBrokerMessage(bMsg extensions.BrokerMessage) (InvalidationsMessage, error) {
+	var msg InvalidationsMessage
+
+	// Convert to string
+	payload := string(bMsg.Payload)
+	msg.Payload = payload // No need for type conversion to reference
+
+	// TODO: run checks on msg type
+
+	return msg, nil
+}
+
+// toBrokerMessage will generate a generic broker message from InvalidationsMessage data
+func (msg InvalidationsMessage) toBrokerMessage() (extensions.BrokerMessage, error) {
+	// TODO: implement checks on message
+
+	// Convert to []byte
+	payload := []byte(msg.Payload)
+
+	// There is no headers here
+	headers := make(map[string][]byte, 0)
+
+	return extensions.BrokerMessage{
+		Headers: headers,
+		Payload: payload,
+	}, nil
+}
+
+// NotificationsMessage is the message expected for 'Notifications' channel
+type NotificationsMessage struct {
+	// Payload will be inserted in the message payload
+	Payload string
+}
+
+func NewNotificationsMessage() NotificationsMessage {
+	var msg NotificationsMessage
+
+	return msg
+}
+
+// newNotificationsMessageFromBrokerMessage will fill a new NotificationsMessage with data from generic broker message
+func newNotificationsMessageFromBrokerMessage(bMsg extensions.BrokerMessage) (NotificationsMessage, error) {
+	var msg NotificationsMessage
+
+	// Convert to string
+	payload := string(bMsg.Payload)
+	msg.Payload = payload // No need for type conversion to reference
+
+	// TODO: run checks on msg type
+
+	return msg, nil
+}
+
+// toBrokerMessage will generate a generic broker message from NotificationsMessage data
+func (msg NotificationsMessage) toBrokerMessage() (extensions.BrokerMessage, error) {
+	// TODO: implement checks on message
+
+	// Convert to []byte
+	payload := []byte(msg.Payload)
+
+	// There is no headers here
+	headers := make(map[string][]byte, 0)
+
+	return extensions.BrokerMessage{
+		Headers: headers,
+		Payload: payload,
+	}, nil
+}
+
+// OrdersMessage is the message expected for 'Orders' channel
+type OrdersMessage struct {
+	// Payload will be inserted in the message payload
+	Payload string
+}
+
+func NewOrdersMessage() OrdersMessage {
+	var msg OrdersMessage
+
+	return msg
+}
+
+// newOrdersMessageFromBrokerMessage will fill a new OrdersMessage with data from generic broker message
+func newOrdersMessageFromBrokerMessage(bMsg extensions.BrokerMessage) (OrdersMessage, error) {
+	var msg OrdersMessage
+
+	// Convert to string
+	payload := string(bMsg.Payload)
+	msg.Payload = payload // No need for type conversion to reference
+
+	// TODO: run checks on msg type
+
+	return msg, nil
+}
+
+// toBrokerMessage will generate a generic broker message from OrdersMessage data
+func (msg OrdersMessage) toBrokerMessage() (extensions.BrokerMessage, error) {
+	// TODO: implement checks on message
+
+	// Convert to []byte
+	payload := []byte(msg.Payload)
+
+	// There is no headers here
+	headers := make(map[string][]byte, 0)
+
+	return extensions.BrokerMessage{
+		Headers: headers,
+		Payload: payload,
+	}, nil
+}
+
+// SensorsTemperatureMessage is the message expected for 'SensorsTemperature' channel
+type SensorsTemperatureMessage struct {
+	// Payload will be inserted in the message payload
+	Payload string
+}
+
+func NewSensorsTemperatureMessage() SensorsTemperatureMessage {
+	var msg SensorsTemperatureMessage
+
+	return msg
+}
+
+// newSensorsTemperatureMessageFromBrokerMessage will fill a new SensorsTemperatureMessage with data from generic broker message
+func newSensorsTemperatureMessageFromBrokerMessage(bMsg extensions.BrokerMessage) (SensorsTemperatureMessage, error) {
+	var msg SensorsTemperatureMessage
+
+	// Convert to string
+	payload := string(bMsg.Payload)
+	msg.Payload = payload // No need for type conversion to reference
+
+	// TODO: run checks on msg type
+
+	return msg, nil
+}
+
+// toBrokerMessage will generate a generic broker message from SensorsTemperatureMessage data
+func (msg SensorsTemperatureMessage) toBrokerMessage() (extensions.BrokerMessage, error) {
+	// TODO: implement checks on message
+
+	// Convert to []byte
+	payload := []byte(msg.Payload)
+
+	// There is no headers here
+	headers := make(map[string][]byte, 0)
+
+	return extensions.BrokerMessage{
+		Headers: headers,
+		Payload: payload,
+	}, nil
+}
+
+// SensorsTemperatureParameters represents SensorsSensorIdTemperature channel parameters
+type SensorsTemperatureParameters struct {
+	SensorId string
+}
+
+// SensorsSensorIdTemperatureMessage is the message expected for 'SensorsSensorIdTemperature' channel
+type SensorsSensorIdTemperatureMessage struct {
+	// Payload will be inserted in the message payload
+	Payload string
+}
+
+func NewSensorsSensorIdTemperatureMessage() SensorsSensorIdTemperatureMessage {
+	var msg SensorsSensorIdTemperatureMessage
+
+	return msg
+}
+
+// newSensorsSensorIdTemperatureMessageFromBrokerMessage will fill a new SensorsSensorIdTemperatureMessage with data from generic broker message
+func newSensorsSensorIdTemperatureMessageFromBrokerMessage(bMsg extensions.BrokerMessage) (SensorsSensorIdTemperatureMessage, error) {
+	var msg SensorsSensorIdTemperatureMessage
+
+	// Convert to string
+	payload := string(bMsg.Payload)
+	msg.Payload = payload // No need for type conversion to reference
+
+	// TODO: run checks on msg type
+
+	return msg, nil
+}
+
+// toBrokerMessage will generate a generic broker message from SensorsSensorIdTemperatureMessage data
+func (msg SensorsSensorIdTemperatureMessage) toBrokerMessage() (extensions.BrokerMessage, error) {
+	// TODO: implement checks on message
+
+	// Convert to []byte
+	payload := []byte(msg.Payload)
+
+	// There is no headers here
+	headers := make(map[string][]byte, 0)
+
+	return extensions.BrokerMessage{
+		Headers: headers,
+		Payload: payload,
+	}, nil
+}
+
+// UserSignupMessage is the message expected for 'UserSignup' channel
+type UserSignupMessage struct {
+	// Payload will be inserted in the message payload
+	Payload string
+}
+
+func NewUserSignupMessage() UserSignupMessage {
+	var msg UserSignupMessage
+
+	return msg
+}
+
+// newUserSignupMessageFromBrokerMessage will fill a new UserSignupMessage with data from generic broker message
+func newUserSignupMessageFromBrokerMessage(bMsg extensions.BrokerMessage) (UserSignupMessage, error) {
+	var msg UserSignupMessage
+
+	// Convert to string
+	payload := string(bMsg.Payload)
+	msg.Payload = payload // No need for type conversion to reference
+
+	// TODO: run checks on msg type
+
+	return msg, nil
+}
+
+// toBrokerMessage will generate a generic broker message from UserSignupMessage data
+func (msg UserSignupMessage) toBrokerMessage() (extensions.BrokerMessage, error) {
+	// TODO: implement checks on message
+
+	// Convert to []byte
+	payload := []byte(msg.Payload)
+
+	// There is no headers here
+	headers := make(map[string][]byte, 0)
+
+	return extensions.BrokerMessage{
+		Headers: headers,
+		Payload: payload,
+	}, nil
+}
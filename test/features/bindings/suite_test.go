@@ -0,0 +1,178 @@
+//go:generate go run ../../../cmd/asyncapi-codegen -p bindings -i ./asyncapi.yaml -o ./asyncapi.gen.go
+
+package bindings
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions/brokers/memory"
+)
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(Suite))
+}
+
+// bindingsBroker records the AMQP channel bindings, the MQTT bindings and the
+// correlation IDs from the context of publications and subscriptions.
+type bindingsBroker struct {
+	*memory.Controller
+
+	mutex               sync.Mutex
+	bindings            map[string]any
+	mqttBindings        map[string]any
+	mqttMessageBindings map[string]any
+	correlationIDs      map[string]any
+}
+
+func (b *bindingsBroker) record(ctx context.Context, channel string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.bindings[channel] = ctx.Value(extensions.ContextKeyIsAMQPChannelBinding)
+	b.mqttBindings[channel] = ctx.Value(extensions.ContextKeyIsMQTTOperationBinding)
+	b.mqttMessageBindings[channel] = ctx.Value(extensions.ContextKeyIsMQTTMessageBinding)
+	b.correlationIDs[channel] = ctx.Value(extensions.ContextKeyIsCorrelationID)
+}
+
+func (b *bindingsBroker) Publish(ctx context.Context, channel string, mw extensions.BrokerMessage) error {
+	b.record(ctx, "publication:"+channel)
+	return b.Controller.Publish(ctx, channel, mw)
+}
+
+func (b *bindingsBroker) Subscribe(ctx context.Context, channel string) (extensions.BrokerChannelSubscription, error) {
+	b.record(ctx, "reception:"+channel)
+	return b.Controller.Subscribe(ctx, channel)
+}
+
+type Suite struct {
+	broker *bindingsBroker
+	app    *AppController
+	user   *UserController
+	suite.Suite
+}
+
+func (suite *Suite) SetupTest() {
+	suite.broker = &bindingsBroker{
+		Controller:          memory.NewController(),
+		bindings:            make(map[string]any),
+		mqttBindings:        make(map[string]any),
+		mqttMessageBindings: make(map[string]any),
+		correlationIDs:      make(map[string]any),
+	}
+
+	app, err := NewAppController(suite.broker)
+	suite.Require().NoError(err)
+	suite.app = app
+
+	user, err := NewUserController(suite.broker)
+	suite.Require().NoError(err)
+	suite.user = user
+}
+
+func (suite *Suite) TearDownTest() {
+	suite.app.Close(context.Background())
+	suite.user.Close(context.Background())
+	suite.broker.Close()
+}
+
+func (suite *Suite) TestRoutingKeyBinding() {
+	suite.Require().NoError(suite.app.SubscribeUserSignup(context.Background(),
+		func(_ context.Context, _ UserSignupMessage) error { return nil }))
+	suite.Require().NoError(suite.user.PublishUserSignup(context.Background(), UserSignupMessage{Payload: "a"}))
+
+	expected := extensions.AMQPChannelBinding{
+		Is:       extensions.AMQPChannelIsRoutingKey,
+		Exchange: extensions.AMQPExchange{Name: "users", Type: "topic", Durable: true},
+	}
+	suite.Require().Equal(expected, suite.broker.bindings["reception:user.signup"])
+	suite.Require().Equal(expected, suite.broker.bindings["publication:user.signup"])
+}
+
+func (suite *Suite) TestQueueBinding() {
+	suite.Require().NoError(suite.app.SubscribeOrders(context.Background(),
+		func(_ context.Context, _ OrdersMessage) error { return nil }))
+	suite.Require().NoError(suite.user.PublishOrders(context.Background(), OrdersMessage{Payload: "a"}))
+
+	expected := extensions.AMQPChannelBinding{
+		Is:    extensions.AMQPChannelIsQueue,
+		Queue: extensions.AMQPQueue{Name: "orders-queue", Durable: true},
+	}
+	suite.Require().Equal(expected, suite.broker.bindings["reception:orders"])
+	suite.Require().Equal(expected, suite.broker.bindings["publication:orders"])
+}
+
+func (suite *Suite) TestNoBinding() {
+	suite.Require().NoError(suite.app.SubscribeInvalidations(context.Background(),
+		func(_ context.Context, _ InvalidationsMessage) error { return nil }))
+	suite.Require().NoError(suite.user.PublishInvalidations(context.Background(), InvalidationsMessage{Payload: "a"}))
+
+	suite.Require().Equal(extensions.AMQPChannelBinding{}, suite.broker.bindings["reception:invalidations"])
+	suite.Require().Equal(extensions.AMQPChannelBinding{}, suite.broker.bindings["publication:invalidations"])
+	suite.Require().Nil(suite.broker.mqttBindings["reception:invalidations"])
+	suite.Require().Nil(suite.broker.mqttBindings["publication:invalidations"])
+	suite.Require().Nil(suite.broker.mqttMessageBindings["reception:invalidations"])
+	suite.Require().Nil(suite.broker.mqttMessageBindings["publication:invalidations"])
+}
+
+func (suite *Suite) TestPublicationFromHandler() {
+	// Publish on a channel without binding from the handler of a channel with
+	// one, with a correlation ID in the context
+	published := make(chan error, 1)
+	ctx := context.WithValue(context.Background(), extensions.ContextKeyIsCorrelationID, "inherited")
+	suite.Require().NoError(suite.app.SubscribeOrders(ctx,
+		func(ctx context.Context, _ OrdersMessage) error {
+			published <- suite.app.PublishNotifications(ctx, NotificationsMessage{Payload: "a"})
+			return nil
+		}))
+	suite.Require().NoError(suite.user.PublishOrders(context.Background(), OrdersMessage{Payload: "a"}))
+	suite.Require().NoError(<-published)
+
+	// The binding of the received channel should not be used, nor the
+	// correlation ID as the published message has none
+	suite.Require().Equal(extensions.AMQPChannelBinding{}, suite.broker.bindings["publication:notifications"])
+	suite.Require().Nil(suite.broker.correlationIDs["publication:notifications"])
+}
+
+func (suite *Suite) TestPublicationFromMQTTHandler() {
+	// Publish on a channel without binding from the handler of a channel with
+	// MQTT bindings
+	published := make(chan error, 1)
+	suite.Require().NoError(suite.app.SubscribeSensorsTemperature(context.Background(),
+		func(ctx context.Context, _ SensorsTemperatureMessage) error {
+			published <- suite.app.PublishNotifications(ctx, NotificationsMessage{Payload: "a"})
+			return nil
+		}))
+	suite.Require().NoError(suite.user.PublishSensorsTemperature(context.Background(), SensorsTemperatureMessage{Payload: "21.5"}))
+	suite.Require().NoError(<-published)
+
+	// The bindings of the received channel should not be used
+	suite.Require().Nil(suite.broker.mqttBindings["publication:notifications"])
+	suite.Require().Nil(suite.broker.mqttMessageBindings["publication:notifications"])
+}
+
+func (suite *Suite) TestMQTTBindings() {
+	suite.Require().NoError(suite.app.SubscribeSensorsTemperature(context.Background(),
+		func(_ context.Context, _ SensorsTemperatureMessage) error { return nil }))
+	suite.Require().NoError(suite.user.PublishSensorsTemperature(context.Background(), SensorsTemperatureMessage{Payload: "21.5"}))
+
+	expected := extensions.MQTTOperationBinding{QoS: 2, Retain: true}
+	suite.Require().Equal(expected, suite.broker.mqttBindings["reception:sensors/temperature"])
+	suite.Require().Equal(expected, suite.broker.mqttBindings["publication:sensors/temperature"])
+
+	expectedMessage := extensions.MQTTMessageBinding{PayloadFormatIndicator: 1, ContentType: "text/plain"}
+	suite.Require().Equal(expectedMessage, suite.broker.mqttMessageBindings["reception:sensors/temperature"])
+	suite.Require().Equal(expectedMessage, suite.broker.mqttMessageBindings["publication:sensors/temperature"])
+}
+
+func (suite *Suite) TestMQTTWildcardParameter() {
+	// Subscribe to the channel of every sensor with a wildcard parameter
+	err := suite.app.SubscribeSensorsSensorIdTemperature(context.Background(), SensorsTemperatureParameters{SensorId: "+"},
+		func(_ context.Context, _ SensorsSensorIdTemperatureMessage) error { return nil })
+	suite.Require().NoError(err)
+
+	// The wildcard should be subscribed with the bindings of the channel
+	suite.Require().Equal(extensions.MQTTOperationBinding{QoS: 1}, suite.broker.mqttBindings["reception:sensors/+/temperature"])
+}
@@ -0,0 +1,116 @@
+//go:generate go run ../../../cmd/asyncapi-codegen -p concurrency -i ./asyncapi.yaml -o ./asyncapi.gen.go
+
+package concurrency
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions/brokers/memory"
+)
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(Suite))
+}
+
+type Suite struct {
+	broker *memory.Controller
+	user   *UserController
+	suite.Suite
+}
+
+func (suite *Suite) SetupTest() {
+	suite.broker = memory.NewController()
+
+	// Create user
+	user, err := NewUserController(suite.broker)
+	suite.Require().NoError(err)
+	suite.user = user
+}
+
+func (suite *Suite) TearDownTest() {
+	suite.user.Close(context.Background())
+	suite.broker.Close()
+}
+
+func (suite *Suite) publish(count, keys int) {
+	for i := 0; i < count; i++ {
+		msg := OrdersMessage{Payload: int64(i)}
+		msg.Headers.CorrelationId = strconv.Itoa(i % keys)
+		suite.Require().NoError(suite.user.PublishOrders(context.Background(), msg))
+	}
+}
+
+func (suite *Suite) TestConcurrencyWithOrderingKey() {
+	// Create app handling messages concurrently, in order by correlation ID
+	app, err := NewAppController(suite.broker,
+		WithConcurrency(4),
+		WithOrderingKey(extensions.OrderingKeyFromCorrelationID()))
+	suite.Require().NoError(err)
+	defer app.Close(context.Background())
+
+	// Track concurrency and received messages by key
+	var current, max int32
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	received := make(map[string][]int64)
+	err = app.SubscribeOrders(context.Background(), func(ctx context.Context, msg OrdersMessage) error {
+		defer wg.Done()
+
+		n := atomic.AddInt32(&current, 1)
+		defer atomic.AddInt32(&current, -1)
+		for m := atomic.LoadInt32(&max); n > m && !atomic.CompareAndSwapInt32(&max, m, n); {
+			m = atomic.LoadInt32(&max)
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		received[msg.Headers.CorrelationId] = append(received[msg.Headers.CorrelationId], msg.Payload)
+		return nil
+	})
+	suite.Require().NoError(err)
+
+	// Publish messages with 8 different keys
+	wg.Add(64)
+	suite.publish(64, 8)
+	wg.Wait()
+
+	// Check that messages have been handled concurrently, in order by key
+	suite.Require().Greater(max, int32(1))
+	suite.Require().LessOrEqual(max, int32(4))
+	suite.Require().Len(received, 8)
+	for key, payloads := range received {
+		suite.Require().Len(payloads, 8, key)
+		suite.Require().IsIncreasing(payloads, key)
+	}
+}
+
+func (suite *Suite) TestSequentialByDefault() {
+	app, err := NewAppController(suite.broker)
+	suite.Require().NoError(err)
+	defer app.Close(context.Background())
+
+	// Record received messages
+	var wg sync.WaitGroup
+	received := make([]int64, 0)
+	err = app.SubscribeOrders(context.Background(), func(ctx context.Context, msg OrdersMessage) error {
+		defer wg.Done()
+		received = append(received, msg.Payload)
+		return nil
+	})
+	suite.Require().NoError(err)
+
+	// Check that messages are handled one by one, in order
+	wg.Add(16)
+	suite.publish(16, 4)
+	wg.Wait()
+	suite.Require().Len(received, 16)
+	suite.Require().IsIncreasing(received)
+}
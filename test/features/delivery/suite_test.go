@@ -0,0 +1,110 @@
+//go:generate go run ../../../cmd/asyncapi-codegen -p delivery -i ./asyncapi.yaml -o ./asyncapi.gen.go
+
+package delivery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions/brokers/memory"
+)
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(Suite))
+}
+
+type Suite struct {
+	broker     *memory.Controller
+	app1, app2 *AppController
+	user       *UserController
+	suite.Suite
+}
+
+func (suite *Suite) SetupTest() {
+	suite.broker = memory.NewController()
+
+	// Create two instances of the app, in the same queue group
+	app1, err := NewAppController(suite.broker)
+	suite.Require().NoError(err)
+	suite.app1 = app1
+
+	app2, err := NewAppController(suite.broker.Connect())
+	suite.Require().NoError(err)
+	suite.app2 = app2
+
+	user, err := NewUserController(suite.broker)
+	suite.Require().NoError(err)
+	suite.user = user
+}
+
+func (suite *Suite) TearDownTest() {
+	suite.app1.Close(context.Background())
+	suite.app2.Close(context.Background())
+	suite.user.Close(context.Background())
+	suite.broker.Close()
+}
+
+// received returns a subscription function counting the received messages.
+func received(count chan<- struct{}) func(context.Context, InvalidationsMessage) error {
+	return func(_ context.Context, _ InvalidationsMessage) error {
+		count <- struct{}{}
+		return nil
+	}
+}
+
+// requireReceived checks that the number of received messages is the expected
+// one, waiting a bit for unexpected messages.
+func (suite *Suite) requireReceived(count <-chan struct{}, expected int) {
+	for i := 0; i < expected; i++ {
+		select {
+		case <-count:
+		case <-time.After(time.Second):
+			suite.Require().FailNow("message not received", "%d messages received on %d", i, expected)
+		}
+	}
+
+	select {
+	case <-count:
+		suite.Require().FailNow("unexpected message received")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func (suite *Suite) TestBroadcastFromSpecification() {
+	count := make(chan struct{}, 2)
+	suite.Require().NoError(suite.app1.SubscribeInvalidations(context.Background(), received(count)))
+	suite.Require().NoError(suite.app2.SubscribeInvalidations(context.Background(), received(count)))
+
+	// Every instance should receive the message
+	suite.Require().NoError(suite.user.PublishInvalidations(context.Background(), InvalidationsMessage{Payload: "a"}))
+	suite.requireReceived(count, 2)
+}
+
+func (suite *Suite) TestQueueByDefault() {
+	count := make(chan struct{}, 2)
+	fn := func(_ context.Context, _ OrdersMessage) error {
+		count <- struct{}{}
+		return nil
+	}
+	suite.Require().NoError(suite.app1.SubscribeOrders(context.Background(), fn))
+	suite.Require().NoError(suite.app2.SubscribeOrders(context.Background(), fn))
+
+	// Only one instance should receive the message
+	suite.Require().NoError(suite.user.PublishOrders(context.Background(), OrdersMessage{Payload: "a"}))
+	suite.requireReceived(count, 1)
+}
+
+func (suite *Suite) TestDeliveryModeFromContext() {
+	// The delivery mode from the context should override the specification one
+	ctx := context.WithValue(context.Background(), extensions.ContextKeyIsDeliveryMode, extensions.DeliveryModeQueue)
+	count := make(chan struct{}, 2)
+	suite.Require().NoError(suite.app1.SubscribeInvalidations(ctx, received(count)))
+	suite.Require().NoError(suite.app2.SubscribeInvalidations(ctx, received(count)))
+
+	// Only one instance should receive the message
+	suite.Require().NoError(suite.user.PublishInvalidations(context.Background(), InvalidationsMessage{Payload: "a"}))
+	suite.requireReceived(count, 1)
+}
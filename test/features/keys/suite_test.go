@@ -0,0 +1,106 @@
+//go:generate go run ../../../cmd/asyncapi-codegen -p keys -i ./asyncapi.yaml -o ./asyncapi.gen.go
+
+package keys
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions/brokers/memory"
+	"github.com/znas-io/asyncapi-codegen/pkg/utils"
+)
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(Suite))
+}
+
+type Suite struct {
+	broker *memory.Controller
+	app    *AppController
+	user   *UserController
+	suite.Suite
+}
+
+func (suite *Suite) SetupTest() {
+	suite.broker = memory.NewController()
+
+	app, err := NewAppController(suite.broker)
+	suite.Require().NoError(err)
+	suite.app = app
+
+	user, err := NewUserController(suite.broker)
+	suite.Require().NoError(err)
+	suite.user = user
+}
+
+func (suite *Suite) TearDownTest() {
+	suite.app.Close(context.Background())
+	suite.user.Close(context.Background())
+	suite.broker.Close()
+}
+
+func (suite *Suite) requirePublishedKey(key string, exists bool) {
+	msgs := suite.broker.PublishedMessages()
+	suite.Require().Len(msgs, 1)
+
+	value, ok := msgs[0].Metadata[extensions.MetadataKeyIsMessageKey]
+	suite.Require().Equal(exists, ok)
+	suite.Require().Equal(key, value)
+	suite.broker.ResetPublishedMessages()
+}
+
+func (suite *Suite) TestKeyFromPayload() {
+	msg := NewOrderMessage()
+	msg.Payload.Id = "order-1"
+
+	suite.Require().NoError(suite.user.PublishOrders(context.Background(), msg))
+	suite.requirePublishedKey("order-1", true)
+}
+
+func (suite *Suite) TestKeyFromOptionalHeader() {
+	// Without header, there should be no key
+	msg := NewEventsMessage()
+	suite.Require().NoError(suite.user.PublishEvents(context.Background(), msg))
+	suite.requirePublishedKey("", false)
+
+	// With header, it should be the key
+	msg.Headers.Entity = utils.ToPointer("entity-1")
+	suite.Require().NoError(suite.user.PublishEvents(context.Background(), msg))
+	suite.requirePublishedKey("entity-1", true)
+}
+
+func (suite *Suite) TestKeyFromInteger() {
+	msg := NewCountersMessage()
+	msg.Payload.Counter = 42
+
+	suite.Require().NoError(suite.user.PublishCounters(context.Background(), msg))
+	suite.requirePublishedKey("42", true)
+}
+
+func (suite *Suite) TestKeyFromBinding() {
+	// Receive the key on app side
+	received := make(chan ItemsMessage, 1)
+	err := suite.app.SubscribeItems(context.Background(), func(_ context.Context, msg ItemsMessage) error {
+		received <- msg
+		return nil
+	})
+	suite.Require().NoError(err)
+
+	// Publish a message with a key
+	msg := NewItemsMessage()
+	msg.Key = "item-1"
+	msg.Payload = "item"
+	suite.Require().NoError(suite.user.PublishItems(context.Background(), msg))
+	suite.requirePublishedKey("item-1", true)
+
+	select {
+	case msg := <-received:
+		suite.Require().Equal("item-1", msg.Key)
+		suite.Require().Equal("item", msg.Payload)
+	case <-time.After(time.Second):
+		suite.Require().FailNow("no message received")
+	}
+}
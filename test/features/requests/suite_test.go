@@ -0,0 +1,278 @@
+//go:generate go run ../../../cmd/asyncapi-codegen -p requests -i ./asyncapi.yaml -o ./asyncapi.gen.go
+
+package requests
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/suite"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions/brokers/jetstream"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions/brokers/memory"
+)
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(Suite))
+}
+
+// countingBroker counts the subscriptions made on the broker. It embeds the
+// broker controller interface, hiding the native request/reply of the memory
+// broker in order to use the reply subscription.
+type countingBroker struct {
+	extensions.BrokerController
+	subscriptions int32
+	lastContext   atomic.Value
+}
+
+func (b *countingBroker) Subscribe(ctx context.Context, channel string) (extensions.BrokerChannelSubscription, error) {
+	atomic.AddInt32(&b.subscriptions, 1)
+	b.lastContext.Store(ctx)
+	return b.BrokerController.Subscribe(ctx, channel)
+}
+
+type Suite struct {
+	broker     *memory.Controller
+	userBroker *countingBroker
+	app        *AppController
+	suite.Suite
+}
+
+func (suite *Suite) SetupTest() {
+	suite.broker = memory.NewController()
+	suite.userBroker = &countingBroker{BrokerController: suite.broker.Connect()}
+
+	// Create app that replies with the square of the received numbers
+	app, err := NewAppController(suite.broker, WithConcurrency(8))
+	suite.Require().NoError(err)
+	suite.app = app
+
+	err = app.SubscribeSquare(context.Background(), SquareParameters{Id: "app"},
+		func(ctx context.Context, msg NumberMessage) error {
+			reply := NewNumberMessage()
+			reply.Payload = msg.Payload * msg.Payload
+			reply.SetAsResponseFrom(&msg)
+			return app.PublishResult(ctx, reply)
+		})
+	suite.Require().NoError(err)
+}
+
+func (suite *Suite) TearDownTest() {
+	suite.app.Close(context.Background())
+	suite.broker.Close()
+}
+
+func (suite *Suite) newUser(options ...ControllerOption) *UserController {
+	user, err := NewUserController(suite.userBroker, options...)
+	suite.Require().NoError(err)
+	suite.T().Cleanup(func() { user.Close(context.Background()) })
+	return user
+}
+
+func (suite *Suite) TestConcurrentRequests() {
+	user := suite.newUser()
+
+	// Send requests concurrently
+	var wg sync.WaitGroup
+	for i := int64(0); i < 100; i++ {
+		wg.Add(1)
+		go func(i int64) {
+			defer wg.Done()
+
+			req := NewNumberMessage()
+			req.Payload = i
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			reply, err := user.RequestSquare(ctx, SquareParameters{Id: "app"}, req)
+			suite.NoError(err)
+			suite.Equal(i*i, reply.Payload)
+			suite.Equal(req.CorrelationID(), reply.CorrelationID())
+		}(i)
+	}
+	wg.Wait()
+
+	// The reply channel should have been subscribed only once
+	suite.Require().Equal(int32(1), atomic.LoadInt32(&suite.userBroker.subscriptions))
+}
+
+func (suite *Suite) TestRequestsFromSeveralInstances() {
+	// Create instances in the same queue group
+	users := []*UserController{suite.newUser()}
+	broker := &countingBroker{BrokerController: suite.broker.Connect()}
+	user, err := NewUserController(broker)
+	suite.Require().NoError(err)
+	defer user.Close(context.Background())
+	users = append(users, user)
+
+	// Send requests from every instance, with a context set for another
+	// channel (i.e. in a handler) that should not be used for the replies
+	ctx := context.WithValue(context.Background(), extensions.ContextKeyIsDeliveryMode, extensions.DeliveryModeQueue)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsChannel, "other")
+	for i := int64(0); i < 20; i++ {
+		req := NewNumberMessage()
+		req.Payload = i
+
+		ctx, cancel := context.WithTimeout(ctx, time.Second)
+		reply, err := users[i%2].RequestSquare(ctx, SquareParameters{Id: "app"}, req)
+		cancel()
+		suite.Require().NoError(err)
+		suite.Require().Equal(i*i, reply.Payload)
+	}
+
+	// The reply channel should have been subscribed in broadcast mode
+	subCtx := broker.lastContext.Load().(context.Context)
+	suite.Require().Equal(extensions.DeliveryModeBroadcast, extensions.DeliveryModeFromContext(subCtx))
+	suite.Require().Equal("results", subCtx.Value(extensions.ContextKeyIsChannel))
+}
+
+func (suite *Suite) TestRequestTimeout() {
+	user := suite.newUser(WithRequestTimeout(20 * time.Millisecond))
+
+	// Send a request on a channel without application
+	_, err := user.RequestSquare(context.Background(), SquareParameters{Id: "none"}, NewNumberMessage())
+	suite.Require().ErrorIs(err, extensions.ErrContextCanceled)
+
+	// Next requests should still work
+	req := NewNumberMessage()
+	req.Payload = 3
+	reply, err := user.RequestSquare(context.Background(), SquareParameters{Id: "app"}, req)
+	suite.Require().NoError(err)
+	suite.Require().Equal(int64(9), reply.Payload)
+}
+
+func (suite *Suite) TestDuplicateCorrelationID() {
+	user := suite.newUser()
+
+	// Send a request that will not be answered
+	req := NewNumberMessage()
+	req.SetCorrelationID("duplicate")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = user.RequestSquare(ctx, SquareParameters{Id: "none"}, req)
+	}()
+
+	// Another request with the same correlation ID should fail
+	suite.Require().Eventually(func() bool {
+		_, err := user.RequestSquare(context.Background(), SquareParameters{Id: "app"}, req)
+		return err != nil && errors.Is(err, extensions.ErrDuplicateCorrelationID)
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	<-done
+}
+
+func (suite *Suite) TestRequestAfterClose() {
+	user := suite.newUser()
+
+	// Send a request, close the controller and send another one
+	_, err := user.RequestSquare(context.Background(), SquareParameters{Id: "app"}, NewNumberMessage())
+	suite.Require().NoError(err)
+	user.Close(context.Background())
+	_, err = user.RequestSquare(context.Background(), SquareParameters{Id: "app"}, NewNumberMessage())
+	suite.Require().NoError(err)
+
+	// The reply channel should have been subscribed again
+	suite.Require().Equal(int32(2), atomic.LoadInt32(&suite.userBroker.subscriptions))
+}
+
+func (suite *Suite) TestNativeRequests() {
+	user, err := NewUserController(suite.broker.Connect())
+	suite.Require().NoError(err)
+	defer user.Close(context.Background())
+
+	// Listen on the reply channel, where replies should not be sent
+	results, err := suite.broker.Connect().Subscribe(context.Background(), "results")
+	suite.Require().NoError(err)
+	defer results.Cancel(context.Background())
+
+	// Send requests concurrently
+	var wg sync.WaitGroup
+	for i := int64(0); i < 20; i++ {
+		wg.Add(1)
+		go func(i int64) {
+			defer wg.Done()
+
+			req := NewNumberMessage()
+			req.Payload = i
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			reply, err := user.RequestSquare(ctx, SquareParameters{Id: "app"}, req)
+			suite.NoError(err)
+			suite.Equal(i*i, reply.Payload)
+			suite.Equal(req.CorrelationID(), reply.CorrelationID())
+		}(i)
+	}
+	wg.Wait()
+
+	// Replies should have been sent on the request inboxes
+	select {
+	case msg := <-results.MessagesChannel():
+		suite.Require().Failf("reply sent on reply channel", "%+v", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func (suite *Suite) TestRequestsOnJetStreamSameStream() {
+	// Start an embedded NATS server with JetStream
+	s, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  suite.T().TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	suite.Require().NoError(err)
+	go s.Start()
+	suite.Require().True(s.ReadyForConnections(10 * time.Second))
+	defer s.Shutdown()
+
+	// Persist the requests and the replies in the same stream
+	stream := jetstream.WithStream(nats.StreamConfig{
+		Name:     "requests",
+		Subjects: []string{"square.>", "results"},
+		Storage:  nats.MemoryStorage,
+	})
+	appBroker, err := jetstream.NewController(s.ClientURL(), stream)
+	suite.Require().NoError(err)
+	defer appBroker.Close()
+	userBroker, err := jetstream.NewController(s.ClientURL(), stream)
+	suite.Require().NoError(err)
+	defer userBroker.Close()
+
+	// Create an app replying with the square of the received numbers
+	app, err := NewAppController(appBroker)
+	suite.Require().NoError(err)
+	defer app.Close(context.Background())
+	err = app.SubscribeSquare(context.Background(), SquareParameters{Id: "jetstream"},
+		func(ctx context.Context, msg NumberMessage) error {
+			reply := NewNumberMessage()
+			reply.Payload = msg.Payload * msg.Payload
+			reply.SetAsResponseFrom(&msg)
+			return app.PublishResult(ctx, reply)
+		})
+	suite.Require().NoError(err)
+
+	// Replies should not be deduplicated with their requests
+	user, err := NewUserController(userBroker)
+	suite.Require().NoError(err)
+	defer user.Close(context.Background())
+
+	req := NewNumberMessage()
+	req.Payload = 4
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	reply, err := user.RequestSquare(ctx, SquareParameters{Id: "jetstream"}, req)
+	suite.Require().NoError(err)
+	suite.Require().Equal(int64(16), reply.Payload)
+}
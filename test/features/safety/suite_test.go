@@ -0,0 +1,183 @@
+//go:generate go run ../../../cmd/asyncapi-codegen -p safety -i ./asyncapi.yaml -o ./asyncapi.gen.go
+
+package safety
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions/brokers/memory"
+)
+
+type contextKey string
+
+const payloadContextKey contextKey = "payload"
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(Suite))
+}
+
+type Suite struct {
+	broker *memory.Controller
+	app    *AppController
+	user   *UserController
+	suite.Suite
+}
+
+func (suite *Suite) SetupTest() {
+	suite.broker = memory.NewController()
+
+	// Create app
+	app, err := NewAppController(suite.broker, WithConcurrency(4))
+	suite.Require().NoError(err)
+	suite.app = app
+
+	// Create user
+	user, err := NewUserController(suite.broker)
+	suite.Require().NoError(err)
+	suite.user = user
+}
+
+func (suite *Suite) TearDownTest() {
+	suite.app.Close(context.Background())
+	suite.user.Close(context.Background())
+	suite.broker.Close()
+}
+
+func (suite *Suite) TestConcurrentSubscriptions() {
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			params := ItemsParameters{Id: strconv.Itoa(i)}
+			for j := 0; j < 5; j++ {
+				// Subscribe to a parameterized channel
+				received := make(chan ItemsIdMessage, 1)
+				err := suite.app.SubscribeItemsId(context.Background(), params,
+					func(_ context.Context, msg ItemsIdMessage) error {
+						received <- msg
+						return nil
+					})
+				suite.Require().NoError(err)
+
+				// Publish and wait for the message
+				msg := NewItemsIdMessage()
+				msg.Payload = params.Id
+				suite.Require().NoError(suite.user.PublishItemsId(context.Background(), params, msg))
+				select {
+				case msg := <-received:
+					suite.Require().Equal(params.Id, msg.Payload)
+				case <-time.After(time.Second):
+					suite.Require().FailNow("message not received")
+				}
+
+				// Unsubscribe from two goroutines at the same time
+				var unsubscribed sync.WaitGroup
+				unsubscribed.Add(1)
+				go func() {
+					defer unsubscribed.Done()
+					suite.app.UnsubscribeItemsId(context.Background(), params)
+				}()
+				suite.app.UnsubscribeItemsId(context.Background(), params)
+				unsubscribed.Wait()
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func (suite *Suite) TestConcurrentSubscriptionsOnSameChannel() {
+	params := ItemsParameters{Id: "same"}
+
+	// Subscribe to the same channel from several goroutines
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- suite.app.SubscribeItemsId(context.Background(), params,
+				func(_ context.Context, _ ItemsIdMessage) error { return nil })
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	// Only one subscription should have succeeded
+	var succeeded int
+	for err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		suite.Require().True(errors.Is(err, extensions.ErrAlreadySubscribedChannel), err)
+	}
+	suite.Require().Equal(1, succeeded)
+}
+
+func (suite *Suite) TestContextValuesPerMessage() {
+	// Create an app with a middleware setting a context value from each message
+	app, err := NewAppController(suite.broker,
+		WithConcurrency(4),
+		WithMiddlewares(func(ctx context.Context, msg *extensions.BrokerMessage, next extensions.NextMiddleware) error {
+			return next(context.WithValue(ctx, payloadContextKey, string(msg.Payload)))
+		}))
+	suite.Require().NoError(err)
+	defer app.Close(context.Background())
+
+	// Check that every handler has the values of its own message
+	const count = 50
+	var wg sync.WaitGroup
+	wg.Add(count)
+	params := ItemsParameters{Id: "context"}
+	err = app.SubscribeItemsId(context.Background(), params, func(ctx context.Context, msg ItemsIdMessage) error {
+		defer wg.Done()
+
+		var correlationID string
+		extensions.IfContextSetWith(ctx, extensions.ContextKeyIsCorrelationID, func(value string) {
+			correlationID = value
+		})
+		suite.Equal(msg.CorrelationID(), correlationID)
+		suite.Equal(msg.Payload, ctx.Value(payloadContextKey))
+		return nil
+	})
+	suite.Require().NoError(err)
+
+	// Publish messages with different values
+	for i := 0; i < count; i++ {
+		msg := NewItemsIdMessage()
+		msg.Payload = strconv.Itoa(i)
+		msg.SetCorrelationID(strconv.Itoa(i))
+		suite.Require().NoError(suite.user.PublishItemsId(context.Background(), params, msg))
+	}
+	wg.Wait()
+}
+
+func (suite *Suite) TestConcurrentShutdownAndUnsubscribe() {
+	// Subscribe to several channels
+	for i := 0; i < 10; i++ {
+		err := suite.app.SubscribeItemsId(context.Background(), ItemsParameters{Id: strconv.Itoa(i)},
+			func(_ context.Context, _ ItemsIdMessage) error { return nil })
+		suite.Require().NoError(err)
+	}
+
+	// Unsubscribe while shutting down
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			suite.app.UnsubscribeItemsId(context.Background(), ItemsParameters{Id: strconv.Itoa(i)})
+		}(i)
+	}
+	suite.Require().NoError(suite.app.Shutdown(context.Background()))
+	wg.Wait()
+}
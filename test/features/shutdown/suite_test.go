@@ -0,0 +1,130 @@
+//go:generate go run ../../../cmd/asyncapi-codegen -p shutdown -i ./asyncapi.yaml -o ./asyncapi.gen.go
+
+package shutdown
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions/brokers/memory"
+)
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(Suite))
+}
+
+type Suite struct {
+	broker *memory.Controller
+	user   *UserController
+	suite.Suite
+}
+
+func (suite *Suite) SetupTest() {
+	suite.broker = memory.NewController()
+
+	// Create user
+	user, err := NewUserController(suite.broker)
+	suite.Require().NoError(err)
+	suite.user = user
+}
+
+func (suite *Suite) TearDownTest() {
+	suite.user.Close(context.Background())
+	suite.broker.Close()
+}
+
+func (suite *Suite) subscribeAndPublish(app *AppController, count int, delay time.Duration) (started, handled *int32) {
+	started, handled = new(int32), new(int32)
+
+	// Subscribe with a slow handler
+	err := app.SubscribeJobs(context.Background(), func(ctx context.Context, msg JobsMessage) error {
+		atomic.AddInt32(started, 1)
+		time.Sleep(delay)
+		atomic.AddInt32(handled, 1)
+		return nil
+	})
+	suite.Require().NoError(err)
+
+	// Publish messages
+	for i := 0; i < count; i++ {
+		err := suite.user.PublishJobs(context.Background(), JobsMessage{Payload: int64(i)})
+		suite.Require().NoError(err)
+	}
+
+	// Wait for handling to start
+	suite.Require().Eventually(func() bool {
+		return atomic.LoadInt32(started) > 0
+	}, time.Second, time.Millisecond)
+
+	return started, handled
+}
+
+func (suite *Suite) TestShutdownWaitsForHandlers() {
+	app, err := NewAppController(suite.broker, WithConcurrency(2))
+	suite.Require().NoError(err)
+	defer app.Close(context.Background())
+
+	// Receive messages that will take some time to be handled
+	_, handled := suite.subscribeAndPublish(app, 2, 50*time.Millisecond)
+
+	// Shutdown should wait for the running handlers
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	suite.Require().NoError(app.Shutdown(ctx))
+	suite.Require().Equal(int32(2), atomic.LoadInt32(handled))
+}
+
+func (suite *Suite) TestShutdownReportsDroppedMessages() {
+	app, err := NewAppController(suite.broker)
+	suite.Require().NoError(err)
+	defer app.Close(context.Background())
+
+	// Receive more messages than can be handled before the deadline
+	_, handled := suite.subscribeAndPublish(app, 5, 50*time.Millisecond)
+
+	// Shutdown should report the messages that have not been handled
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = app.Shutdown(ctx)
+	suite.Require().ErrorIs(err, extensions.ErrMessagesDropped)
+	suite.Require().Less(atomic.LoadInt32(handled), int32(5))
+}
+
+func (suite *Suite) TestNoMessageAfterShutdown() {
+	app, err := NewAppController(suite.broker)
+	suite.Require().NoError(err)
+	defer app.Close(context.Background())
+
+	// Receive a message then shutdown
+	started, _ := suite.subscribeAndPublish(app, 1, 0)
+	suite.Require().NoError(app.Shutdown(context.Background()))
+
+	// New messages should not be received anymore
+	err = suite.user.PublishJobs(context.Background(), JobsMessage{Payload: 42})
+	suite.Require().NoError(err)
+	time.Sleep(20 * time.Millisecond)
+	suite.Require().Equal(int32(1), atomic.LoadInt32(started))
+}
+
+func (suite *Suite) TestSubscriptionAfterShutdownDeadline() {
+	app, err := NewAppController(suite.broker)
+	suite.Require().NoError(err)
+	defer app.Close(context.Background())
+
+	// Shutdown with messages dropped on deadline
+	suite.subscribeAndPublish(app, 5, 20*time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	suite.Require().ErrorIs(app.Shutdown(ctx), extensions.ErrMessagesDropped)
+
+	// New messages should be handled after subscribing again
+	_, handled := suite.subscribeAndPublish(app, 3, 0)
+	suite.Require().Eventually(func() bool {
+		return atomic.LoadInt32(handled) == 3
+	}, time.Second, time.Millisecond)
+	suite.Require().NoError(app.Shutdown(context.Background()))
+}
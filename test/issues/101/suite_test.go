@@ -71,8 +71,9 @@ func (suite *Suite) TestAddingHeader() {
 
 	// Check what the app receive
 	wg.Add(1)
-	err := suite.app.SubscribeTest101(context.Background(), func(_ context.Context, msg Test101Message) {
+	err := suite.app.SubscribeTest101(context.Background(), func(_ context.Context, msg Test101Message) error {
 		wg.Done()
+		return nil
 	})
 	suite.Require().NoError(err)
 
@@ -98,15 +98,17 @@ func (suite *Suite) TestV1Reception() {
 	// Check what the app receive and translate
 	var recvMsg v1.HelloMessage
 	wg.Add(1)
-	err := suite.v1.app.SubscribeHello(context.Background(), func(_ context.Context, msg v1.HelloMessage) {
+	err := suite.v1.app.SubscribeHello(context.Background(), func(_ context.Context, msg v1.HelloMessage) error {
 		recvMsg = msg
 		wg.Done()
+		return nil
 	})
 	suite.Require().NoError(err)
 
 	// Check that the other app doesn't receive
-	err = suite.v2.app.SubscribeHello(context.Background(), func(_ context.Context, _ v2.HelloMessage) {
+	err = suite.v2.app.SubscribeHello(context.Background(), func(_ context.Context, _ v2.HelloMessage) error {
 		suite.Require().FailNow("this should not happen")
+		return nil
 	})
 	suite.Require().NoError(err)
 
@@ -142,17 +144,19 @@ func (suite *Suite) TestV2Reception() {
 	}
 
 	// Check that the other app doesn't receive
-	err := suite.v1.app.SubscribeHello(context.Background(), func(_ context.Context, _ v1.HelloMessage) {
+	err := suite.v1.app.SubscribeHello(context.Background(), func(_ context.Context, _ v1.HelloMessage) error {
 		suite.Require().FailNow("this should not happen")
+		return nil
 	})
 	suite.Require().NoError(err)
 
 	// Check what the app receive and translate
 	var recvMsg v2.HelloMessage
 	wg.Add(1)
-	err = suite.v2.app.SubscribeHello(context.Background(), func(_ context.Context, msg v2.HelloMessage) {
+	err = suite.v2.app.SubscribeHello(context.Background(), func(_ context.Context, msg v2.HelloMessage) error {
 		recvMsg = msg
 		wg.Done()
+		return nil
 	})
 	suite.Require().NoError(err)
 
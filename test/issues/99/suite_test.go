@@ -77,9 +77,10 @@ func (suite *Suite) TestAddingHeader() {
 	// Check what the app receive and translate
 	var recvMsg Test99Message
 	wg.Add(1)
-	err := suite.app.SubscribeTest99(context.Background(), func(_ context.Context, msg Test99Message) {
+	err := suite.app.SubscribeTest99(context.Background(), func(_ context.Context, msg Test99Message) error {
 		recvMsg = msg
 		wg.Done()
+		return nil
 	})
 	suite.Require().NoError(err)
 
@@ -0,0 +1,1938 @@
+// Package "mocks" provides primitives to interact with the AsyncAPI specification.
+//
+// Code generated by github.com/znas-io/asyncapi-codegen version (devel) DO NOT EDIT.
+package mocks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/znas-io/asyncapi-codegen/pkg/extensions"
+
+	"github.com/google/uuid"
+)
+
+// AppSubscriber represents all handlers that are expecting messages for App
+type AppSubscriber interface {
+	// Ping subscribes to messages placed on the 'ping.{id}' channel.
+	// If an error is returned, the message will be negatively acknowledged.
+	Ping(ctx context.Context, msg PingMessage) error
+}
+
+// AppControllerInterface is the interface of AppController, that
+// can be used to replace it in tests (i.e. with AppControllerMock).
+type AppControllerInterface interface {
+	Close(ctx context.Context)
+	Shutdown(ctx context.Context) error
+	SubscribeAll(ctx context.Context, as AppSubscriber) error
+	UnsubscribeAll(ctx context.Context)
+	SubscribePing(ctx context.Context, params PingParameters, fn func(ctx context.Context, msg PingMessage) error) error
+	UnsubscribePing(ctx context.Context, params PingParameters)
+	PublishPong(ctx context.Context, msg PongMessage) error
+}
+
+var _ AppControllerInterface = (*AppController)(nil)
+
+// AppController is the structure that provides publishing capabilities to the
+// developer and and connect the broker with the App
+type AppController struct {
+	controller
+}
+
+// NewAppController links the App to the broker
+func NewAppController(bc extensions.BrokerController, options ...ControllerOption) (*AppController, error) {
+	// Check if broker controller has been provided
+	if bc == nil {
+		return nil, extensions.ErrNilBrokerController
+	}
+
+	// Create default controller
+	controller := controller{
+		broker:             bc,
+		subscriptions:      make(map[string]extensions.BrokerChannelSubscription),
+		subscriptionsMutex: &sync.Mutex{},
+		replies:            make(map[string]*extensions.ReplyDispatcher),
+		logger:             extensions.DummyLogger{},
+		middlewares:        make([]extensions.Middleware, 0),
+		concurrency:        1,
+		tracker:            extensions.NewHandlersTracker(),
+	}
+
+	// Apply options
+	for _, option := range options {
+		option(&controller)
+	}
+
+	return &AppController{controller: controller}, nil
+}
+
+func (c AppController) wrapMiddlewares(
+	middlewares []extensions.Middleware,
+	callback extensions.NextMiddleware,
+) func(ctx context.Context, msg *extensions.BrokerMessage) error {
+	// If there is no more middleware
+	if len(middlewares) == 0 {
+		return func(ctx context.Context, msg *extensions.BrokerMessage) error {
+			// Call the callback if it exists
+			if callback != nil {
+				return callback(ctx)
+			}
+
+			return nil
+		}
+	}
+
+	// Get the next function to call from next middlewares or callback
+	next := c.wrapMiddlewares(middlewares[1:], callback)
+
+	// Wrap middleware into a check function that will call execute the middleware
+	// and call the next wrapped middleware if it has not been called by the
+	// middleware. Note: the middleware can call it several times (i.e. to retry).
+	return func(ctx context.Context, msg *extensions.BrokerMessage) error {
+		// Create the next call with the context and the message
+		var called bool
+		nextWithArgs := func(ctx context.Context) error {
+			called = true
+			return next(ctx, msg)
+		}
+
+		// Call the middleware
+		if err := middlewares[0](ctx, msg, nextWithArgs); err != nil {
+			return err
+		}
+
+		// If next has already been called in middleware, it should not be executed again
+		if called {
+			return nil
+		}
+
+		return nextWithArgs(ctx)
+	}
+}
+
+func (c AppController) executeMiddlewares(ctx context.Context, msg *extensions.BrokerMessage, callback extensions.NextMiddleware) error {
+	// Wrap middleware to have 'next' function when calling them
+	wrapped := c.wrapMiddlewares(c.middlewares, callback)
+
+	// Execute wrapped middlewares
+	return wrapped(ctx, msg)
+}
+
+// dropMessage negatively acknowledges a received message that will not be
+// handled because of the controller shutdown.
+func (c AppController) dropMessage(ctx context.Context, msg extensions.BrokerMessage) {
+	c.logger.Warning(ctx, "Message dropped on shutdown")
+	if err := msg.Nack(); err != nil {
+		c.logger.Error(ctx, err.Error())
+	}
+	c.tracker.AddDropped()
+}
+
+func addAppContextValues(ctx context.Context, path string) context.Context {
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsVersion, "1.0.0")
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsProvider, "app")
+	return context.WithValue(ctx, extensions.ContextKeyIsChannel, path)
+}
+
+// Close will clean up any existing resources on the controller
+func (c *AppController) Close(ctx context.Context) {
+	// Unsubscribing remaining channels
+	c.UnsubscribeAll(ctx)
+
+	c.logger.Info(ctx, "Closed app controller")
+}
+
+// Shutdown will gracefully stop the controller: subscriptions stop receiving new
+// messages, then it waits for the already received messages to be handled and
+// flushes the messages published on the broker (if supported).
+//
+// If the context is done before every received message has been handled, the
+// remaining messages are dropped and an error wrapping extensions.ErrMessagesDropped
+// is returned with the number of messages that were not handled.
+func (c *AppController) Shutdown(ctx context.Context) error {
+	// Remove every subscription
+	c.subscriptionsMutex.Lock()
+	subscriptions := make([]extensions.BrokerChannelSubscription, 0, len(c.subscriptions))
+	for path, sub := range c.subscriptions {
+		subscriptions = append(subscriptions, sub)
+		delete(c.subscriptions, path)
+	}
+	c.subscriptionsMutex.Unlock()
+
+	// Stop receiving new messages
+	for _, sub := range subscriptions {
+		sub.Cancel(ctx)
+	}
+
+	// Wait for received messages to be handled
+	dropped := c.tracker.Wait(ctx)
+
+	// Flush published messages
+	if flusher, ok := c.broker.(extensions.BrokerFlusher); ok {
+		if err := flusher.Flush(ctx); err != nil {
+			c.logger.Error(ctx, err.Error())
+			return err
+		}
+	}
+
+	// Report dropped messages
+	if dropped > 0 {
+		err := fmt.Errorf("%w: %d message(s) not handled before shutdown", extensions.ErrMessagesDropped, dropped)
+		c.logger.Error(ctx, err.Error())
+		return err
+	}
+
+	c.logger.Info(ctx, "Shut down app controller")
+	return nil
+}
+
+// SubscribeAll will subscribe to channels without parameters on which the app is expecting messages.
+// For channels with parameters, they should be subscribed independently.
+func (c *AppController) SubscribeAll(ctx context.Context, as AppSubscriber) error {
+	if as == nil {
+		return extensions.ErrNilAppSubscriber
+	}
+
+	return nil
+}
+
+// UnsubscribeAll will unsubscribe all remaining subscribed channels
+func (c *AppController) UnsubscribeAll(ctx context.Context) {
+}
+
+// SubscribePing will subscribe to new messages from 'ping.{id}' channel.
+//
+// Callback function 'fn' will be called each time a new message is received.
+// The message will be acknowledged if it returns no error, or negatively
+// acknowledged to be redelivered otherwise (if supported by the broker).
+func (c *AppController) SubscribePing(ctx context.Context, params PingParameters, fn func(ctx context.Context, msg PingMessage) error) error {
+	// Get channel path
+	path := fmt.Sprintf("ping.%v", params.Id)
+
+	// Set context
+	ctx = addAppContextValues(ctx, path)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsDirection, "reception")
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsAMQPChannelBinding, extensions.AMQPChannelBinding{})
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTOperationBinding, nil)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTMessageBinding, nil)
+
+	// Lock the subscriptions until this one is added, to avoid a concurrent
+	// subscription on the same channel
+	c.subscriptionsMutex.Lock()
+	defer c.subscriptionsMutex.Unlock()
+
+	// Check if there is already a subscription
+	_, exists := c.subscriptions[path]
+	if exists {
+		err := fmt.Errorf("%w: %q channel is already subscribed", extensions.ErrAlreadySubscribedChannel, path)
+		c.logger.Error(ctx, err.Error())
+		return err
+	}
+
+	// Subscribe to broker channel
+	sub, err := c.broker.Subscribe(ctx, path)
+	if err != nil {
+		c.logger.Error(ctx, err.Error())
+		return err
+	}
+	c.logger.Info(ctx, "Subscribed to channel")
+
+	// Handle a received message through middlewares and subscription function
+	handle := func(ctx context.Context, brokerMsg extensions.BrokerMessage) {
+		// Execute middlewares before handling the message
+		if err := c.executeMiddlewares(ctx, &brokerMsg, func(ctx context.Context) error {
+			// Process message
+			msg, err := newPingMessageFromBrokerMessage(brokerMsg)
+			if err != nil {
+				return err
+			}
+
+			// Add correlation ID to context if it exists
+			if id := msg.CorrelationID(); id != "" {
+				ctx = context.WithValue(ctx, extensions.ContextKeyIsCorrelationID, id)
+			}
+
+			// Execute the subscription function
+			return fn(ctx, msg)
+		}); err != nil {
+			c.logger.Error(ctx, err.Error())
+
+			// Terminate the message if it can't be handled, or negatively
+			// acknowledge it to get it redelivered
+			nack := brokerMsg.Nack
+			if errors.Is(err, extensions.ErrUnrecoverable) {
+				nack = brokerMsg.Term
+			}
+			if err := nack(); err != nil {
+				c.logger.Error(ctx, err.Error())
+			}
+			return
+		}
+
+		// Acknowledge the message as it has been successfully handled
+		if err := brokerMsg.Ack(); err != nil {
+			c.logger.Error(ctx, err.Error())
+		}
+	}
+
+	// Asynchronously listen to new messages and pass them to app subscriber
+	c.tracker.StartSubscription()
+	go func() {
+		// Handle messages with workers, and wait for them before leaving
+		workers := extensions.NewWorkerPool(c.concurrency)
+		defer workers.Close()
+		defer c.tracker.EndSubscription()
+
+		for {
+			// Wait for next message, or drop the remaining ones on shutdown
+			var brokerMsg extensions.BrokerMessage
+			var open bool
+			select {
+			case brokerMsg, open = <-sub.MessagesChannel():
+			case <-c.tracker.Dropping():
+				for {
+					select {
+					case brokerMsg, open := <-sub.MessagesChannel():
+						if open {
+							c.dropMessage(ctx, brokerMsg)
+							continue
+						}
+					default:
+					}
+					return
+				}
+			}
+
+			// If subscription is closed and there is no more message
+			// (i.e. uninitialized message), then exit the function
+			if !open && brokerMsg.IsUninitialized() {
+				return
+			}
+
+			// Set broker message to context
+			msgCtx := context.WithValue(ctx, extensions.ContextKeyIsBrokerMessage, brokerMsg.String())
+
+			// Get the ordering key of the message
+			var key string
+			if c.orderingKey != nil {
+				// Add correlation ID to context if it exists, as it can be used as key
+				if msg, err := newPingMessageFromBrokerMessage(brokerMsg); err == nil && msg.CorrelationID() != "" {
+					msgCtx = context.WithValue(msgCtx, extensions.ContextKeyIsCorrelationID, msg.CorrelationID())
+				}
+				key = c.orderingKey(msgCtx, brokerMsg)
+			}
+
+			// Handle the message on a worker, in order with messages with the same key
+			c.tracker.Accept()
+			if !workers.SubmitUntil(c.tracker.Dropping(), key, func() {
+				defer c.tracker.Done()
+				handle(msgCtx, brokerMsg)
+			}) {
+				c.tracker.Done()
+				c.dropMessage(msgCtx, brokerMsg)
+			}
+		}
+	}()
+
+	// Add the cancel channel to the inside map
+	c.subscriptions[path] = sub
+
+	return nil
+}
+
+// UnsubscribePing will unsubscribe messages from 'ping.{id}' channel.
+// A timeout can be set in context to avoid blocking operation, if needed.
+func (c *AppController) UnsubscribePing(ctx context.Context, params PingParameters) {
+	// Get channel path
+	path := fmt.Sprintf("ping.%v", params.Id)
+
+	// Check if there subscribers for this channel and remove it from the
+	// subscribers, so it is cancelled only once
+	c.subscriptionsMutex.Lock()
+	sub, exists := c.subscriptions[path]
+	delete(c.subscriptions, path)
+	c.subscriptionsMutex.Unlock()
+	if !exists {
+		return
+	}
+
+	// Set context
+	ctx = addAppContextValues(ctx, path)
+
+	// Stop the subscription
+	sub.Cancel(ctx)
+
+	c.logger.Info(ctx, "Unsubscribed from channel")
+}
+
+// PublishPong will publish messages to 'pong' channel
+func (c *AppController) PublishPong(ctx context.Context, msg PongMessage) error {
+	// Get channel path
+	path := "pong"
+
+	// Set correlation ID if it does not exist
+	if id := msg.CorrelationID(); id == "" {
+		msg.SetCorrelationID(uuid.New().String())
+	}
+
+	// Set context
+	ctx = addAppContextValues(ctx, path)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsDirection, "publication")
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsAMQPChannelBinding, extensions.AMQPChannelBinding{})
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTOperationBinding, nil)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTMessageBinding, nil)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsCorrelationID, msg.CorrelationID())
+
+	// Convert to BrokerMessage
+	brokerMsg, err := msg.toBrokerMessage()
+	if err != nil {
+		return err
+	}
+
+	// Set broker message to context
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsBrokerMessage, brokerMsg.String())
+
+	// Publish the message on event-broker through middlewares
+	return c.executeMiddlewares(ctx, &brokerMsg, func(ctx context.Context) error {
+		return c.broker.Publish(ctx, path, brokerMsg)
+	})
+}
+
+// UserSubscriber represents all handlers that are expecting messages for User
+type UserSubscriber interface {
+	// Pong subscribes to messages placed on the 'pong' channel.
+	// If an error is returned, the message will be negatively acknowledged.
+	Pong(ctx context.Context, msg PongMessage) error
+}
+
+// UserControllerInterface is the interface of UserController, that
+// can be used to replace it in tests (i.e. with UserControllerMock).
+type UserControllerInterface interface {
+	Close(ctx context.Context)
+	Shutdown(ctx context.Context) error
+	SubscribeAll(ctx context.Context, as UserSubscriber) error
+	UnsubscribeAll(ctx context.Context)
+	SubscribePong(ctx context.Context, fn func(ctx context.Context, msg PongMessage) error) error
+	UnsubscribePong(ctx context.Context)
+	PublishPing(ctx context.Context, params PingParameters, msg PingMessage) error
+	WaitForPong(ctx context.Context, publishMsg MessageWithCorrelationID, pub func(ctx context.Context) error) (PongMessage, error)
+}
+
+var _ UserControllerInterface = (*UserController)(nil)
+
+// UserController is the structure that provides publishing capabilities to the
+// developer and and connect the broker with the User
+type UserController struct {
+	controller
+}
+
+// NewUserController links the User to the broker
+func NewUserController(bc extensions.BrokerController, options ...ControllerOption) (*UserController, error) {
+	// Check if broker controller has been provided
+	if bc == nil {
+		return nil, extensions.ErrNilBrokerController
+	}
+
+	// Create default controller
+	controller := controller{
+		broker:             bc,
+		subscriptions:      make(map[string]extensions.BrokerChannelSubscription),
+		subscriptionsMutex: &sync.Mutex{},
+		replies:            make(map[string]*extensions.ReplyDispatcher),
+		logger:             extensions.DummyLogger{},
+		middlewares:        make([]extensions.Middleware, 0),
+		concurrency:        1,
+		tracker:            extensions.NewHandlersTracker(),
+	}
+
+	// Apply options
+	for _, option := range options {
+		option(&controller)
+	}
+
+	return &UserController{controller: controller}, nil
+}
+
+func (c UserController) wrapMiddlewares(
+	middlewares []extensions.Middleware,
+	callback extensions.NextMiddleware,
+) func(ctx context.Context, msg *extensions.BrokerMessage) error {
+	// If there is no more middleware
+	if len(middlewares) == 0 {
+		return func(ctx context.Context, msg *extensions.BrokerMessage) error {
+			// Call the callback if it exists
+			if callback != nil {
+				return callback(ctx)
+			}
+
+			return nil
+		}
+	}
+
+	// Get the next function to call from next middlewares or callback
+	next := c.wrapMiddlewares(middlewares[1:], callback)
+
+	// Wrap middleware into a check function that will call execute the middleware
+	// and call the next wrapped middleware if it has not been called by the
+	// middleware. Note: the middleware can call it several times (i.e. to retry).
+	return func(ctx context.Context, msg *extensions.BrokerMessage) error {
+		// Create the next call with the context and the message
+		var called bool
+		nextWithArgs := func(ctx context.Context) error {
+			called = true
+			return next(ctx, msg)
+		}
+
+		// Call the middleware
+		if err := middlewares[0](ctx, msg, nextWithArgs); err != nil {
+			return err
+		}
+
+		// If next has already been called in middleware, it should not be executed again
+		if called {
+			return nil
+		}
+
+		return nextWithArgs(ctx)
+	}
+}
+
+func (c UserController) executeMiddlewares(ctx context.Context, msg *extensions.BrokerMessage, callback extensions.NextMiddleware) error {
+	// Wrap middleware to have 'next' function when calling them
+	wrapped := c.wrapMiddlewares(c.middlewares, callback)
+
+	// Execute wrapped middlewares
+	return wrapped(ctx, msg)
+}
+
+// dropMessage negatively acknowledges a received message that will not be
+// handled because of the controller shutdown.
+func (c UserController) dropMessage(ctx context.Context, msg extensions.BrokerMessage) {
+	c.logger.Warning(ctx, "Message dropped on shutdown")
+	if err := msg.Nack(); err != nil {
+		c.logger.Error(ctx, err.Error())
+	}
+	c.tracker.AddDropped()
+}
+
+func addUserContextValues(ctx context.Context, path string) context.Context {
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsVersion, "1.0.0")
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsProvider, "user")
+	return context.WithValue(ctx, extensions.ContextKeyIsChannel, path)
+}
+
+// Close will clean up any existing resources on the controller
+func (c *UserController) Close(ctx context.Context) {
+	// Unsubscribing remaining channels
+	c.UnsubscribeAll(ctx)
+
+	c.logger.Info(ctx, "Closed user controller")
+}
+
+// Shutdown will gracefully stop the controller: subscriptions stop receiving new
+// messages, then it waits for the already received messages to be handled and
+// flushes the messages published on the broker (if supported).
+//
+// If the context is done before every received message has been handled, the
+// remaining messages are dropped and an error wrapping extensions.ErrMessagesDropped
+// is returned with the number of messages that were not handled.
+func (c *UserController) Shutdown(ctx context.Context) error {
+	// Remove every subscription
+	c.subscriptionsMutex.Lock()
+	subscriptions := make([]extensions.BrokerChannelSubscription, 0, len(c.subscriptions))
+	for path, sub := range c.subscriptions {
+		subscriptions = append(subscriptions, sub)
+		delete(c.subscriptions, path)
+	}
+	c.subscriptionsMutex.Unlock()
+
+	// Stop receiving new messages
+	for _, sub := range subscriptions {
+		sub.Cancel(ctx)
+	}
+
+	// Wait for received messages to be handled
+	dropped := c.tracker.Wait(ctx)
+
+	// Flush published messages
+	if flusher, ok := c.broker.(extensions.BrokerFlusher); ok {
+		if err := flusher.Flush(ctx); err != nil {
+			c.logger.Error(ctx, err.Error())
+			return err
+		}
+	}
+
+	// Report dropped messages
+	if dropped > 0 {
+		err := fmt.Errorf("%w: %d message(s) not handled before shutdown", extensions.ErrMessagesDropped, dropped)
+		c.logger.Error(ctx, err.Error())
+		return err
+	}
+
+	c.logger.Info(ctx, "Shut down user controller")
+	return nil
+}
+
+// SubscribeAll will subscribe to channels without parameters on which the app is expecting messages.
+// For channels with parameters, they should be subscribed independently.
+func (c *UserController) SubscribeAll(ctx context.Context, as UserSubscriber) error {
+	if as == nil {
+		return extensions.ErrNilUserSubscriber
+	}
+
+	if err := c.SubscribePong(ctx, as.Pong); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UnsubscribeAll will unsubscribe all remaining subscribed channels
+func (c *UserController) UnsubscribeAll(ctx context.Context) {
+	c.UnsubscribePong(ctx)
+}
+
+// SubscribePong will subscribe to new messages from 'pong' channel.
+//
+// Callback function 'fn' will be called each time a new message is received.
+// The message will be acknowledged if it returns no error, or negatively
+// acknowledged to be redelivered otherwise (if supported by the broker).
+func (c *UserController) SubscribePong(ctx context.Context, fn func(ctx context.Context, msg PongMessage) error) error {
+	// Get channel path
+	path := "pong"
+
+	// Set context
+	ctx = addUserContextValues(ctx, path)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsDirection, "reception")
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsAMQPChannelBinding, extensions.AMQPChannelBinding{})
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTOperationBinding, nil)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTMessageBinding, nil)
+
+	// Lock the subscriptions until this one is added, to avoid a concurrent
+	// subscription on the same channel
+	c.subscriptionsMutex.Lock()
+	defer c.subscriptionsMutex.Unlock()
+
+	// Check if there is already a subscription
+	_, exists := c.subscriptions[path]
+	if exists {
+		err := fmt.Errorf("%w: %q channel is already subscribed", extensions.ErrAlreadySubscribedChannel, path)
+		c.logger.Error(ctx, err.Error())
+		return err
+	}
+
+	// Subscribe to broker channel
+	sub, err := c.broker.Subscribe(ctx, path)
+	if err != nil {
+		c.logger.Error(ctx, err.Error())
+		return err
+	}
+	c.logger.Info(ctx, "Subscribed to channel")
+
+	// Handle a received message through middlewares and subscription function
+	handle := func(ctx context.Context, brokerMsg extensions.BrokerMessage) {
+		// Execute middlewares before handling the message
+		if err := c.executeMiddlewares(ctx, &brokerMsg, func(ctx context.Context) error {
+			// Process message
+			msg, err := newPongMessageFromBrokerMessage(brokerMsg)
+			if err != nil {
+				return err
+			}
+
+			// Add correlation ID to context if it exists
+			if id := msg.CorrelationID(); id != "" {
+				ctx = context.WithValue(ctx, extensions.ContextKeyIsCorrelationID, id)
+			}
+
+			// Execute the subscription function
+			return fn(ctx, msg)
+		}); err != nil {
+			c.logger.Error(ctx, err.Error())
+
+			// Terminate the message if it can't be handled, or negatively
+			// acknowledge it to get it redelivered
+			nack := brokerMsg.Nack
+			if errors.Is(err, extensions.ErrUnrecoverable) {
+				nack = brokerMsg.Term
+			}
+			if err := nack(); err != nil {
+				c.logger.Error(ctx, err.Error())
+			}
+			return
+		}
+
+		// Acknowledge the message as it has been successfully handled
+		if err := brokerMsg.Ack(); err != nil {
+			c.logger.Error(ctx, err.Error())
+		}
+	}
+
+	// Asynchronously listen to new messages and pass them to app subscriber
+	c.tracker.StartSubscription()
+	go func() {
+		// Handle messages with workers, and wait for them before leaving
+		workers := extensions.NewWorkerPool(c.concurrency)
+		defer workers.Close()
+		defer c.tracker.EndSubscription()
+
+		for {
+			// Wait for next message, or drop the remaining ones on shutdown
+			var brokerMsg extensions.BrokerMessage
+			var open bool
+			select {
+			case brokerMsg, open = <-sub.MessagesChannel():
+			case <-c.tracker.Dropping():
+				for {
+					select {
+					case brokerMsg, open := <-sub.MessagesChannel():
+						if open {
+							c.dropMessage(ctx, brokerMsg)
+							continue
+						}
+					default:
+					}
+					return
+				}
+			}
+
+			// If subscription is closed and there is no more message
+			// (i.e. uninitialized message), then exit the function
+			if !open && brokerMsg.IsUninitialized() {
+				return
+			}
+
+			// Set broker message to context
+			msgCtx := context.WithValue(ctx, extensions.ContextKeyIsBrokerMessage, brokerMsg.String())
+
+			// Get the ordering key of the message
+			var key string
+			if c.orderingKey != nil {
+				// Add correlation ID to context if it exists, as it can be used as key
+				if msg, err := newPongMessageFromBrokerMessage(brokerMsg); err == nil && msg.CorrelationID() != "" {
+					msgCtx = context.WithValue(msgCtx, extensions.ContextKeyIsCorrelationID, msg.CorrelationID())
+				}
+				key = c.orderingKey(msgCtx, brokerMsg)
+			}
+
+			// Handle the message on a worker, in order with messages with the same key
+			c.tracker.Accept()
+			if !workers.SubmitUntil(c.tracker.Dropping(), key, func() {
+				defer c.tracker.Done()
+				handle(msgCtx, brokerMsg)
+			}) {
+				c.tracker.Done()
+				c.dropMessage(msgCtx, brokerMsg)
+			}
+		}
+	}()
+
+	// Add the cancel channel to the inside map
+	c.subscriptions[path] = sub
+
+	return nil
+}
+
+// UnsubscribePong will unsubscribe messages from 'pong' channel.
+// A timeout can be set in context to avoid blocking operation, if needed.
+func (c *UserController) UnsubscribePong(ctx context.Context) {
+	// Get channel path
+	path := "pong"
+
+	// Check if there subscribers for this channel and remove it from the
+	// subscribers, so it is cancelled only once
+	c.subscriptionsMutex.Lock()
+	sub, exists := c.subscriptions[path]
+	delete(c.subscriptions, path)
+	c.subscriptionsMutex.Unlock()
+	if !exists {
+		return
+	}
+
+	// Set context
+	ctx = addUserContextValues(ctx, path)
+
+	// Stop the subscription
+	sub.Cancel(ctx)
+
+	c.logger.Info(ctx, "Unsubscribed from channel")
+}
+
+// PublishPing will publish messages to 'ping.{id}' channel
+func (c *UserController) PublishPing(ctx context.Context, params PingParameters, msg PingMessage) error {
+	// Get channel path
+	path := fmt.Sprintf("ping.%v", params.Id)
+
+	// Set correlation ID if it does not exist
+	if id := msg.CorrelationID(); id == "" {
+		msg.SetCorrelationID(uuid.New().String())
+	}
+
+	// Set context
+	ctx = addUserContextValues(ctx, path)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsDirection, "publication")
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsAMQPChannelBinding, extensions.AMQPChannelBinding{})
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTOperationBinding, nil)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTMessageBinding, nil)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsCorrelationID, msg.CorrelationID())
+
+	// Convert to BrokerMessage
+	brokerMsg, err := msg.toBrokerMessage()
+	if err != nil {
+		return err
+	}
+
+	// Set broker message to context
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsBrokerMessage, brokerMsg.String())
+
+	// Publish the message on event-broker through middlewares
+	return c.executeMiddlewares(ctx, &brokerMsg, func(ctx context.Context) error {
+		return c.broker.Publish(ctx, path, brokerMsg)
+	})
+}
+
+// WaitForPong will wait for a specific message by its correlation ID.
+//
+// The pub function is the publication function that should be used to send the message.
+// It will be called after subscribing to the channel to avoid race condition, and potentially loose the message.
+//
+// A timeout can be set in context to avoid blocking operation, if needed.
+func (c *UserController) WaitForPong(ctx context.Context, publishMsg MessageWithCorrelationID, pub func(ctx context.Context) error) (PongMessage, error) {
+	// Get channel path
+	path := "pong"
+
+	// Set context
+	ctx = addUserContextValues(ctx, path)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsAMQPChannelBinding, extensions.AMQPChannelBinding{})
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTOperationBinding, nil)
+	ctx = context.WithValue(ctx, extensions.ContextKeyIsMQTTMessageBinding, nil)
+
+	// Subscribe to broker channel
+	sub, err := c.broker.Subscribe(ctx, path)
+	if err != nil {
+		c.logger.Error(ctx, err.Error())
+		return PongMessage{}, err
+	}
+	c.logger.Info(ctx, "Subscribed to channel")
+
+	// Close subscriber on leave
+	defer func() {
+		// Stop the subscription
+		sub.Cancel(ctx)
+
+		// Logging unsubscribing
+		c.logger.Info(ctx, "Unsubscribed from channel")
+	}()
+
+	// Execute callback for publication
+	if err = pub(ctx); err != nil {
+		return PongMessage{}, err
+	}
+
+	// Wait for corresponding response
+	for {
+		select {
+		case brokerMsg, open := <-sub.MessagesChannel():
+			// If subscription is closed and there is no more message
+			// (i.e. uninitialized message), then the subscription ended before
+			// receiving the expected message
+			if !open && brokerMsg.IsUninitialized() {
+				c.logger.Error(ctx, "Channel closed before getting message")
+				return PongMessage{}, extensions.ErrSubscriptionCanceled
+			}
+
+			// Acknowledge the message as it is only read by this function
+			if err := brokerMsg.Ack(); err != nil {
+				c.logger.Error(ctx, err.Error())
+			}
+
+			// Get new message
+			msg, err := newPongMessageFromBrokerMessage(brokerMsg)
+			if err != nil {
+				c.logger.Error(ctx, err.Error())
+			}
+
+			// If message doesn't have corresponding correlation ID, then continue
+			if publishMsg.CorrelationID() != msg.CorrelationID() {
+				continue
+			}
+
+			// Set context with received values as it is the expected message
+			msgCtx := context.WithValue(ctx, extensions.ContextKeyIsBrokerMessage, brokerMsg.String())
+			msgCtx = context.WithValue(msgCtx, extensions.ContextKeyIsDirection, "reception")
+			msgCtx = context.WithValue(msgCtx, extensions.ContextKeyIsCorrelationID, publishMsg.CorrelationID())
+
+			// Execute middlewares before returning
+			if err := c.executeMiddlewares(msgCtx, &brokerMsg, nil); err != nil {
+				return PongMessage{}, err
+			}
+
+			// Return the message to the caller from the broker that could have
+			// been modified by middlewares
+			return newPongMessageFromBrokerMessage(brokerMsg)
+		case <-ctx.Done(): // Set corrsponding error if context is done
+			c.logger.Error(ctx, "Context done before getting message")
+			return PongMessage{}, extensions.ErrContextCanceled
+		}
+	}
+}
+
+// AsyncAPIVersion is the version of the used AsyncAPI document
+const AsyncAPIVersion = "1.0.0"
+
+// controller is the controller that will be used to communicate with the broker
+// It will be used internally by AppController and UserController
+type controller struct {
+	// broker is the broker controller that will be used to communicate
+	broker extensions.BrokerController
+	// subscriptions is a map of all subscriptions
+	subscriptions map[string]extensions.BrokerChannelSubscription
+	// subscriptionsMutex protects the subscriptions and replies maps, as
+	// subscriptions can be made and cancelled from different goroutines
+	subscriptionsMutex *sync.Mutex
+	// replies is a map of the reply dispatchers, by reply channel
+	replies map[string]*extensions.ReplyDispatcher
+	// requestTimeout is the maximum duration to wait for a reply to a request
+	requestTimeout time.Duration
+	// logger is the logger that will be used² to log operations on controller
+	logger extensions.Logger
+	// middlewares are the middlewares that will be executed when sending or
+	// receiving messages
+	middlewares []extensions.Middleware
+	// concurrency is the maximum number of messages handled simultaneously by
+	// each subscription
+	concurrency int
+	// orderingKey is the function returning the key of received messages, in
+	// order to handle messages with the same key in order
+	orderingKey extensions.OrderingKey
+	// tracker tracks the received messages being handled, in order to wait for
+	// them on shutdown
+	tracker *extensions.HandlersTracker
+}
+
+// ControllerOption is the type of the options that can be passed
+// when creating a new Controller
+type ControllerOption func(controller *controller)
+
+// WithLogger attaches a logger to the controller
+func WithLogger(logger extensions.Logger) ControllerOption {
+	return func(controller *controller) {
+		controller.logger = logger
+	}
+}
+
+// WithMiddlewares attaches middlewares that will be executed when sending or receiving messages
+func WithMiddlewares(middlewares ...extensions.Middleware) ControllerOption {
+	return func(controller *controller) {
+		controller.middlewares = middlewares
+	}
+}
+
+// WithConcurrency sets the maximum number of messages handled simultaneously by
+// each subscription. Default is 1, meaning that messages are handled one by one.
+func WithConcurrency(workers int) ControllerOption {
+	return func(controller *controller) {
+		controller.concurrency = workers
+	}
+}
+
+// WithOrderingKey sets the function returning the key of received messages:
+// messages with the same key are handled in order, while messages with
+// different keys can be handled concurrently (see WithConcurrency).
+func WithOrderingKey(key extensions.OrderingKey) ControllerOption {
+	return func(controller *controller) {
+		controller.orderingKey = key
+	}
+}
+
+// WithRequestTimeout sets the maximum duration to wait for the reply of a
+// request. Default is 0, meaning that it waits until the context is done.
+func WithRequestTimeout(timeout time.Duration) ControllerOption {
+	return func(controller *controller) {
+		controller.requestTimeout = timeout
+	}
+}
+
+type MessageWithCorrelationID interface {
+	CorrelationID() string
+	SetCorrelationID(id string)
+}
+
+type Error struct {
+	Channel string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("channel %q: err %v", e.Channel, e.Err)
+}
+
+// PingParameters represents PingId channel parameters
+type PingParameters struct {
+	Id string
+}
+
+// PingMessage is the message expected for 'Ping' channel
+type PingMessage struct {
+	// Headers will be used to fill the message headers
+	Headers struct {
+		CorrelationId *string `json:"correlationId"`
+	}
+
+	// Payload will be inserted in the message payload
+	Payload string
+}
+
+func NewPingMessage() PingMessage {
+	var msg PingMessage
+
+	// Set correlation ID
+	u := uuid.New().String()
+	msg.Headers.CorrelationId = &u
+
+	return msg
+}
+
+// newPingMessageFromBrokerMessage will fill a new PingMessage with data from generic broker message
+func newPingMessageFromBrokerMessage(bMsg extensions.BrokerMessage) (PingMessage, error) {
+	var msg PingMessage
+
+	// Convert to string
+	payload := string(bMsg.Payload)
+	msg.Payload = payload // No need for type conversion to reference
+
+	// Get each headers from broker message
+	for k, v := range bMsg.Headers {
+		switch {
+		case k == "correlationId": // Retrieving CorrelationId header
+			h := string(v)
+			msg.Headers.CorrelationId = &h
+		default:
+			// TODO: log unknown error
+		}
+	}
+
+	// TODO: run checks on msg type
+
+	return msg, nil
+}
+
+// toBrokerMessage will generate a generic broker message from PingMessage data
+func (msg PingMessage) toBrokerMessage() (extensions.BrokerMessage, error) {
+	// TODO: implement checks on message
+
+	// Convert to []byte
+	payload := []byte(msg.Payload)
+
+	// Add each headers to broker message
+	headers := make(map[string][]byte, 1)
+
+	// Adding CorrelationId header
+	if msg.Headers.CorrelationId != nil {
+		headers["correlationId"] = []byte(*msg.Headers.CorrelationId)
+	}
+
+	return extensions.BrokerMessage{
+		Headers: headers,
+		Payload: payload,
+	}, nil
+}
+
+// CorrelationID will give the correlation ID of the message, based on AsyncAPI spec
+func (msg PingMessage) CorrelationID() string {
+	if msg.Headers.CorrelationId != nil {
+		return *msg.Headers.CorrelationId
+	}
+
+	return ""
+}
+
+// SetCorrelationID will set the correlation ID of the message, based on AsyncAPI spec
+func (msg *PingMessage) SetCorrelationID(id string) {
+	msg.Headers.CorrelationId = &id
+}
+
+// SetAsResponseFrom will correlate the message with the one passed in parameter.
+// It will assign the 'req' message correlation ID to the message correlation ID,
+// both specified in AsyncAPI spec.
+func (msg *PingMessage) SetAsResponseFrom(req MessageWithCorrelationID) {
+	id := req.CorrelationID()
+	msg.Headers.CorrelationId = &id
+}
+
+// PongMessage is the message expected for 'Pong' channel
+type PongMessage struct {
+	// Headers will be used to fill the message headers
+	Headers struct {
+		CorrelationId *string `json:"correlationId"`
+	}
+
+	// Payload will be inserted in the message payload
+	Payload string
+}
+
+func NewPongMessage() PongMessage {
+	var msg PongMessage
+
+	// Set correlation ID
+	u := uuid.New().String()
+	msg.Headers.CorrelationId = &u
+
+	return msg
+}
+
+// newPongMessageFromBrokerMessage will fill a new PongMessage with data from generic broker message
+func newPongMessageFromBrokerMessage(bMsg extensions.BrokerMessage) (PongMessage, error) {
+	var msg PongMessage
+
+	// Convert to string
+	payload := string(bMsg.Payload)
+	msg.Payload = payload // No need for type conversion to reference
+
+	// Get each headers from broker message
+	for k, v := range bMsg.Headers {
+		switch {
+		case k == "correlationId": // Retrieving CorrelationId header
+			h := string(v)
+			msg.Headers.CorrelationId = &h
+		default:
+			// TODO: log unknown error
+		}
+	}
+
+	// TODO: run checks on msg type
+
+	return msg, nil
+}
+
+// toBrokerMessage will generate a generic broker message from PongMessage data
+func (msg PongMessage) toBrokerMessage() (extensions.BrokerMessage, error) {
+	// TODO: implement checks on message
+
+	// Convert to []byte
+	payload := []byte(msg.Payload)
+
+	// Add each headers to broker message
+	headers := make(map[string][]byte, 1)
+
+	// Adding CorrelationId header
+	if msg.Headers.CorrelationId != nil {
+		headers["correlationId"] = []byte(*msg.Headers.CorrelationId)
+	}
+
+	return extensions.BrokerMessage{
+		Headers: headers,
+		Payload: payload,
+	}, nil
+}
+
+// CorrelationID will give the correlation ID of the message, based on AsyncAPI spec
+func (msg PongMessage) CorrelationID() string {
+	if msg.Headers.CorrelationId != nil {
+		return *msg.Headers.CorrelationId
+	}
+
+	return ""
+}
+
+// SetCorrelationID will set the correlation ID of the message, based on AsyncAPI spec
+func (msg *PongMessage) SetCorrelationID(id string) {
+	msg.Headers.CorrelationId = &id
+}
+
+// SetAsResponseFrom will correlate the message with the one passed in parameter.
+// It will assign the 'req' message correlation ID to the message correlation ID,
+// both specified in AsyncAPI spec.
+func (msg *PongMessage) SetAsResponseFrom(req MessageWithCorrelationID) {
+	id := req.CorrelationID()
+	msg.Headers.CorrelationId = &id
+}
+
+// MockTestingT is the interface used by mocks to report unmet expectations.
+// It is implemented by *testing.T.
+type MockTestingT interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// MockExpectation is an expectation on the calls of a mock method.
+type MockExpectation struct {
+	mutex  *sync.Mutex
+	method string
+	match  func(call any) bool
+	times  int
+	calls  int
+}
+
+// Times sets the exact number of matching calls that are expected.
+// By default, at least one matching call is expected.
+func (e *MockExpectation) Times(n int) *MockExpectation {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.times = n
+	return e
+}
+
+// mockRecorder records the calls of a mock and checks its expectations
+type mockRecorder struct {
+	mutex        sync.Mutex
+	calls        map[string][]any
+	expectations []*MockExpectation
+}
+
+func (r *mockRecorder) record(method string, call any) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// Record call
+	if r.calls == nil {
+		r.calls = make(map[string][]any)
+	}
+	r.calls[method] = append(r.calls[method], call)
+
+	// Update matching expectations
+	for _, e := range r.expectations {
+		if e.method == method && e.match(call) {
+			e.calls++
+		}
+	}
+}
+
+func (r *mockRecorder) recordedCalls(method string) []any {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]any(nil), r.calls[method]...)
+}
+
+func (r *mockRecorder) expect(method string, match func(call any) bool) *MockExpectation {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	e := &MockExpectation{mutex: &r.mutex, method: method, match: match, times: -1}
+	r.expectations = append(r.expectations, e)
+	return e
+}
+
+// AssertExpectations checks that every expectation set on the mock is met,
+// and reports the unmet ones to t.
+func (r *mockRecorder) AssertExpectations(t MockTestingT) bool {
+	t.Helper()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	ok := true
+	for _, e := range r.expectations {
+		switch {
+		case e.times < 0 && e.calls == 0:
+			t.Errorf("expected a matching call to %s, but there was none", e.method)
+		case e.times >= 0 && e.calls != e.times:
+			t.Errorf("expected %d matching call(s) to %s, but there was %d", e.times, e.method, e.calls)
+		default:
+			continue
+		}
+		ok = false
+	}
+
+	return ok
+}
+
+// AppControllerMock is a mock implementation of AppControllerInterface that records calls,
+// returns stubbed values and checks expectations.
+type AppControllerMock struct {
+	mockRecorder
+
+	// CloseFunc is called by Close if it is set
+	CloseFunc func(ctx context.Context)
+	// ShutdownFunc is called by Shutdown if it is set
+	ShutdownFunc func(ctx context.Context) error
+	// SubscribeAllFunc is called by SubscribeAll if it is set
+	SubscribeAllFunc func(ctx context.Context, as AppSubscriber) error
+	// UnsubscribeAllFunc is called by UnsubscribeAll if it is set
+	UnsubscribeAllFunc func(ctx context.Context)
+	// SubscribePingFunc is called by SubscribePing if it is set
+	SubscribePingFunc func(ctx context.Context, params PingParameters, fn func(ctx context.Context, msg PingMessage) error) error
+	// UnsubscribePingFunc is called by UnsubscribePing if it is set
+	UnsubscribePingFunc func(ctx context.Context, params PingParameters)
+	// PublishPongFunc is called by PublishPong if it is set
+	PublishPongFunc func(ctx context.Context, msg PongMessage) error
+}
+
+var _ AppControllerInterface = (*AppControllerMock)(nil)
+
+// AppControllerMockCloseCall is a call to AppControllerMock.Close
+type AppControllerMockCloseCall struct {
+	Ctx context.Context
+}
+
+// Close records the call and calls CloseFunc if it is set.
+func (m *AppControllerMock) Close(ctx context.Context) {
+	m.record("Close", AppControllerMockCloseCall{
+		Ctx: ctx,
+	})
+
+	if m.CloseFunc != nil {
+		m.CloseFunc(ctx)
+	}
+}
+
+// CloseCalls returns the recorded calls to Close.
+func (m *AppControllerMock) CloseCalls() []AppControllerMockCloseCall {
+	recorded := m.recordedCalls("Close")
+	calls := make([]AppControllerMockCloseCall, len(recorded))
+	for i, c := range recorded {
+		calls[i] = c.(AppControllerMockCloseCall)
+	}
+	return calls
+}
+
+// ExpectClose expects Close to be called with arguments matching
+// the given function, or with any arguments if it is nil.
+func (m *AppControllerMock) ExpectClose(match func(call AppControllerMockCloseCall) bool) *MockExpectation {
+	return m.expect("Close", func(call any) bool {
+		return match == nil || match(call.(AppControllerMockCloseCall))
+	})
+}
+
+// AppControllerMockShutdownCall is a call to AppControllerMock.Shutdown
+type AppControllerMockShutdownCall struct {
+	Ctx context.Context
+}
+
+// Shutdown records the call and calls ShutdownFunc if it is set.
+func (m *AppControllerMock) Shutdown(ctx context.Context) error {
+	m.record("Shutdown", AppControllerMockShutdownCall{
+		Ctx: ctx,
+	})
+
+	if m.ShutdownFunc != nil {
+		return m.ShutdownFunc(ctx)
+	}
+
+	return nil
+}
+
+// ShutdownCalls returns the recorded calls to Shutdown.
+func (m *AppControllerMock) ShutdownCalls() []AppControllerMockShutdownCall {
+	recorded := m.recordedCalls("Shutdown")
+	calls := make([]AppControllerMockShutdownCall, len(recorded))
+	for i, c := range recorded {
+		calls[i] = c.(AppControllerMockShutdownCall)
+	}
+	return calls
+}
+
+// ExpectShutdown expects Shutdown to be called with arguments matching
+// the given function, or with any arguments if it is nil.
+func (m *AppControllerMock) ExpectShutdown(match func(call AppControllerMockShutdownCall) bool) *MockExpectation {
+	return m.expect("Shutdown", func(call any) bool {
+		return match == nil || match(call.(AppControllerMockShutdownCall))
+	})
+}
+
+// AppControllerMockSubscribeAllCall is a call to AppControllerMock.SubscribeAll
+type AppControllerMockSubscribeAllCall struct {
+	Ctx context.Context
+	As  AppSubscriber
+}
+
+// SubscribeAll records the call and calls SubscribeAllFunc if it is set.
+func (m *AppControllerMock) SubscribeAll(ctx context.Context, as AppSubscriber) error {
+	m.record("SubscribeAll", AppControllerMockSubscribeAllCall{
+		Ctx: ctx,
+		As:  as,
+	})
+
+	if m.SubscribeAllFunc != nil {
+		return m.SubscribeAllFunc(ctx, as)
+	}
+
+	return nil
+}
+
+// SubscribeAllCalls returns the recorded calls to SubscribeAll.
+func (m *AppControllerMock) SubscribeAllCalls() []AppControllerMockSubscribeAllCall {
+	recorded := m.recordedCalls("SubscribeAll")
+	calls := make([]AppControllerMockSubscribeAllCall, len(recorded))
+	for i, c := range recorded {
+		calls[i] = c.(AppControllerMockSubscribeAllCall)
+	}
+	return calls
+}
+
+// ExpectSubscribeAll expects SubscribeAll to be called with arguments matching
+// the given function, or with any arguments if it is nil.
+func (m *AppControllerMock) ExpectSubscribeAll(match func(call AppControllerMockSubscribeAllCall) bool) *MockExpectation {
+	return m.expect("SubscribeAll", func(call any) bool {
+		return match == nil || match(call.(AppControllerMockSubscribeAllCall))
+	})
+}
+
+// AppControllerMockUnsubscribeAllCall is a call to AppControllerMock.UnsubscribeAll
+type AppControllerMockUnsubscribeAllCall struct {
+	Ctx context.Context
+}
+
+// UnsubscribeAll records the call and calls UnsubscribeAllFunc if it is set.
+func (m *AppControllerMock) UnsubscribeAll(ctx context.Context) {
+	m.record("UnsubscribeAll", AppControllerMockUnsubscribeAllCall{
+		Ctx: ctx,
+	})
+
+	if m.UnsubscribeAllFunc != nil {
+		m.UnsubscribeAllFunc(ctx)
+	}
+}
+
+// UnsubscribeAllCalls returns the recorded calls to UnsubscribeAll.
+func (m *AppControllerMock) UnsubscribeAllCalls() []AppControllerMockUnsubscribeAllCall {
+	recorded := m.recordedCalls("UnsubscribeAll")
+	calls := make([]AppControllerMockUnsubscribeAllCall, len(recorded))
+	for i, c := range recorded {
+		calls[i] = c.(AppControllerMockUnsubscribeAllCall)
+	}
+	return calls
+}
+
+// ExpectUnsubscribeAll expects UnsubscribeAll to be called with arguments matching
+// the given function, or with any arguments if it is nil.
+func (m *AppControllerMock) ExpectUnsubscribeAll(match func(call AppControllerMockUnsubscribeAllCall) bool) *MockExpectation {
+	return m.expect("UnsubscribeAll", func(call any) bool {
+		return match == nil || match(call.(AppControllerMockUnsubscribeAllCall))
+	})
+}
+
+// AppControllerMockSubscribePingCall is a call to AppControllerMock.SubscribePing
+type AppControllerMockSubscribePingCall struct {
+	Ctx    context.Context
+	Params PingParameters
+	Fn     func(ctx context.Context, msg PingMessage) error
+}
+
+// SubscribePing records the call and calls SubscribePingFunc if it is set.
+func (m *AppControllerMock) SubscribePing(ctx context.Context, params PingParameters, fn func(ctx context.Context, msg PingMessage) error) error {
+	m.record("SubscribePing", AppControllerMockSubscribePingCall{
+		Ctx:    ctx,
+		Params: params,
+		Fn:     fn,
+	})
+
+	if m.SubscribePingFunc != nil {
+		return m.SubscribePingFunc(ctx, params, fn)
+	}
+
+	return nil
+}
+
+// SubscribePingCalls returns the recorded calls to SubscribePing.
+func (m *AppControllerMock) SubscribePingCalls() []AppControllerMockSubscribePingCall {
+	recorded := m.recordedCalls("SubscribePing")
+	calls := make([]AppControllerMockSubscribePingCall, len(recorded))
+	for i, c := range recorded {
+		calls[i] = c.(AppControllerMockSubscribePingCall)
+	}
+	return calls
+}
+
+// ExpectSubscribePing expects SubscribePing to be called with arguments matching
+// the given function, or with any arguments if it is nil.
+func (m *AppControllerMock) ExpectSubscribePing(match func(call AppControllerMockSubscribePingCall) bool) *MockExpectation {
+	return m.expect("SubscribePing", func(call any) bool {
+		return match == nil || match(call.(AppControllerMockSubscribePingCall))
+	})
+}
+
+// AppControllerMockUnsubscribePingCall is a call to AppControllerMock.UnsubscribePing
+type AppControllerMockUnsubscribePingCall struct {
+	Ctx    context.Context
+	Params PingParameters
+}
+
+// UnsubscribePing records the call and calls UnsubscribePingFunc if it is set.
+func (m *AppControllerMock) UnsubscribePing(ctx context.Context, params PingParameters) {
+	m.record("UnsubscribePing", AppControllerMockUnsubscribePingCall{
+		Ctx:    ctx,
+		Params: params,
+	})
+
+	if m.UnsubscribePingFunc != nil {
+		m.UnsubscribePingFunc(ctx, params)
+	}
+}
+
+// UnsubscribePingCalls returns the recorded calls to UnsubscribePing.
+func (m *AppControllerMock) UnsubscribePingCalls() []AppControllerMockUnsubscribePingCall {
+	recorded := m.recordedCalls("UnsubscribePing")
+	calls := make([]AppControllerMockUnsubscribePingCall, len(recorded))
+	for i, c := range recorded {
+		calls[i] = c.(AppControllerMockUnsubscribePingCall)
+	}
+	return calls
+}
+
+// ExpectUnsubscribePing expects UnsubscribePing to be called with arguments matching
+// the given function, or with any arguments if it is nil.
+func (m *AppControllerMock) ExpectUnsubscribePing(match func(call AppControllerMockUnsubscribePingCall) bool) *MockExpectation {
+	return m.expect("UnsubscribePing", func(call any) bool {
+		return match == nil || match(call.(AppControllerMockUnsubscribePingCall))
+	})
+}
+
+// AppControllerMockPublishPongCall is a call to AppControllerMock.PublishPong
+type AppControllerMockPublishPongCall struct {
+	Ctx context.Context
+	Msg PongMessage
+}
+
+// PublishPong records the call and calls PublishPongFunc if it is set.
+func (m *AppControllerMock) PublishPong(ctx context.Context, msg PongMessage) error {
+	m.record("PublishPong", AppControllerMockPublishPongCall{
+		Ctx: ctx,
+		Msg: msg,
+	})
+
+	if m.PublishPongFunc != nil {
+		return m.PublishPongFunc(ctx, msg)
+	}
+
+	return nil
+}
+
+// PublishPongCalls returns the recorded calls to PublishPong.
+func (m *AppControllerMock) PublishPongCalls() []AppControllerMockPublishPongCall {
+	recorded := m.recordedCalls("PublishPong")
+	calls := make([]AppControllerMockPublishPongCall, len(recorded))
+	for i, c := range recorded {
+		calls[i] = c.(AppControllerMockPublishPongCall)
+	}
+	return calls
+}
+
+// ExpectPublishPong expects PublishPong to be called with arguments matching
+// the given function, or with any arguments if it is nil.
+func (m *AppControllerMock) ExpectPublishPong(match func(call AppControllerMockPublishPongCall) bool) *MockExpectation {
+	return m.expect("PublishPong", func(call any) bool {
+		return match == nil || match(call.(AppControllerMockPublishPongCall))
+	})
+}
+
+// AppSubscriberMock is a mock implementation of AppSubscriber that records calls,
+// returns stubbed values and checks expectations.
+type AppSubscriberMock struct {
+	mockRecorder
+
+	// PingFunc is called by Ping if it is set
+	PingFunc func(ctx context.Context, msg PingMessage) error
+}
+
+var _ AppSubscriber = (*AppSubscriberMock)(nil)
+
+// AppSubscriberMockPingCall is a call to AppSubscriberMock.Ping
+type AppSubscriberMockPingCall struct {
+	Ctx context.Context
+	Msg PingMessage
+}
+
+// Ping records the call and calls PingFunc if it is set.
+func (m *AppSubscriberMock) Ping(ctx context.Context, msg PingMessage) error {
+	m.record("Ping", AppSubscriberMockPingCall{
+		Ctx: ctx,
+		Msg: msg,
+	})
+
+	if m.PingFunc != nil {
+		return m.PingFunc(ctx, msg)
+	}
+
+	return nil
+}
+
+// PingCalls returns the recorded calls to Ping.
+func (m *AppSubscriberMock) PingCalls() []AppSubscriberMockPingCall {
+	recorded := m.recordedCalls("Ping")
+	calls := make([]AppSubscriberMockPingCall, len(recorded))
+	for i, c := range recorded {
+		calls[i] = c.(AppSubscriberMockPingCall)
+	}
+	return calls
+}
+
+// ExpectPing expects Ping to be called with arguments matching
+// the given function, or with any arguments if it is nil.
+func (m *AppSubscriberMock) ExpectPing(match func(call AppSubscriberMockPingCall) bool) *MockExpectation {
+	return m.expect("Ping", func(call any) bool {
+		return match == nil || match(call.(AppSubscriberMockPingCall))
+	})
+}
+
+// UserControllerMock is a mock implementation of UserControllerInterface that records calls,
+// returns stubbed values and checks expectations.
+type UserControllerMock struct {
+	mockRecorder
+
+	// CloseFunc is called by Close if it is set
+	CloseFunc func(ctx context.Context)
+	// ShutdownFunc is called by Shutdown if it is set
+	ShutdownFunc func(ctx context.Context) error
+	// SubscribeAllFunc is called by SubscribeAll if it is set
+	SubscribeAllFunc func(ctx context.Context, as UserSubscriber) error
+	// UnsubscribeAllFunc is called by UnsubscribeAll if it is set
+	UnsubscribeAllFunc func(ctx context.Context)
+	// SubscribePongFunc is called by SubscribePong if it is set
+	SubscribePongFunc func(ctx context.Context, fn func(ctx context.Context, msg PongMessage) error) error
+	// UnsubscribePongFunc is called by UnsubscribePong if it is set
+	UnsubscribePongFunc func(ctx context.Context)
+	// PublishPingFunc is called by PublishPing if it is set
+	PublishPingFunc func(ctx context.Context, params PingParameters, msg PingMessage) error
+	// WaitForPongFunc is called by WaitForPong if it is set
+	WaitForPongFunc func(ctx context.Context, publishMsg MessageWithCorrelationID, pub func(ctx context.Context) error) (PongMessage, error)
+}
+
+var _ UserControllerInterface = (*UserControllerMock)(nil)
+
+// UserControllerMockCloseCall is a call to UserControllerMock.Close
+type UserControllerMockCloseCall struct {
+	Ctx context.Context
+}
+
+// Close records the call and calls CloseFunc if it is set.
+func (m *UserControllerMock) Close(ctx context.Context) {
+	m.record("Close", UserControllerMockCloseCall{
+		Ctx: ctx,
+	})
+
+	if m.CloseFunc != nil {
+		m.CloseFunc(ctx)
+	}
+}
+
+// CloseCalls returns the recorded calls to Close.
+func (m *UserControllerMock) CloseCalls() []UserControllerMockCloseCall {
+	recorded := m.recordedCalls("Close")
+	calls := make([]UserControllerMockCloseCall, len(recorded))
+	for i, c := range recorded {
+		calls[i] = c.(UserControllerMockCloseCall)
+	}
+	return calls
+}
+
+// ExpectClose expects Close to be called with arguments matching
+// the given function, or with any arguments if it is nil.
+func (m *UserControllerMock) ExpectClose(match func(call UserControllerMockCloseCall) bool) *MockExpectation {
+	return m.expect("Close", func(call any) bool {
+		return match == nil || match(call.(UserControllerMockCloseCall))
+	})
+}
+
+// UserControllerMockShutdownCall is a call to UserControllerMock.Shutdown
+type UserControllerMockShutdownCall struct {
+	Ctx context.Context
+}
+
+// Shutdown records the call and calls ShutdownFunc if it is set.
+func (m *UserControllerMock) Shutdown(ctx context.Context) error {
+	m.record("Shutdown", UserControllerMockShutdownCall{
+		Ctx: ctx,
+	})
+
+	if m.ShutdownFunc != nil {
+		return m.ShutdownFunc(ctx)
+	}
+
+	return nil
+}
+
+// ShutdownCalls returns the recorded calls to Shutdown.
+func (m *UserControllerMock) ShutdownCalls() []UserControllerMockShutdownCall {
+	recorded := m.recordedCalls("Shutdown")
+	calls := make([]UserControllerMockShutdownCall, len(recorded))
+	for i, c := range recorded {
+		calls[i] = c.(UserControllerMockShutdownCall)
+	}
+	return calls
+}
+
+// ExpectShutdown expects Shutdown to be called with arguments matching
+// the given function, or with any arguments if it is nil.
+func (m *UserControllerMock) ExpectShutdown(match func(call UserControllerMockShutdownCall) bool) *MockExpectation {
+	return m.expect("Shutdown", func(call any) bool {
+		return match == nil || match(call.(UserControllerMockShutdownCall))
+	})
+}
+
+// UserControllerMockSubscribeAllCall is a call to UserControllerMock.SubscribeAll
+type UserControllerMockSubscribeAllCall struct {
+	Ctx context.Context
+	As  UserSubscriber
+}
+
+// SubscribeAll records the call and calls SubscribeAllFunc if it is set.
+func (m *UserControllerMock) SubscribeAll(ctx context.Context, as UserSubscriber) error {
+	m.record("SubscribeAll", UserControllerMockSubscribeAllCall{
+		Ctx: ctx,
+		As:  as,
+	})
+
+	if m.SubscribeAllFunc != nil {
+		return m.SubscribeAllFunc(ctx, as)
+	}
+
+	return nil
+}
+
+// SubscribeAllCalls returns the recorded calls to SubscribeAll.
+func (m *UserControllerMock) SubscribeAllCalls() []UserControllerMockSubscribeAllCall {
+	recorded := m.recordedCalls("SubscribeAll")
+	calls := make([]UserControllerMockSubscribeAllCall, len(recorded))
+	for i, c := range recorded {
+		calls[i] = c.(UserControllerMockSubscribeAllCall)
+	}
+	return calls
+}
+
+// ExpectSubscribeAll expects SubscribeAll to be called with arguments matching
+// the given function, or with any arguments if it is nil.
+func (m *UserControllerMock) ExpectSubscribeAll(match func(call UserControllerMockSubscribeAllCall) bool) *MockExpectation {
+	return m.expect("SubscribeAll", func(call any) bool {
+		return match == nil || match(call.(UserControllerMockSubscribeAllCall))
+	})
+}
+
+// UserControllerMockUnsubscribeAllCall is a call to UserControllerMock.UnsubscribeAll
+type UserControllerMockUnsubscribeAllCall struct {
+	Ctx context.Context
+}
+
+// UnsubscribeAll records the call and calls UnsubscribeAllFunc if it is set.
+func (m *UserControllerMock) UnsubscribeAll(ctx context.Context) {
+	m.record("UnsubscribeAll", UserControllerMockUnsubscribeAllCall{
+		Ctx: ctx,
+	})
+
+	if m.UnsubscribeAllFunc != nil {
+		m.UnsubscribeAllFunc(ctx)
+	}
+}
+
+// UnsubscribeAllCalls returns the recorded calls to UnsubscribeAll.
+func (m *UserControllerMock) UnsubscribeAllCalls() []UserControllerMockUnsubscribeAllCall {
+	recorded := m.recordedCalls("UnsubscribeAll")
+	calls := make([]UserControllerMockUnsubscribeAllCall, len(recorded))
+	for i, c := range recorded {
+		calls[i] = c.(UserControllerMockUnsubscribeAllCall)
+	}
+	return calls
+}
+
+// ExpectUnsubscribeAll expects UnsubscribeAll to be called with arguments matching
+// the given function, or with any arguments if it is nil.
+func (m *UserControllerMock) ExpectUnsubscribeAll(match func(call UserControllerMockUnsubscribeAllCall) bool) *MockExpectation {
+	return m.expect("UnsubscribeAll", func(call any) bool {
+		return match == nil || match(call.(UserControllerMockUnsubscribeAllCall))
+	})
+}
+
+// UserControllerMockSubscribePongCall is a call to UserControllerMock.SubscribePong
+type UserControllerMockSubscribePongCall struct {
+	Ctx context.Context
+	Fn  func(ctx context.Context, msg PongMessage) error
+}
+
+// SubscribePong records the call and calls SubscribePongFunc if it is set.
+func (m *UserControllerMock) SubscribePong(ctx context.Context, fn func(ctx context.Context, msg PongMessage) error) error {
+	m.record("SubscribePong", UserControllerMockSubscribePongCall{
+		Ctx: ctx,
+		Fn:  fn,
+	})
+
+	if m.SubscribePongFunc != nil {
+		return m.SubscribePongFunc(ctx, fn)
+	}
+
+	return nil
+}
+
+// SubscribePongCalls returns the recorded calls to SubscribePong.
+func (m *UserControllerMock) SubscribePongCalls() []UserControllerMockSubscribePongCall {
+	recorded := m.recordedCalls("SubscribePong")
+	calls := make([]UserControllerMockSubscribePongCall, len(recorded))
+	for i, c := range recorded {
+		calls[i] = c.(UserControllerMockSubscribePongCall)
+	}
+	return calls
+}
+
+// ExpectSubscribePong expects SubscribePong to be called with arguments matching
+// the given function, or with any arguments if it is nil.
+func (m *UserControllerMock) ExpectSubscribePong(match func(call UserControllerMockSubscribePongCall) bool) *MockExpectation {
+	return m.expect("SubscribePong", func(call any) bool {
+		return match == nil || match(call.(UserControllerMockSubscribePongCall))
+	})
+}
+
+// UserControllerMockUnsubscribePongCall is a call to UserControllerMock.UnsubscribePong
+type UserControllerMockUnsubscribePongCall struct {
+	Ctx context.Context
+}
+
+// UnsubscribePong records the call and calls UnsubscribePongFunc if it is set.
+func (m *UserControllerMock) UnsubscribePong(ctx context.Context) {
+	m.record("UnsubscribePong", UserControllerMockUnsubscribePongCall{
+		Ctx: ctx,
+	})
+
+	if m.UnsubscribePongFunc != nil {
+		m.UnsubscribePongFunc(ctx)
+	}
+}
+
+// UnsubscribePongCalls returns the recorded calls to UnsubscribePong.
+func (m *UserControllerMock) UnsubscribePongCalls() []UserControllerMockUnsubscribePongCall {
+	recorded := m.recordedCalls("UnsubscribePong")
+	calls := make([]UserControllerMockUnsubscribePongCall, len(recorded))
+	for i, c := range recorded {
+		calls[i] = c.(UserControllerMockUnsubscribePongCall)
+	}
+	return calls
+}
+
+// ExpectUnsubscribePong expects UnsubscribePong to be called with arguments matching
+// the given function, or with any arguments if it is nil.
+func (m *UserControllerMock) ExpectUnsubscribePong(match func(call UserControllerMockUnsubscribePongCall) bool) *MockExpectation {
+	return m.expect("UnsubscribePong", func(call any) bool {
+		return match == nil || match(call.(UserControllerMockUnsubscribePongCall))
+	})
+}
+
+// UserControllerMockPublishPingCall is a call to UserControllerMock.PublishPing
+type UserControllerMockPublishPingCall struct {
+	Ctx    context.Context
+	Params PingParameters
+	Msg    PingMessage
+}
+
+// PublishPing records the call and calls PublishPingFunc if it is set.
+func (m *UserControllerMock) PublishPing(ctx context.Context, params PingParameters, msg PingMessage) error {
+	m.record("PublishPing", UserControllerMockPublishPingCall{
+		Ctx:    ctx,
+		Params: params,
+		Msg:    msg,
+	})
+
+	if m.PublishPingFunc != nil {
+		return m.PublishPingFunc(ctx, params, msg)
+	}
+
+	return nil
+}
+
+// PublishPingCalls returns the recorded calls to PublishPing.
+func (m *UserControllerMock) PublishPingCalls() []UserControllerMockPublishPingCall {
+	recorded := m.recordedCalls("PublishPing")
+	calls := make([]UserControllerMockPublishPingCall, len(recorded))
+	for i, c := range recorded {
+		calls[i] = c.(UserControllerMockPublishPingCall)
+	}
+	return calls
+}
+
+// ExpectPublishPing expects PublishPing to be called with arguments matching
+// the given function, or with any arguments if it is nil.
+func (m *UserControllerMock) ExpectPublishPing(match func(call UserControllerMockPublishPingCall) bool) *MockExpectation {
+	return m.expect("PublishPing", func(call any) bool {
+		return match == nil || match(call.(UserControllerMockPublishPingCall))
+	})
+}
+
+// UserControllerMockWaitForPongCall is a call to UserControllerMock.WaitForPong
+type UserControllerMockWaitForPongCall struct {
+	Ctx        context.Context
+	PublishMsg MessageWithCorrelationID
+	Pub        func(ctx context.Context) error
+}
+
+// WaitForPong records the call and calls WaitForPongFunc if it is set.
+func (m *UserControllerMock) WaitForPong(ctx context.Context, publishMsg MessageWithCorrelationID, pub func(ctx context.Context) error) (PongMessage, error) {
+	m.record("WaitForPong", UserControllerMockWaitForPongCall{
+		Ctx:        ctx,
+		PublishMsg: publishMsg,
+		Pub:        pub,
+	})
+
+	if m.WaitForPongFunc != nil {
+		return m.WaitForPongFunc(ctx, publishMsg, pub)
+	}
+
+	return PongMessage{}, nil
+}
+
+// WaitForPongCalls returns the recorded calls to WaitForPong.
+func (m *UserControllerMock) WaitForPongCalls() []UserControllerMockWaitForPongCall {
+	recorded := m.recordedCalls("WaitForPong")
+	calls := make([]UserControllerMockWaitForPongCall, len(recorded))
+	for i, c := range recorded {
+		calls[i] = c.(UserControllerMockWaitForPongCall)
+	}
+	return calls
+}
+
+// ExpectWaitForPong expects WaitForPong to be called with arguments matching
+// the given function, or with any arguments if it is nil.
+func (m *UserControllerMock) ExpectWaitForPong(match func(call UserControllerMockWaitForPongCall) bool) *MockExpectation {
+	return m.expect("WaitForPong", func(call any) bool {
+		return match == nil || match(call.(UserControllerMockWaitForPongCall))
+	})
+}
+
+// UserSubscriberMock is a mock implementation of UserSubscriber that records calls,
+// returns stubbed values and checks expectations.
+type UserSubscriberMock struct {
+	mockRecorder
+
+	// PongFunc is called by Pong if it is set
+	PongFunc func(ctx context.Context, msg PongMessage) error
+}
+
+var _ UserSubscriber = (*UserSubscriberMock)(nil)
+
+// UserSubscriberMockPongCall is a call to UserSubscriberMock.Pong
+type UserSubscriberMockPongCall struct {
+	Ctx context.Context
+	Msg PongMessage
+}
+
+// Pong records the call and calls PongFunc if it is set.
+func (m *UserSubscriberMock) Pong(ctx context.Context, msg PongMessage) error {
+	m.record("Pong", UserSubscriberMockPongCall{
+		Ctx: ctx,
+		Msg: msg,
+	})
+
+	if m.PongFunc != nil {
+		return m.PongFunc(ctx, msg)
+	}
+
+	return nil
+}
+
+// PongCalls returns the recorded calls to Pong.
+func (m *UserSubscriberMock) PongCalls() []UserSubscriberMockPongCall {
+	recorded := m.recordedCalls("Pong")
+	calls := make([]UserSubscriberMockPongCall, len(recorded))
+	for i, c := range recorded {
+		calls[i] = c.(UserSubscriberMockPongCall)
+	}
+	return calls
+}
+
+// ExpectPong expects Pong to be called with arguments matching
+// the given function, or with any arguments if it is nil.
+func (m *UserSubscriberMock) ExpectPong(match func(call UserSubscriberMockPongCall) bool) *MockExpectation {
+	return m.expect("Pong", func(call any) bool {
+		return match == nil || match(call.(UserSubscriberMockPongCall))
+	})
+}
@@ -0,0 +1,111 @@
+//go:generate go run ../../cmd/asyncapi-codegen -g application,user,types,mocks -p mocks -i ./asyncapi.yaml -o ./asyncapi.gen.go
+
+package mocks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(Suite))
+}
+
+type Suite struct {
+	suite.Suite
+}
+
+// pingService is a service that depends on the controller interface.
+type pingService struct {
+	controller AppControllerInterface
+}
+
+func (s pingService) Ping(ctx context.Context, _ PingMessage) error {
+	msg := NewPongMessage()
+	msg.Payload = "pong"
+	return s.controller.PublishPong(ctx, msg)
+}
+
+// testingT records errors reported by mocks assertions.
+type testingT struct {
+	errors []string
+}
+
+func (t *testingT) Helper() {}
+
+func (t *testingT) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func (suite *Suite) TestControllerMock() {
+	ctrl := &AppControllerMock{}
+	expectation := ctrl.ExpectPublishPong(func(call AppControllerMockPublishPongCall) bool {
+		return call.Msg.Payload == "pong"
+	})
+
+	// Use the mock through the service
+	suite.Require().NoError(pingService{controller: ctrl}.Ping(context.Background(), NewPingMessage()))
+
+	// Check recorded calls and expectations
+	suite.Require().Len(ctrl.PublishPongCalls(), 1)
+	suite.Require().Equal("pong", ctrl.PublishPongCalls()[0].Msg.Payload)
+	suite.Require().True(ctrl.AssertExpectations(suite.T()))
+
+	// Check that expectation count is checked
+	expectation.Times(2)
+	t := &testingT{}
+	suite.Require().False(ctrl.AssertExpectations(t))
+	suite.Require().Equal([]string{"expected 2 matching call(s) to PublishPong, but there was 1"}, t.errors)
+}
+
+func (suite *Suite) TestControllerMockWithStubs() {
+	expectedErr := errors.New("error")
+	ctrl := &UserControllerMock{
+		PublishPingFunc: func(_ context.Context, _ PingParameters, _ PingMessage) error {
+			return expectedErr
+		},
+	}
+
+	// Check stubbed and default returns
+	suite.Require().ErrorIs(ctrl.PublishPing(context.Background(), PingParameters{Id: "1"}, NewPingMessage()), expectedErr)
+	suite.Require().NoError(ctrl.SubscribePong(context.Background(), func(_ context.Context, _ PongMessage) error { return nil }))
+
+	// Check recorded parameters
+	suite.Require().Equal("1", ctrl.PublishPingCalls()[0].Params.Id)
+
+	// Check stubbed wait for
+	ctrl.WaitForPongFunc = func(ctx context.Context, publishMsg MessageWithCorrelationID, pub func(ctx context.Context) error) (PongMessage, error) {
+		msg := NewPongMessage()
+		msg.SetAsResponseFrom(publishMsg)
+		return msg, pub(ctx)
+	}
+	req := NewPingMessage()
+	resp, err := ctrl.WaitForPong(context.Background(), &req, func(ctx context.Context) error {
+		return ctrl.PublishPing(ctx, PingParameters{Id: "2"}, req)
+	})
+	suite.Require().ErrorIs(err, expectedErr)
+	suite.Require().Equal(req.CorrelationID(), resp.CorrelationID())
+	suite.Require().Len(ctrl.PublishPingCalls(), 2)
+}
+
+func (suite *Suite) TestSubscriberMock() {
+	sub := &AppSubscriberMock{}
+	sub.ExpectPing(nil).Times(1)
+
+	// Check unmet expectation
+	t := &testingT{}
+	suite.Require().False(sub.AssertExpectations(t))
+	suite.Require().Equal([]string{"expected 1 matching call(s) to Ping, but there was 0"}, t.errors)
+
+	// Subscribe the mock through a controller mock and deliver a message
+	ctrl := &AppControllerMock{}
+	suite.Require().NoError(ctrl.SubscribeAll(context.Background(), sub))
+	suite.Require().NoError(ctrl.SubscribeAllCalls()[0].As.Ping(context.Background(), NewPingMessage()))
+
+	suite.Require().Len(sub.PingCalls(), 1)
+	suite.Require().True(sub.AssertExpectations(suite.T()))
+}